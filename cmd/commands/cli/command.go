@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -123,6 +124,7 @@ func (c *cliApp) handleActions(line string) {
 		{"status", c.status},
 		{"healthcheck", c.healthcheck},
 		{"ip", c.ip},
+		{"diagnostics", c.diagnostics},
 		{"disconnect", c.disconnect},
 		{"stop", c.stopClient},
 	}
@@ -164,24 +166,24 @@ func (c *cliApp) connect(argsString string) {
 	options := strings.Fields(argsString)
 
 	if len(options) < 3 {
-		info("Please type in the provider identity. Connect <consumer-identity> <provider-identity> <service-type> [disable-kill-switch]")
+		info("Please type in the provider identity. Connect <consumer-identity> <provider-identity> <service-type> [enable-kill-switch]")
 		return
 	}
 
 	consumerID, providerID, serviceType := options[0], options[1], options[2]
 
-	var disableKill bool
+	var enableKill bool
 	var err error
 	if len(options) > 3 {
-		disableKillStr := options[3]
-		disableKill, err = strconv.ParseBool(disableKillStr)
+		enableKillStr := options[3]
+		enableKill, err = strconv.ParseBool(enableKillStr)
 		if err != nil {
-			info("Please use true / false for <disable-kill-switch>")
+			info("Please use true / false for <enable-kill-switch>")
 			return
 		}
 	}
 
-	connectOptions := endpoints.ConnectOptions{DisableKillSwitch: disableKill}
+	connectOptions := endpoints.ConnectOptions{EnableKillSwitch: enableKill}
 
 	if consumerID == "new" {
 		id, err := c.tequilapi.NewIdentity(identityDefaultPassphrase)
@@ -324,6 +326,36 @@ func (c *cliApp) ip() {
 	info("IP:", ip)
 }
 
+func (c *cliApp) diagnostics() {
+	report, err := c.tequilapi.ConnectionDiagnostics()
+	if err != nil {
+		warn(err)
+		return
+	}
+
+	for _, check := range report.Checks {
+		if check.Passed {
+			status("PASS", check.Name+":", check.Message)
+		} else {
+			status("FAIL", check.Name+":", check.Message)
+		}
+		names := make([]string, 0, len(check.Values))
+		for name := range check.Values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			info(fmt.Sprintf("    %s: %s", name, check.Values[name]))
+		}
+	}
+
+	if report.Passed {
+		success("No leaks detected.")
+	} else {
+		warn("Leaks detected, see failed checks above.")
+	}
+}
+
 func (c *cliApp) help() {
 	info("Mysterium CLI tequilapi commands:")
 	fmt.Println(c.completer.Tree("  "))
@@ -491,6 +523,7 @@ func newAutocompleter(tequilapi *tequilapi_client.Client, proposals []tequilapi_
 		readline.PcItem("healthcheck"),
 		readline.PcItem("proposals"),
 		readline.PcItem("ip"),
+		readline.PcItem("diagnostics"),
 		readline.PcItem("disconnect"),
 		readline.PcItem("help"),
 		readline.PcItem("quit"),
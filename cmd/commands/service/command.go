@@ -19,16 +19,23 @@ package service
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mysteriumnetwork/node/cmd"
 	"github.com/mysteriumnetwork/node/cmd/commands/license"
 	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/metadata"
 	service_noop "github.com/mysteriumnetwork/node/services/noop"
 	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn/service"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/shaper"
 	"github.com/urfave/cli"
 )
 
@@ -50,6 +57,71 @@ var (
 		Name:  "agreed-terms-and-conditions",
 		Usage: "Agree with terms & conditions",
 	}
+
+	maxSessionsFlag = cli.IntFlag{
+		Name:  "service.max-sessions",
+		Usage: "Maximum count of concurrent sessions of each service, 0 means unlimited",
+		Value: 0,
+	}
+	maxSessionsPerConsumerFlag = cli.IntFlag{
+		Name:  "service.max-sessions-per-consumer",
+		Usage: "Maximum count of concurrent sessions of each service per consumer identity, 0 means unlimited",
+		Value: 0,
+	}
+	maxNewSessionsPerMinuteFlag = cli.IntFlag{
+		Name:  "service.max-new-sessions-per-minute",
+		Usage: "Maximum count of sessions each service creates per minute, 0 means unlimited",
+		Value: 0,
+	}
+
+	sessionUploadLimitFlag = cli.UintFlag{
+		Name:  "service.session-upload-limit",
+		Usage: "Upload rate limit of each session in KB/s, 0 means unlimited",
+		Value: 0,
+	}
+	sessionDownloadLimitFlag = cli.UintFlag{
+		Name:  "service.session-download-limit",
+		Usage: "Download rate limit of each session in KB/s, 0 means unlimited",
+		Value: 0,
+	}
+
+	egressBlockedPortsFlag = cli.StringFlag{
+		Name:  "service.egress-blocked-ports",
+		Usage: "Comma separated list of destination ports, which consumers are not allowed to reach",
+		Value: "25",
+	}
+	egressBlockedNetworksFlag = cli.StringFlag{
+		Name:  "service.egress-blocked-networks",
+		Usage: "Comma separated list of destination networks in CIDR notation, which consumers are not allowed to reach. Private and link-local networks are always blocked",
+		Value: "",
+	}
+
+	restartFlag = cli.StringFlag{
+		Name:  "service.restart",
+		Usage: "Restart policy of services, which stop by themselves. Options: { never, on-failure, always }",
+		Value: string(service.RestartOnFailure),
+	}
+	restartMaxFlag = cli.IntFlag{
+		Name:  "service.restart-max",
+		Usage: "Maximum count of restarts of each service within restart window, 0 means unlimited",
+		Value: 5,
+	}
+	restartWindowFlag = cli.DurationFlag{
+		Name:  "service.restart-window",
+		Usage: "Period restarts of service are counted in",
+		Value: 10 * time.Minute,
+	}
+	restartBackoffFlag = cli.DurationFlag{
+		Name:  "service.restart-backoff",
+		Usage: "Delay before service is restarted, it's doubled for each restart within restart window",
+		Value: time.Second,
+	}
+
+	dnsFlag = cli.StringFlag{
+		Name:  "service.dns",
+		Usage: "Comma separated list of DNS servers advertised to consumers. Public system resolvers are advertised if none are given",
+		Value: "",
+	}
 )
 
 // NewCommand function creates service command
@@ -94,9 +166,6 @@ func runServices(ctx *cli.Context, di *cmd.Dependencies, licenseCommandName stri
 	if err := di.Bootstrap(nodeOptions); err != nil {
 		return err
 	}
-	if err := di.BootstrapServices(nodeOptions); err != nil {
-		return err
-	}
 
 	go func() { errorChannel <- di.Node.Wait() }()
 
@@ -127,15 +196,117 @@ func registerFlags(flags *[]cli.Flag) {
 	*flags = append(*flags,
 		agreedTermsConditionsFlag,
 		identityFlag, identityPassphraseFlag,
+		maxSessionsFlag, maxSessionsPerConsumerFlag, maxNewSessionsPerMinuteFlag,
+		sessionUploadLimitFlag, sessionDownloadLimitFlag,
+		egressBlockedPortsFlag, egressBlockedNetworksFlag,
+		restartFlag, restartMaxFlag, restartWindowFlag, restartBackoffFlag,
+		dnsFlag,
 	)
 	openvpn_service.RegisterFlags(flags)
 }
 
 func parseFlagsByServiceType(ctx *cli.Context, serviceType string) (service.Options, error) {
-	if f, ok := serviceTypesFlagsParser[serviceType]; ok {
-		return f(ctx), nil
+	f, ok := serviceTypesFlagsParser[serviceType]
+	if !ok {
+		return service.Options{}, fmt.Errorf("Unknown service type: %q", serviceType)
+	}
+
+	egress, err := parseEgressFlags(ctx)
+	if err != nil {
+		return service.Options{}, err
+	}
+	restart, err := parseRestartFlags(ctx)
+	if err != nil {
+		return service.Options{}, err
+	}
+	dns, err := parseDNSFlag(ctx)
+	if err != nil {
+		return service.Options{}, err
+	}
+
+	options := f(ctx)
+	options.Egress = egress
+	options.Restart = restart
+	options.DNS = dns
+	return options, nil
+}
+
+// parseDNSFlag function fills in DNS servers advertised to consumers from CLI context
+func parseDNSFlag(ctx *cli.Context) ([]net.IP, error) {
+	var servers []net.IP
+	for _, value := range splitList(ctx.String(dnsFlag.Name)) {
+		server := net.ParseIP(value)
+		if server == nil || server.To4() == nil {
+			return nil, fmt.Errorf("invalid DNS server: %q", value)
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// parseLimitsFlags function fills in session limits of service from CLI context
+func parseLimitsFlags(ctx *cli.Context) session.Limits {
+	return session.Limits{
+		MaxSessions:             ctx.Int(maxSessionsFlag.Name),
+		MaxSessionsPerConsumer:  ctx.Int(maxSessionsPerConsumerFlag.Name),
+		MaxNewSessionsPerMinute: ctx.Int(maxNewSessionsPerMinuteFlag.Name),
+	}
+}
+
+// parseBandwidthFlags function fills in bandwidth limits of service sessions from CLI context
+func parseBandwidthFlags(ctx *cli.Context) shaper.Limits {
+	return shaper.Limits{
+		Upload:   datasize.BitSize(ctx.Uint(sessionUploadLimitFlag.Name)) * datasize.KB,
+		Download: datasize.BitSize(ctx.Uint(sessionDownloadLimitFlag.Name)) * datasize.KB,
+	}
+}
+
+// parseEgressFlags function fills in egress policy of services from CLI context
+func parseEgressFlags(ctx *cli.Context) (firewall.EgressPolicy, error) {
+	var ports []int
+	for _, value := range splitList(ctx.String(egressBlockedPortsFlag.Name)) {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return firewall.EgressPolicy{}, fmt.Errorf("invalid blocked port: %q", value)
+		}
+		ports = append(ports, port)
+	}
+
+	var networks []net.IPNet
+	for _, value := range splitList(ctx.String(egressBlockedNetworksFlag.Name)) {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return firewall.EgressPolicy{}, fmt.Errorf("invalid blocked network: %q", value)
+		}
+		networks = append(networks, *network)
+	}
+
+	return firewall.NewEgressPolicy(ports, networks), nil
+}
+
+// parseRestartFlags function fills in restart policy of services from CLI context
+func parseRestartFlags(ctx *cli.Context) (service.RestartPolicy, error) {
+	mode := service.RestartMode(ctx.String(restartFlag.Name))
+	if !service.ValidRestartMode(mode) {
+		return service.RestartPolicy{}, fmt.Errorf("invalid restart policy: %q", mode)
+	}
+
+	return service.RestartPolicy{
+		Mode:        mode,
+		MaxRestarts: ctx.Int(restartMaxFlag.Name),
+		Window:      ctx.Duration(restartWindowFlag.Name),
+		Backoff:     ctx.Duration(restartBackoffFlag.Name),
+	}, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return service.Options{}, fmt.Errorf("Unknown service type: %q", serviceType)
+	return items
 }
 
 // parseOpenvpnFlags function fills in openvpn options from CLI context
@@ -144,6 +315,8 @@ func parseOpenvpnFlags(ctx *cli.Context) service.Options {
 		Identity:   ctx.String(identityFlag.Name),
 		Passphrase: ctx.String(identityPassphraseFlag.Name),
 		Type:       service_openvpn.ServiceType,
+		Limits:     parseLimitsFlags(ctx),
+		Bandwidth:  parseBandwidthFlags(ctx),
 		Options:    openvpn_service.ParseFlags(ctx),
 	}
 }
@@ -154,6 +327,8 @@ func parseNoopFlags(ctx *cli.Context) service.Options {
 		Identity:   ctx.String(identityFlag.Name),
 		Passphrase: ctx.String(identityPassphraseFlag.Name),
 		Type:       service_noop.ServiceType,
+		Limits:     parseLimitsFlags(ctx),
+		Bandwidth:  parseBandwidthFlags(ctx),
 	}
 }
 
@@ -44,5 +44,7 @@ func parseWireguardFlags(ctx *cli.Context) service.Options {
 		Identity:   ctx.String(identityFlag.Name),
 		Passphrase: ctx.String(identityPassphraseFlag.Name),
 		Type:       service_wireguard.ServiceType,
+		Limits:     parseLimitsFlags(ctx),
+		Bandwidth:  parseBandwidthFlags(ctx),
 	}
 }
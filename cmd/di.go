@@ -18,6 +18,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"time"
 
@@ -30,12 +31,15 @@ import (
 	"github.com/mysteriumnetwork/node/communication"
 	nats_dialog "github.com/mysteriumnetwork/node/communication/nats/dialog"
 	nats_discovery "github.com/mysteriumnetwork/node/communication/nats/discovery"
+	"github.com/mysteriumnetwork/node/consumer/autoconnect"
 	consumer_session "github.com/mysteriumnetwork/node/consumer/session"
 	"github.com/mysteriumnetwork/node/consumer/statistics"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/diagnostics"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/core/policy"
 	promise_noop "github.com/mysteriumnetwork/node/core/promise/methods/noop"
 	"github.com/mysteriumnetwork/node/core/service"
 	"github.com/mysteriumnetwork/node/core/storage/boltdb"
@@ -47,6 +51,7 @@ import (
 	"github.com/mysteriumnetwork/node/market/metrics"
 	"github.com/mysteriumnetwork/node/market/metrics/oracle"
 	"github.com/mysteriumnetwork/node/market/mysterium"
+	"github.com/mysteriumnetwork/node/market/proposals/selector"
 	"github.com/mysteriumnetwork/node/metadata"
 	service_noop "github.com/mysteriumnetwork/node/services/noop"
 	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
@@ -92,12 +97,17 @@ type Dependencies struct {
 
 	EventBus EventBus.Bus
 
+	ConnectionPool     *connection.Pool
 	ConnectionManager  connection.Manager
 	ConnectionRegistry *connection.Registry
+	ProposalSelector   *selector.Selector
+	LastConnection     *autoconnect.Keeper
 
 	ServiceRunner         *service.Runner
 	ServiceRegistry       *service.Registry
-	ServiceSessionStorage *session.StorageMemory
+	ServiceSessionStorage *session.StorageBolt
+	ServiceSessionReaper  *session.Reaper
+	AccessPolicy          *policy.Policy
 }
 
 // Bootstrap initiates all container dependencies
@@ -125,7 +135,9 @@ func (di *Dependencies) Bootstrap(nodeOptions node.Options) error {
 
 	di.bootstrapIdentityComponents(nodeOptions)
 	di.bootstrapLocationComponents(nodeOptions.Location, nodeOptions.Directories.Config)
-	di.bootstrapNodeComponents(nodeOptions)
+	if err := di.bootstrapNodeComponents(nodeOptions); err != nil {
+		return err
+	}
 
 	di.registerConnections(nodeOptions)
 
@@ -134,13 +146,34 @@ func (di *Dependencies) Bootstrap(nodeOptions node.Options) error {
 		return err
 	}
 
+	// original location is detected while starting, it has to be done before traffic is restricted
 	if err := di.Node.Start(); err != nil {
 		return err
 	}
 
+	if di.LastConnection != nil {
+		// traffic has to be restricted before the last connection is restored
+		if err := di.LastConnection.RestoreKillSwitch(); err != nil {
+			return err
+		}
+		go di.restoreLastConnection(nodeOptions.AutoConnect)
+	}
+
 	return nil
 }
 
+func (di *Dependencies) restoreLastConnection(options node.OptionsAutoConnect) {
+	unlock := func(consumerID identity.Identity) error {
+		return di.IdentityManager.Unlock(consumerID.Address, options.Passphrase)
+	}
+	connect := func(request json.RawMessage) error {
+		return tequilapi_endpoints.RestoreConnection(di.ConnectionManager, di.MysteriumAPI, di.ProposalSelector, unlock, request)
+	}
+	if err := di.LastConnection.Restore(connect); err != nil {
+		log.Error("Failed to restore the last connection: ", err)
+	}
+}
+
 func (di *Dependencies) registerOpenvpnConnection(nodeOptions node.Options) {
 	service_openvpn.Bootstrap()
 	connectionFactory := service_openvpn.NewProcessBasedConnectionFactory(
@@ -176,6 +209,13 @@ func (di *Dependencies) Shutdown() (err error) {
 		errs = append(errs, runnerErrs...)
 	}
 
+	if di.ServiceSessionReaper != nil {
+		di.ServiceSessionReaper.Stop()
+	}
+	if di.AccessPolicy != nil {
+		di.AccessPolicy.Stop()
+	}
+
 	if di.Node != nil {
 		if err := di.Node.Kill(); err != nil {
 			errs = append(errs, err)
@@ -231,7 +271,7 @@ func (di *Dependencies) subscribeEventConsumers() error {
 	return nil
 }
 
-func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
+func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) error {
 	dialogFactory := func(consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error) {
 		dialogEstablisher := nats_dialog.NewDialogEstablisher(consumerID, di.SignerFactory(consumerID))
 		return dialogEstablisher.EstablishDialog(providerID, contact)
@@ -253,35 +293,75 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 		time.Minute,
 	)
 	di.SessionStorage = consumer_session.NewSessionStorage(di.Storage, di.StatisticsTracker)
+	di.ServiceSessionStorage = session.NewStorageBolt(di.Storage)
+	di.AccessPolicy = newAccessPolicy(nodeOptions.AccessPolicy)
 
 	di.EventBus = EventBus.New()
 
 	di.ConnectionRegistry = connection.NewRegistry()
-	di.ConnectionManager = connection.NewManager(
+	di.ConnectionPool = connection.NewPool(
 		dialogFactory,
 		promiseIssuerFactory,
 		di.ConnectionRegistry.CreateConnection,
 		di.EventBus,
+		di.MysteriumAPI,
+		[]string{di.NetworkDefinition.DiscoveryAPIAddress, di.NetworkDefinition.BrokerAddress},
 	)
+	defaultManager := di.ConnectionPool.Default()
+	di.ConnectionManager = defaultManager
+
+	var lastConnection tequilapi_endpoints.LastConnectionKeeper
+	if nodeOptions.AutoConnect.Enabled {
+		di.LastConnection = autoconnect.NewKeeper(di.Storage, defaultManager)
+		lastConnection = di.LastConnection
+	}
+
+	// services are bootstrapped before Tequilapi, so that they can be started and stopped through it
+	if err := di.BootstrapServices(nodeOptions); err != nil {
+		log.Warn("Services can not be provided: ", err)
+	}
 
 	router := tequilapi.NewAPIRouter()
 	tequilapi_endpoints.AddRouteForStop(router, utils.SoftKiller(di.Shutdown))
 	tequilapi_endpoints.AddRoutesForIdentities(router, di.IdentityManager, di.SignerFactory)
-	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatisticsTracker, di.MysteriumAPI)
+	di.ProposalSelector = selector.NewSelector(di.MysteriumAPI, di.MysteriumMorqaClient)
+	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatisticsTracker, di.MysteriumAPI, di.ProposalSelector, lastConnection)
+	tequilapi_endpoints.AddRoutesForConnections(router, di.ConnectionPool, di.MysteriumAPI, di.ProposalSelector)
 	tequilapi_endpoints.AddRoutesForLocation(router, di.ConnectionManager, di.LocationDetector, di.LocationOriginal)
+	connectionDiagnostics := diagnostics.NewDiagnostics(defaultManager, di.IPResolver, di.LocationOriginal, di.LocationResolver, diagnostics.NewNetwork())
+	tequilapi_endpoints.AddRoutesForDiagnostics(router, connectionDiagnostics)
 	tequilapi_endpoints.AddRoutesForProposals(router, di.MysteriumAPI, di.MysteriumMorqaClient)
 	tequilapi_endpoints.AddRoutesForSession(router, di.SessionStorage)
+	tequilapi_endpoints.AddRoutesForServiceSessions(router, di.ServiceSessionStorage, di.ServiceSessionStorage, session.NewKiller(di.ServiceSessionStorage))
+	tequilapi_endpoints.AddRoutesForAccessPolicy(router, di.AccessPolicy)
+	if di.ServiceRunner != nil {
+		tequilapi_endpoints.AddRoutesForServices(router, di.ServiceRunner)
+	}
 	identity_registry.AddIdentityRegistrationEndpoint(router, di.IdentityRegistration, di.IdentityRegistry)
+	if err := tequilapi_endpoints.AddRoutesForEvents(router, di.EventBus); err != nil {
+		return err
+	}
 
 	httpAPIServer := tequilapi.NewServer(nodeOptions.TequilapiAddress, nodeOptions.TequilapiPort, router)
 
 	di.Node = node.NewNode(di.ConnectionManager, httpAPIServer, di.LocationOriginal)
+	return nil
+}
+
+func newAccessPolicy(options node.OptionsAccessPolicy) *policy.Policy {
+	if options.Source == "" {
+		return policy.NewPolicy(nil, options.RefreshInterval)
+	}
+	return policy.NewPolicy(policy.NewSource(options.Source), options.RefreshInterval)
 }
 
 func newSessionManagerFactory(
-	proposal market.ServiceProposal,
-	sessionStorage *session.StorageMemory,
+	proposal session.ProposalProvider,
+	sessionStorage session.Storage,
 	promiseHandler func(dialog communication.Dialog) session.PromiseProcessor,
+	statsProvider session.StatsProvider,
+	activityProvider session.ActivityProvider,
+	limiter *session.Limiter,
 ) session.ManagerFactory {
 	return func(dialog communication.Dialog) *session.Manager {
 		return session.NewManager(
@@ -289,6 +369,10 @@ func newSessionManagerFactory(
 			session.GenerateUUID,
 			sessionStorage,
 			promiseHandler(dialog),
+			statsProvider,
+			activityProvider,
+			limiter,
+			dialog,
 		)
 	}
 }
@@ -18,6 +18,8 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/mysteriumnetwork/node/core/node"
 	openvpn_core "github.com/mysteriumnetwork/node/services/openvpn/core"
 	"github.com/urfave/cli"
@@ -38,6 +40,30 @@ var (
 		Name:  "keystore.lightweight",
 		Usage: "Determines the scrypt memory complexity. If set to true, will use 4MB blocks instead of the standard 256MB ones",
 	}
+	autoConnectFlag = cli.BoolFlag{
+		Name:  "auto-connect",
+		Usage: "Remembers the last successful connection and restores it (with kill switch) after node restart",
+	}
+	autoConnectPassphraseFlag = cli.StringFlag{
+		Name:  "auto-connect.passphrase",
+		Usage: "Passphrase to unlock consumer identity of the restored connection",
+		Value: "",
+	}
+	sessionGracePeriodFlag = cli.DurationFlag{
+		Name:  "session.grace-period",
+		Usage: "Time after which provider ends sessions of consumers, which weren't seen alive",
+		Value: 10 * time.Minute,
+	}
+	accessPolicySourceFlag = cli.StringFlag{
+		Name:  "access-policy.source",
+		Usage: "Path to file or URL of JSON rules, which consumer identities are allowed or denied to use services",
+		Value: "",
+	}
+	accessPolicyRefreshIntervalFlag = cli.DurationFlag{
+		Name:  "access-policy.refresh-interval",
+		Usage: "How often access policy rules are reloaded from their source, rules are loaded only once if it's 0",
+		Value: time.Minute,
+	}
 )
 
 // ParseKeystoreFlags parses the keystore options for node
@@ -47,13 +73,31 @@ func ParseKeystoreFlags(ctx *cli.Context) node.OptionsKeystore {
 	}
 }
 
+// ParseAutoConnectFlags parses the auto-connect options for node
+func ParseAutoConnectFlags(ctx *cli.Context) node.OptionsAutoConnect {
+	return node.OptionsAutoConnect{
+		Enabled:    ctx.GlobalBool(autoConnectFlag.Name),
+		Passphrase: ctx.GlobalString(autoConnectPassphraseFlag.Name),
+	}
+}
+
+// ParseAccessPolicyFlags parses the access policy options for node
+func ParseAccessPolicyFlags(ctx *cli.Context) node.OptionsAccessPolicy {
+	return node.OptionsAccessPolicy{
+		Source:          ctx.GlobalString(accessPolicySourceFlag.Name),
+		RefreshInterval: ctx.GlobalDuration(accessPolicyRefreshIntervalFlag.Name),
+	}
+}
+
 // RegisterFlagsNode function register node flags to flag list
 func RegisterFlagsNode(flags *[]cli.Flag) error {
 	if err := RegisterFlagsDirectory(flags); err != nil {
 		return err
 	}
 
-	*flags = append(*flags, tequilapiAddressFlag, tequilapiPortFlag, keystoreLightweightFlag)
+	*flags = append(*flags, tequilapiAddressFlag, tequilapiPortFlag, keystoreLightweightFlag, autoConnectFlag, autoConnectPassphraseFlag, sessionGracePeriodFlag,
+		accessPolicySourceFlag, accessPolicyRefreshIntervalFlag,
+	)
 
 	RegisterFlagsNetwork(flags)
 	openvpn_core.RegisterFlags(flags)
@@ -72,6 +116,11 @@ func ParseFlagsNode(ctx *cli.Context) node.Options {
 
 		Keystore: ParseKeystoreFlags(ctx),
 
+		AutoConnect: ParseAutoConnectFlags(ctx),
+
+		SessionGracePeriod: ctx.GlobalDuration(sessionGracePeriodFlag.Name),
+		AccessPolicy:       ParseAccessPolicyFlags(ctx),
+
 		Openvpn:        wrapper{nodeOptions: openvpn_core.ParseFlags(ctx)},
 		Location:       ParseFlagsLocation(ctx),
 		OptionsNetwork: ParseFlagsNetwork(ctx),
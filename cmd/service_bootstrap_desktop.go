@@ -20,6 +20,10 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	nats_dialog "github.com/mysteriumnetwork/node/communication/nats/dialog"
@@ -42,6 +46,7 @@ const logPrefix = "[service bootstrap] "
 
 type locationInfo struct {
 	OutIP   string
+	OutIPv6 string
 	PubIP   string
 	Country string
 }
@@ -59,6 +64,12 @@ func (di *Dependencies) resolveIPsAndLocation() (loc locationInfo, err error) {
 	}
 	loc.OutIP = outboundIP
 
+	if outboundIPv6, errIPv6 := di.IPResolver.GetOutboundIPv6(); errIPv6 == nil {
+		loc.OutIPv6 = outboundIPv6
+	} else {
+		log.Info(logPrefix, "IPv6 will not be provided: ", errIPv6)
+	}
+
 	currentCountry, err := di.LocationResolver.ResolveCountry(pubIP)
 	if err != nil {
 		log.Warn(logPrefix, "Failed to detect service country. ", err)
@@ -79,16 +90,54 @@ func (di *Dependencies) bootstrapServiceOpenvpn(nodeOptions node.Options) {
 		}
 
 		currentLocation := market.Location{Country: location.Country}
-		transportOptions := serviceOptions.Options.(openvpn_service.Options)
+		transportOptions, err := openvpnOptions(serviceOptions.Options)
+		if err != nil {
+			return nil, market.ServiceProposal{}, err
+		}
+		egress, err := serviceOptions.Egress.WithLocalNetworks(location.OutIP, location.OutIPv6)
+		if err != nil {
+			return nil, market.ServiceProposal{}, err
+		}
 
-		proposal := openvpn_discovery.NewServiceProposalWithLocation(currentLocation, transportOptions.OpenvpnProtocol)
-		return openvpn_service.NewManager(nodeOptions, transportOptions, location.PubIP, location.OutIP, location.Country, di.ServiceSessionStorage), proposal, nil
+		proposal := openvpn_discovery.NewServiceProposalWithLocation(
+			currentLocation,
+			transportOptions.OpenvpnProtocol,
+			serviceOptions.Bandwidth,
+			serviceOptions.Egress,
+		)
+		manager := openvpn_service.NewManager(
+			nodeOptions,
+			transportOptions,
+			location.PubIP,
+			location.OutIP,
+			location.OutIPv6,
+			location.Country,
+			di.ServiceSessionStorage,
+			serviceOptions.Bandwidth,
+			egress,
+			service.ConsumerDNS(serviceOptions.DNS),
+		)
+		return manager, proposal, nil
 	}
 
 	di.ServiceRegistry.Register(service_openvpn.ServiceType, createService)
 	di.ServiceRunner.Register(service_openvpn.ServiceType)
 }
 
+// openvpnOptions takes openvpn options parsed from CLI flags or given as JSON by Tequilapi request
+func openvpnOptions(options service.TransportOptions) (openvpn_service.Options, error) {
+	switch options := options.(type) {
+	case openvpn_service.Options:
+		return options, nil
+	case json.RawMessage:
+		return openvpn_service.ParseJSONOptions(&options)
+	case nil:
+		return openvpn_service.ParseJSONOptions(nil)
+	default:
+		return openvpn_service.Options{}, fmt.Errorf("unexpected openvpn options: %T", options)
+	}
+}
+
 func (di *Dependencies) bootstrapServiceNoop(nodeOptions node.Options) {
 	di.ServiceRegistry.Register(service_noop.ServiceType, func(serviceOptions service.Options) (service.Service, market.ServiceProposal, error) {
 		location, err := di.resolveIPsAndLocation()
@@ -112,10 +161,26 @@ func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
 	)
 
 	di.ServiceRegistry = service.NewRegistry()
-	di.ServiceSessionStorage = session.NewStorageMemory()
+	sessionKiller := session.NewKiller(di.ServiceSessionStorage)
+	di.ServiceSessionReaper = session.NewReaper(
+		di.ServiceSessionStorage,
+		sessionKiller,
+		nodeOptions.SessionGracePeriod,
+	)
+	di.AccessPolicy.OnRulesApplied(func() {
+		sessionKiller.KillDenied(di.AccessPolicy)
+	})
+	// sessions are reaped and access rules refreshed only once node starts to provide services
+	var providingOnce sync.Once
+	startProviding := func() {
+		providingOnce.Do(func() {
+			di.ServiceSessionReaper.Start()
+			di.AccessPolicy.Start()
+		})
+	}
 
-	newDialogWaiter := func(providerID identity.Identity, serviceType string) (communication.DialogWaiter, error) {
-		address, err := nats_discovery.NewAddressFromHostAndID(di.NetworkDefinition.BrokerAddress, providerID, serviceType)
+	newDialogWaiter := func(providerID identity.Identity, serviceType string, proposalID int) (communication.DialogWaiter, error) {
+		address, err := nats_discovery.NewAddressFromHostAndID(di.NetworkDefinition.BrokerAddress, providerID, serviceType, proposalID)
 		if err != nil {
 			return nil, err
 		}
@@ -124,28 +189,54 @@ func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
 			address,
 			di.SignerFactory(providerID),
 			di.IdentityRegistry,
+			di.AccessPolicy,
 		), nil
 	}
-	newDialogHandler := func(proposal market.ServiceProposal, configProvider session.ConfigNegotiator) communication.DialogHandler {
+	newDialogHandler := func(proposal session.ProposalProvider, configProvider session.ConfigNegotiator, limiter *session.Limiter) communication.DialogHandler {
 		promiseHandler := func(dialog communication.Dialog) session.PromiseProcessor {
 			if nodeOptions.ExperimentPromiseCheck {
 				return promise_noop.NewPromiseProcessor(dialog, identity.NewBalance(di.EtherClient), di.Storage)
 			}
 			return &promise_noop.FakePromiseEngine{}
 		}
-		sessionManagerFactory := newSessionManagerFactory(proposal, di.ServiceSessionStorage, promiseHandler)
+		statsProvider, _ := configProvider.(session.StatsProvider)
+		activityProvider, _ := configProvider.(session.ActivityProvider)
+		sessionManagerFactory := newSessionManagerFactory(proposal, di.ServiceSessionStorage, promiseHandler, statsProvider, activityProvider, limiter)
 		return session.NewDialogHandler(sessionManagerFactory, configProvider.ProvideConfig)
 	}
 
-	runnableServiceFactory := func() service.RunnableService {
-		return service.NewManager(
+	runnableServiceFactory := func(id service.ID, proposalID int) service.RunnableService {
+		startProviding()
+		manager := service.NewManager(
+			id,
+			proposalID,
 			identityHandler,
-			di.ServiceRegistry.Create,
+			func(options service.Options) (service.Service, market.ServiceProposal, error) {
+				return di.createService(options, proposalID)
+			},
 			newDialogWaiter,
 			newDialogHandler,
-			registry.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumAPI, di.SignerFactory),
+			registry.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumAPI, di.SignerFactory, di.EventBus),
+			di.ServiceSessionStorage,
+			di.EventBus,
 		)
+		return service.NewSupervisor(manager, di.EventBus)
 	}
 
 	di.ServiceRunner = service.NewRunner(runnableServiceFactory)
 }
+
+// createService creates service instance with given options and recovers its sessions, which outlived the node
+func (di *Dependencies) createService(options service.Options, proposalID int) (service.Service, market.ServiceProposal, error) {
+	createdService, proposal, err := di.ServiceRegistry.Create(options)
+	if err != nil {
+		return nil, market.ServiceProposal{}, err
+	}
+
+	if recoverer, ok := createdService.(session.Recoverer); ok {
+		if err := di.ServiceSessionStorage.Recover(proposal.ServiceType, proposalID, recoverer); err != nil {
+			log.Warn(logPrefix, "Failed to recover sessions of ", proposal.ServiceType, " service: ", err)
+		}
+	}
+	return createdService, proposal, nil
+}
@@ -44,8 +44,22 @@ func (di *Dependencies) bootstrapServiceWireguard(nodeOptions node.Options) {
 		if err != nil {
 			return nil, market.ServiceProposal{}, err
 		}
+		egress, err := serviceOptions.Egress.WithLocalNetworks(location.OutIP, location.OutIPv6)
+		if err != nil {
+			return nil, market.ServiceProposal{}, err
+		}
 
-		return wireguard_service.NewManager(location.PubIP, location.OutIP, location.Country), wireguard_service.GetProposal(location.Country), nil
+		manager := wireguard_service.NewManager(
+			&wireguardResources,
+			location.PubIP,
+			location.OutIP,
+			location.OutIPv6,
+			location.Country,
+			serviceOptions.Bandwidth,
+			egress,
+			service.ConsumerDNS(serviceOptions.DNS),
+		)
+		return manager, wireguard_service.GetProposal(location.Country, serviceOptions.Bandwidth, serviceOptions.Egress), nil
 	})
 
 	di.ServiceRunner.Register(wireguard.ServiceType)
@@ -31,15 +31,21 @@ import (
 )
 
 // NewDialogWaiter constructs new DialogWaiter which works through NATS connection.
-func NewDialogWaiter(address *discovery.AddressNATS, signer identity.Signer, identityRegistry registry.IdentityRegistry) *dialogWaiter {
+func NewDialogWaiter(address *discovery.AddressNATS, signer identity.Signer, identityRegistry registry.IdentityRegistry, accessPolicy AccessPolicy) *dialogWaiter {
 	return &dialogWaiter{
 		address:          address,
 		signer:           signer,
 		dialogs:          make([]communication.Dialog, 0),
 		identityRegistry: identityRegistry,
+		accessPolicy:     accessPolicy,
 	}
 }
 
+// AccessPolicy decides whether peer is allowed to establish dialog
+type AccessPolicy interface {
+	IsAllowed(peerID identity.Identity) bool
+}
+
 const waiterLogPrefix = "[NATS.DialogWaiter] "
 
 type dialogWaiter struct {
@@ -47,6 +53,7 @@ type dialogWaiter struct {
 	signer           identity.Signer
 	dialogs          []communication.Dialog
 	identityRegistry registry.IdentityRegistry
+	accessPolicy     AccessPolicy
 
 	sync.RWMutex
 }
@@ -88,6 +95,10 @@ func (waiter *dialogWaiter) ServeDialogs(dialogHandler communication.DialogHandl
 			log.Error(waiterLogPrefix, "Rejecting invalid peerID: ", request.PeerID)
 			return &responseInvalidIdentity, nil
 		}
+		if waiter.accessPolicy != nil && !waiter.accessPolicy.IsAllowed(identity.FromAddress(request.PeerID)) {
+			log.Warn(waiterLogPrefix, "Rejecting peerID denied by access policy: ", request.PeerID)
+			return &responseAccessDenied, nil
+		}
 
 		peerID := identity.FromAddress(request.PeerID)
 		dialog := waiter.newDialogToPeer(peerID, waiter.newCodecForPeer(peerID))
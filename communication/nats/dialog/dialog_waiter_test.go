@@ -36,7 +36,7 @@ func TestDialogWaiter_Factory(t *testing.T) {
 	address := discovery.NewAddress("custom", "nats://far-server:4222")
 	signer := &identity.SignerFake{}
 
-	waiter := NewDialogWaiter(address, signer, &mockedIdentityRegistry{})
+	waiter := NewDialogWaiter(address, signer, &mockedIdentityRegistry{}, nil)
 	assert.NotNil(t, waiter)
 	assert.Equal(t, address, waiter.address)
 	assert.Equal(t, signer, waiter.signer)
@@ -108,7 +108,7 @@ func TestDialogWaiter_ServeDialogsRejectUnregisteredConsumers(t *testing.T) {
 		dialogReceived: make(chan communication.Dialog),
 	}
 
-	waiter := NewDialogWaiter(discovery.NewAddressWithConnection(connection, "test-topic"), signer, mockedRegistry)
+	waiter := NewDialogWaiter(discovery.NewAddressWithConnection(connection, "test-topic"), signer, mockedRegistry, nil)
 
 	err := waiter.ServeDialogs(mockeDialogHandler)
 	assert.NoError(t, err)
@@ -132,6 +132,50 @@ func TestDialogWaiter_ServeDialogsRejectUnregisteredConsumers(t *testing.T) {
 	)
 }
 
+type fakeAccessPolicy struct {
+	allowed bool
+}
+
+func (policy *fakeAccessPolicy) IsAllowed(peerID identity.Identity) bool {
+	return policy.allowed
+}
+
+func TestDialogWaiter_ServeDialogsRejectConsumersDeniedByAccessPolicy(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	signer := &identity.SignerFake{}
+	mockedRegistry := &mockedIdentityRegistry{
+		anyIdentityRegistered: true,
+	}
+	mockeDialogHandler := &dialogHandler{
+		dialogReceived: make(chan communication.Dialog),
+	}
+
+	waiter := NewDialogWaiter(discovery.NewAddressWithConnection(connection, "test-topic"), signer, mockedRegistry, &fakeAccessPolicy{allowed: false})
+
+	err := waiter.ServeDialogs(mockeDialogHandler)
+	assert.NoError(t, err)
+
+	msg, err := connection.Request("test-topic.dialog-create", []byte(`{
+		"payload": {"peer_id":"0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea"},
+		"signature": "tl+WbYkJdXD5foaIP3bqVGFHfr6kdd5FzmJAmu1GdpINEnNR3bTto6wgEoke/Fpy4zsWOjrulDVfrc32f5ArTgA="
+	}`), 100*time.Millisecond)
+	assert.NoError(t, err)
+
+	assert.JSONEq(
+		t,
+		`{
+			"payload":	{
+				"reason":403,
+				"reasonMessage":"Access Denied"
+			},
+			"signature":"c2lnbmVkeyJyZWFzb24iOjQwMywicmVhc29uTWVzc2FnZSI6IkFjY2VzcyBEZW5pZWQifQ=="
+		}`,
+		string(msg.Data),
+	)
+}
+
 func dialogServe(connection nats.Connection, signer identity.Signer) (waiter *dialogWaiter, handler *dialogHandler) {
 	topic := "my-topic"
 	waiter = &dialogWaiter{
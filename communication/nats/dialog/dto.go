@@ -27,6 +27,7 @@ const endpointDialogCreate = communication.RequestEndpoint("dialog-create")
 var (
 	responseOK              = dialogCreateResponse{200, "OK"}
 	responseInvalidIdentity = dialogCreateResponse{400, "Invalid Identity"}
+	responseAccessDenied    = dialogCreateResponse{403, "Access Denied"}
 	responseInternalError   = dialogCreateResponse{500, "Internal Error"}
 )
 
@@ -36,8 +36,9 @@ func NewAddress(topic string, addresses ...string) *AddressNATS {
 	}
 }
 
-// NewAddressFromHostAndID generates NATS address for current node
-func NewAddressFromHostAndID(uri string, myID identity.Identity, serviceType string) (*AddressNATS, error) {
+// NewAddressFromHostAndID generates NATS address for the service instance of current node,
+// instances are told apart by IDs of their proposals
+func NewAddressFromHostAndID(uri string, myID identity.Identity, serviceType string, proposalID int) (*AddressNATS, error) {
 	// Add scheme first otherwise url.Parse() fails.
 	var rawurl string
 	if strings.HasPrefix(uri, "nats:") {
@@ -55,7 +56,7 @@ func NewAddressFromHostAndID(uri string, myID identity.Identity, serviceType str
 		url.Host = fmt.Sprintf("%s:%d", url.Host, BrokerPort)
 	}
 
-	topic := fmt.Sprintf("%v.%v", myID.Address, serviceType)
+	topic := fmt.Sprintf("%v.%v.%v", myID.Address, serviceType, proposalID)
 	return NewAddress(topic, url.String()), nil
 }
 
@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package autoconnect
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/firewall"
+)
+
+const (
+	autoConnectLogPrefix = "[auto-connect] "
+	lastConnectionBucket = "last-connection"
+	// only the last connection is kept, so record ID is always the same
+	lastConnectionID = 1
+)
+
+// Storer allows to save, get and delete stored objects
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	Delete(bucket string, object interface{}) error
+	GetAllFrom(bucket string, array interface{}) error
+}
+
+// KillSwitch exposes kill switch state of the connection, so it can be restored before the connection is re-established
+type KillSwitch interface {
+	KillSwitchTunnel() (firewall.Tunnel, bool)
+	RestoreKillSwitch(tunnel firewall.Tunnel) error
+}
+
+// ConnectFunc connects with given connect request
+type ConnectFunc func(request json.RawMessage) error
+
+// LastConnection is the last successfully connected request
+type LastConnection struct {
+	ID int `storm:"id"`
+	// Request is the connect request in the format it was received by API
+	Request json.RawMessage
+	// KillSwitch is the tunnel which traffic was restricted to, nil if kill switch wasn't enabled
+	KillSwitch *firewall.Tunnel
+	Created    time.Time
+}
+
+// Keeper remembers the last successful connection and re-establishes it after node restart
+type Keeper struct {
+	storage    Storer
+	killSwitch KillSwitch
+}
+
+// NewKeeper creates keeper of the last connection with given dependencies
+func NewKeeper(storage Storer, killSwitch KillSwitch) *Keeper {
+	return &Keeper{
+		storage:    storage,
+		killSwitch: killSwitch,
+	}
+}
+
+// Remember replaces stored connection with the given connect request, which was just connected
+func (keeper *Keeper) Remember(request json.RawMessage) error {
+	lastConnection := LastConnection{
+		ID:      lastConnectionID,
+		Request: request,
+		Created: time.Now().UTC(),
+	}
+	if tunnel, enabled := keeper.killSwitch.KillSwitchTunnel(); enabled {
+		lastConnection.KillSwitch = &tunnel
+	}
+	return keeper.storage.Store(lastConnectionBucket, &lastConnection)
+}
+
+// Forget removes stored connection, so it isn't restored anymore
+func (keeper *Keeper) Forget() error {
+	lastConnection, found, err := keeper.load()
+	if err != nil || !found {
+		return err
+	}
+	return keeper.storage.Delete(lastConnectionBucket, &lastConnection)
+}
+
+// RestoreKillSwitch restricts traffic to the tunnel of stored connection, if it had kill switch enabled.
+// It has to be done before the connection is restored, only the endpoints needed to restore it are let through.
+func (keeper *Keeper) RestoreKillSwitch() error {
+	lastConnection, found, err := keeper.load()
+	if err != nil || !found || lastConnection.KillSwitch == nil {
+		return err
+	}
+
+	log.Info(autoConnectLogPrefix, "Restoring kill switch of the last connection")
+	return keeper.killSwitch.RestoreKillSwitch(*lastConnection.KillSwitch)
+}
+
+// Restore connects with stored connect request, if there is one.
+// Restored kill switch stays in place while connecting and after connect fails, until user disconnects.
+func (keeper *Keeper) Restore(connect ConnectFunc) error {
+	lastConnection, found, err := keeper.load()
+	if err != nil || !found {
+		return err
+	}
+
+	log.Info(autoConnectLogPrefix, "Restoring the last connection")
+	return connect(lastConnection.Request)
+}
+
+func (keeper *Keeper) load() (LastConnection, bool, error) {
+	var connections []LastConnection
+	if err := keeper.storage.GetAllFrom(lastConnectionBucket, &connections); err != nil {
+		return LastConnection{}, false, err
+	}
+	if len(connections) == 0 {
+		return LastConnection{}, false, nil
+	}
+	return connections[0], true, nil
+}
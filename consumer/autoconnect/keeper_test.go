@@ -0,0 +1,189 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package autoconnect
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/storage/boltdb"
+	"github.com/mysteriumnetwork/node/core/storage/boltdb/boltdbtest"
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	connectRequest = json.RawMessage(`{"providerId":"0x1","serviceType":"openvpn"}`)
+	tunnel         = firewall.Tunnel{Interface: "tun+", Endpoint: net.ParseIP("1.2.3.4")}
+)
+
+type fakeKillSwitch struct {
+	tunnel   *firewall.Tunnel
+	restored *firewall.Tunnel
+}
+
+func (fks *fakeKillSwitch) KillSwitchTunnel() (firewall.Tunnel, bool) {
+	if fks.tunnel == nil {
+		return firewall.Tunnel{}, false
+	}
+	return *fks.tunnel, true
+}
+
+func (fks *fakeKillSwitch) RestoreKillSwitch(tunnel firewall.Tunnel) error {
+	fks.restored = &tunnel
+	return nil
+}
+
+type connectRecorder struct {
+	requests []json.RawMessage
+	err      error
+}
+
+func (cr *connectRecorder) connect(request json.RawMessage) error {
+	cr.requests = append(cr.requests, request)
+	return cr.err
+}
+
+func newTestStorage(t *testing.T) (*boltdb.Bolt, func()) {
+	dir := boltdbtest.CreateTempDir(t)
+	storage, err := boltdb.NewStorage(dir)
+	assert.NoError(t, err)
+	return storage, func() {
+		storage.Close()
+		boltdbtest.RemoveTempDir(t, dir)
+	}
+}
+
+func TestKeeper_RestoreConnectsWithRememberedRequest(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{}).Remember(connectRequest))
+
+	// new keeper is created, as it would be after node restart
+	recorder := &connectRecorder{}
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{}).Restore(recorder.connect))
+	assert.Len(t, recorder.requests, 1)
+	assert.JSONEq(t, string(connectRequest), string(recorder.requests[0]))
+}
+
+func TestKeeper_RememberReplacesPreviousConnection(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	keeper := NewKeeper(storage, &fakeKillSwitch{})
+	assert.NoError(t, keeper.Remember(json.RawMessage(`{"providerId":"0x2"}`)))
+	assert.NoError(t, keeper.Remember(connectRequest))
+
+	recorder := &connectRecorder{}
+	assert.NoError(t, keeper.Restore(recorder.connect))
+	assert.Len(t, recorder.requests, 1)
+	assert.JSONEq(t, string(connectRequest), string(recorder.requests[0]))
+}
+
+func TestKeeper_RestoreDoesNothingAfterForget(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	keeper := NewKeeper(storage, &fakeKillSwitch{})
+	assert.NoError(t, keeper.Remember(connectRequest))
+	assert.NoError(t, keeper.Forget())
+
+	recorder := &connectRecorder{}
+	assert.NoError(t, keeper.Restore(recorder.connect))
+	assert.Empty(t, recorder.requests)
+}
+
+func TestKeeper_ForgetWithoutRememberedConnection(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{}).Forget())
+}
+
+func TestKeeper_RestoreReturnsConnectError(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	keeper := NewKeeper(storage, &fakeKillSwitch{})
+	assert.NoError(t, keeper.Remember(connectRequest))
+
+	recorder := &connectRecorder{err: errors.New("no provider")}
+	assert.EqualError(t, keeper.Restore(recorder.connect), "no provider")
+}
+
+func TestKeeper_RestoreKillSwitchEnablesRememberedTunnel(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{tunnel: &tunnel}).Remember(connectRequest))
+
+	killSwitch := &fakeKillSwitch{}
+	assert.NoError(t, NewKeeper(storage, killSwitch).RestoreKillSwitch())
+	assert.NotNil(t, killSwitch.restored)
+	assert.Equal(t, tunnel.Interface, killSwitch.restored.Interface)
+	assert.True(t, tunnel.Endpoint.Equal(killSwitch.restored.Endpoint))
+}
+
+func TestKeeper_RestoreKillSwitchSkipsConnectionWithoutKillSwitch(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{}).Remember(connectRequest))
+
+	killSwitch := &fakeKillSwitch{}
+	assert.NoError(t, NewKeeper(storage, killSwitch).RestoreKillSwitch())
+	assert.Nil(t, killSwitch.restored)
+}
+
+func TestKeeper_RestoreKeepsKillSwitchWhileConnecting(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{tunnel: &tunnel}).Remember(connectRequest))
+
+	killSwitch := &fakeKillSwitch{}
+	keeper := NewKeeper(storage, killSwitch)
+	assert.NoError(t, keeper.RestoreKillSwitch())
+
+	restrictedWhileConnecting := false
+	err := keeper.Restore(func(request json.RawMessage) error {
+		restrictedWhileConnecting = killSwitch.restored != nil
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, restrictedWhileConnecting)
+}
+
+func TestKeeper_RestoreKeepsKillSwitchWhenConnectFails(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	assert.NoError(t, NewKeeper(storage, &fakeKillSwitch{tunnel: &tunnel}).Remember(connectRequest))
+
+	killSwitch := &fakeKillSwitch{}
+	keeper := NewKeeper(storage, killSwitch)
+	assert.NoError(t, keeper.RestoreKillSwitch())
+
+	recorder := &connectRecorder{err: errors.New("no provider")}
+	assert.Error(t, keeper.Restore(recorder.connect))
+	assert.NotNil(t, killSwitch.restored)
+	assert.Equal(t, tunnel.Interface, killSwitch.restored.Interface)
+}
@@ -68,6 +68,10 @@ func (repo *Storage) GetAll() ([]History, error) {
 
 // ConsumeSessionEvent consumes the session state change events
 func (repo *Storage) ConsumeSessionEvent(sessionEvent connection.SessionEvent) {
+	// history is stored with statistics, which are tracked for the default connection only
+	if sessionEvent.ConnectionID != connection.DefaultConnectionID {
+		return
+	}
 	switch sessionEvent.Status {
 	case connection.SessionEndedStatus:
 		repo.handleEndedEvent(sessionEvent.SessionInfo.SessionID)
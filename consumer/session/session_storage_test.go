@@ -40,7 +40,8 @@ var (
 	serviceType = "serviceType"
 
 	mockPayload = connection.SessionEvent{
-		Status: connection.SessionCreatedStatus,
+		ConnectionID: connection.DefaultConnectionID,
+		Status:       connection.SessionCreatedStatus,
 		SessionInfo: connection.SessionInfo{
 			SessionID:  sessionID,
 			ConsumerID: consumerID,
@@ -78,7 +79,8 @@ func TestSessionStorageConsumeEventEndedOK(t *testing.T) {
 
 	storage := NewSessionStorage(storer, stubRetriever)
 	storage.ConsumeSessionEvent(connection.SessionEvent{
-		Status: connection.SessionEndedStatus,
+		ConnectionID: connection.DefaultConnectionID,
+		Status:       connection.SessionEndedStatus,
 	})
 	assert.True(t, storer.UpdateCalled)
 }
@@ -90,7 +92,7 @@ func TestSessionStorageConsumeEventEndedErrors(t *testing.T) {
 
 	storage := NewSessionStorage(storer, stubRetriever)
 	assert.NotPanics(t, func() {
-		storage.ConsumeSessionEvent(connection.SessionEvent{Status: connection.SessionEndedStatus})
+		storage.ConsumeSessionEvent(connection.SessionEvent{ConnectionID: connection.DefaultConnectionID, Status: connection.SessionEndedStatus})
 	})
 
 	assert.True(t, storer.UpdateCalled)
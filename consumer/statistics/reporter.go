@@ -150,6 +150,10 @@ func (sr *SessionStatisticsReporter) send(serviceType, providerID, country strin
 
 // ConsumeSessionEvent handles the session state changes
 func (sr *SessionStatisticsReporter) ConsumeSessionEvent(sessionEvent connection.SessionEvent) {
+	// statistics tracker keeps statistics of the default connection only
+	if sessionEvent.ConnectionID != connection.DefaultConnectionID {
+		return
+	}
 	switch sessionEvent.Status {
 	case connection.SessionEndedStatus:
 		sr.stop()
@@ -32,7 +32,8 @@ import (
 )
 
 var mockSessionEvent = connection.SessionEvent{
-	Status: connection.SessionCreatedStatus,
+	ConnectionID: connection.DefaultConnectionID,
+	Status:       connection.SessionCreatedStatus,
 	SessionInfo: connection.SessionInfo{
 		ConsumerID: identity.FromAddress("0x000"),
 		SessionID:  session.ID("test"),
@@ -78,6 +78,10 @@ func (sst *SessionStatisticsTracker) ConsumeStatisticsEvent(stats consumer.Sessi
 
 // ConsumeSessionEvent handles the session state changes
 func (sst *SessionStatisticsTracker) ConsumeSessionEvent(sessionEvent connection.SessionEvent) {
+	// statistics are kept for the default connection only
+	if sessionEvent.ConnectionID != connection.DefaultConnectionID {
+		return
+	}
 	switch sessionEvent.Status {
 	case connection.SessionEndedStatus:
 		sst.markSessionEnd()
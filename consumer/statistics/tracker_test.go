@@ -70,7 +70,8 @@ func TestStopSessionResetsSessionDuration(t *testing.T) {
 func TestStatisticsTrackerConsumeSessionEventCreated(t *testing.T) {
 	statisticsTracker := NewSessionStatisticsTracker(time.Now)
 	statisticsTracker.ConsumeSessionEvent(connection.SessionEvent{
-		Status: connection.SessionCreatedStatus,
+		ConnectionID: connection.DefaultConnectionID,
+		Status:       connection.SessionCreatedStatus,
 	})
 	assert.NotNil(t, statisticsTracker.sessionStart)
 }
@@ -80,7 +81,17 @@ func TestStatisticsTrackerConsumeSessionEventEnded(t *testing.T) {
 	statisticsTracker := NewSessionStatisticsTracker(time.Now)
 	statisticsTracker.sessionStart = &now
 	statisticsTracker.ConsumeSessionEvent(connection.SessionEvent{
-		Status: connection.SessionEndedStatus,
+		ConnectionID: connection.DefaultConnectionID,
+		Status:       connection.SessionEndedStatus,
+	})
+	assert.Nil(t, statisticsTracker.sessionStart)
+}
+
+func TestStatisticsTrackerIgnoresSessionEventsOfOtherConnections(t *testing.T) {
+	statisticsTracker := NewSessionStatisticsTracker(time.Now)
+	statisticsTracker.ConsumeSessionEvent(connection.SessionEvent{
+		ConnectionID: connection.ID("other"),
+		Status:       connection.SessionCreatedStatus,
 	})
 	assert.Nil(t, statisticsTracker.sessionStart)
 }
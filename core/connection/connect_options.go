@@ -18,6 +18,9 @@
 package connection
 
 import (
+	"net"
+	"time"
+
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/session"
@@ -26,7 +29,52 @@ import (
 // ConnectParams holds plugin specific params
 type ConnectParams struct {
 	// kill switch option restricting communication only through VPN
-	DisableKillSwitch bool
+	EnableKillSwitch bool
+	// Reconnect defines how lost connection is restored
+	Reconnect ReconnectPolicy
+	// IncludeRoutes limits tunnel to given CIDRs, IP addresses or host names, all traffic is tunneled if empty
+	IncludeRoutes []string
+	// ExcludeRoutes keeps given CIDRs, IP addresses or host names outside of the tunnel
+	ExcludeRoutes []string
+	// DNS defines which DNS servers are used while connected
+	DNS DNSOption
+	// EntryHops are proposals of providers which connection is chained through in given order before reaching
+	// the exit provider, so that no single provider sees both consumer's address and its destinations
+	EntryHops []market.ServiceProposal
+	// Timeouts limit duration of each connect phase, so that unresponsive provider doesn't keep connect hanging
+	Timeouts ConnectTimeouts
+	// Limits cap usage of connection, it's disconnected once any of them is reached
+	Limits Limits
+}
+
+// ConnectTimeouts are deadlines of connect phases, zero value means no deadline for the phase
+type ConnectTimeouts struct {
+	// Dialog limits establishing communication dialog with provider
+	Dialog time.Duration
+	// SessionCreate limits waiting for provider to create session
+	SessionCreate time.Duration
+	// TunnelUp limits starting the tunnel of service
+	TunnelUp time.Duration
+	// Handshake limits waiting for started tunnel to report connected state
+	Handshake time.Duration
+}
+
+// DefaultConnectTimeouts are deadlines used when client doesn't specify its own ones
+var DefaultConnectTimeouts = ConnectTimeouts{
+	Dialog:        30 * time.Second,
+	SessionCreate: 30 * time.Second,
+	TunnelUp:      60 * time.Second,
+	Handshake:     60 * time.Second,
+}
+
+// ReconnectPolicy defines how connection manager restores lost connection
+type ReconnectPolicy struct {
+	// MaxAttempts is the number of reconnect attempts, zero disables reconnect
+	MaxAttempts int
+	// Backoff is the delay before the first attempt, it doubles after every failed attempt up to a minute
+	Backoff time.Duration
+	// Failover allows reconnecting to other provider with the same service type and country
+	Failover bool
 }
 
 // ConnectOptions represents the params we need to ensure a successful connection
@@ -36,4 +84,6 @@ type ConnectOptions struct {
 	Proposal      market.ServiceProposal
 	SessionID     session.ID
 	SessionConfig []byte
+	Routes        Routes
+	DNS           []net.IP
 }
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNSOption defines which DNS servers are used while connection is established.
+// Besides the predefined options, comma separated list of server IP addresses can be given.
+type DNSOption string
+
+const (
+	// DNSOptionProvider queries resolvers advertised by provider through the tunnel, so names are resolved from provider's network
+	DNSOptionProvider = DNSOption("provider")
+	// DNSOptionSystem leaves system resolvers untouched
+	DNSOptionSystem = DNSOption("system")
+)
+
+// ErrProviderDNSUnknown indicates that provider DNS servers were asked for, but provider didn't advertise any
+var ErrProviderDNSUnknown = errors.New("provider has not advertised DNS servers")
+
+// Validate checks if DNS option is either a predefined one, or a list of valid server addresses
+func (option DNSOption) Validate() error {
+	switch option {
+	case "", DNSOptionProvider, DNSOptionSystem:
+		return nil
+	}
+	_, err := option.customServers()
+	return err
+}
+
+// Servers returns DNS servers which should be configured for the connection, empty list means system resolvers.
+// Servers advertised by provider are used by default, system resolvers are left in place if provider advertised none.
+func (option DNSOption) Servers(advertised []net.IP) ([]net.IP, error) {
+	switch option {
+	case "":
+		return advertised, nil
+	case DNSOptionProvider:
+		if len(advertised) == 0 {
+			return nil, ErrProviderDNSUnknown
+		}
+		return advertised, nil
+	case DNSOptionSystem:
+		return nil, nil
+	}
+	return option.customServers()
+}
+
+func (option DNSOption) customServers() ([]net.IP, error) {
+	var servers []net.IP
+	for _, address := range strings.Split(string(option), ",") {
+		server := net.ParseIP(strings.TrimSpace(address))
+		if server == nil || server.To4() == nil {
+			return nil, fmt.Errorf("invalid DNS server address %q", address)
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var advertisedDNS = []net.IP{net.ParseIP("10.8.0.1")}
+
+func TestDNSOptionServers(t *testing.T) {
+	servers, err := DNSOption("").Servers(advertisedDNS)
+	assert.NoError(t, err)
+	assert.Equal(t, advertisedDNS, servers)
+
+	servers, err = DNSOptionProvider.Servers(advertisedDNS)
+	assert.NoError(t, err)
+	assert.Equal(t, advertisedDNS, servers)
+
+	servers, err = DNSOptionSystem.Servers(advertisedDNS)
+	assert.NoError(t, err)
+	assert.Empty(t, servers)
+
+	servers, err = DNSOption("1.1.1.1, 8.8.8.8").Servers(advertisedDNS)
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("8.8.8.8")}, servers)
+}
+
+func TestDNSOptionServersWhenProviderAdvertisedNone(t *testing.T) {
+	servers, err := DNSOption("").Servers(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, servers)
+
+	_, err = DNSOptionProvider.Servers(nil)
+	assert.Equal(t, ErrProviderDNSUnknown, err)
+}
+
+func TestDNSOptionServersRejectsInvalidAddresses(t *testing.T) {
+	_, err := DNSOption("1.1.1.1,cloudflare").Servers(advertisedDNS)
+	assert.EqualError(t, err, `invalid DNS server address "cloudflare"`)
+}
+
+func TestDNSOptionValidate(t *testing.T) {
+	assert.NoError(t, DNSOption("").Validate())
+	assert.NoError(t, DNSOptionProvider.Validate())
+	assert.NoError(t, DNSOptionSystem.Validate())
+	assert.NoError(t, DNSOption("1.1.1.1").Validate())
+	assert.EqualError(t, DNSOption("cloudflare").Validate(), `invalid DNS server address "cloudflare"`)
+}
@@ -25,12 +25,15 @@ const (
 	StatisticsEventTopic = "Statistics"
 	// SessionEventTopic represents the session event
 	SessionEventTopic = "Session"
+	// LimitEventTopic represents the connection limits topic
+	LimitEventTopic = "Limit"
 )
 
 // StateEvent is the struct we'll emit on a StateEvent topic event
 type StateEvent struct {
-	State       State
-	SessionInfo SessionInfo
+	ConnectionID ID
+	State        State
+	SessionInfo  SessionInfo
 }
 
 const (
@@ -42,6 +45,25 @@ const (
 
 // SessionEvent represents a session related event
 type SessionEvent struct {
-	Status      string
-	SessionInfo SessionInfo
+	ConnectionID ID
+	Status       string
+	SessionInfo  SessionInfo
+}
+
+// LimitStatus tells how close connection is to its limit
+type LimitStatus string
+
+const (
+	// LimitWarningStatus is published when connection gets close to its limit
+	LimitWarningStatus = LimitStatus("Warning")
+	// LimitReachedStatus is published when limit is reached and connection is being closed
+	LimitReachedStatus = LimitStatus("Reached")
+)
+
+// LimitEvent represents connection approaching or reaching one of its limits
+type LimitEvent struct {
+	ConnectionID ID
+	Status       LimitStatus
+	Limit        Limit
+	Quota        Quota
 }
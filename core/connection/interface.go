@@ -18,8 +18,12 @@
 package connection
 
 import (
+	"encoding/json"
+	"net"
+
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 )
@@ -36,6 +40,19 @@ type Connection interface {
 	Wait() error
 	Stop()
 	GetConfig() (ConsumerConfig, error)
+	// GetTunnel describes the network path of established connection, it's used to restrict traffic with kill switch
+	GetTunnel() (firewall.Tunnel, error)
+}
+
+// EndpointLocator is implemented by connections, which can tell the address of provider's tunnel endpoint from
+// session config before the tunnel is started. It lets the endpoint through kill switch while connection is re-established.
+type EndpointLocator interface {
+	LocateEndpoint(sessionConfig json.RawMessage) (net.IP, error)
+}
+
+// DNSLocator is implemented by connections, which session config tells DNS servers advertised by provider
+type DNSLocator interface {
+	LocateDNS(sessionConfig json.RawMessage) ([]net.IP, error)
 }
 
 // StateChannel is the channel we receive state change events on
@@ -60,6 +77,8 @@ type Manager interface {
 	Connect(consumerID identity.Identity, proposal market.ServiceProposal, params ConnectParams) error
 	// Status queries current status of connection
 	Status() ConnectionStatus
+	// Quota returns limits of connection and how much of them is used
+	Quota() Quota
 	// Disconnect closes established connection, reports error if no connection
 	Disconnect() error
 }
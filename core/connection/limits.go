@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"context"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+)
+
+// LimitWarningShare is the used share of a limit, after which warning is published
+const LimitWarningShare = 0.9
+
+// Limit names usage dimension which can be capped
+type Limit string
+
+const (
+	// LimitBytes caps bytes sent and received
+	LimitBytes = Limit("Bytes")
+	// LimitDuration caps connection duration
+	LimitDuration = Limit("Duration")
+	// LimitSpend caps money paid for the service
+	LimitSpend = Limit("Spend")
+)
+
+// Limits cap usage of connection, it's disconnected once any of them is reached. Zero value means no limit.
+type Limits struct {
+	MaxBytes    uint64
+	MaxDuration time.Duration
+	MaxSpend    money.Money
+}
+
+// Usage is consumption of connection counted against its limits
+type Usage struct {
+	Bytes    uint64
+	Duration time.Duration
+	Spend    money.Money
+}
+
+// Quota holds limits of connection together with its usage
+type Quota struct {
+	Limits Limits
+	Used   Usage
+}
+
+// HasLimits tells whether connection is limited at all
+func (quota Quota) HasLimits() bool {
+	return len(quota.usedShares()) > 0
+}
+
+// BytesLeft returns bytes which can be transferred until the bytes limit is reached
+func (quota Quota) BytesLeft() uint64 {
+	if quota.Used.Bytes >= quota.Limits.MaxBytes {
+		return 0
+	}
+	return quota.Limits.MaxBytes - quota.Used.Bytes
+}
+
+// DurationLeft returns time left until the duration limit is reached
+func (quota Quota) DurationLeft() time.Duration {
+	if quota.Used.Duration >= quota.Limits.MaxDuration {
+		return 0
+	}
+	return quota.Limits.MaxDuration - quota.Used.Duration
+}
+
+// SpendLeft returns money which can be spent until the spend limit is reached
+func (quota Quota) SpendLeft() money.Money {
+	left := money.Money{Currency: quota.Limits.MaxSpend.Currency}
+	if quota.Used.Spend.Amount < quota.Limits.MaxSpend.Amount {
+		left.Amount = quota.Limits.MaxSpend.Amount - quota.Used.Spend.Amount
+	}
+	return left
+}
+
+// usedShares returns used share of every limit which is set
+func (quota Quota) usedShares() map[Limit]float64 {
+	shares := make(map[Limit]float64)
+	if quota.Limits.MaxBytes > 0 {
+		shares[LimitBytes] = float64(quota.Used.Bytes) / float64(quota.Limits.MaxBytes)
+	}
+	if quota.Limits.MaxDuration > 0 {
+		shares[LimitDuration] = float64(quota.Used.Duration) / float64(quota.Limits.MaxDuration)
+	}
+	if quota.Limits.MaxSpend.Amount > 0 {
+		shares[LimitSpend] = float64(quota.Used.Spend.Amount) / float64(quota.Limits.MaxSpend.Amount)
+	}
+	return shares
+}
+
+// estimateSpend calculates price of used service by metering unit of the payment method.
+// Price of the method without metering unit is paid once, as it's promised on connect.
+func estimateSpend(method market.PaymentMethod, bytes uint64, duration time.Duration) money.Money {
+	if method == nil {
+		return money.Money{}
+	}
+	if _, unsupported := method.(market.UnsupportedPaymentMethod); unsupported {
+		return money.Money{}
+	}
+
+	price := method.GetPrice()
+	units := 1.0
+	switch metered := method.(type) {
+	case market.TimeMeteredPaymentMethod:
+		if metered.GetDuration() > 0 {
+			units = float64(duration) / float64(metered.GetDuration())
+		}
+	case market.BytesMeteredPaymentMethod:
+		if metered.GetBytes() > 0 {
+			units = float64(bytes) / metered.GetBytes().Bytes()
+		}
+	}
+	return money.Money{Amount: uint64(float64(price.Amount) * units), Currency: price.Currency}
+}
+
+// watchLimits periodically checks usage of connection, warns when it gets close to the limits
+// and disconnects when any of them is reached
+func (manager *connectionManager) watchLimits(ctx context.Context) {
+	ticker := time.NewTicker(manager.limitsCheckInterval)
+	defer ticker.Stop()
+
+	warned := make(map[Limit]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		quota := manager.Quota()
+		for limit, share := range quota.usedShares() {
+			if share >= 1 {
+				log.Info(managerLogPrefix, "Connection limit reached, disconnecting: ", limit)
+				manager.publishLimitEvent(LimitReachedStatus, limit, quota)
+				// kill switch stays in place until user disconnects, as after any other connection loss
+				if err := manager.disconnect(); err != nil && err != ErrNoConnection {
+					log.Error(managerLogPrefix, "Failed to disconnect on reached limit: ", err)
+				}
+				return
+			}
+			if share >= LimitWarningShare && !warned[limit] {
+				warned[limit] = true
+				manager.publishLimitEvent(LimitWarningStatus, limit, quota)
+			}
+		}
+	}
+}
+
+func (manager *connectionManager) publishLimitEvent(status LimitStatus, limit Limit, quota Quota) {
+	manager.eventPublisher.Publish(LimitEventTopic, LimitEvent{
+		ConnectionID: manager.id,
+		Status:       status,
+		Limit:        limit,
+		Quota:        quota,
+	})
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePaymentPerTime struct {
+	price    money.Money
+	duration time.Duration
+}
+
+func (method fakePaymentPerTime) GetPrice() money.Money      { return method.price }
+func (method fakePaymentPerTime) GetDuration() time.Duration { return method.duration }
+
+type fakePaymentPerBytes struct {
+	price money.Money
+	bytes datasize.BitSize
+}
+
+func (method fakePaymentPerBytes) GetPrice() money.Money      { return method.price }
+func (method fakePaymentPerBytes) GetBytes() datasize.BitSize { return method.bytes }
+
+type fakeFlatPayment struct {
+	price money.Money
+}
+
+func (method fakeFlatPayment) GetPrice() money.Money { return method.price }
+
+func TestEstimateSpendByPaymentMethod(t *testing.T) {
+	price := money.NewMoney(1, money.CURRENCY_MYST)
+	tests := []struct {
+		method   market.PaymentMethod
+		expected money.Money
+	}{
+		{fakePaymentPerTime{price, time.Hour}, money.NewMoney(0.5, money.CURRENCY_MYST)},
+		{fakePaymentPerBytes{price, 2 * datasize.KB}, money.NewMoney(2, money.CURRENCY_MYST)},
+		{fakeFlatPayment{price}, price},
+		{market.UnsupportedPaymentMethod{}, money.Money{}},
+		{nil, money.Money{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, estimateSpend(test.method, 4096, 30*time.Minute))
+	}
+}
+
+func TestQuotaReturnsWhatIsLeftOfLimits(t *testing.T) {
+	quota := Quota{
+		Limits: Limits{MaxBytes: 100, MaxDuration: time.Minute, MaxSpend: money.Money{Amount: 10, Currency: money.CURRENCY_MYST}},
+		Used:   Usage{Bytes: 120, Duration: 20 * time.Second, Spend: money.Money{Amount: 4, Currency: money.CURRENCY_MYST}},
+	}
+
+	assert.True(t, quota.HasLimits())
+	assert.Equal(t, uint64(0), quota.BytesLeft())
+	assert.Equal(t, 40*time.Second, quota.DurationLeft())
+	assert.Equal(t, money.Money{Amount: 6, Currency: money.CURRENCY_MYST}, quota.SpendLeft())
+	assert.False(t, Quota{Used: quota.Used}.HasLimits())
+}
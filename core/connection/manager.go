@@ -19,8 +19,12 @@ package connection
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net"
+	"net/url"
 	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
@@ -44,6 +48,16 @@ var (
 	ErrConnectionFailed = errors.New("connection has failed")
 	// ErrUnsupportedServiceType indicates that target proposal contains unsupported service type
 	ErrUnsupportedServiceType = errors.New("unsupported service type in proposal")
+	// ErrMultiHopKillSwitch indicates that kill switch was requested for connection chained through several providers
+	ErrMultiHopKillSwitch = errors.New("kill switch is not supported for multi-hop connections")
+	// ErrDialogTimeout indicates that dialog with provider wasn't established in time
+	ErrDialogTimeout = errors.New("connect timed out while establishing dialog with provider")
+	// ErrSessionCreateTimeout indicates that provider didn't create session in time
+	ErrSessionCreateTimeout = errors.New("connect timed out while creating session")
+	// ErrTunnelTimeout indicates that tunnel wasn't started in time
+	ErrTunnelTimeout = errors.New("connect timed out while bringing tunnel up")
+	// ErrHandshakeTimeout indicates that started tunnel didn't report connected state in time
+	ErrHandshakeTimeout = errors.New("connect timed out while waiting for the first handshake")
 )
 
 // Creator creates new connection by given options and uses state channel to report state changes
@@ -56,24 +70,54 @@ type SessionInfo struct {
 	Proposal   market.ServiceProposal
 }
 
+// ProposalProvider finds proposals of given provider and service type, empty values match any
+type ProposalProvider interface {
+	FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error)
+}
+
 // Publisher is responsible for publishing given events
 type Publisher interface {
 	Publish(topic string, args ...interface{})
 }
 
 type connectionManager struct {
+	id ID
 	//these are passed on creation
 	newDialog        DialogCreator
 	newPromiseIssuer PromiseIssuerCreator
 	newConnection    Creator
 	eventPublisher   Publisher
+	proposalProvider ProposalProvider
+	killSwitch       firewall.KillSwitch
+	dnsLeakBlocker   firewall.DNSLeakBlocker
+	ipv6LeakBlocker  firewall.IPv6LeakBlocker
+	// limitsCheckInterval is period of checking usage of limited connection
+	limitsCheckInterval time.Duration
+	// minReconnectBackoff is the least delay before reconnect attempt, so that attempts don't follow each other
+	minReconnectBackoff time.Duration
+	// maxReconnectBackoff is the delay which doubling backoff of failed reconnect attempts stops at
+	maxReconnectBackoff time.Duration
+	// reachableHosts are addresses of discovery and broker, which are let through kill switch while connecting
+	reachableHosts []string
+
+	// killSwitchTunnel is the tunnel which traffic is restricted to, nil if kill switch is disabled
+	killSwitchMutex  sync.Mutex
+	killSwitchTunnel *firewall.Tunnel
+	// reachable are resolved addresses of reachable hosts, they are resolved while traffic isn't restricted
+	reachable []net.IP
+	// killSwitchAllowed are destinations let through kill switch besides the tunnel
+	killSwitchAllowed []net.IP
 
 	//these are populated by Connect at runtime
-	ctx             context.Context
 	mutex           sync.RWMutex
 	status          ConnectionStatus
 	sessionInfo     SessionInfo
 	cleanConnection func()
+	cleanSession    func()
+	statistics      consumer.SessionStatistics
+	startedAt       time.Time
+	limits          Limits
+	tunnel          firewall.Tunnel
 }
 
 // NewManager creates connection manager with given dependencies
@@ -82,14 +126,24 @@ func NewManager(
 	promiseIssuerCreator PromiseIssuerCreator,
 	connectionCreator Creator,
 	eventPublisher Publisher,
+	proposalProvider ProposalProvider,
 ) *connectionManager {
 	return &connectionManager{
-		newDialog:        dialogCreator,
-		newPromiseIssuer: promiseIssuerCreator,
-		newConnection:    connectionCreator,
-		status:           statusNotConnected(),
-		cleanConnection:  warnOnClean,
-		eventPublisher:   eventPublisher,
+		id:                  DefaultConnectionID,
+		newDialog:           dialogCreator,
+		newPromiseIssuer:    promiseIssuerCreator,
+		newConnection:       connectionCreator,
+		status:              statusNotConnected(),
+		cleanConnection:     warnOnClean,
+		cleanSession:        func() {},
+		eventPublisher:      eventPublisher,
+		proposalProvider:    proposalProvider,
+		killSwitch:          firewall.NewKillSwitch(),
+		dnsLeakBlocker:      firewall.NewDNSLeakBlocker(),
+		ipv6LeakBlocker:     firewall.NewIPv6LeakBlocker(),
+		limitsCheckInterval: time.Second,
+		minReconnectBackoff: time.Second,
+		maxReconnectBackoff: time.Minute,
 	}
 }
 
@@ -98,9 +152,18 @@ func (manager *connectionManager) Connect(consumerID identity.Identity, proposal
 		return ErrAlreadyExists
 	}
 
+	ctx, cancelCtx := context.WithCancel(context.Background())
 	manager.mutex.Lock()
-	manager.ctx, manager.cleanConnection = context.WithCancel(context.Background())
+	manager.cleanSession = func() {}
+	// concurrent disconnects clean the connection once, the others wait for it to be cleaned
+	manager.cleanConnection = onceFunc(func() {
+		cancelCtx()
+		manager.cleanSession()
+	})
 	manager.status = statusConnecting()
+	manager.statistics = consumer.SessionStatistics{}
+	manager.startedAt = time.Now()
+	manager.limits = params.Limits
 	manager.mutex.Unlock()
 	defer func() {
 		if err != nil {
@@ -110,39 +173,80 @@ func (manager *connectionManager) Connect(consumerID identity.Identity, proposal
 		}
 	}()
 
-	err = manager.startConnection(consumerID, proposal, params)
+	err = manager.startConnection(ctx, consumerID, proposal, params)
 	if err == context.Canceled {
 		return ErrConnectionCancelled
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	if (Quota{Limits: params.Limits}).HasLimits() {
+		go manager.watchLimits(ctx)
+	}
+	return nil
 }
 
-func (manager *connectionManager) startConnection(consumerID identity.Identity, proposal market.ServiceProposal, params ConnectParams) (err error) {
-	manager.mutex.Lock()
-	cancelCtx := manager.cleanConnection
-	manager.mutex.Unlock()
+func (manager *connectionManager) startConnection(ctx context.Context, consumerID identity.Identity, proposal market.ServiceProposal, params ConnectParams) (err error) {
+	if params.EnableKillSwitch && len(params.EntryHops) > 0 {
+		return ErrMultiHopKillSwitch
+	}
 
-	var cancel []func()
-	defer func() {
-		manager.cleanConnection = func() {
-			manager.status = statusDisconnecting()
-			cancelCtx()
-			for _, f := range cancel {
-				f()
+	// kill switch may be left enabled by the connection which was lost, it stays in place while connecting,
+	// only discovery, broker and provider endpoint are let through until the new connection is established
+	restricted, err := manager.allowThroughKillSwitch()
+	if err != nil {
+		return err
+	}
+	if restricted {
+		defer func() {
+			if err != nil {
+				manager.revokeKillSwitchAllowance()
 			}
-		}
+		}()
+	}
+
+	if err = params.DNS.Validate(); err != nil {
+		return err
+	}
+	routes, err := resolveConnectRoutes(params)
+	if err != nil {
+		return err
+	}
+
+	sessionCleaned := make(chan struct{})
+	var cancel, cancelHops []func()
+	// steps are undone in reverse order, e.g. session is destroyed before its dialog is closed.
+	// Entry hops are torn down after the exit one, so that exit provider stays reachable while cleaning up
+	cleanSession := func() {
+		close(sessionCleaned)
+		runInReverse(cancel)
+		runInReverse(cancelHops)
+	}
+	defer func() {
 		if err != nil {
 			log.Info(managerLogPrefix, "Cancelling connection initiation")
-			defer manager.cleanConnection()
+			cleanSession()
 		}
 	}()
 
+	hopStateChannels := make([]StateChannel, 0, len(params.EntryHops))
+	for _, hop := range params.EntryHops {
+		hopStateChannel, hopTunnel, err := manager.startEntryHop(ctx, params.Timeouts, consumerID, hop, routes.Via, &cancelHops)
+		if err != nil {
+			return err
+		}
+		hopStateChannels = append(hopStateChannels, hopStateChannel)
+		routes.Via = hopTunnel.Interface
+	}
+
 	providerID := identity.FromAddress(proposal.ProviderID)
-	dialog, err := manager.newDialog(consumerID, providerID, proposal.ProviderContacts[0])
+	dialog, err := manager.createDialog(ctx, params.Timeouts, consumerID, providerID, proposal.ProviderContacts[0])
 	if err != nil {
 		return err
 	}
-	cancel = append(cancel, func() { dialog.Close() })
+	var sessionSettled <-chan struct{}
+	cancel = append(cancel, func() { closeDialog(dialog, sessionSettled) })
 
 	stateChannel := make(chan State, 10)
 	statisticsChannel := make(chan consumer.SessionStatistics, 10)
@@ -157,29 +261,60 @@ func (manager *connectionManager) startConnection(consumerID identity.Identity,
 		return err
 	}
 
-	sessionID, sessionConfig, err := session.RequestSessionCreate(dialog, proposal.ID, sessionCreateConfig)
+	sessionID, sessionConfig, settled, err := manager.createSession(ctx, params.Timeouts, dialog, proposal.ID, sessionCreateConfig)
+	sessionSettled = settled
 	if err != nil {
 		return err
 	}
 
 	cancel = append(cancel, func() { session.RequestSessionDestroy(dialog, sessionID) })
 
+	if restricted {
+		if err = manager.allowEndpointThroughKillSwitch(connection, sessionConfig); err != nil {
+			return err
+		}
+	}
+
+	dnsServers, err := params.DNS.Servers(locateDNS(connection, sessionConfig))
+	if err != nil {
+		return err
+	}
+
+	err = dialog.Receive(&session.EndedMessageConsumer{
+		Callback: func(message session.EndedMessage) error {
+			if session.ID(message.SessionID) != sessionID {
+				return nil
+			}
+			log.Info(managerLogPrefix, "Provider ended session ", sessionID, ", disconnecting")
+			go manager.disconnect()
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
 	// set the session info for future use
-	manager.sessionInfo = SessionInfo{
+	sessionInfo := SessionInfo{
 		SessionID:  sessionID,
 		ConsumerID: consumerID,
 		Proposal:   proposal,
 	}
+	manager.mutex.Lock()
+	manager.sessionInfo = sessionInfo
+	manager.mutex.Unlock()
 
 	manager.eventPublisher.Publish(SessionEventTopic, SessionEvent{
-		Status:      SessionCreatedStatus,
-		SessionInfo: manager.sessionInfo,
+		ConnectionID: manager.id,
+		Status:       SessionCreatedStatus,
+		SessionInfo:  sessionInfo,
 	})
 
 	cancel = append(cancel, func() {
 		manager.eventPublisher.Publish(SessionEventTopic, SessionEvent{
-			Status:      SessionEndedStatus,
-			SessionInfo: manager.sessionInfo,
+			ConnectionID: manager.id,
+			Status:       SessionEndedStatus,
+			SessionInfo:  sessionInfo,
 		})
 	})
 
@@ -196,30 +331,386 @@ func (manager *connectionManager) startConnection(consumerID identity.Identity,
 		ConsumerID:    consumerID,
 		ProviderID:    providerID,
 		Proposal:      proposal,
+		Routes:        routeDNSServers(routes, dnsServers),
+		DNS:           dnsServers,
 	}
 
-	if err = connection.Start(connectOptions); err != nil {
+	if err = startTunnel(ctx, params.Timeouts, connection, connectOptions); err != nil {
 		return err
 	}
-	cancel = append(cancel, connection.Stop)
+	stopConnection := onceFunc(connection.Stop)
+	cancel = append(cancel, stopConnection)
 
-	err = manager.waitForConnectedState(stateChannel, sessionID)
+	err = manager.waitForConnectedState(ctx, params.Timeouts, stateChannel)
 	if err != nil {
 		return err
 	}
 
-	if !params.DisableKillSwitch {
-		// TODO: Implement fw based kill switch for respective OS
-		// we may need to wait for tun device setup to be finished
-		firewall.NewKillSwitch().Enable()
+	// tunnel is reported for diagnostics too, so failing lookup matters only when traffic has to be restricted
+	tunnel, tunnelErr := connection.GetTunnel()
+	if tunnelErr != nil && (params.EnableKillSwitch || len(dnsServers) > 0) {
+		return tunnelErr
+	}
+	tunnel.Excluded = routes.Exclude
+
+	// system resolvers are outside of the tunnel, connections without tunnel interface have nothing to protect.
+	// Choosing DNS servers explicitly means asking to keep queries in the tunnel, so connection fails without it,
+	// while connection with default DNS option is established even where leaks can't be blocked (e.g. without iptables)
+	if len(dnsServers) > 0 && tunnel.Interface != "" {
+		if err = manager.dnsLeakBlocker.Enable(tunnel); err == nil {
+			cancel = append(cancel, manager.disableDNSLeakBlocker)
+		} else if params.DNS == "" {
+			log.Warn(managerLogPrefix, "Failed to block DNS leaks, queries may leave outside of the tunnel: ", err)
+			err = nil
+		} else {
+			log.Error(managerLogPrefix, "Failed to block DNS leaks: ", err)
+			return err
+		}
+	}
+
+	// tunnel carrying only IPv4 would let IPv6 traffic leave outside, unless only chosen networks are tunnelled
+	if tunnel.Interface != "" && !tunnel.IPv6 && len(routes.Include) == 0 {
+		if err = manager.ipv6LeakBlocker.Enable(tunnel); err != nil {
+			log.Error(managerLogPrefix, "Failed to block IPv6 leaks: ", err)
+			return err
+		}
+		cancel = append(cancel, manager.disableIPv6LeakBlocker)
+	}
+
+	// kill switch is removed only by user's disconnect, it stays while connection is reconnecting or lost
+	if params.EnableKillSwitch {
+		if !restricted {
+			manager.resolveReachableHosts()
+		}
+		// disconnect removes kill switch, it must not be enabled again after connecting was cancelled
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = manager.enableKillSwitch(tunnel); err != nil {
+			return err
+		}
+	} else if restricted {
+		// user has chosen to connect without kill switch left by the previous connection
+		manager.disableKillSwitch()
+	}
+
+	manager.mutex.Lock()
+	manager.cleanSession = cleanSession
+	manager.tunnel = tunnel
+	manager.mutex.Unlock()
+	// disconnect could be requested while session cleanup wasn't registered yet
+	if err = ctx.Err(); err != nil {
+		return err
 	}
 
 	go manager.consumeStats(statisticsChannel)
-	go manager.consumeConnectionStates(stateChannel)
-	go connectionWaiter(connection, dialog, promiseIssuer)
+	var reconnect func()
+	if params.Reconnect.MaxAttempts > 0 {
+		reconnect = func() { manager.reconnect(ctx, consumerID, proposal, params) }
+	}
+	go manager.consumeConnectionStates(ctx, stateChannel, reconnect)
+	go connectionWaiter(connection)
+	for _, hopStateChannel := range hopStateChannels {
+		go watchEntryHop(ctx, sessionCleaned, hopStateChannel, stopConnection)
+	}
 	return nil
 }
 
+// startEntryHop establishes connection to intermediate provider, which next hop's dialog and tunnel go through.
+// Entry hop tunnels all traffic, so it has no routes of its own except the one to its provider via previous hop.
+func (manager *connectionManager) startEntryHop(ctx context.Context, timeouts ConnectTimeouts, consumerID identity.Identity, proposal market.ServiceProposal, via string, cancel *[]func()) (StateChannel, firewall.Tunnel, error) {
+	providerID := identity.FromAddress(proposal.ProviderID)
+	dialog, err := manager.createDialog(ctx, timeouts, consumerID, providerID, proposal.ProviderContacts[0])
+	if err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+	var sessionSettled <-chan struct{}
+	*cancel = append(*cancel, func() { closeDialog(dialog, sessionSettled) })
+
+	stateChannel := make(chan State, 10)
+	statisticsChannel := make(chan consumer.SessionStatistics, 10)
+
+	connection, err := manager.newConnection(proposal.ServiceType, stateChannel, statisticsChannel)
+	if err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+
+	sessionCreateConfig, err := connection.GetConfig()
+	if err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+
+	sessionID, sessionConfig, settled, err := manager.createSession(ctx, timeouts, dialog, proposal.ID, sessionCreateConfig)
+	sessionSettled = settled
+	if err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+	*cancel = append(*cancel, func() { session.RequestSessionDestroy(dialog, sessionID) })
+
+	if err = manager.allowEndpointThroughKillSwitch(connection, sessionConfig); err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+
+	promiseIssuer := manager.newPromiseIssuer(consumerID, dialog)
+	if err = promiseIssuer.Start(proposal); err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+	*cancel = append(*cancel, func() { promiseIssuer.Stop() })
+
+	err = startTunnel(ctx, timeouts, connection, ConnectOptions{
+		SessionID:     sessionID,
+		SessionConfig: sessionConfig,
+		ConsumerID:    consumerID,
+		ProviderID:    providerID,
+		Proposal:      proposal,
+		Routes:        Routes{Via: via},
+	})
+	if err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+	*cancel = append(*cancel, connection.Stop)
+
+	if err = manager.waitForConnectedState(ctx, timeouts, stateChannel); err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+	// statistics of entry hop are not reported, consumer sees traffic of the exit hop only
+	go func() {
+		for range statisticsChannel {
+		}
+	}()
+
+	tunnel, err := connection.GetTunnel()
+	if err != nil {
+		return nil, firewall.Tunnel{}, err
+	}
+	log.Info(managerLogPrefix, "Entry hop connected to provider: ", proposal.ProviderID)
+	return stateChannel, tunnel, nil
+}
+
+// watchEntryHop stops chained connection when entry hop is lost, so it's handled as any other lost connection
+func watchEntryHop(ctx context.Context, sessionCleaned <-chan struct{}, stateChannel StateChannel, stopChained func()) {
+	for state := range stateChannel {
+		if state == NotConnected {
+			break
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-sessionCleaned:
+	default:
+		log.Warn(managerLogPrefix, "Entry hop was lost, stopping chained connection")
+		stopChained()
+	}
+}
+
+// createDialog establishes dialog with provider unless deadline of dialog phase passes first
+func (manager *connectionManager) createDialog(ctx context.Context, timeouts ConnectTimeouts, consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error) {
+	// results are owned by the step until it's done, they must not be read if the step was given up
+	var dialog communication.Dialog
+	err := runPhase(
+		ctx,
+		timeouts.Dialog,
+		ErrDialogTimeout,
+		func() (err error) {
+			dialog, err = manager.newDialog(consumerID, providerID, contact)
+			return err
+		},
+		func() { dialog.Close() },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return dialog, nil
+}
+
+// createSession requests provider to create session unless deadline of session create phase passes first.
+// Session created after the phase was given up is destroyed through the dialog, returned channel is closed
+// once the request is settled this way, so that dialog is closed only after it.
+func (manager *connectionManager) createSession(ctx context.Context, timeouts ConnectTimeouts, dialog communication.Dialog, proposalID int, config ConsumerConfig) (session.ID, json.RawMessage, <-chan struct{}, error) {
+	settled := make(chan struct{})
+	var sessionID session.ID
+	var sessionConfig json.RawMessage
+	err := runPhase(
+		ctx,
+		timeouts.SessionCreate,
+		ErrSessionCreateTimeout,
+		func() (err error) {
+			sessionID, sessionConfig, err = session.RequestSessionCreate(dialog, proposalID, config)
+			if err != nil {
+				close(settled)
+			}
+			return err
+		},
+		func() {
+			session.RequestSessionDestroy(dialog, sessionID)
+			close(settled)
+		},
+	)
+	if err != nil {
+		return session.ID(""), nil, settled, err
+	}
+	close(settled)
+	return sessionID, sessionConfig, settled, nil
+}
+
+// closeDialog closes dialog once session create request sent through it is settled, nil channel means no request was sent
+func closeDialog(dialog communication.Dialog, sessionSettled <-chan struct{}) {
+	if sessionSettled != nil {
+		select {
+		case <-sessionSettled:
+		default:
+			// abandoned request still needs the dialog to destroy the session it may create
+			go func() {
+				<-sessionSettled
+				dialog.Close()
+			}()
+			return
+		}
+	}
+	dialog.Close()
+}
+
+// runInReverse runs cleanup steps in reverse order of their setup
+func runInReverse(steps []func()) {
+	for i := len(steps) - 1; i >= 0; i-- {
+		steps[i]()
+	}
+}
+
+// startTunnel starts connection unless deadline of tunnel up phase passes first
+func startTunnel(ctx context.Context, timeouts ConnectTimeouts, connection Connection, options ConnectOptions) error {
+	return runPhase(
+		ctx,
+		timeouts.TunnelUp,
+		ErrTunnelTimeout,
+		func() error { return connection.Start(options) },
+		connection.Stop,
+	)
+}
+
+// runPhase runs blocking step of connect in background and waits for it until the step is done, its deadline passes
+// or connect is cancelled. Step which succeeds after it was given up is reverted by undo, as nobody else owns its result.
+func runPhase(ctx context.Context, timeout time.Duration, timeoutErr error, step func() error, undo func()) error {
+	done := make(chan error, 1)
+	go func() { done <- step() }()
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var err error
+	select {
+	case err = <-done:
+		return err
+	case <-deadline:
+		err = timeoutErr
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	log.Warn(managerLogPrefix, "Giving up connect phase: ", err)
+	go func() {
+		if stepErr := <-done; stepErr == nil {
+			undo()
+		}
+	}()
+	return err
+}
+
+func onceFunc(f func()) func() {
+	var once sync.Once
+	return func() { once.Do(f) }
+}
+
+// reconnect rebuilds dialog, session and connection after established connection was lost.
+// Failover to other providers is tried after the first attempt fails, if enabled by policy.
+// Kill switch stays enabled while reconnecting, only discovery, broker and provider endpoints are let through it.
+func (manager *connectionManager) reconnect(ctx context.Context, consumerID identity.Identity, proposal market.ServiceProposal, params ConnectParams) {
+	policy := params.Reconnect
+	tried := map[string]bool{proposal.ProviderID: true}
+	target := proposal
+	backoff := policy.Backoff
+	if backoff < manager.minReconnectBackoff {
+		backoff = manager.minReconnectBackoff
+	}
+
+	for attempt := 1; attempt <= policy.MaxAttempts && ctx.Err() == nil; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			continue
+		}
+		backoff = nextReconnectBackoff(backoff, manager.maxReconnectBackoff)
+
+		if attempt > 1 && policy.Failover {
+			if next, found := manager.findFailoverProposal(proposal, tried); found {
+				log.Info(managerLogPrefix, "Failing over to provider: ", next.ProviderID)
+				tried[next.ProviderID] = true
+				target = next
+			}
+		}
+
+		log.Info(managerLogPrefix, "Reconnecting, attempt ", attempt, " of ", policy.MaxAttempts)
+
+		err := manager.startConnection(ctx, consumerID, target, params)
+		if err == nil {
+			return
+		}
+		log.Warn(managerLogPrefix, "Reconnect attempt failed: ", err)
+	}
+
+	if ctx.Err() == nil {
+		log.Warn(managerLogPrefix, "Giving up reconnecting after ", policy.MaxAttempts, " attempts")
+	}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.status = statusNotConnected()
+}
+
+// nextReconnectBackoff doubles the delay before reconnect attempt up to the maximum one, longer delay given by policy is kept
+func nextReconnectBackoff(backoff, max time.Duration) time.Duration {
+	if backoff >= max {
+		return backoff
+	}
+	if backoff*2 > max {
+		return max
+	}
+	return backoff * 2
+}
+
+// findFailoverProposal looks up the proposal of other provider with the same service type and country
+func (manager *connectionManager) findFailoverProposal(proposal market.ServiceProposal, excludedProviders map[string]bool) (market.ServiceProposal, bool) {
+	// country of the original proposal isn't known without its service definition
+	if manager.proposalProvider == nil || proposal.ServiceDefinition == nil {
+		return market.ServiceProposal{}, false
+	}
+
+	proposals, err := manager.proposalProvider.FindProposals("", proposal.ServiceType)
+	if err != nil {
+		log.Warn(managerLogPrefix, "Failed to find proposals for failover: ", err)
+		return market.ServiceProposal{}, false
+	}
+
+	country := proposal.ServiceDefinition.GetLocation().Country
+	for _, candidate := range proposals {
+		if excludedProviders[candidate.ProviderID] || candidate.ServiceDefinition == nil {
+			continue
+		}
+		if candidate.Load != nil && candidate.Load.IsFull() {
+			continue
+		}
+		if candidate.ServiceDefinition.GetLocation().Country == country {
+			return candidate, true
+		}
+	}
+	return market.ServiceProposal{}, false
+}
+
 func (manager *connectionManager) Status() ConnectionStatus {
 	manager.mutex.RLock()
 	defer manager.mutex.RUnlock()
@@ -227,34 +718,245 @@ func (manager *connectionManager) Status() ConnectionStatus {
 	return manager.status
 }
 
-func (manager *connectionManager) Disconnect() error {
+// Tunnel returns the network path of established connection, it's not known while connection isn't established
+func (manager *connectionManager) Tunnel() (firewall.Tunnel, bool) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	if manager.status.State != Connected || manager.tunnel.Interface == "" {
+		return firewall.Tunnel{}, false
+	}
+	return manager.tunnel, true
+}
+
+// Statistics returns traffic of the connection summed up over all of its sessions and the time since connect was requested
+func (manager *connectionManager) Statistics() (consumer.SessionStatistics, time.Duration) {
 	manager.mutex.RLock()
 	defer manager.mutex.RUnlock()
 
 	if manager.status.State == NotConnected {
+		return manager.statistics, 0
+	}
+	return manager.statistics, time.Since(manager.startedAt)
+}
+
+// Quota returns limits of the connection with its usage counted since connect was requested
+func (manager *connectionManager) Quota() Quota {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	var duration time.Duration
+	if manager.status.State != NotConnected {
+		duration = time.Since(manager.startedAt)
+	}
+	bytes := manager.statistics.BytesSent + manager.statistics.BytesReceived
+	return Quota{
+		Limits: manager.limits,
+		Used: Usage{
+			Bytes:    bytes,
+			Duration: duration,
+			Spend:    estimateSpend(manager.sessionInfo.Proposal.PaymentMethod, bytes, duration),
+		},
+	}
+}
+
+// Disconnect closes connection by request of user. Kill switch is removed only this way,
+// so it stays enabled while connection is lost, reconnecting or closed by provider or limits.
+func (manager *connectionManager) Disconnect() error {
+	err := manager.disconnect()
+	manager.disableKillSwitch()
+	return err
+}
+
+// disconnect closes connection, leaving kill switch enabled
+func (manager *connectionManager) disconnect() error {
+	manager.mutex.Lock()
+	if manager.status.State == NotConnected {
+		manager.mutex.Unlock()
 		return ErrNoConnection
 	}
+	// disconnect may come from provider or limits watcher, concurrently with status readers
+	manager.status = statusDisconnecting()
+	manager.mutex.Unlock()
+
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
 	manager.cleanConnection()
 	return nil
 }
 
+// KillSwitchTunnel returns the tunnel which traffic is restricted to, if kill switch is enabled
+func (manager *connectionManager) KillSwitchTunnel() (firewall.Tunnel, bool) {
+	manager.killSwitchMutex.Lock()
+	defer manager.killSwitchMutex.Unlock()
+
+	if manager.killSwitchTunnel == nil {
+		return firewall.Tunnel{}, false
+	}
+	return *manager.killSwitchTunnel, true
+}
+
+// RestoreKillSwitch restricts traffic to the tunnel of previous connection, so nothing leaks until connection is re-established.
+// Discovery and broker are let through it, so that the previous connection can be looked up and re-established.
+// Kill switch stays enabled until the next connect replaces it or user disconnects.
+func (manager *connectionManager) RestoreKillSwitch(tunnel firewall.Tunnel) error {
+	if manager.Status().State != NotConnected {
+		return ErrAlreadyExists
+	}
+	manager.resolveReachableHosts()
+	if err := manager.enableKillSwitch(tunnel); err != nil {
+		return err
+	}
+	_, err := manager.allowThroughKillSwitch()
+	return err
+}
+
+func (manager *connectionManager) enableKillSwitch(tunnel firewall.Tunnel) error {
+	manager.killSwitchMutex.Lock()
+	defer manager.killSwitchMutex.Unlock()
+
+	if err := manager.killSwitch.Enable(tunnel); err != nil {
+		return err
+	}
+	manager.killSwitchTunnel = &tunnel
+	manager.killSwitchAllowed = nil
+	return nil
+}
+
+func (manager *connectionManager) disableKillSwitch() {
+	manager.killSwitchMutex.Lock()
+	defer manager.killSwitchMutex.Unlock()
+
+	if err := manager.killSwitch.Disable(); err != nil {
+		log.Error(managerLogPrefix, "Failed to disable kill switch: ", err)
+		return
+	}
+	manager.killSwitchTunnel = nil
+	manager.killSwitchAllowed = nil
+}
+
+// allowThroughKillSwitch lets reachable hosts and given endpoints through kill switch, if it's enabled
+func (manager *connectionManager) allowThroughKillSwitch(endpoints ...net.IP) (bool, error) {
+	manager.killSwitchMutex.Lock()
+	defer manager.killSwitchMutex.Unlock()
+
+	if manager.killSwitchTunnel == nil {
+		return false, nil
+	}
+
+	allowed := append([]net.IP{}, manager.killSwitchAllowed...)
+	if len(allowed) == 0 {
+		allowed = append(allowed, manager.reachable...)
+	}
+	allowed = append(allowed, endpoints...)
+	if err := manager.killSwitch.Allow(allowed); err != nil {
+		return true, err
+	}
+	manager.killSwitchAllowed = allowed
+	return true, nil
+}
+
+// allowEndpointThroughKillSwitch lets provider's tunnel endpoint through kill switch, if the connection can tell it
+func (manager *connectionManager) allowEndpointThroughKillSwitch(connection Connection, sessionConfig json.RawMessage) error {
+	locator, ok := connection.(EndpointLocator)
+	if !ok {
+		return nil
+	}
+	endpoint, err := locator.LocateEndpoint(sessionConfig)
+	if err != nil {
+		return err
+	}
+	_, err = manager.allowThroughKillSwitch(endpoint)
+	return err
+}
+
+// locateDNS returns DNS servers advertised by provider, if the connection can tell them
+func locateDNS(connection Connection, sessionConfig json.RawMessage) []net.IP {
+	locator, ok := connection.(DNSLocator)
+	if !ok {
+		return nil
+	}
+	servers, err := locator.LocateDNS(sessionConfig)
+	if err != nil {
+		log.Warn(managerLogPrefix, "Failed to read DNS servers advertised by provider: ", err)
+		return nil
+	}
+	return servers
+}
+
+// revokeKillSwitchAllowance restricts traffic to the tunnel of kill switch only
+func (manager *connectionManager) revokeKillSwitchAllowance() {
+	manager.killSwitchMutex.Lock()
+	defer manager.killSwitchMutex.Unlock()
+
+	if manager.killSwitchTunnel == nil || len(manager.killSwitchAllowed) == 0 {
+		return
+	}
+	if err := manager.killSwitch.Allow(nil); err != nil {
+		log.Error(managerLogPrefix, "Failed to revoke destinations allowed through kill switch: ", err)
+		return
+	}
+	manager.killSwitchAllowed = nil
+}
+
+// resolveReachableHosts looks up addresses of reachable hosts, it has to be done before kill switch blocks DNS
+func (manager *connectionManager) resolveReachableHosts() {
+	var reachable []net.IP
+	for _, address := range manager.reachableHosts {
+		host := address
+		if parsed, err := url.Parse(address); err == nil && parsed.Host != "" {
+			host = parsed.Hostname()
+		} else if hostname, _, err := net.SplitHostPort(address); err == nil {
+			host = hostname
+		}
+
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			log.Warn(managerLogPrefix, "Failed to resolve ", host, ", it won't be reachable through kill switch: ", err)
+			continue
+		}
+		reachable = append(reachable, ips...)
+	}
+
+	manager.killSwitchMutex.Lock()
+	manager.reachable = reachable
+	manager.killSwitchMutex.Unlock()
+}
+
+func (manager *connectionManager) disableDNSLeakBlocker() {
+	if err := manager.dnsLeakBlocker.Disable(); err != nil {
+		log.Error(managerLogPrefix, "Failed to disable DNS leak blocker: ", err)
+	}
+}
+
+func (manager *connectionManager) disableIPv6LeakBlocker() {
+	if err := manager.ipv6LeakBlocker.Disable(); err != nil {
+		log.Error(managerLogPrefix, "Failed to disable IPv6 leak blocker: ", err)
+	}
+}
+
 func warnOnClean() {
 	log.Warn(managerLogPrefix, "Trying to close when there is nothing to close. Possible bug or race condition")
 }
 
-func connectionWaiter(connection Connection, dialog communication.Dialog, promiseIssuer PromiseIssuer) {
+// connectionWaiter logs exit of the connection, its session is cleaned up by disconnect or once the connection is lost
+func connectionWaiter(connection Connection) {
 	err := connection.Wait()
 	if err != nil {
 		log.Warn(managerLogPrefix, "Connection exited with error: ", err)
 	} else {
 		log.Info(managerLogPrefix, "Connection exited")
 	}
-
-	promiseIssuer.Stop()
-	dialog.Close()
 }
 
-func (manager *connectionManager) waitForConnectedState(stateChannel <-chan State, sessionID session.ID) error {
+func (manager *connectionManager) waitForConnectedState(ctx context.Context, timeouts ConnectTimeouts, stateChannel <-chan State) error {
+	var deadline <-chan time.Time
+	if timeouts.Handshake > 0 {
+		timer := time.NewTimer(timeouts.Handshake)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
 	for {
 		select {
 		case state, more := <-stateChannel:
@@ -269,17 +971,34 @@ func (manager *connectionManager) waitForConnectedState(stateChannel <-chan Stat
 			default:
 				manager.onStateChanged(state)
 			}
-		case <-manager.ctx.Done():
-			return manager.ctx.Err()
+		case <-deadline:
+			return ErrHandshakeTimeout
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
 
-func (manager *connectionManager) consumeConnectionStates(stateChannel <-chan State) {
+func (manager *connectionManager) consumeConnectionStates(ctx context.Context, stateChannel <-chan State, reconnect func()) {
 	for state := range stateChannel {
 		manager.onStateChanged(state)
 	}
 
+	// DNS queries can't go through the tunnel which is gone
+	manager.disableDNSLeakBlocker()
+
+	// connection was lost without disconnect request
+	if ctx.Err() == nil {
+		// nothing else cleans up entry hops, session and leak blockers of the lost connection,
+		// disconnect finds no connection once reconnect gives up
+		manager.cleanLostSession()
+		if reconnect != nil {
+			manager.onStateChanged(Reconnecting)
+			reconnect()
+			return
+		}
+	}
+
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
 
@@ -287,9 +1006,27 @@ func (manager *connectionManager) consumeConnectionStates(stateChannel <-chan St
 	log.Debug(managerLogPrefix, "State updater stopCalled")
 }
 
+// cleanLostSession cleans up session of the connection which was lost, it's cleaned only once
+func (manager *connectionManager) cleanLostSession() {
+	manager.mutex.Lock()
+	cleanSession := manager.cleanSession
+	manager.cleanSession = func() {}
+	manager.mutex.Unlock()
+	cleanSession()
+}
+
 func (manager *connectionManager) consumeStats(statisticsChannel <-chan consumer.SessionStatistics) {
+	var lastStats consumer.SessionStatistics
 	for stats := range statisticsChannel {
-		manager.eventPublisher.Publish(StatisticsEventTopic, stats)
+		manager.mutex.Lock()
+		manager.statistics = consumer.AddUpStatistics(manager.statistics, lastStats.DiffWithNew(stats))
+		manager.mutex.Unlock()
+		lastStats = stats
+
+		// statistics event has no connection ID, its consumers keep track of the default connection only
+		if manager.id == DefaultConnectionID {
+			manager.eventPublisher.Publish(StatisticsEventTopic, stats)
+		}
 	}
 }
 
@@ -298,8 +1035,9 @@ func (manager *connectionManager) onStateChanged(state State) {
 	defer manager.mutex.Unlock()
 
 	manager.eventPublisher.Publish(StateEventTopic, StateEvent{
-		State:       state,
-		SessionInfo: manager.sessionInfo,
+		ConnectionID: manager.id,
+		State:        state,
+		SessionInfo:  manager.sessionInfo,
 	})
 
 	switch state {
@@ -19,6 +19,7 @@ package connection
 
 import (
 	"errors"
+	"net"
 	"sync"
 	"testing"
 	"time"
@@ -38,8 +39,16 @@ type testContext struct {
 	connManager           *connectionManager
 	fakeDialog            *fakeDialog
 	fakePromiseIssuer     *fakePromiseIssuer
-	stubPublisher         *StubPublisher
-	mockStatistics        consumer.SessionStatistics
+	fakeKillSwitch        *fakeFirewall
+	fakeDNSLeakBlocker    *fakeFirewall
+	fakeIPv6LeakBlocker   *fakeFirewall
+	fakeProposalProvider  *fakeProposalProvider
+	unreachableProvider   string
+	// dialogRelease holds dialog creation back until it's closed
+	dialogRelease   chan struct{}
+	dialedProviders []string
+	stubPublisher   *StubPublisher
+	mockStatistics  consumer.SessionStatistics
 	sync.RWMutex
 }
 
@@ -62,10 +71,25 @@ func (tc *testContext) SetupTest() {
 	defer tc.Unlock()
 
 	tc.stubPublisher = NewStubPublisher()
+	tc.fakeProposalProvider = &fakeProposalProvider{}
 	tc.fakeDialog = &fakeDialog{sessionID: establishedSessionID}
+	tc.unreachableProvider = ""
+	tc.dialogRelease = nil
+	tc.dialedProviders = nil
 	dialogCreator := func(consumer, provider identity.Identity, contact market.Contact) (communication.Dialog, error) {
 		tc.RLock()
-		defer tc.RUnlock()
+		release := tc.dialogRelease
+		tc.RUnlock()
+		if release != nil {
+			<-release
+		}
+
+		tc.Lock()
+		defer tc.Unlock()
+		tc.dialedProviders = append(tc.dialedProviders, provider.Address)
+		if provider.Address == tc.unreachableProvider {
+			return nil, errors.New("provider is unreachable")
+		}
 		return tc.fakeDialog, nil
 	}
 
@@ -98,6 +122,11 @@ func (tc *testContext) SetupTest() {
 			tc.mockStatistics,
 			sync.WaitGroup{},
 			sync.RWMutex{},
+			ConnectOptions{},
+			nil,
+			nil,
+			false,
+			false,
 		},
 	}
 
@@ -106,7 +135,15 @@ func (tc *testContext) SetupTest() {
 		promiseIssuerFactory,
 		tc.fakeConnectionFactory.CreateConnection,
 		tc.stubPublisher,
+		tc.fakeProposalProvider,
 	)
+	tc.connManager.minReconnectBackoff = time.Millisecond
+	tc.fakeKillSwitch = &fakeFirewall{}
+	tc.connManager.killSwitch = tc.fakeKillSwitch
+	tc.fakeDNSLeakBlocker = &fakeFirewall{}
+	tc.connManager.dnsLeakBlocker = tc.fakeDNSLeakBlocker
+	tc.fakeIPv6LeakBlocker = &fakeFirewall{}
+	tc.connManager.ipv6LeakBlocker = tc.fakeIPv6LeakBlocker
 }
 
 func (tc *testContext) TestWhenNoConnectionIsMadeStatusIsNotConnected() {
@@ -130,14 +167,16 @@ func (tc *testContext) TestWhenManagerMadeConnectionStatusReturnsConnectedStateA
 func (tc *testContext) TestStatusReportsConnectingWhenConnectionIsInProgress() {
 	tc.fakeConnectionFactory.mockConnection.onStartReportStates = []fakeState{}
 
+	connected := make(chan error)
 	go func() {
-		tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+		connected <- tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
 	}()
 
 	waitABit()
 
 	assert.Equal(tc.T(), statusConnecting(), tc.connManager.Status())
 	tc.connManager.Disconnect()
+	assert.Equal(tc.T(), ErrConnectionCancelled, <-connected)
 }
 
 func (tc *testContext) TestStatusReportsDisconnectingThenNotConnected() {
@@ -154,6 +193,114 @@ func (tc *testContext) TestStatusReportsDisconnectingThenNotConnected() {
 	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
 }
 
+func (tc *testContext) TestConnectFailsWithDialogTimeoutWhenProviderDoesNotRespond() {
+	tc.dialogRelease = make(chan struct{})
+	tc.fakeDialog.closedSignal = make(chan struct{})
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{Timeouts: ConnectTimeouts{Dialog: time.Millisecond}})
+
+	assert.Equal(tc.T(), ErrDialogTimeout, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	// dialog established after the deadline is closed
+	close(tc.dialogRelease)
+	tc.waitForSignal(tc.fakeDialog.closedSignal)
+}
+
+func (tc *testContext) TestConnectFailsWithSessionCreateTimeoutWhenSessionIsNotCreatedInTime() {
+	tc.fakeDialog.sessionCreateRelease = make(chan struct{})
+	tc.fakeDialog.closedSignal = make(chan struct{})
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{Timeouts: ConnectTimeouts{SessionCreate: time.Millisecond}})
+
+	assert.Equal(tc.T(), ErrSessionCreateTimeout, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Empty(tc.T(), tc.fakeConnectionFactory.created[0].startedWith)
+	// dialog is kept open until the abandoned request is settled
+	assert.False(tc.T(), tc.fakeDialog.Closed())
+	close(tc.fakeDialog.sessionCreateRelease)
+	tc.waitForSignal(tc.fakeDialog.closedSignal)
+}
+
+func (tc *testContext) TestSessionCreatedAfterTimeoutIsDestroyedBeforeDialogIsClosed() {
+	tc.fakeDialog.sessionCreateRelease = make(chan struct{})
+	tc.fakeDialog.closedSignal = make(chan struct{})
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{Timeouts: ConnectTimeouts{SessionCreate: time.Millisecond}})
+	assert.Equal(tc.T(), ErrSessionCreateTimeout, err)
+
+	close(tc.fakeDialog.sessionCreateRelease)
+	tc.waitForSignal(tc.fakeDialog.closedSignal)
+	assert.Equal(
+		tc.T(),
+		[]communication.RequestEndpoint{"session-create", "session-destroy"},
+		tc.fakeDialog.RequestedBeforeClose(),
+	)
+}
+
+func (tc *testContext) TestConnectFailsWithTunnelTimeoutWhenTunnelIsNotStartedInTime() {
+	tc.fakeConnectionFactory.mockConnection.startRelease = make(chan struct{})
+	tc.fakeConnectionFactory.mockConnection.stoppedSignal = make(chan struct{})
+	tc.fakeConnectionFactory.mockConnection.onStartReportStates = []fakeState{}
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{Timeouts: ConnectTimeouts{TunnelUp: time.Millisecond}})
+
+	assert.Equal(tc.T(), ErrTunnelTimeout, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakePromiseIssuer.stopCalled)
+	// connection started after the deadline is stopped
+	close(tc.fakeConnectionFactory.mockConnection.startRelease)
+	tc.waitForSignal(tc.fakeConnectionFactory.mockConnection.stoppedSignal)
+}
+
+func (tc *testContext) TestConnectFailsWithHandshakeTimeoutWhenConnectedStateIsNotReported() {
+	tc.fakeConnectionFactory.mockConnection.onStartReportStates = []fakeState{processStarted, connectingState}
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{Timeouts: ConnectTimeouts{Handshake: 10 * time.Millisecond}})
+
+	assert.Equal(tc.T(), ErrHandshakeTimeout, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeDialog.closed)
+}
+
+func (tc *testContext) TestConnectionIsClosedWhenLimitIsReached() {
+	tc.connManager.limitsCheckInterval = time.Millisecond
+	params := ConnectParams{Limits: Limits{MaxBytes: 25}}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.waitForStatus(statusNotConnected())
+
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	events := tc.limitEvents()
+	assert.Len(tc.T(), events, 1)
+	assert.Equal(tc.T(), LimitReachedStatus, events[0].Status)
+	assert.Equal(tc.T(), LimitBytes, events[0].Limit)
+	assert.Equal(tc.T(), uint64(30), events[0].Quota.Used.Bytes)
+}
+
+func (tc *testContext) TestWarningIsPublishedWhenLimitIsAlmostReached() {
+	tc.connManager.limitsCheckInterval = time.Millisecond
+	params := ConnectParams{Limits: Limits{MaxBytes: 32}}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	waitABit()
+
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+	events := tc.limitEvents()
+	assert.Len(tc.T(), events, 1)
+	assert.Equal(tc.T(), LimitWarningStatus, events[0].Status)
+	assert.Equal(tc.T(), uint64(2), tc.connManager.Quota().BytesLeft())
+}
+
+func (tc *testContext) limitEvents() []LimitEvent {
+	var events []LimitEvent
+	for _, event := range tc.stubPublisher.GetEventHistory() {
+		if event.calledWithTopic == LimitEventTopic {
+			events = append(events, event.calledWithArgs[0].(LimitEvent))
+		}
+	}
+	return events
+}
+
 func (tc *testContext) TestConnectResultsInAlreadyConnectedErrorWhenConnectionExists() {
 	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
 	assert.Equal(tc.T(), ErrAlreadyExists, tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
@@ -163,6 +310,18 @@ func (tc *testContext) TestDisconnectReturnsErrorWhenNoConnectionExists() {
 	assert.Equal(tc.T(), ErrNoConnection, tc.connManager.Disconnect())
 }
 
+func (tc *testContext) TestSessionEndedByProviderDisconnects() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+
+	tc.fakeDialog.deliver("session-ended", &session.EndedMessage{SessionID: "other-session"})
+	waitABit()
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+
+	tc.fakeDialog.deliver("session-ended", &session.EndedMessage{SessionID: string(establishedSessionID)})
+	tc.waitForStatus(statusNotConnected())
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+}
+
 func (tc *testContext) TestReconnectingStatusIsReportedWhenOpenVpnGoesIntoReconnectingState() {
 	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
 	tc.fakeConnectionFactory.mockConnection.reportState(reconnectingState)
@@ -170,6 +329,42 @@ func (tc *testContext) TestReconnectingStatusIsReportedWhenOpenVpnGoesIntoReconn
 	assert.Equal(tc.T(), statusReconnecting(), tc.connManager.Status())
 }
 
+func (tc *testContext) TestDisconnectDestroysSessionBeforeClosingDialog() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+
+	assert.Equal(
+		tc.T(),
+		[]communication.RequestEndpoint{"session-create", "session-destroy"},
+		tc.fakeDialog.RequestedBeforeClose(),
+	)
+}
+
+func (tc *testContext) TestConcurrentDisconnectsCleanConnectionOnce() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	tc.fakeDialog.sessionDestroyRelease = make(chan struct{})
+	tc.fakeDialog.sessionDestroySignal = make(chan struct{})
+
+	var disconnects sync.WaitGroup
+	disconnect := func() {
+		disconnects.Add(1)
+		go func() {
+			defer disconnects.Done()
+			tc.connManager.Disconnect()
+		}()
+	}
+	disconnect()
+	// the second disconnect comes while the first one is cleaning the connection
+	tc.waitForSignal(tc.fakeDialog.sessionDestroySignal)
+	disconnect()
+	waitABit()
+	close(tc.fakeDialog.sessionDestroyRelease)
+	disconnects.Wait()
+
+	assert.Equal(tc.T(), 1, tc.fakeDialog.RequestCount("session-destroy"))
+	tc.waitForStatus(statusNotConnected())
+}
+
 func (tc *testContext) TestDoubleDisconnectResultsInError() {
 	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
 	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
@@ -240,6 +435,378 @@ func (tc *testContext) TestConnectMethodReturnsErrorIfConnectionExitsDuringConne
 	assert.Equal(tc.T(), ErrConnectionFailed, err)
 }
 
+func (tc *testContext) TestTunnelIsKnownOnlyWhileConnected() {
+	_, known := tc.connManager.Tunnel()
+	assert.False(tc.T(), known)
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	tunnel, known := tc.connManager.Tunnel()
+	assert.True(tc.T(), known)
+	assert.Equal(tc.T(), fakeTunnel, tunnel)
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	_, known = tc.connManager.Tunnel()
+	assert.False(tc.T(), known)
+}
+
+func (tc *testContext) TestKillSwitchIsNotEnabledByDefault() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.False(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestKillSwitchIsEnabledWithConnectionTunnel() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true}))
+	assert.Equal(tc.T(), &fakeTunnel, tc.fakeKillSwitch.enabledWith)
+}
+
+func (tc *testContext) TestKillSwitchLetsThroughNetworksExcludedFromTunnel() {
+	params := ConnectParams{EnableKillSwitch: true, ExcludeRoutes: []string{"192.168.1.0/24"}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+
+	_, lan, _ := net.ParseCIDR("192.168.1.0/24")
+	expectedTunnel := fakeTunnel
+	expectedTunnel.Excluded = []net.IPNet{*lan}
+	assert.Equal(tc.T(), &expectedTunnel, tc.fakeKillSwitch.enabledWith)
+}
+
+func (tc *testContext) TestKillSwitchStaysEnabledWhileReconnecting() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true}))
+	tc.fakeConnectionFactory.mockConnection.reportState(reconnectingState)
+	waitABit()
+	assert.Equal(tc.T(), statusReconnecting(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestKillSwitchIsDisabledOnDisconnect() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true}))
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	assert.False(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestKillSwitchStaysEnabledWhenConnectionIsLostUntilDisconnect() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true}))
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.Enabled())
+
+	assert.Equal(tc.T(), ErrNoConnection, tc.connManager.Disconnect())
+	assert.False(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestRestoredKillSwitchLetsReachableHostsThrough() {
+	tc.connManager.reachableHosts = []string{"http://127.0.0.1:8080/v1", "127.0.0.2:4222"}
+
+	assert.NoError(tc.T(), tc.connManager.RestoreKillSwitch(fakeTunnel))
+
+	assert.Equal(tc.T(), &fakeTunnel, tc.fakeKillSwitch.enabledWith)
+	allowed, _ := tc.fakeKillSwitch.Allowed()
+	assert.Equal(tc.T(), []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}, allowed)
+}
+
+func (tc *testContext) TestRestoredKillSwitchStaysInPlaceWhenConnectFails() {
+	assert.NoError(tc.T(), tc.connManager.RestoreKillSwitch(fakeTunnel))
+	tc.fakeConnectionFactory.mockConnection.onStartReturnError = errors.New("fatal connection error")
+
+	assert.Error(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true}))
+	assert.True(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestKillSwitchStaysInPlaceWhileLostConnectionIsRestored() {
+	params := ConnectParams{EnableKillSwitch: true, Reconnect: ReconnectPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.connManager.reachable = []net.IP{net.ParseIP("9.9.9.9")}
+
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	tc.waitForStatus(statusConnected(establishedSessionID))
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+
+	allowed, history := tc.fakeKillSwitch.Allowed()
+	assert.Empty(tc.T(), allowed)
+	assert.Equal(
+		tc.T(),
+		[][]net.IP{
+			{net.ParseIP("9.9.9.9")},
+			{net.ParseIP("9.9.9.9"), net.ParseIP("5.6.7.8")},
+		},
+		history,
+	)
+	assert.True(tc.T(), tc.fakeKillSwitch.Enabled())
+	assert.Zero(tc.T(), tc.fakeKillSwitch.disabled)
+}
+
+func (tc *testContext) TestKillSwitchStaysEnabledWhenProviderEndsSession() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true}))
+	tc.fakeDialog.deliver("session-ended", &session.EndedMessage{SessionID: string(establishedSessionID)})
+	tc.waitForStatus(statusNotConnected())
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestReconnectAttemptsAreSpacedByMinimalBackoff() {
+	tc.connManager.minReconnectBackoff = time.Second
+	params := ConnectParams{Reconnect: ReconnectPolicy{MaxAttempts: 3}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.unreachableProvider = activeProviderID.Address
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusReconnecting(), tc.connManager.Status())
+	assert.Len(tc.T(), tc.dialedProviders, 1)
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+}
+
+func (tc *testContext) TestConnectFailsWhenKillSwitchCanNotBeEnabled() {
+	tc.fakeKillSwitch.mockError = errors.New("iptables failure")
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true})
+	assert.EqualError(tc.T(), err, "iptables failure")
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakePromiseIssuer.stopCalled)
+}
+
+func (tc *testContext) TestConnectFailsWhenRoutesAreInvalid() {
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{ExcludeRoutes: []string{"10.0.0.0/33"}})
+	assert.Error(tc.T(), err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Empty(tc.T(), tc.dialedProviders)
+}
+
+func (tc *testContext) TestProviderDNSIsUsedByDefault() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Equal(tc.T(), fakeAdvertisedDNS, tc.fakeConnectionFactory.created[0].startedWith.DNS)
+}
+
+func (tc *testContext) TestSystemDNSIsKeptWhenProviderAdvertisesNone() {
+	tc.fakeConnectionFactory.mockConnection.noDNS = true
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Nil(tc.T(), tc.fakeConnectionFactory.created[0].startedWith.DNS)
+	assert.False(tc.T(), tc.fakeDNSLeakBlocker.Enabled())
+}
+
+func (tc *testContext) TestConnectFailsWhenProviderDNSIsNotAdvertised() {
+	tc.fakeConnectionFactory.mockConnection.noDNS = true
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{DNS: DNSOptionProvider})
+	assert.Equal(tc.T(), ErrProviderDNSUnknown, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+}
+
+func (tc *testContext) TestDNSLeaksAreBlockedByDefault() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Equal(tc.T(), &fakeTunnel, tc.fakeDNSLeakBlocker.enabledWith)
+}
+
+func (tc *testContext) TestDNSLeaksAreNotBlockedWithSystemDNS() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{DNS: DNSOptionSystem}))
+	assert.False(tc.T(), tc.fakeDNSLeakBlocker.Enabled())
+}
+
+func (tc *testContext) TestDNSLeakBlockingIsRemovedWhenConnectionEnds() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.False(tc.T(), tc.fakeDNSLeakBlocker.Enabled())
+}
+
+func (tc *testContext) TestConnectFailsWhenDNSLeaksCanNotBeBlocked() {
+	tc.fakeDNSLeakBlocker.mockError = errors.New("iptables failure")
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{DNS: DNSOption("1.1.1.1")})
+	assert.EqualError(tc.T(), err, "iptables failure")
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.False(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestConnectWithDefaultDNSSucceedsWhenDNSLeaksCanNotBeBlocked() {
+	tc.fakeDNSLeakBlocker.mockError = errors.New("iptables failure")
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+	assert.Equal(tc.T(), fakeAdvertisedDNS, tc.fakeConnectionFactory.created[0].startedWith.DNS)
+}
+
+func (tc *testContext) TestIPv6IsBlockedWhenTunnelCarriesOnlyIPv4() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.Equal(tc.T(), &fakeTunnel, tc.fakeIPv6LeakBlocker.enabledWith)
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	assert.False(tc.T(), tc.fakeIPv6LeakBlocker.Enabled())
+}
+
+func (tc *testContext) TestConnectFailsWhenIPv6LeaksCanNotBeBlocked() {
+	tc.fakeIPv6LeakBlocker.mockError = errors.New("ip6tables failure")
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true})
+	assert.EqualError(tc.T(), err, "ip6tables failure")
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.False(tc.T(), tc.fakeDNSLeakBlocker.Enabled())
+	assert.False(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestIPv6IsNotBlockedWhenTunnelCarriesIPv6() {
+	tc.fakeConnectionFactory.mockConnection.tunnelIPv6 = true
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	assert.False(tc.T(), tc.fakeIPv6LeakBlocker.Enabled())
+}
+
+func (tc *testContext) TestIPv6IsNotBlockedWhenOnlyIncludedRoutesAreTunnelled() {
+	params := ConnectParams{IncludeRoutes: []string{"10.0.0.0/8"}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	assert.False(tc.T(), tc.fakeIPv6LeakBlocker.Enabled())
+}
+
+func (tc *testContext) TestConnectFailsWhenDNSServersAreInvalid() {
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{DNS: DNSOption("resolver")})
+	assert.EqualError(tc.T(), err, `invalid DNS server address "resolver"`)
+	assert.Empty(tc.T(), tc.dialedProviders)
+}
+
+func (tc *testContext) TestLostConnectionIsNotRestoredByDefault() {
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, ConnectParams{}))
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Len(tc.T(), tc.dialedProviders, 1)
+}
+
+func (tc *testContext) TestLostConnectionIsRestored() {
+	params := ConnectParams{Reconnect: ReconnectPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	tc.waitForStatus(statusConnected(establishedSessionID))
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+	assert.Equal(tc.T(), []string{activeProviderID.Address, activeProviderID.Address}, tc.dialedProviders)
+}
+
+func (tc *testContext) TestStatusIsReconnectingWhileConnectionIsRestored() {
+	params := ConnectParams{Reconnect: ReconnectPolicy{MaxAttempts: 1, Backoff: time.Second}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusReconnecting(), tc.connManager.Status())
+}
+
+func (tc *testContext) TestDisconnectStopsReconnecting() {
+	params := ConnectParams{Reconnect: ReconnectPolicy{MaxAttempts: 1, Backoff: time.Second}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Len(tc.T(), tc.dialedProviders, 1)
+}
+
+func (tc *testContext) TestStatusIsNotConnectedWhenReconnectAttemptsFail() {
+	params := ConnectParams{
+		EnableKillSwitch: true,
+		Reconnect:        ReconnectPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.unreachableProvider = activeProviderID.Address
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	tc.waitForStatus(statusNotConnected())
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Len(tc.T(), tc.dialedProviders, 3)
+	assert.True(tc.T(), tc.fakeKillSwitch.Enabled())
+}
+
+func (tc *testContext) TestReconnectFailsOverToProviderInTheSameCountry() {
+	proposal := activeProposal
+	proposal.ServiceDefinition = &fakeServiceDefinition{country: "LT"}
+	tc.fakeProposalProvider.proposals = []market.ServiceProposal{
+		proposal,
+		{
+			ProviderID:        "provider-de",
+			ProviderContacts:  []market.Contact{activeProviderContact},
+			ServiceType:       activeServiceType,
+			ServiceDefinition: &fakeServiceDefinition{country: "DE"},
+		},
+		{
+			ProviderID:        "provider-lt",
+			ProviderContacts:  []market.Contact{activeProviderContact},
+			ServiceType:       activeServiceType,
+			ServiceDefinition: &fakeServiceDefinition{country: "LT"},
+		},
+	}
+
+	params := ConnectParams{Reconnect: ReconnectPolicy{MaxAttempts: 2, Backoff: time.Millisecond, Failover: true}}
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, proposal, params))
+	tc.unreachableProvider = activeProviderID.Address
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	tc.waitForStatus(statusConnected(establishedSessionID))
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+	assert.Equal(tc.T(), []string{activeProviderID.Address, activeProviderID.Address, "provider-lt"}, tc.dialedProviders)
+}
+
+func (tc *testContext) TestReconnectDoesNotFailOverWithoutServiceDefinition() {
+	tc.fakeProposalProvider.proposals = []market.ServiceProposal{activeProposal}
+	proposal := activeProposal
+	proposal.ServiceDefinition = nil
+
+	_, found := tc.connManager.findFailoverProposal(proposal, map[string]bool{})
+	assert.False(tc.T(), found)
+}
+
+func (tc *testContext) TestMultiHopConnectionIsChainedThroughEntryProviders() {
+	entryProposal := activeProposal
+	entryProposal.ProviderID = "entry-provider"
+	params := ConnectParams{EntryHops: []market.ServiceProposal{entryProposal}}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	assert.Equal(tc.T(), statusConnected(establishedSessionID), tc.connManager.Status())
+	assert.Equal(tc.T(), []string{"entry-provider", activeProviderID.Address}, tc.dialedProviders)
+
+	created := tc.fakeConnectionFactory.created
+	assert.Len(tc.T(), created, 2)
+	assert.Equal(tc.T(), "", created[0].startedWith.Routes.Via)
+	assert.Nil(tc.T(), created[0].startedWith.DNS)
+	assert.Equal(tc.T(), fakeTunnel.Interface, created[1].startedWith.Routes.Via)
+	assert.NotNil(tc.T(), created[1].startedWith.DNS)
+	assert.Equal(tc.T(), activeProviderID.Address, tc.connManager.sessionInfo.Proposal.ProviderID)
+}
+
+func (tc *testContext) TestMultiHopConnectionIsLostWhenEntryHopIsLost() {
+	entryProposal := activeProposal
+	entryProposal.ProviderID = "entry-provider"
+	params := ConnectParams{EntryHops: []market.ServiceProposal{entryProposal}}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	tc.fakeConnectionFactory.created[0].reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Equal(tc.T(), ErrNoConnection, tc.connManager.Disconnect())
+}
+
+func (tc *testContext) TestLostMultiHopConnectionIsCleanedUp() {
+	entryProposal := activeProposal
+	entryProposal.ProviderID = "entry-provider"
+	params := ConnectParams{EntryHops: []market.ServiceProposal{entryProposal}}
+
+	assert.NoError(tc.T(), tc.connManager.Connect(consumerID, activeProposal, params))
+	entryHop := tc.fakeConnectionFactory.created[0]
+	tc.fakeConnectionFactory.mockConnection.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.False(tc.T(), tc.fakeIPv6LeakBlocker.Enabled())
+	assert.False(tc.T(), tc.fakeDNSLeakBlocker.Enabled())
+	assert.True(tc.T(), tc.fakePromiseIssuer.stopCalled)
+
+	entryHopStopped := make(chan struct{})
+	go func() {
+		entryHop.Wait()
+		close(entryHopStopped)
+	}()
+	tc.waitForSignal(entryHopStopped)
+}
+
+func (tc *testContext) TestMultiHopConnectionDoesNotSupportKillSwitch() {
+	params := ConnectParams{EnableKillSwitch: true, EntryHops: []market.ServiceProposal{activeProposal}}
+
+	err := tc.connManager.Connect(consumerID, activeProposal, params)
+	assert.Equal(tc.T(), ErrMultiHopKillSwitch, err)
+	assert.Empty(tc.T(), tc.dialedProviders)
+}
+
 func (tc *testContext) Test_PromiseIssuer_WhenManagerMadeConnectionIsStarted() {
 	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
 	assert.NoError(tc.T(), err)
@@ -322,16 +889,50 @@ func (tc *testContext) Test_ManagerPublishesEvents() {
 	}
 }
 
+func TestNextReconnectBackoffIsCapped(t *testing.T) {
+	assert.Equal(t, 2*time.Second, nextReconnectBackoff(time.Second, time.Minute))
+	assert.Equal(t, time.Minute, nextReconnectBackoff(40*time.Second, time.Minute))
+	assert.Equal(t, time.Minute, nextReconnectBackoff(time.Minute, time.Minute))
+	assert.Equal(t, 2*time.Minute, nextReconnectBackoff(2*time.Minute, time.Minute))
+}
+
 func TestConnectionManagerSuite(t *testing.T) {
 	suite.Run(t, new(testContext))
 }
 
+// waitForStatus waits until reconnect attempts, which are spaced by backoff, bring manager to given status
+func (tc *testContext) waitForStatus(status ConnectionStatus) {
+	deadline := time.After(waitTimeout)
+	for tc.connManager.Status() != status {
+		select {
+		case <-deadline:
+			tc.FailNow("status was not reached in time", "expected %v, got %v", status, tc.connManager.Status())
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// waitForSignal waits until fake signals that connect step given up in background has finished
+func (tc *testContext) waitForSignal(signal <-chan struct{}) {
+	select {
+	case <-signal:
+	case <-time.After(waitTimeout):
+		tc.FailNow("abandoned connect step was not finished in time")
+	}
+}
+
+const waitTimeout = 2 * time.Second
+
 func waitABit() {
 	//usually time.Sleep call gives a chance for other goroutines to kick in
 	//important when testing async code
 	time.Sleep(10 * time.Millisecond)
 }
 
-type fakeServiceDefinition struct{}
+type fakeServiceDefinition struct {
+	country string
+}
 
-func (fs *fakeServiceDefinition) GetLocation() market.Location { return market.Location{} }
+func (fs *fakeServiceDefinition) GetLocation() market.Location {
+	return market.Location{Country: fs.country}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+// ID identifies one of concurrent connections of the node
+type ID string
+
+// DefaultConnectionID identifies the connection which is managed through the single connection Manager interface
+const DefaultConnectionID = ID("default")
+
+// ErrConcurrentKillSwitch indicates that kill switch was requested for connection other than the default one
+var ErrConcurrentKillSwitch = errors.New("kill switch is available for the default connection only")
+
+// Pool keeps concurrent connections, each of them is handled by separate manager with its own status,
+// statistics and events. The default connection is a part of the pool too.
+type Pool struct {
+	newManager func(id ID) *connectionManager
+	generateID func() (ID, error)
+
+	mutex    sync.Mutex
+	managers map[ID]*connectionManager
+	// pending connections are not started by their managers yet, so they are still reported as not connected
+	pending map[ID]bool
+}
+
+// NewPool creates connection pool, all the connections of it share given dependencies.
+// Reachable hosts (i.e. discovery and broker) are let through kill switch while connection is re-established.
+func NewPool(
+	dialogCreator DialogCreator,
+	promiseIssuerCreator PromiseIssuerCreator,
+	connectionCreator Creator,
+	eventPublisher Publisher,
+	proposalProvider ProposalProvider,
+	reachableHosts []string,
+) *Pool {
+	newManager := func(id ID) *connectionManager {
+		manager := NewManager(dialogCreator, promiseIssuerCreator, connectionCreator, eventPublisher, proposalProvider)
+		manager.id = id
+		// firewall rules are global, so they are left for the default connection to manage
+		manager.killSwitch = noFirewall{}
+		manager.dnsLeakBlocker = noFirewall{}
+		manager.ipv6LeakBlocker = noFirewall{}
+		return manager
+	}
+
+	defaultManager := NewManager(dialogCreator, promiseIssuerCreator, connectionCreator, eventPublisher, proposalProvider)
+	defaultManager.reachableHosts = reachableHosts
+
+	return &Pool{
+		newManager: newManager,
+		generateID: generateID,
+		managers: map[ID]*connectionManager{
+			DefaultConnectionID: defaultManager,
+		},
+		pending: make(map[ID]bool),
+	}
+}
+
+// noFirewall neither restricts traffic nor lifts restrictions of other connections
+type noFirewall struct{}
+
+func (noFirewall) Enable(_ firewall.Tunnel) error { return nil }
+func (noFirewall) Allow(_ []net.IP) error         { return nil }
+func (noFirewall) Disable() error                 { return nil }
+
+func generateID() (ID, error) {
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return ID(""), err
+	}
+	return ID(uid.String()), nil
+}
+
+// Default returns manager of the default connection
+func (pool *Pool) Default() *connectionManager {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.managers[DefaultConnectionID]
+}
+
+// Connect creates new connection alongside the existing ones and returns its ID
+func (pool *Pool) Connect(consumerID identity.Identity, proposal market.ServiceProposal, params ConnectParams) (ID, error) {
+	// kill switch restricts all the traffic to a single tunnel, it would break other connections
+	if params.EnableKillSwitch {
+		return ID(""), ErrConcurrentKillSwitch
+	}
+
+	id, err := pool.generateID()
+	if err != nil {
+		return ID(""), err
+	}
+
+	manager := pool.newManager(id)
+	pool.mutex.Lock()
+	pool.managers[id] = manager
+	pool.pending[id] = true
+	pool.mutex.Unlock()
+
+	err = manager.Connect(consumerID, proposal, params)
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	delete(pool.pending, id)
+	if err != nil {
+		delete(pool.managers, id)
+		return ID(""), err
+	}
+	return id, nil
+}
+
+// Connections returns statuses of all the connections which are not closed yet
+func (pool *Pool) Connections() map[ID]ConnectionStatus {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	pool.removeClosed()
+	statuses := make(map[ID]ConnectionStatus, len(pool.managers))
+	for id, manager := range pool.managers {
+		if status := manager.Status(); status.State != NotConnected {
+			statuses[id] = status
+		}
+	}
+	return statuses
+}
+
+// Status returns status of connection with given ID
+func (pool *Pool) Status(id ID) (ConnectionStatus, error) {
+	manager, err := pool.find(id)
+	if err != nil {
+		return ConnectionStatus{}, err
+	}
+	return manager.Status(), nil
+}
+
+// Statistics returns traffic and duration of connection with given ID
+func (pool *Pool) Statistics(id ID) (consumer.SessionStatistics, time.Duration, error) {
+	manager, err := pool.find(id)
+	if err != nil {
+		return consumer.SessionStatistics{}, 0, err
+	}
+	statistics, duration := manager.Statistics()
+	return statistics, duration, nil
+}
+
+// Quota returns limits and usage of connection with given ID
+func (pool *Pool) Quota(id ID) (Quota, error) {
+	manager, err := pool.find(id)
+	if err != nil {
+		return Quota{}, err
+	}
+	return manager.Quota(), nil
+}
+
+// Disconnect closes connection with given ID
+func (pool *Pool) Disconnect(id ID) error {
+	manager, err := pool.find(id)
+	if err != nil {
+		return err
+	}
+	return manager.Disconnect()
+}
+
+func (pool *Pool) find(id ID) (*connectionManager, error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	pool.removeClosed()
+	manager, found := pool.managers[id]
+	if !found {
+		return nil, ErrNoConnection
+	}
+	return manager, nil
+}
+
+// removeClosed forgets connections which were disconnected or lost, the default connection is always kept
+func (pool *Pool) removeClosed() {
+	for id, manager := range pool.managers {
+		if id != DefaultConnectionID && !pool.pending[id] && manager.Status().State == NotConnected {
+			delete(pool.managers, id)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPool() (*Pool, *connectionFactoryFake, *fakeFirewall) {
+	dialogCreator := func(_, _ identity.Identity, _ market.Contact) (communication.Dialog, error) {
+		return &fakeDialog{sessionID: establishedSessionID}, nil
+	}
+	promiseIssuerFactory := func(_ identity.Identity, _ communication.Dialog) PromiseIssuer {
+		return &fakePromiseIssuer{}
+	}
+	connectionFactory := &connectionFactoryFake{
+		mockConnection: &connectionMock{
+			onStartReportStates: []fakeState{processStarted, connectedState},
+			onStopReportStates:  []fakeState{exitingState, processExited},
+			onStartReportStats:  consumer.SessionStatistics{BytesSent: 20, BytesReceived: 10},
+		},
+	}
+
+	pool := NewPool(dialogCreator, promiseIssuerFactory, connectionFactory.CreateConnection, NewStubPublisher(), &fakeProposalProvider{}, nil)
+	killSwitch := &fakeFirewall{}
+	defaultManager := pool.managers[DefaultConnectionID]
+	defaultManager.killSwitch = killSwitch
+	defaultManager.dnsLeakBlocker = &fakeFirewall{}
+	defaultManager.ipv6LeakBlocker = &fakeFirewall{}
+
+	var lastID int
+	var mutex sync.Mutex
+	pool.generateID = func() (ID, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		lastID++
+		return ID(fmt.Sprintf("connection-%d", lastID)), nil
+	}
+	return pool, connectionFactory, killSwitch
+}
+
+func TestPoolConnectsAlongsideDefaultConnection(t *testing.T) {
+	pool, _, _ := newTestPool()
+
+	assert.NoError(t, pool.Default().Connect(consumerID, activeProposal, ConnectParams{}))
+	id, err := pool.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, ID("connection-1"), id)
+
+	assert.Equal(
+		t,
+		map[ID]ConnectionStatus{
+			DefaultConnectionID: statusConnected(establishedSessionID),
+			id:                  statusConnected(establishedSessionID),
+		},
+		pool.Connections(),
+	)
+}
+
+func TestPoolGivesDistinctIDsToConnections(t *testing.T) {
+	pool, _, _ := newTestPool()
+
+	first, err := pool.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(t, err)
+	second, err := pool.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Len(t, pool.Connections(), 2)
+}
+
+func TestPoolRejectsKillSwitchOfAdditionalConnection(t *testing.T) {
+	pool, connectionFactory, killSwitch := newTestPool()
+
+	_, err := pool.Connect(consumerID, activeProposal, ConnectParams{EnableKillSwitch: true})
+
+	assert.Equal(t, ErrConcurrentKillSwitch, err)
+	assert.Empty(t, connectionFactory.created)
+	assert.Nil(t, killSwitch.enabledWith)
+	assert.Empty(t, pool.Connections())
+}
+
+func TestPoolDisconnectsOnlyGivenConnection(t *testing.T) {
+	pool, _, _ := newTestPool()
+	first, err := pool.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(t, err)
+	second, err := pool.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, pool.Disconnect(first))
+	waitABit()
+
+	_, err = pool.Status(first)
+	assert.Equal(t, ErrNoConnection, err)
+	status, err := pool.Status(second)
+	assert.NoError(t, err)
+	assert.Equal(t, statusConnected(establishedSessionID), status)
+}
+
+func TestPoolReturnsErrorForUnknownConnection(t *testing.T) {
+	pool, _, _ := newTestPool()
+
+	_, err := pool.Status(ID("unknown"))
+	assert.Equal(t, ErrNoConnection, err)
+	_, _, err = pool.Statistics(ID("unknown"))
+	assert.Equal(t, ErrNoConnection, err)
+	assert.Equal(t, ErrNoConnection, pool.Disconnect(ID("unknown")))
+}
+
+func TestPoolKeepsDefaultConnectionWhenItIsNotConnected(t *testing.T) {
+	pool, _, _ := newTestPool()
+
+	status, err := pool.Status(DefaultConnectionID)
+	assert.NoError(t, err)
+	assert.Equal(t, statusNotConnected(), status)
+	assert.Empty(t, pool.Connections())
+}
+
+func TestPoolReturnsStatisticsOfConnection(t *testing.T) {
+	pool, _, _ := newTestPool()
+	id, err := pool.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(t, err)
+	waitABit()
+
+	statistics, _, err := pool.Statistics(id)
+	assert.NoError(t, err)
+	assert.Equal(t, consumer.SessionStatistics{BytesSent: 20, BytesReceived: 10}, statistics)
+
+	statistics, _, err = pool.Statistics(DefaultConnectionID)
+	assert.NoError(t, err)
+	assert.Equal(t, consumer.SessionStatistics{}, statistics)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Routes describes which networks are routed through the tunnel
+type Routes struct {
+	// Include lists networks routed through the tunnel, all traffic is routed if empty
+	Include []net.IPNet
+	// Exclude lists networks kept outside of the tunnel
+	Exclude []net.IPNet
+	// Via is the tunnel interface of previous hop which provider endpoint is reached through,
+	// endpoint is routed via default gateway if empty
+	Via string
+}
+
+var lookupIP = net.LookupIP
+
+// ResolveRoutes parses given CIDRs, IP addresses and host names into IPv4 networks.
+// Host names are resolved at the moment of call, each of their addresses becomes a single host network.
+func ResolveRoutes(entries []string) ([]net.IPNet, error) {
+	var networks []net.IPNet
+	for _, entry := range entries {
+		resolved, err := resolveRoute(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, resolved...)
+	}
+	return networks, nil
+}
+
+func resolveRoute(entry string) ([]net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+		if network.IP.To4() == nil {
+			return nil, fmt.Errorf("route %q is not IPv4 network", entry)
+		}
+		return []net.IPNet{*network}, nil
+	}
+
+	if ip := net.ParseIP(entry); ip != nil {
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("route %q is not IPv4 address", entry)
+		}
+		return []net.IPNet{hostNetwork(ip)}, nil
+	}
+
+	ips, err := lookupIP(entry)
+	if err != nil {
+		return nil, err
+	}
+	var networks []net.IPNet
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			networks = append(networks, hostNetwork(ip))
+		}
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("route %q has no IPv4 addresses", entry)
+	}
+	return networks, nil
+}
+
+func hostNetwork(ip net.IP) net.IPNet {
+	return net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}
+}
+
+func resolveConnectRoutes(params ConnectParams) (routes Routes, err error) {
+	if routes.Include, err = ResolveRoutes(params.IncludeRoutes); err != nil {
+		return routes, err
+	}
+	routes.Exclude, err = ResolveRoutes(params.ExcludeRoutes)
+	return routes, err
+}
+
+// routeDNSServers makes DNS servers reachable through the tunnel when only selected networks are routed
+func routeDNSServers(routes Routes, dnsServers []net.IP) Routes {
+	if len(routes.Include) == 0 {
+		return routes
+	}
+	include := append([]net.IPNet{}, routes.Include...)
+	for _, server := range dnsServers {
+		include = append(include, hostNetwork(server))
+	}
+	routes.Include = include
+	return routes
+}
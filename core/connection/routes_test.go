@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRoutesParsesNetworksAndAddresses(t *testing.T) {
+	networks, err := ResolveRoutes([]string{"10.0.0.0/8", " 192.168.1.1 "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1/32"}, networkStrings(networks))
+}
+
+func TestResolveRoutesResolvesHostNames(t *testing.T) {
+	lookupIP = func(host string) ([]net.IP, error) {
+		assert.Equal(t, "intranet.example.com", host)
+		return []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("172.16.0.5")}, nil
+	}
+	defer func() { lookupIP = net.LookupIP }()
+
+	networks, err := ResolveRoutes([]string{"intranet.example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"172.16.0.5/32"}, networkStrings(networks))
+}
+
+func TestResolveRoutesFailsOnUnresolvableHostName(t *testing.T) {
+	lookupIP = func(host string) ([]net.IP, error) {
+		return nil, errors.New("no such host")
+	}
+	defer func() { lookupIP = net.LookupIP }()
+
+	_, err := ResolveRoutes([]string{"unknown.example.com"})
+
+	assert.EqualError(t, err, "no such host")
+}
+
+func TestResolveRoutesRejectsInvalidAndIPv6Routes(t *testing.T) {
+	_, err := ResolveRoutes([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = ResolveRoutes([]string{"2001:db8::/32"})
+	assert.EqualError(t, err, `route "2001:db8::/32" is not IPv4 network`)
+
+	_, err = ResolveRoutes([]string{"2001:db8::1"})
+	assert.EqualError(t, err, `route "2001:db8::1" is not IPv4 address`)
+}
+
+func TestRouteDNSServersWhenTunnelIsSplit(t *testing.T) {
+	dnsServers := []net.IP{net.ParseIP("1.1.1.1")}
+
+	routes, err := resolveConnectRoutes(ConnectParams{})
+	assert.NoError(t, err)
+	assert.Empty(t, routeDNSServers(routes, dnsServers).Include)
+
+	routes, err = resolveConnectRoutes(ConnectParams{IncludeRoutes: []string{"10.0.0.0/8"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "1.1.1.1/32"}, networkStrings(routeDNSServers(routes, dnsServers).Include))
+	assert.Equal(t, []string{"10.0.0.0/8"}, networkStrings(routes.Include))
+}
+
+func networkStrings(networks []net.IPNet) []string {
+	var result []string
+	for _, network := range networks {
+		result = append(result, network.String())
+	}
+	return result
+}
@@ -18,11 +18,14 @@
 package connection
 
 import (
+	"encoding/json"
 	"errors"
+	"net"
 	"sync"
 
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/session"
@@ -130,6 +133,7 @@ const (
 type connectionFactoryFake struct {
 	mockError      error
 	mockConnection *connectionMock
+	created        []*connectionMock
 }
 
 func (cff *connectionFactoryFake) CreateConnection(serviceType string, stateChannel StateChannel, statisticsChannel StatisticsChannel) (Connection, error) {
@@ -138,7 +142,12 @@ func (cff *connectionFactoryFake) CreateConnection(serviceType string, stateChan
 		return nil, cff.mockError
 	}
 
+	channelClosed := false
 	stateCallback := func(state fakeState) {
+		//connection which was lost may still be stopped during cleanup
+		if channelClosed {
+			return
+		}
 		if state == connectedState {
 			stateChannel <- Connected
 			statisticsChannel <- cff.mockConnection.onStartReportStats
@@ -151,6 +160,7 @@ func (cff *connectionFactoryFake) CreateConnection(serviceType string, stateChan
 		}
 		//this is the last state - close channel (according to best practices of go - channel writer controls channel)
 		if state == processExited {
+			channelClosed = true
 			close(stateChannel)
 		}
 	}
@@ -164,7 +174,12 @@ func (cff *connectionFactoryFake) CreateConnection(serviceType string, stateChan
 		stateCallback:       cff.mockConnection.stateCallback,
 		onStartReportStats:  cff.mockConnection.onStartReportStats,
 		fakeProcess:         sync.WaitGroup{},
+		startRelease:        cff.mockConnection.startRelease,
+		stoppedSignal:       cff.mockConnection.stoppedSignal,
+		tunnelIPv6:          cff.mockConnection.tunnelIPv6,
+		noDNS:               cff.mockConnection.noDNS,
 	}
+	cff.created = append(cff.created, &copy)
 
 	return &copy, nil
 }
@@ -177,13 +192,47 @@ type connectionMock struct {
 	onStartReportStats  consumer.SessionStatistics
 	fakeProcess         sync.WaitGroup
 	sync.RWMutex
+	startedWith ConnectOptions
+	// startRelease holds start back until it's closed
+	startRelease chan struct{}
+	// stoppedSignal is closed once connection is stopped
+	stoppedSignal chan struct{}
+	tunnelIPv6    bool
+	// noDNS makes provider advertise no DNS servers
+	noDNS bool
 }
 
+var fakeAdvertisedDNS = []net.IP{net.ParseIP("10.8.0.1")}
+
 func (foc *connectionMock) GetConfig() (ConsumerConfig, error) {
 	return nil, nil
 }
 
+func (foc *connectionMock) GetTunnel() (firewall.Tunnel, error) {
+	tunnel := fakeTunnel
+	tunnel.IPv6 = foc.tunnelIPv6
+	return tunnel, nil
+}
+
+func (foc *connectionMock) LocateEndpoint(_ json.RawMessage) (net.IP, error) {
+	return net.ParseIP("5.6.7.8"), nil
+}
+
+func (foc *connectionMock) LocateDNS(_ json.RawMessage) ([]net.IP, error) {
+	if foc.noDNS {
+		return nil, nil
+	}
+	return fakeAdvertisedDNS, nil
+}
+
 func (foc *connectionMock) Start(connectionParams ConnectOptions) error {
+	foc.Lock()
+	foc.startedWith = connectionParams
+	foc.Unlock()
+	if foc.startRelease != nil {
+		<-foc.startRelease
+	}
+
 	foc.RLock()
 	defer foc.RUnlock()
 
@@ -208,6 +257,9 @@ func (foc *connectionMock) Stop() {
 		foc.reportState(fakeState)
 	}
 	foc.fakeProcess.Done()
+	if foc.stoppedSignal != nil {
+		close(foc.stoppedSignal)
+	}
 }
 
 func (foc *connectionMock) reportState(state fakeState) {
@@ -224,11 +276,81 @@ func (foc *connectionMock) StateCallback(callback func(state fakeState)) {
 	foc.stateCallback = callback
 }
 
+var fakeTunnel = firewall.Tunnel{Interface: "tun0", Endpoint: net.ParseIP("1.2.3.4")}
+
+// fakeFirewall fakes kill switch and leak blockers
+type fakeFirewall struct {
+	enabledWith *firewall.Tunnel
+	allowed     []net.IP
+	// allowedHistory are destinations of all the allow calls
+	allowedHistory [][]net.IP
+	disabled       int
+	mockError      error
+	sync.Mutex
+}
+
+func (ks *fakeFirewall) Enable(tunnel firewall.Tunnel) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if ks.mockError != nil {
+		return ks.mockError
+	}
+	ks.enabledWith = &tunnel
+	ks.allowed = nil
+	return nil
+}
+
+func (ks *fakeFirewall) Allow(destinations []net.IP) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.allowed = destinations
+	ks.allowedHistory = append(ks.allowedHistory, destinations)
+	return nil
+}
+
+func (ks *fakeFirewall) Allowed() ([]net.IP, [][]net.IP) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	return ks.allowed, ks.allowedHistory
+}
+
+func (ks *fakeFirewall) Disable() error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.enabledWith = nil
+	ks.allowed = nil
+	ks.disabled++
+	return nil
+}
+
+func (ks *fakeFirewall) Enabled() bool {
+	ks.Lock()
+	defer ks.Unlock()
+
+	return ks.enabledWith != nil
+}
+
 type fakeDialog struct {
 	peerID    identity.Identity
 	sessionID session.ID
-
-	closed bool
+	// sessionCreateRelease holds session create request back until it's closed
+	sessionCreateRelease chan struct{}
+	// closedSignal is closed once dialog is closed for the first time
+	closedSignal chan struct{}
+	// sessionDestroyRelease holds session destroy request back until it's closed
+	sessionDestroyRelease chan struct{}
+	// sessionDestroySignal is closed once session destroy is requested for the first time
+	sessionDestroySignal chan struct{}
+
+	closed    bool
+	requested []communication.RequestEndpoint
+	// requestedBeforeClose are endpoints requested until dialog was closed for the first time
+	requestedBeforeClose []communication.RequestEndpoint
+	consumers            []communication.MessageConsumer
 	sync.RWMutex
 }
 
@@ -243,13 +365,67 @@ func (fd *fakeDialog) Close() error {
 	fd.Lock()
 	defer fd.Unlock()
 
+	if !fd.closed {
+		fd.requestedBeforeClose = append([]communication.RequestEndpoint{}, fd.requested...)
+		if fd.closedSignal != nil {
+			close(fd.closedSignal)
+		}
+	}
 	fd.closed = true
 	return nil
 }
 
+func (fd *fakeDialog) Closed() bool {
+	fd.RLock()
+	defer fd.RUnlock()
+
+	return fd.closed
+}
+
+func (fd *fakeDialog) RequestCount(endpoint communication.RequestEndpoint) int {
+	fd.RLock()
+	defer fd.RUnlock()
+
+	return fd.requestCountLocked(endpoint)
+}
+
+func (fd *fakeDialog) requestCountLocked(endpoint communication.RequestEndpoint) int {
+	count := 0
+	for _, requested := range fd.requested {
+		if requested == endpoint {
+			count++
+		}
+	}
+	return count
+}
+
+func (fd *fakeDialog) RequestedBeforeClose() []communication.RequestEndpoint {
+	fd.RLock()
+	defer fd.RUnlock()
+
+	return fd.requestedBeforeClose
+}
+
 func (fd *fakeDialog) Receive(consumer communication.MessageConsumer) error {
+	fd.Lock()
+	defer fd.Unlock()
+
+	fd.consumers = append(fd.consumers, consumer)
 	return nil
 }
+
+// deliver hands message to consumers of given endpoint, as if peer has sent it
+func (fd *fakeDialog) deliver(endpoint communication.MessageEndpoint, messagePtr interface{}) {
+	fd.RLock()
+	consumers := fd.consumers
+	fd.RUnlock()
+
+	for _, consumer := range consumers {
+		if consumer.GetMessageEndpoint() == endpoint {
+			consumer.Consume(messagePtr)
+		}
+	}
+}
 func (fd *fakeDialog) Respond(consumer communication.RequestConsumer) error {
 	return nil
 }
@@ -262,7 +438,20 @@ func (fd *fakeDialog) Send(producer communication.MessageProducer) error {
 var ErrUnknownRequest = errors.New("unknown request")
 
 func (fd *fakeDialog) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	fd.Lock()
+	fd.requested = append(fd.requested, producer.GetRequestEndpoint())
+	fd.Unlock()
+
 	if producer.GetRequestEndpoint() == communication.RequestEndpoint("session-destroy") {
+		fd.Lock()
+		if fd.sessionDestroySignal != nil && fd.requestCountLocked("session-destroy") == 1 {
+			close(fd.sessionDestroySignal)
+		}
+		release := fd.sessionDestroyRelease
+		fd.Unlock()
+		if release != nil {
+			<-release
+		}
 		return &session.DestroyResponse{
 				Success: true,
 			},
@@ -270,6 +459,9 @@ func (fd *fakeDialog) Request(producer communication.RequestProducer) (responseP
 	}
 
 	if producer.GetRequestEndpoint() == communication.RequestEndpoint("session-create") {
+		if fd.sessionCreateRelease != nil {
+			<-fd.sessionCreateRelease
+		}
 		return &session.CreateResponse{
 				Success: true,
 				Session: session.SessionDto{
@@ -281,3 +473,11 @@ func (fd *fakeDialog) Request(producer communication.RequestProducer) (responseP
 	}
 	return nil, ErrUnknownRequest
 }
+
+type fakeProposalProvider struct {
+	proposals []market.ServiceProposal
+}
+
+func (fpp *fakeProposalProvider) FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error) {
+	return fpp.proposals, nil
+}
@@ -0,0 +1,263 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/ip"
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/firewall"
+)
+
+const diagnosticsLogPrefix = "[diagnostics] "
+
+// Check is a name of single diagnostics check
+type Check string
+
+const (
+	// CheckPublicIP verifies that public IP differs from the one detected before connecting
+	CheckPublicIP = Check("public-ip")
+	// CheckDNS verifies that DNS queries are answered by resolvers outside of consumer's original network
+	CheckDNS = Check("dns")
+	// CheckIPv6 verifies that IPv6 traffic does not bypass the tunnel
+	CheckIPv6 = Check("ipv6")
+	// CheckDefaultRoute verifies that traffic to the internet goes through tunnel device
+	CheckDefaultRoute = Check("default-route")
+)
+
+// ErrNotConnected indicates that diagnostics were requested without established connection
+var ErrNotConnected = errors.New("diagnostics require established connection")
+
+// Result is an outcome of single check
+type Result struct {
+	Check  Check
+	Passed bool
+	// Message explains the outcome in human readable form
+	Message string
+	// Values are the facts check was based on, e.g. detected addresses and interfaces
+	Values map[string]string
+}
+
+// Report is an outcome of all checks, it's passed only if every check is passed
+type Report struct {
+	Passed  bool
+	Results []Result
+}
+
+// Connection exposes the network path of established connection
+type Connection interface {
+	Tunnel() (firewall.Tunnel, bool)
+}
+
+// Network probes how traffic leaves the system
+type Network interface {
+	// DNSResolvers returns addresses of resolvers which answered queries made with system settings
+	DNSResolvers() ([]string, error)
+	// EgressInterface returns network interface which traffic to the internet leaves through, for "ip4" or "ip6" network.
+	// Empty name is returned if there is no route to the internet in given network.
+	EgressInterface(network string) (string, error)
+}
+
+// Diagnostics checks established connection for traffic leaking outside of the tunnel
+type Diagnostics struct {
+	connection       Connection
+	ipResolver       ip.Resolver
+	originalLocation location.Cache
+	locationResolver location.Resolver
+	network          Network
+}
+
+// NewDiagnostics creates diagnostics of given connection
+func NewDiagnostics(
+	connection Connection,
+	ipResolver ip.Resolver,
+	originalLocation location.Cache,
+	locationResolver location.Resolver,
+	network Network,
+) *Diagnostics {
+	return &Diagnostics{
+		connection:       connection,
+		ipResolver:       ipResolver,
+		originalLocation: originalLocation,
+		locationResolver: locationResolver,
+		network:          network,
+	}
+}
+
+// Run runs all checks of established connection
+func (d *Diagnostics) Run() (Report, error) {
+	tunnel, connected := d.connection.Tunnel()
+	if !connected {
+		return Report{}, ErrNotConnected
+	}
+
+	original := d.originalLocation.Get()
+	publicIPResult, publicIP := d.checkPublicIP(original)
+	results := []Result{
+		publicIPResult,
+		d.checkDNS(original, publicIP),
+		d.checkIPv6(tunnel),
+		d.checkDefaultRoute(tunnel),
+	}
+
+	report := Report{Passed: true, Results: results}
+	for _, result := range results {
+		if !result.Passed {
+			log.Warn(diagnosticsLogPrefix, "Check '", result.Check, "' failed: ", result.Message)
+			report.Passed = false
+		}
+	}
+	return report, nil
+}
+
+func (d *Diagnostics) checkPublicIP(original location.Location) (Result, string) {
+	result := Result{
+		Check:  CheckPublicIP,
+		Values: map[string]string{"originalIP": original.IP},
+	}
+
+	publicIP, err := d.ipResolver.GetPublicIP()
+	if err != nil {
+		result.Message = fmt.Sprint("failed to detect public IP: ", err)
+		return result, ""
+	}
+	result.Values["publicIP"] = publicIP
+
+	switch {
+	case original.IP == "":
+		result.Message = "original IP was not detected before connecting, it can't be compared"
+	case publicIP == original.IP:
+		result.Message = "public IP did not change, traffic leaves outside of the tunnel"
+	default:
+		result.Passed = true
+		result.Message = "public IP changed"
+	}
+	return result, publicIP
+}
+
+func (d *Diagnostics) checkDNS(original location.Location, publicIP string) Result {
+	result := Result{
+		Check:  CheckDNS,
+		Values: map[string]string{},
+	}
+
+	resolvers, err := d.network.DNSResolvers()
+	if err != nil {
+		result.Message = fmt.Sprint("failed to detect DNS resolvers: ", err)
+		return result
+	}
+	if len(resolvers) == 0 {
+		result.Message = "no DNS resolver answered queries"
+		return result
+	}
+	result.Values["resolvers"] = strings.Join(resolvers, ",")
+
+	var tunnelCountry string
+	if publicIP != "" {
+		tunnelCountry, _ = d.locationResolver.ResolveCountry(publicIP)
+	}
+	if original.Country == "" || tunnelCountry == "" || original.Country == tunnelCountry {
+		// resolvers of the original network can't be told apart from provider's ones by location, leak can't be ruled out
+		result.Message = "resolvers detected, their origin can't be determined by location"
+		return result
+	}
+
+	var leaking, unknown []string
+	for _, resolver := range resolvers {
+		country, err := d.locationResolver.ResolveCountry(resolver)
+		switch {
+		case err != nil || country == "":
+			unknown = append(unknown, resolver)
+		case country == original.Country:
+			leaking = append(leaking, resolver)
+		}
+	}
+	if len(leaking) > 0 {
+		result.Values["leakingResolvers"] = strings.Join(leaking, ",")
+		result.Message = "DNS queries are answered by resolvers in original country " + original.Country
+		return result
+	}
+	if len(unknown) > 0 {
+		result.Values["unknownResolvers"] = strings.Join(unknown, ",")
+		result.Message = "origin of some resolvers can't be determined by location"
+		return result
+	}
+
+	result.Passed = true
+	result.Message = "DNS queries are answered by resolvers outside of original country"
+	return result
+}
+
+func (d *Diagnostics) checkIPv6(tunnel firewall.Tunnel) Result {
+	result := Result{
+		Check:  CheckIPv6,
+		Values: map[string]string{"tunnelInterface": tunnel.Interface},
+	}
+
+	iface, err := d.network.EgressInterface("ip6")
+	switch {
+	case err != nil:
+		result.Message = fmt.Sprint("failed to detect IPv6 route: ", err)
+	case iface == "":
+		result.Passed = true
+		result.Message = "there is no IPv6 route to the internet"
+	case matchesInterface(tunnel.Interface, iface):
+		result.Values["interface"] = iface
+		result.Passed = true
+		result.Message = "IPv6 traffic goes through the tunnel"
+	default:
+		result.Values["interface"] = iface
+		result.Message = "IPv6 traffic bypasses the tunnel via " + iface
+	}
+	return result
+}
+
+func (d *Diagnostics) checkDefaultRoute(tunnel firewall.Tunnel) Result {
+	result := Result{
+		Check:  CheckDefaultRoute,
+		Values: map[string]string{"tunnelInterface": tunnel.Interface},
+	}
+
+	iface, err := d.network.EgressInterface("ip4")
+	switch {
+	case err != nil:
+		result.Message = fmt.Sprint("failed to detect default route: ", err)
+	case iface == "":
+		result.Message = "there is no route to the internet"
+	case matchesInterface(tunnel.Interface, iface):
+		result.Values["interface"] = iface
+		result.Passed = true
+		result.Message = "default route points at the tunnel device"
+	default:
+		result.Values["interface"] = iface
+		result.Message = "default route points at " + iface + " instead of the tunnel device"
+	}
+	return result
+}
+
+// matchesInterface matches interface name against tunnel interface, which may have iptables style wildcard (e.g. "tun+")
+func matchesInterface(pattern, name string) bool {
+	if strings.HasSuffix(pattern, "+") {
+		return strings.HasPrefix(name, strings.TrimSuffix(pattern, "+"))
+	}
+	return pattern == name
+}
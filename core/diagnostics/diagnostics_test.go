@@ -0,0 +1,214 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package diagnostics
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/ip"
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/stretchr/testify/assert"
+)
+
+var tunnel = firewall.Tunnel{Interface: "tun+", Endpoint: net.ParseIP("1.2.3.4")}
+
+type fakeConnection struct {
+	connected bool
+}
+
+func (fc *fakeConnection) Tunnel() (firewall.Tunnel, bool) {
+	return tunnel, fc.connected
+}
+
+type fakeLocationCache struct {
+	location location.Location
+}
+
+func (flc *fakeLocationCache) Get() location.Location {
+	return flc.location
+}
+
+func (flc *fakeLocationCache) RefreshAndGet() (location.Location, error) {
+	return flc.location, nil
+}
+
+type fakeLocationResolver struct {
+	countries map[string]string
+}
+
+func (flr *fakeLocationResolver) ResolveCountry(ip string) (string, error) {
+	country, found := flr.countries[ip]
+	if !found {
+		return "", errors.New("unknown ip")
+	}
+	return country, nil
+}
+
+type fakeNetwork struct {
+	resolvers  []string
+	interfaces map[string]string
+}
+
+func (fn *fakeNetwork) DNSResolvers() ([]string, error) {
+	return fn.resolvers, nil
+}
+
+func (fn *fakeNetwork) EgressInterface(network string) (string, error) {
+	return fn.interfaces[network], nil
+}
+
+func newTestDiagnostics(publicIP string, network *fakeNetwork) *Diagnostics {
+	return NewDiagnostics(
+		&fakeConnection{connected: true},
+		ip.NewResolverFake(publicIP),
+		&fakeLocationCache{location.Location{IP: "10.0.0.1", Country: "LT"}},
+		&fakeLocationResolver{countries: map[string]string{
+			"10.0.0.1":  "LT",
+			"10.0.0.53": "LT",
+			"20.0.0.1":  "DE",
+			"20.0.0.53": "DE",
+		}},
+		network,
+	)
+}
+
+func resultOf(report Report, check Check) Result {
+	for _, result := range report.Results {
+		if result.Check == check {
+			return result
+		}
+	}
+	return Result{}
+}
+
+func TestDiagnostics_RunRequiresConnection(t *testing.T) {
+	diagnostics := NewDiagnostics(&fakeConnection{}, nil, nil, nil, nil)
+
+	_, err := diagnostics.Run()
+	assert.Equal(t, ErrNotConnected, err)
+}
+
+func TestDiagnostics_RunPassesWhenTrafficGoesThroughTunnel(t *testing.T) {
+	diagnostics := newTestDiagnostics("20.0.0.1", &fakeNetwork{
+		resolvers:  []string{"20.0.0.53"},
+		interfaces: map[string]string{"ip4": "tun0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	assert.True(t, report.Passed)
+	assert.Len(t, report.Results, 4)
+	for _, result := range report.Results {
+		assert.True(t, result.Passed, "check %s: %s", result.Check, result.Message)
+	}
+	assert.Equal(t, "20.0.0.1", resultOf(report, CheckPublicIP).Values["publicIP"])
+	assert.Equal(t, "20.0.0.53", resultOf(report, CheckDNS).Values["resolvers"])
+}
+
+func TestDiagnostics_PublicIPFailsWhenIPDidNotChange(t *testing.T) {
+	diagnostics := newTestDiagnostics("10.0.0.1", &fakeNetwork{
+		interfaces: map[string]string{"ip4": "tun0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	assert.False(t, report.Passed)
+	assert.False(t, resultOf(report, CheckPublicIP).Passed)
+}
+
+func TestDiagnostics_DNSFailsWhenResolverIsInOriginalCountry(t *testing.T) {
+	diagnostics := newTestDiagnostics("20.0.0.1", &fakeNetwork{
+		resolvers:  []string{"10.0.0.53", "20.0.0.53"},
+		interfaces: map[string]string{"ip4": "tun0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	dnsResult := resultOf(report, CheckDNS)
+	assert.False(t, dnsResult.Passed)
+	assert.Equal(t, "10.0.0.53", dnsResult.Values["leakingResolvers"])
+}
+
+func TestDiagnostics_DNSFailsWhenNoResolverAnswers(t *testing.T) {
+	diagnostics := newTestDiagnostics("20.0.0.1", &fakeNetwork{
+		interfaces: map[string]string{"ip4": "tun0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	assert.False(t, resultOf(report, CheckDNS).Passed)
+}
+
+func TestDiagnostics_DNSFailsWhenResolverOriginIsUnknown(t *testing.T) {
+	diagnostics := newTestDiagnostics("20.0.0.1", &fakeNetwork{
+		resolvers:  []string{"20.0.0.53", "30.0.0.53"},
+		interfaces: map[string]string{"ip4": "tun0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	dnsResult := resultOf(report, CheckDNS)
+	assert.False(t, dnsResult.Passed)
+	assert.Equal(t, "30.0.0.53", dnsResult.Values["unknownResolvers"])
+}
+
+func TestDiagnostics_DNSFailsWhenTunnelCountryIsUnknown(t *testing.T) {
+	diagnostics := newTestDiagnostics("30.0.0.1", &fakeNetwork{
+		resolvers:  []string{"20.0.0.53"},
+		interfaces: map[string]string{"ip4": "tun0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	assert.False(t, resultOf(report, CheckDNS).Passed)
+}
+
+func TestDiagnostics_IPv6FailsWhenItBypassesTunnel(t *testing.T) {
+	diagnostics := newTestDiagnostics("20.0.0.1", &fakeNetwork{
+		resolvers:  []string{"20.0.0.53"},
+		interfaces: map[string]string{"ip4": "tun0", "ip6": "eth0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	ipv6Result := resultOf(report, CheckIPv6)
+	assert.False(t, ipv6Result.Passed)
+	assert.Equal(t, "eth0", ipv6Result.Values["interface"])
+}
+
+func TestDiagnostics_DefaultRouteFailsWhenItIsNotTunnel(t *testing.T) {
+	diagnostics := newTestDiagnostics("20.0.0.1", &fakeNetwork{
+		resolvers:  []string{"20.0.0.53"},
+		interfaces: map[string]string{"ip4": "eth0"},
+	})
+
+	report, err := diagnostics.Run()
+	assert.NoError(t, err)
+	assert.False(t, report.Passed)
+	assert.False(t, resultOf(report, CheckDefaultRoute).Passed)
+}
+
+func TestMatchesInterface(t *testing.T) {
+	assert.True(t, matchesInterface("tun+", "tun0"))
+	assert.True(t, matchesInterface("wg0", "wg0"))
+	assert.False(t, matchesInterface("tun+", "eth0"))
+	assert.False(t, matchesInterface("wg0", "wg1"))
+}
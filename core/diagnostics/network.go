@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+const (
+	// whoamiHost answers with the address of resolver which queried it
+	whoamiHost = "whoami.akamai.net"
+	// whoamiQueries is a number of queries made, system may balance them over several resolvers
+	whoamiQueries = 3
+	dnsTimeout    = 5 * time.Second
+)
+
+// egressProbe is a public address used only to pick route, no packets are sent to it
+type egressProbe struct {
+	network string
+	address string
+}
+
+var egressProbes = map[string]egressProbe{
+	"ip4": {network: "udp4", address: "8.8.8.8:53"},
+	"ip6": {network: "udp6", address: "[2001:4860:4860::8888]:53"},
+}
+
+// NewNetwork creates probes of system network
+func NewNetwork() Network {
+	return &systemNetwork{
+		resolver: net.DefaultResolver,
+	}
+}
+
+type systemNetwork struct {
+	resolver *net.Resolver
+}
+
+func (sn *systemNetwork) DNSResolvers() ([]string, error) {
+	found := map[string]bool{}
+	var lastErr error
+	for i := 0; i < whoamiQueries; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+		addresses, err := sn.resolver.LookupHost(ctx, whoamiHost)
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, address := range addresses {
+			found[address] = true
+		}
+	}
+	if len(found) == 0 {
+		return nil, lastErr
+	}
+
+	resolvers := make([]string, 0, len(found))
+	for address := range found {
+		resolvers = append(resolvers, address)
+	}
+	sort.Strings(resolvers)
+	return resolvers, nil
+}
+
+func (sn *systemNetwork) EgressInterface(network string) (string, error) {
+	probe, ok := egressProbes[network]
+	if !ok {
+		return "", fmt.Errorf("unknown network %q", network)
+	}
+
+	// dialing UDP only selects route and local address, nothing is sent
+	conn, err := net.Dial(probe.network, probe.address)
+	if err != nil {
+		// no route to the internet in this network
+		return "", nil
+	}
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	return interfaceByIP(localIP)
+}
+
+func interfaceByIP(ip net.IP) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	for _, iface := range ifaces {
+		addresses, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			if ipNet, ok := address.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface has address %s", ip)
+}
@@ -45,3 +45,10 @@ func (client *fakeResolver) GetPublicIP() (string, error) {
 func (client *fakeResolver) GetOutboundIP() (string, error) {
 	return client.ipAddress, client.error
 }
+
+func (client *fakeResolver) GetOutboundIPv6() (string, error) {
+	if client.error != nil {
+		return "", client.error
+	}
+	return "", ErrNoIPv6
+}
@@ -17,8 +17,15 @@
 
 package ip
 
+import "errors"
+
 // Resolver allows resolving current IP
 type Resolver interface {
 	GetPublicIP() (string, error)
 	GetOutboundIP() (string, error)
+	// GetOutboundIPv6 returns global IPv6 address of outbound interface, ErrNoIPv6 is returned if there is none
+	GetOutboundIPv6() (string, error)
 }
+
+// ErrNoIPv6 indicates that host has no IPv6 connectivity to the internet
+var ErrNoIPv6 = errors.New("no IPv6 connectivity")
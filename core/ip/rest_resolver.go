@@ -92,6 +92,22 @@ func (client *clientRest) GetOutboundIP() (string, error) {
 	return localAddr.IP.String(), nil
 }
 
+func (client *clientRest) GetOutboundIPv6() (string, error) {
+	conn, err := net.Dial("udp6", "[2001:4860:4860::8888]:53")
+	if err != nil {
+		return "", ErrNoIPv6
+	}
+	defer conn.Close()
+
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	// unique local addresses (fc00::/7) are not reachable from the internet
+	if !localIP.IsGlobalUnicast() || localIP[0]&0xfe == 0xfc {
+		return "", ErrNoIPv6
+	}
+	log.Info("[Detect Outbound IPv6] ", "IP detected: ", localIP.String())
+	return localIP.String(), nil
+}
+
 func (client *clientRest) doRequest(request *http.Request, responseDto interface{}) error {
 	response, err := client.httpClient.Do(request)
 	if err != nil {
@@ -17,6 +17,8 @@
 
 package node
 
+import "time"
+
 // Openvpn interface is abstraction over real openvpn options to unblock mobile development
 // will disappear as soon as go-openvpn will unify common factory for openvpn creation
 type Openvpn interface {
@@ -33,6 +35,13 @@ type Options struct {
 
 	Keystore OptionsKeystore
 
+	AutoConnect OptionsAutoConnect
+
+	// SessionGracePeriod is how long provider waits for silent consumer before ending its session
+	SessionGracePeriod time.Duration
+
+	AccessPolicy OptionsAccessPolicy
+
 	Openvpn  Openvpn
 	Location OptionsLocation
 	OptionsNetwork
@@ -42,3 +51,17 @@ type Options struct {
 type OptionsKeystore struct {
 	UseLightweight bool
 }
+
+// OptionsAutoConnect describes restoring of the last connection after node restart
+type OptionsAutoConnect struct {
+	Enabled bool
+	// Passphrase unlocks consumer identity of the restored connection
+	Passphrase string
+}
+
+// OptionsAccessPolicy describes where provider gets rules of consumer access to its services
+type OptionsAccessPolicy struct {
+	// Source is path to local file or URL of rules, rules are kept in memory only if it's empty
+	Source          string
+	RefreshInterval time.Duration
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const policyLogPrefix = "[access-policy] "
+
+// ErrReadOnly is returned when rules are edited, but their source can't be written to
+var ErrReadOnly = errors.New("access policy rules are managed by remote source")
+
+// Rules lists consumer identities, which are allowed or denied to use services of the provider
+type Rules struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// Source loads rules from the place they are kept in
+type Source interface {
+	Load() (Rules, error)
+}
+
+// WritableSource is the source, which edited rules are saved to
+type WritableSource interface {
+	Source
+	Save(rules Rules) error
+}
+
+// NewPolicy creates policy, which keeps rules of given source up to date.
+// Without source, policy allows everyone until rules are set.
+func NewPolicy(source Source, refreshInterval time.Duration) *Policy {
+	return &Policy{
+		source:          source,
+		refreshInterval: refreshInterval,
+		done:            make(chan struct{}),
+	}
+}
+
+// Policy decides which consumers are allowed to establish dialogs with the provider
+type Policy struct {
+	source          Source
+	refreshInterval time.Duration
+
+	rules  Rules
+	allow  map[string]bool
+	deny   map[string]bool
+	loaded bool
+	mutex  sync.RWMutex
+
+	// listeners are notified after rules are applied, so that they can act on consumers denied meanwhile
+	listeners []func()
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// IsAllowed tells if consumer is allowed to use services.
+// Denied identities are refused always, others are refused only if allowlist is not empty and doesn't include them.
+// Everyone is refused while rules of the source haven't been loaded yet.
+func (policy *Policy) IsAllowed(consumerID identity.Identity) bool {
+	policy.mutex.RLock()
+	defer policy.mutex.RUnlock()
+
+	if policy.source != nil && !policy.loaded {
+		return false
+	}
+
+	address := normalize(consumerID.Address)
+	if policy.deny[address] {
+		return false
+	}
+	return len(policy.allow) == 0 || policy.allow[address]
+}
+
+// OnRulesApplied registers listener, which is called each time rules are set or reloaded from the source
+func (policy *Policy) OnRulesApplied(listener func()) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+
+	policy.listeners = append(policy.listeners, listener)
+}
+
+// Rules returns rules currently in force
+func (policy *Policy) Rules() Rules {
+	policy.mutex.RLock()
+	defer policy.mutex.RUnlock()
+
+	return policy.rules
+}
+
+// SetRules replaces current rules and saves them to the source
+func (policy *Policy) SetRules(rules Rules) error {
+	if policy.source != nil {
+		writable, ok := policy.source.(WritableSource)
+		if !ok {
+			return ErrReadOnly
+		}
+		if err := writable.Save(rules); err != nil {
+			return err
+		}
+	}
+
+	policy.apply(rules)
+	return nil
+}
+
+// Refresh reloads rules from the source, current rules are kept if loading fails
+func (policy *Policy) Refresh() error {
+	if policy.source == nil {
+		return nil
+	}
+
+	rules, err := policy.source.Load()
+	if err != nil {
+		return err
+	}
+	policy.apply(rules)
+	return nil
+}
+
+// Start loads rules from the source and keeps refreshing them - does not block.
+// Rules are loaded only once, if refresh interval is not positive.
+func (policy *Policy) Start() {
+	if policy.source == nil {
+		return
+	}
+
+	policy.refresh()
+	if policy.refreshInterval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-policy.done:
+				return
+			case <-time.After(policy.refreshInterval):
+				policy.refresh()
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing rules
+func (policy *Policy) Stop() {
+	policy.stopOnce.Do(func() {
+		close(policy.done)
+	})
+}
+
+func (policy *Policy) refresh() {
+	if err := policy.Refresh(); err != nil {
+		log.Warn(policyLogPrefix, "Failed to load access policy rules: ", err)
+	}
+}
+
+func (policy *Policy) apply(rules Rules) {
+	allow := toSet(rules.Allow)
+	deny := toSet(rules.Deny)
+
+	policy.mutex.Lock()
+	policy.rules = rules
+	policy.allow = allow
+	policy.deny = deny
+	policy.loaded = true
+	listeners := policy.listeners
+	policy.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+func toSet(addresses []string) map[string]bool {
+	set := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		set[normalize(address)] = true
+	}
+	return set
+}
+
+func normalize(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
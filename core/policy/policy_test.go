@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	teamMember = identity.FromAddress("0x1111111111111111111111111111111111111111")
+	outsider   = identity.FromAddress("0x2222222222222222222222222222222222222222")
+)
+
+type fakeSource struct {
+	rules   Rules
+	loadErr error
+}
+
+func (source *fakeSource) Load() (Rules, error) {
+	return source.rules, source.loadErr
+}
+
+type fakeWritableSource struct {
+	fakeSource
+	saved []Rules
+}
+
+func (source *fakeWritableSource) Save(rules Rules) error {
+	source.saved = append(source.saved, rules)
+	return nil
+}
+
+func TestPolicy_AllowsEveryoneWithoutRules(t *testing.T) {
+	policy := NewPolicy(nil, time.Minute)
+
+	assert.True(t, policy.IsAllowed(teamMember))
+	assert.True(t, policy.IsAllowed(outsider))
+}
+
+func TestPolicy_AllowsOnlyAllowlistedWhenAllowlistIsSet(t *testing.T) {
+	policy := NewPolicy(nil, time.Minute)
+	assert.NoError(t, policy.SetRules(Rules{Allow: []string{"0x1111111111111111111111111111111111111111"}}))
+
+	assert.True(t, policy.IsAllowed(teamMember))
+	assert.False(t, policy.IsAllowed(outsider))
+}
+
+func TestPolicy_DenylistWinsOverAllowlist(t *testing.T) {
+	policy := NewPolicy(nil, time.Minute)
+	assert.NoError(t, policy.SetRules(Rules{
+		Allow: []string{"0x1111111111111111111111111111111111111111"},
+		Deny:  []string{"0X1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"},
+	}))
+
+	assert.False(t, policy.IsAllowed(teamMember))
+	assert.False(t, policy.IsAllowed(outsider))
+}
+
+func TestPolicy_RefusesEveryoneUntilSourceIsLoaded(t *testing.T) {
+	source := &fakeSource{loadErr: errors.New("file not found")}
+	policy := NewPolicy(source, time.Minute)
+
+	assert.Error(t, policy.Refresh())
+	assert.False(t, policy.IsAllowed(teamMember))
+
+	source.loadErr = nil
+	assert.NoError(t, policy.Refresh())
+	assert.True(t, policy.IsAllowed(teamMember))
+}
+
+func TestPolicy_KeepsRulesWhenRefreshFails(t *testing.T) {
+	source := &fakeSource{rules: Rules{Deny: []string{outsider.Address}}}
+	policy := NewPolicy(source, time.Minute)
+	assert.NoError(t, policy.Refresh())
+
+	source.loadErr = errors.New("server is down")
+	assert.Error(t, policy.Refresh())
+	assert.False(t, policy.IsAllowed(outsider))
+	assert.Equal(t, Rules{Deny: []string{outsider.Address}}, policy.Rules())
+}
+
+func TestPolicy_SetRulesSavesToWritableSource(t *testing.T) {
+	source := &fakeWritableSource{}
+	policy := NewPolicy(source, time.Minute)
+	rules := Rules{Allow: []string{teamMember.Address}}
+
+	assert.NoError(t, policy.SetRules(rules))
+	assert.Equal(t, []Rules{rules}, source.saved)
+	assert.Equal(t, rules, policy.Rules())
+}
+
+func TestPolicy_SetRulesFailsForReadOnlySource(t *testing.T) {
+	policy := NewPolicy(&fakeSource{}, time.Minute)
+
+	assert.Equal(t, ErrReadOnly, policy.SetRules(Rules{}))
+	assert.False(t, policy.IsAllowed(teamMember))
+}
+
+func TestPolicy_NotifiesListenersWhenRulesAreApplied(t *testing.T) {
+	source := &fakeWritableSource{}
+	policy := NewPolicy(source, time.Minute)
+	notified := 0
+	policy.OnRulesApplied(func() {
+		notified++
+		assert.False(t, policy.IsAllowed(outsider))
+	})
+
+	assert.NoError(t, policy.SetRules(Rules{Deny: []string{outsider.Address}}))
+	assert.Equal(t, 1, notified)
+
+	source.rules = Rules{Allow: []string{teamMember.Address}}
+	assert.NoError(t, policy.Refresh())
+	assert.Equal(t, 2, notified)
+}
+
+func TestPolicy_StartWithoutRefreshIntervalLoadsRulesOnce(t *testing.T) {
+	source := &fakeSource{rules: Rules{Allow: []string{teamMember.Address}}}
+	policy := NewPolicy(source, 0)
+	defer policy.Stop()
+
+	policy.Start()
+	assert.True(t, policy.IsAllowed(teamMember))
+	assert.False(t, policy.IsAllowed(outsider))
+}
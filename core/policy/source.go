@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NewSource creates source of rules kept at given location, which is either http(s) URL or path to local file
+func NewSource(location string) Source {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewURLSource(location)
+	}
+	return NewFileSource(location)
+}
+
+// NewFileSource creates source of rules kept in local JSON file
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// FileSource keeps rules in local JSON file
+type FileSource struct {
+	path string
+}
+
+// Load reads rules from the file
+func (source *FileSource) Load() (Rules, error) {
+	var rules Rules
+	data, err := ioutil.ReadFile(source.path)
+	if err != nil {
+		return rules, err
+	}
+	err = json.Unmarshal(data, &rules)
+	return rules, err
+}
+
+// Save writes rules to the file, replacing it at once, so that concurrent load never sees partial rules
+func (source *FileSource) Save(rules Rules) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(source.path), filepath.Base(source.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), source.path)
+}
+
+// NewURLSource creates source of rules published at given URL
+func NewURLSource(url string) *URLSource {
+	return &URLSource{
+		url: url,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// URLSource fetches rules published as JSON document at URL, it's read-only
+type URLSource struct {
+	url  string
+	http *http.Client
+}
+
+// Load fetches rules from the URL
+func (source *URLSource) Load() (Rules, error) {
+	var rules Rules
+	response, err := source.http.Get(source.url)
+	if err != nil {
+		return rules, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return rules, fmt.Errorf("failed to fetch access policy rules, server responded with %s", response.Status)
+	}
+	err = json.NewDecoder(response.Body).Decode(&rules)
+	return rules, err
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSource_PicksSourceByLocation(t *testing.T) {
+	assert.IsType(t, &URLSource{}, NewSource("https://example.com/policy.json"))
+	assert.IsType(t, &FileSource{}, NewSource("/etc/mysterium/policy.json"))
+}
+
+func TestFileSource_SavedRulesAreLoaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access-policy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	source := NewFileSource(filepath.Join(dir, "policy.json"))
+	rules := Rules{Allow: []string{teamMember.Address}, Deny: []string{outsider.Address}}
+
+	assert.NoError(t, source.Save(rules))
+	loaded, err := source.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, rules, loaded)
+}
+
+func TestURLSource_LoadsPublishedRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Write([]byte(`{"allow": ["0x1111111111111111111111111111111111111111"]}`))
+	}))
+	defer server.Close()
+
+	rules, err := NewURLSource(server.URL).Load()
+	assert.NoError(t, err)
+	assert.Equal(t, Rules{Allow: []string{teamMember.Address}}, rules)
+}
+
+func TestURLSource_LoadFailsOnErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewURLSource(server.URL).Load()
+	assert.EqualError(t, err, "failed to fetch access policy rules, server responded with 404 Not Found")
+}
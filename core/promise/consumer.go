@@ -18,6 +18,9 @@
 package promise
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
@@ -38,6 +41,9 @@ type Consumer struct {
 	proposal market.ServiceProposal
 	balance  identity.Balance
 	storage  Storer
+
+	received     int64
+	lastReceived int64
 }
 
 // NewConsumer creates new instance of the promise consumer
@@ -73,6 +79,22 @@ func (c *Consumer) Consume(requestPtr interface{}) (response interface{}, err er
 	if err := c.storage.Store(request.SignedPromise.Promise.IssuerID, &request.SignedPromise.Promise); err != nil {
 		return responseInternalError, err
 	}
+	atomic.AddInt64(&c.received, 1)
+	atomic.StoreInt64(&c.lastReceived, time.Now().UnixNano())
 
 	return &Response{Success: true}, nil
 }
+
+// Received returns count of valid promises accepted by this consumer
+func (c *Consumer) Received() int {
+	return int(atomic.LoadInt64(&c.received))
+}
+
+// LastReceived returns time when the last valid promise was accepted, zero if none was
+func (c *Consumer) LastReceived() time.Time {
+	lastReceived := atomic.LoadInt64(&c.lastReceived)
+	if lastReceived == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, lastReceived)
+}
@@ -104,6 +104,8 @@ func TestConsumeLowBalance(t *testing.T) {
 	response, err := consumer.Consume(&request)
 	assert.Equal(t, errLowBalance, err)
 	assert.Equal(t, responseInvalidPromise, response)
+	assert.Equal(t, 0, consumer.Received())
+	assert.True(t, consumer.LastReceived().IsZero())
 
 }
 
@@ -120,6 +122,8 @@ func TestConsume(t *testing.T) {
 	response, err := consumer.Consume(&request)
 	assert.NoError(t, err)
 	assert.Equal(t, &Response{Success: true}, response)
+	assert.Equal(t, 1, consumer.Received())
+	assert.False(t, consumer.LastReceived().IsZero())
 }
 
 type fakePayment struct {
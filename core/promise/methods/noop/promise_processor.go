@@ -64,7 +64,9 @@ type PromiseProcessor struct {
 	balanceShutdown   chan bool
 
 	// these are populated later at runtime
-	lastPromise promise.Promise
+	lastPromise   promise.Promise
+	consumer      *promise.Consumer
+	consumerMutex sync.RWMutex
 }
 
 // Start processing promises for given service proposal
@@ -78,6 +80,9 @@ func (processor *PromiseProcessor) Start(proposal market.ServiceProposal) error
 	if err := processor.dialog.Respond(consumer); err != nil {
 		return err
 	}
+	processor.consumerMutex.Lock()
+	processor.consumer = consumer
+	processor.consumerMutex.Unlock()
 
 	processor.balanceShutdown = make(chan bool, 1)
 	go processor.balanceLoop()
@@ -91,6 +96,28 @@ func (processor *PromiseProcessor) Stop() error {
 	return nil
 }
 
+// PromisesReceived returns count of promises accepted from consumer
+func (processor *PromiseProcessor) PromisesReceived() int {
+	processor.consumerMutex.RLock()
+	defer processor.consumerMutex.RUnlock()
+
+	if processor.consumer == nil {
+		return 0
+	}
+	return processor.consumer.Received()
+}
+
+// LastPromiseReceived returns time when consumer has sent the last promise, false if promises aren't processed yet
+func (processor *PromiseProcessor) LastPromiseReceived() (time.Time, bool) {
+	processor.consumerMutex.RLock()
+	defer processor.consumerMutex.RUnlock()
+
+	if processor.consumer == nil {
+		return time.Time{}, false
+	}
+	return processor.consumer.LastReceived(), true
+}
+
 func (processor *PromiseProcessor) balanceLoop() {
 	processor.setBalanceState(balanceNotifying)
 
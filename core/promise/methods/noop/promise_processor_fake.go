@@ -17,7 +17,11 @@
 
 package noop
 
-import "github.com/mysteriumnetwork/node/market"
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/market"
+)
 
 // FakePromiseEngine do nothing. It required for the temporary --experiment-promise-check flag.
 // TODO it should be removed once --experiment-promise-check will be deleted.
@@ -32,3 +36,13 @@ func (*FakePromiseEngine) Start(_ market.ServiceProposal) error {
 func (*FakePromiseEngine) Stop() error {
 	return nil
 }
+
+// PromisesReceived fakes count of received promises
+func (*FakePromiseEngine) PromisesReceived() int {
+	return 0
+}
+
+// LastPromiseReceived tells that promises aren't checked
+func (*FakePromiseEngine) LastPromiseReceived() (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -58,6 +58,21 @@ func TestPromiseProcessor_Start_SendsBalanceMessages(t *testing.T) {
 	)
 }
 
+func TestPromiseProcessor_LastPromiseReceivedIsUnknownUntilStarted(t *testing.T) {
+	processor := &PromiseProcessor{
+		dialog:          &fakeDialog{},
+		balanceInterval: time.Millisecond,
+		storage:         &MockStorer{},
+	}
+	_, known := processor.LastPromiseReceived()
+	assert.False(t, known)
+
+	assert.NoError(t, processor.Start(proposal))
+	defer processor.Stop()
+	_, known = processor.LastPromiseReceived()
+	assert.True(t, known)
+}
+
 func TestPromiseProcessor_Stop_StopsBalanceMessages(t *testing.T) {
 	dialog := &fakeDialog{}
 
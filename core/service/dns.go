@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+// resolvConfPath is the file system resolvers are configured in on unix systems
+var resolvConfPath = "/etc/resolv.conf"
+
+// ConsumerDNS returns DNS servers advertised to consumers, which resolve names through the tunnel.
+// Configured servers are advertised if given, public system resolvers of provider otherwise.
+// Resolvers in private networks are skipped, egress policy doesn't let consumers reach them.
+func ConsumerDNS(configured []net.IP) []net.IP {
+	if len(configured) > 0 {
+		return configured
+	}
+
+	servers, err := systemDNS(resolvConfPath)
+	if err != nil {
+		log.Warn(logPrefix, "Failed to read system DNS servers: ", err)
+		return nil
+	}
+	if len(servers) == 0 {
+		log.Warn(logPrefix, "No public system DNS servers found, consumers will keep their own")
+	}
+	return servers
+}
+
+func systemDNS(path string) ([]net.IP, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var servers []net.IP
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		server := net.ParseIP(fields[1])
+		if server == nil || server.To4() == nil || isPrivateIP(server) {
+			continue
+		}
+		servers = append(servers, server)
+	}
+	return servers, scanner.Err()
+}
+
+var privateNetworks = []net.IPNet{
+	{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(172, 16, 0, 0), Mask: net.CIDRMask(12, 32)},
+	{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)},
+	{IP: net.IPv4(169, 254, 0, 0), Mask: net.CIDRMask(16, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+}
+
+func isPrivateIP(ip net.IP) bool {
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumerDNSPrefersConfiguredServers(t *testing.T) {
+	configured := []net.IP{net.ParseIP("1.1.1.1")}
+	assert.Equal(t, configured, ConsumerDNS(configured))
+}
+
+func TestSystemDNSSkipsPrivateAndIPv6Servers(t *testing.T) {
+	file, err := ioutil.TempFile("", "resolv.conf")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("# generated\nsearch lan\nnameserver 127.0.0.53\nnameserver 192.168.1.1\nnameserver 2001:4860:4860::8888\nnameserver 8.8.8.8\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	servers, err := systemDNS(file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("8.8.8.8")}, servers)
+}
+
+func TestSystemDNSFailsWithoutResolvConf(t *testing.T) {
+	_, err := systemDNS("/non-existing/resolv.conf")
+	assert.Error(t, err)
+}
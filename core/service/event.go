@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+// StatusEventTopic represents the service lifecycle topic
+const StatusEventTopic = "ServiceStatus"
+
+// RestartEventTopic represents the topic of service restarts
+const RestartEventTopic = "ServiceRestart"
+
+// Status describes stage of service lifecycle
+type Status string
+
+const (
+	// Starting means that service is being prepared for serving
+	Starting = Status("Starting")
+	// Running means that service is serving consumers
+	Running = Status("Running")
+	// NotRunning means that service has stopped or failed to start
+	NotRunning = Status("NotRunning")
+)
+
+// StatusEvent is the struct we'll emit on a StatusEventTopic event
+type StatusEvent struct {
+	ServiceID   ID
+	ServiceType string
+	ProviderID  identity.Identity
+	Status      Status
+	Error       error
+}
+
+// RestartEvent is the struct we'll emit on a RestartEventTopic event, before service which stopped is restarted
+type RestartEvent struct {
+	ServiceID   ID
+	ServiceType string
+	ProviderID  identity.Identity
+	// Attempt is count of restarts within window of restart policy, including this one
+	Attempt int
+	Delay   time.Duration
+	Error   error
+}
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
@@ -20,7 +20,9 @@ package service
 import (
 	"encoding/json"
 	"errors"
+	"sync"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 	identity_selector "github.com/mysteriumnetwork/node/identity/selector"
@@ -29,11 +31,15 @@ import (
 	"github.com/mysteriumnetwork/node/session"
 )
 
+const logPrefix = "[service-manager] "
+
 var (
 	// ErrorLocation error indicates that action (i.e. disconnect)
 	ErrorLocation = errors.New("failed to detect service location")
 	// ErrUnsupportedServiceType indicates that manager tried to create an unsupported service type
 	ErrUnsupportedServiceType = errors.New("unsupported service type")
+	// ErrProposalServiceTypeMismatch indicates that proposal of running service was tried to replace with one of other service type
+	ErrProposalServiceTypeMismatch = errors.New("proposal is of other service type")
 )
 
 // ServiceFactory initiates instance which is able to serve connections
@@ -46,31 +52,43 @@ type Service interface {
 	ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error)
 }
 
-// DialogWaiterFactory initiates communication channel which waits for incoming dialogs
-type DialogWaiterFactory func(providerID identity.Identity, serviceType string) (communication.DialogWaiter, error)
+// DialogWaiterFactory initiates communication channel which waits for incoming dialogs of the proposal
+type DialogWaiterFactory func(providerID identity.Identity, serviceType string, proposalID int) (communication.DialogWaiter, error)
 
 // DialogHandlerFactory initiates instance which is able to handle incoming dialogs
-type DialogHandlerFactory func(market.ServiceProposal, session.ConfigNegotiator) communication.DialogHandler
+type DialogHandlerFactory func(session.ProposalProvider, session.ConfigNegotiator, *session.Limiter) communication.DialogHandler
 
-// NewManager creates new instance of pluggable services manager
+// NewManager creates new instance of pluggable services manager, which runs service instance of given ID
+// and offers it with given proposal ID
 func NewManager(
+	id ID,
+	proposalID int,
 	identityLoader identity_selector.Handler,
 	serviceFactory ServiceFactory,
 	dialogWaiterFactory DialogWaiterFactory,
 	dialogHandlerFactory DialogHandlerFactory,
 	discoveryService *registry.Discovery,
+	sessionStorage session.Storage,
+	eventPublisher Publisher,
 ) *Manager {
 	return &Manager{
+		id:                   id,
+		proposalID:           proposalID,
 		identityHandler:      identityLoader,
 		serviceFactory:       serviceFactory,
 		dialogWaiterFactory:  dialogWaiterFactory,
 		dialogHandlerFactory: dialogHandlerFactory,
 		discovery:            discoveryService,
+		sessionStorage:       sessionStorage,
+		eventPublisher:       eventPublisher,
+		status:               NotRunning,
 	}
 }
 
 // Manager entrypoint which knows how to start pluggable Mysterium services
 type Manager struct {
+	id              ID
+	proposalID      int
 	identityHandler identity_selector.Handler
 
 	dialogWaiterFactory  DialogWaiterFactory
@@ -80,7 +98,35 @@ type Manager struct {
 	serviceFactory ServiceFactory
 	service        Service
 
-	discovery *registry.Discovery
+	discovery        *registry.Discovery
+	discoveryStarted bool
+	sessionStorage   session.Storage
+	eventPublisher   Publisher
+
+	stateMutex  sync.Mutex
+	serviceType string
+	status      Status
+	providerID  identity.Identity
+	proposal    market.ServiceProposal
+	limiter     *session.Limiter
+	killed      bool
+	// proposal updated at runtime, its terms are kept when service is restarted
+	updatedProposal *market.ServiceProposal
+}
+
+// ID identifies service instance, multiple instances of the same service type can be run
+type ID string
+
+// Info describes state of the service run by manager
+type Info struct {
+	ID         ID
+	Type       string
+	ProviderID identity.Identity
+	Status     Status
+	Proposal   market.ServiceProposal
+	Discovery  registry.Status
+	Sessions   int
+	Restarts   int
 }
 
 // Start starts service - does not block
@@ -91,47 +137,146 @@ func (manager *Manager) Start(options Options) (err error) {
 		return err
 	}
 
+	manager.publishStatus(options.Type, providerID, Starting, nil)
+	defer func() {
+		manager.publishStatus(options.Type, providerID, NotRunning, err)
+	}()
+
 	service, proposal, err := manager.serviceFactory(options)
 	if err != nil {
 		return err
 	}
+	manager.stateMutex.Lock()
+	if manager.updatedProposal != nil {
+		proposal = *manager.updatedProposal
+	}
+	manager.stateMutex.Unlock()
 
-	manager.service = service
+	// whatever has been started is stopped once service exits, so that it can be started again
+	discoveryStarted := false
+	defer func() {
+		if errStop := manager.shutdown(); errStop != nil {
+			log.Warn(logPrefix, "Failed to stop service ", manager.id, ": ", errStop)
+		}
+		if discoveryStarted {
+			manager.discovery.Wait()
+		}
+	}()
 
-	manager.dialogWaiter, err = manager.dialogWaiterFactory(providerID, proposal.ServiceType)
+	dialogWaiter, err := manager.dialogWaiterFactory(providerID, proposal.ServiceType, manager.proposalID)
 	if err != nil {
 		return err
 	}
-	providerContact, err := manager.dialogWaiter.Start()
+	providerContact, err := dialogWaiter.Start()
 	if err != nil {
 		return err
 	}
-	proposal.SetProviderContact(providerID, providerContact)
+	proposal.SetProviderContact(manager.proposalID, providerID, providerContact)
+
+	// service killed while it's being started is left as soon as the started part is kept for shutdown
+	limiter := session.NewLimiter(manager.proposalID, options.Limits, manager.sessionStorage)
+	manager.stateMutex.Lock()
+	manager.dialogWaiter = dialogWaiter
+	manager.proposal = proposal
+	manager.limiter = limiter
+	killed := manager.killed
+	manager.stateMutex.Unlock()
+	if killed {
+		return nil
+	}
 
-	dialogHandler := manager.dialogHandlerFactory(proposal, service)
-	if err = manager.dialogWaiter.ServeDialogs(dialogHandler); err != nil {
+	dialogHandler := manager.dialogHandlerFactory(manager.currentProposal, service, limiter)
+	if err = dialogWaiter.ServeDialogs(dialogHandler); err != nil {
 		return err
 	}
 
-	manager.discovery.Start(providerID, proposal)
+	manager.stateMutex.Lock()
+	if manager.killed {
+		manager.stateMutex.Unlock()
+		return nil
+	}
+	manager.discovery.Start(providerID, proposal, limiter.Load)
+	discoveryStarted = true
+	manager.service = service
+	manager.discoveryStarted = true
+	manager.stateMutex.Unlock()
+	manager.publishStatus(options.Type, providerID, Running, nil)
+
+	return service.Serve(providerID)
+}
+
+// UpdateProposal replaces price and location of the running service proposal and announces it in discovery.
+// New sessions are created on terms of the updated proposal, existing ones keep the terms they were created on.
+func (manager *Manager) UpdateProposal(proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	manager.stateMutex.Lock()
+	if manager.status != Running {
+		manager.stateMutex.Unlock()
+		return market.ServiceProposal{}, ErrServiceNotRunning
+	}
+	current := manager.proposal
+	if proposal.ServiceType != current.ServiceType {
+		manager.stateMutex.Unlock()
+		return market.ServiceProposal{}, ErrProposalServiceTypeMismatch
+	}
+
+	// only price and location are edited, the way consumers identify and reach the service stays the same
+	proposal, err := current.WithEditableTerms(proposal)
+	if err != nil {
+		manager.stateMutex.Unlock()
+		return market.ServiceProposal{}, err
+	}
+	proposal.Load = nil
+	proposal.Version = current.Version + 1
+
+	manager.proposal = proposal
+	manager.updatedProposal = &proposal
+	manager.stateMutex.Unlock()
+
+	if err := manager.discovery.UpdateProposal(proposal); err != nil {
+		log.Warn(logPrefix, "Failed to register updated proposal of service ", manager.id, ", it's announced with next ping: ", err)
+	}
+	return proposal, nil
+}
+
+// currentProposal returns proposal, terms of which new sessions are created on
+func (manager *Manager) currentProposal() market.ServiceProposal {
+	manager.stateMutex.Lock()
+	defer manager.stateMutex.Unlock()
 
-	err = manager.service.Serve(providerID)
-	manager.discovery.Wait()
-	return err
+	return manager.proposal
 }
 
-// Kill stops service
+// Kill stops service, service which is being started is stopped once it gets started
 func (manager *Manager) Kill() error {
+	manager.stateMutex.Lock()
+	manager.killed = true
+	manager.stateMutex.Unlock()
+
+	return manager.shutdown()
+}
+
+// shutdown unregisters proposal and stops everything what was started to serve it.
+// It's done once, regardless of service being killed or exiting by itself.
+func (manager *Manager) shutdown() error {
+	manager.stateMutex.Lock()
+	discoveryStarted := manager.discoveryStarted
+	dialogWaiter := manager.dialogWaiter
+	service := manager.service
+	manager.discoveryStarted = false
+	manager.dialogWaiter = nil
+	manager.service = nil
+	manager.stateMutex.Unlock()
+
 	var errDialogWaiter, errService error
 
-	if manager.discovery != nil {
+	if discoveryStarted {
 		manager.discovery.Stop()
 	}
-	if manager.dialogWaiter != nil {
-		errDialogWaiter = manager.dialogWaiter.Stop()
+	if dialogWaiter != nil {
+		errDialogWaiter = dialogWaiter.Stop()
 	}
-	if manager.service != nil {
-		errService = manager.service.Stop()
+	if service != nil {
+		errService = service.Stop()
 	}
 
 	if errDialogWaiter != nil {
@@ -142,3 +287,41 @@ func (manager *Manager) Kill() error {
 	}
 	return nil
 }
+
+// Info returns current state of the service
+func (manager *Manager) Info() Info {
+	manager.stateMutex.Lock()
+	defer manager.stateMutex.Unlock()
+
+	info := Info{
+		ID:         manager.id,
+		Type:       manager.serviceType,
+		ProviderID: manager.providerID,
+		Status:     manager.status,
+		Proposal:   manager.proposal,
+		Discovery:  registry.StatusUndefined,
+	}
+	if manager.discovery != nil && manager.status == Running {
+		info.Discovery = manager.discovery.Status()
+	}
+	if manager.limiter != nil {
+		info.Sessions = manager.limiter.Load().Sessions
+	}
+	return info
+}
+
+func (manager *Manager) publishStatus(serviceType string, providerID identity.Identity, status Status, err error) {
+	manager.stateMutex.Lock()
+	manager.serviceType = serviceType
+	manager.status = status
+	manager.providerID = providerID
+	manager.stateMutex.Unlock()
+
+	manager.eventPublisher.Publish(StatusEventTopic, StatusEvent{
+		ServiceID:   manager.id,
+		ServiceType: serviceType,
+		ProviderID:  providerID,
+		Status:      status,
+		Error:       err,
+	})
+}
@@ -17,12 +17,26 @@
 
 package service
 
+import (
+	"net"
+
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/shaper"
+)
+
 // Options describes options which are required to start a service
 type Options struct {
 	Identity   string
 	Passphrase string
 	Type       string
-	Options    TransportOptions
+	Limits     session.Limits
+	Bandwidth  shaper.Limits
+	Egress     firewall.EgressPolicy
+	Restart    RestartPolicy
+	// DNS are servers advertised to consumers, public system resolvers of provider are advertised if none are given
+	DNS     []net.IP
+	Options TransportOptions
 }
 
 // TransportOptions represents any type of options for plugable service
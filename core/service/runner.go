@@ -18,55 +18,189 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/gofrs/uuid"
+	"github.com/mysteriumnetwork/node/market"
 )
 
+// ErrServiceNotRunning indicates that service instance of given ID is not running
+var ErrServiceNotRunning = errors.New("service is not running")
+
 // RunnableService represents a runnable service
 type RunnableService interface {
 	Start(options Options) (err error)
 	Kill() error
+	Info() Info
+	UpdateProposal(proposal market.ServiceProposal) (market.ServiceProposal, error)
 }
 
-// RunnableServiceFactory creates a new runnable service instance
-type RunnableServiceFactory func() RunnableService
+// RunnableServiceFactory creates a new runnable service instance of given ID, which is offered with given proposal ID
+type RunnableServiceFactory func(id ID, proposalID int) RunnableService
 
-// Runner is responsible for starting the provided service managers
+// Runner is responsible for starting the provided service managers.
+// Multiple instances of the same service type can be run, each of them with its own options.
 type Runner struct {
-	serviceFactory  RunnableServiceFactory
-	serviceManagers map[string]RunnableService
+	serviceFactory RunnableServiceFactory
+	serviceTypes   map[string]bool
+	instances      map[ID]*instance
+	lastProposalID int
+	mutex          sync.Mutex
+}
+
+// instance is a running service manager
+type instance struct {
+	proposalID int
+	manager    RunnableService
 }
 
 // NewRunner returns a new instance of runner with the runnable services
 func NewRunner(factory RunnableServiceFactory) *Runner {
 	return &Runner{
-		serviceFactory:  factory,
-		serviceManagers: make(map[string]RunnableService),
+		serviceFactory: factory,
+		serviceTypes:   make(map[string]bool),
+		instances:      make(map[ID]*instance),
 	}
 }
 
-// Register registers a service as a candidate for running
+// Register registers a service type as a candidate for running
 func (sr *Runner) Register(serviceType string) {
-	sr.serviceManagers[serviceType] = sr.serviceFactory()
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.serviceTypes[serviceType] = true
 }
 
-// StartServiceByType starts a manager of the given service type if it has one. The method blocks.
+// StartServiceByType starts a new instance of the given service type if it's registered. The method blocks.
 // It passes the options to the start method of the manager.
 // If an error occurs in the underlying service, the error is then returned.
 func (sr *Runner) StartServiceByType(serviceType string, options Options) error {
-	if _, ok := sr.serviceManagers[serviceType]; !ok {
-		return fmt.Errorf("unknown service type %q", serviceType)
+	id, serviceManager, err := sr.acquire(serviceType)
+	if err != nil {
+		return err
+	}
+	defer sr.release(id)
+
+	return serviceManager.Start(options)
+}
+
+// Launch starts a new instance of the given service type in background and returns its ID. Errors of starting
+// the service which can be told immediately are returned, the later ones are only published in service status events.
+func (sr *Runner) Launch(serviceType string, options Options) (ID, error) {
+	id, serviceManager, err := sr.acquire(serviceType)
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		defer sr.release(id)
+		if err := serviceManager.Start(options); err != nil {
+			log.Error("[service-runner] ", "Service ", serviceType, " ", id, " stopped: ", err)
+		}
+	}()
+	return id, nil
+}
+
+// KillService kills running service instance of the given ID
+func (sr *Runner) KillService(id ID) error {
+	sr.mutex.Lock()
+	running, found := sr.instances[id]
+	sr.mutex.Unlock()
+
+	if !found {
+		return ErrServiceNotRunning
 	}
+	return running.manager.Kill()
+}
+
+// UpdateProposal replaces proposal of the running service instance of the given ID, new sessions are created on its terms
+func (sr *Runner) UpdateProposal(id ID, proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	sr.mutex.Lock()
+	running, found := sr.instances[id]
+	sr.mutex.Unlock()
 
-	return sr.serviceManagers[serviceType].Start(options)
+	if !found {
+		return market.ServiceProposal{}, ErrServiceNotRunning
+	}
+	return running.manager.UpdateProposal(proposal)
 }
 
-// KillAll kills all service managers
+// ServiceInfo returns state of the running service instance of the given ID
+func (sr *Runner) ServiceInfo(id ID) (Info, error) {
+	sr.mutex.Lock()
+	running, found := sr.instances[id]
+	sr.mutex.Unlock()
+
+	if !found {
+		return Info{}, ErrServiceNotRunning
+	}
+	return running.manager.Info(), nil
+}
+
+// Services returns states of all running service instances in order they were started
+func (sr *Runner) Services() []Info {
+	running := sr.running()
+	services := make([]Info, 0, len(running))
+	for _, serviceInstance := range running {
+		services = append(services, serviceInstance.manager.Info())
+	}
+	return services
+}
+
+// KillAll kills all running service instances
 func (sr *Runner) KillAll() []error {
 	errors := make([]error, 0)
-	for _, serviceManager := range sr.serviceManagers {
-		if err := serviceManager.Kill(); err != nil {
+	for _, serviceInstance := range sr.running() {
+		if err := serviceInstance.manager.Kill(); err != nil {
 			errors = append(errors, err)
 		}
 	}
 	return errors
 }
+
+// acquire creates a new instance of the given service type, it's given unique ID and proposal ID
+func (sr *Runner) acquire(serviceType string) (ID, RunnableService, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if !sr.serviceTypes[serviceType] {
+		return "", nil, fmt.Errorf("unknown service type %q", serviceType)
+	}
+
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return "", nil, err
+	}
+	id := ID(uid.String())
+
+	sr.lastProposalID++
+	serviceManager := sr.serviceFactory(id, sr.lastProposalID)
+	sr.instances[id] = &instance{proposalID: sr.lastProposalID, manager: serviceManager}
+	return id, serviceManager, nil
+}
+
+func (sr *Runner) release(id ID) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	delete(sr.instances, id)
+}
+
+// running returns running service instances ordered by their proposal IDs
+func (sr *Runner) running() []*instance {
+	sr.mutex.Lock()
+	running := make([]*instance, 0, len(sr.instances))
+	for _, serviceInstance := range sr.instances {
+		running = append(running, serviceInstance)
+	}
+	sr.mutex.Unlock()
+
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].proposalID < running[j].proposalID
+	})
+	return running
+}
@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/market"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,11 +44,52 @@ func (mr *MockRunnable) Kill() error {
 	return mr.killErr
 }
 
+func (mr *MockRunnable) Info() Info {
+	return Info{}
+}
+
+func (mr *MockRunnable) UpdateProposal(proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	return proposal, nil
+}
+
+// blockingRunnable serves until it's killed
+type blockingRunnable struct {
+	id         ID
+	proposalID int
+	started    chan Options
+	stop       chan struct{}
+	killErr    error
+}
+
+func newBlockingRunnable(id ID, proposalID int) *blockingRunnable {
+	return &blockingRunnable{id: id, proposalID: proposalID, started: make(chan Options, 1), stop: make(chan struct{})}
+}
+
+func (br *blockingRunnable) Start(options Options) error {
+	br.started <- options
+	<-br.stop
+	return nil
+}
+
+func (br *blockingRunnable) Kill() error {
+	close(br.stop)
+	return br.killErr
+}
+
+func (br *blockingRunnable) Info() Info {
+	return Info{ID: br.id, Type: "test", Status: Running, Proposal: market.ServiceProposal{ID: br.proposalID}}
+}
+
+func (br *blockingRunnable) UpdateProposal(proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	proposal.ID = br.proposalID
+	return proposal, nil
+}
+
 type mockFactory struct {
 	MockRunnable *MockRunnable
 }
 
-func (mf *mockFactory) serviceFactory() RunnableService {
+func (mf *mockFactory) serviceFactory(ID, int) RunnableService {
 	return mf.MockRunnable
 }
 
@@ -106,20 +148,81 @@ func Test_RunnerBubblesErrors(t *testing.T) {
 
 func Test_RunnerKillReturnsErrors(t *testing.T) {
 	fakeErr := errors.New("error")
-	m := &mockFactory{MockRunnable: &MockRunnable{
-		killErr: fakeErr,
-	}}
+	runnable := newBlockingRunnable("", 0)
+	runnable.killErr = fakeErr
 	sType := "test"
 
-	runner := NewRunner(m.serviceFactory)
+	runner := NewRunner(func(ID, int) RunnableService { return runnable })
 	runner.Register(sType)
 
+	errs := make(chan []error)
 	go func() {
-		wait()
-		errs := runner.KillAll()
-		assert.Len(t, errs, 1)
-		assert.Equal(t, fakeErr, errs[0])
+		<-runnable.started
+		errs <- runner.KillAll()
 	}()
 	err := runner.StartServiceByType(sType, Options{})
 	assert.Nil(t, err)
+	assert.Equal(t, []error{fakeErr}, <-errs)
+}
+
+func Test_RunnerLaunchesMultipleInstancesOfService(t *testing.T) {
+	runnables := make(chan *blockingRunnable, 2)
+	runner := NewRunner(func(id ID, proposalID int) RunnableService {
+		runnable := newBlockingRunnable(id, proposalID)
+		runnables <- runnable
+		return runnable
+	})
+	runner.Register("test")
+
+	udpID, err := runner.Launch("test", Options{Type: "test", Options: "udp"})
+	assert.NoError(t, err)
+	udp := <-runnables
+	assert.Equal(t, Options{Type: "test", Options: "udp"}, <-udp.started)
+
+	tcpID, err := runner.Launch("test", Options{Type: "test", Options: "tcp"})
+	assert.NoError(t, err)
+	tcp := <-runnables
+	assert.Equal(t, Options{Type: "test", Options: "tcp"}, <-tcp.started)
+
+	assert.NotEqual(t, udpID, tcpID)
+	assert.Equal(t, udpID, udp.id)
+	assert.Equal(t, tcpID, tcp.id)
+	assert.Equal(t, 1, udp.proposalID)
+	assert.Equal(t, 2, tcp.proposalID)
+
+	_, err = runner.Launch("unknown", Options{})
+	assert.EqualError(t, err, `unknown service type "unknown"`)
+
+	info, err := runner.ServiceInfo(tcpID)
+	assert.NoError(t, err)
+	assert.Equal(t, tcpID, info.ID)
+
+	proposal, err := runner.UpdateProposal(tcpID, market.ServiceProposal{ServiceType: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, proposal.ID)
+	assert.Equal(t, []Info{udp.Info(), tcp.Info()}, runner.Services())
+
+	assert.NoError(t, runner.KillService(udpID))
+	for i := 0; i < 100 && len(runner.Services()) > 1; i++ {
+		wait()
+	}
+	assert.Equal(t, []Info{tcp.Info()}, runner.Services())
+
+	assert.Len(t, runner.KillAll(), 0)
+	for i := 0; i < 100 && len(runner.Services()) > 0; i++ {
+		wait()
+	}
+	assert.Empty(t, runner.Services())
+}
+
+func Test_RunnerReportsServiceWhichIsNotRunning(t *testing.T) {
+	runner := NewRunner(func(id ID, proposalID int) RunnableService { return newBlockingRunnable(id, proposalID) })
+	runner.Register("test")
+
+	_, err := runner.ServiceInfo("unknown")
+	assert.Equal(t, ErrServiceNotRunning, err)
+	_, err = runner.UpdateProposal("unknown", market.ServiceProposal{})
+	assert.Equal(t, ErrServiceNotRunning, err)
+	assert.Equal(t, ErrServiceNotRunning, runner.KillService("unknown"))
+	assert.Empty(t, runner.Services())
 }
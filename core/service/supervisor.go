@@ -0,0 +1,215 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+const supervisorLogPrefix = "[service-supervisor] "
+
+// RestartMode tells whether service is restarted once it stops by itself
+type RestartMode string
+
+const (
+	// RestartNever leaves stopped service as it is
+	RestartNever = RestartMode("never")
+	// RestartOnFailure restarts service which stopped with an error
+	RestartOnFailure = RestartMode("on-failure")
+	// RestartAlways restarts service whenever it stops, unless it was killed
+	RestartAlways = RestartMode("always")
+)
+
+const (
+	// minRestartBackoff keeps service, which stops right after start, from being restarted in a busy loop
+	minRestartBackoff = time.Second
+	// maxRestartBackoff limits growth of delay between consecutive restarts
+	maxRestartBackoff = 5 * time.Minute
+)
+
+// RestartPolicy describes how service is restarted once it stops by itself
+type RestartPolicy struct {
+	Mode RestartMode
+	// MaxRestarts limits count of restarts within Window, 0 means unlimited
+	MaxRestarts int
+	// Window is period restarts are counted in, 0 means that all restarts are counted
+	Window time.Duration
+	// Backoff is delay before the restart, it's doubled for each restart counted in Window.
+	// Service is not restarted sooner than in a second, even if backoff is shorter.
+	Backoff time.Duration
+}
+
+// ValidRestartMode tells whether restart mode is known
+func ValidRestartMode(mode RestartMode) bool {
+	return mode == RestartNever || mode == RestartOnFailure || mode == RestartAlways
+}
+
+// shouldRestart tells whether service which stopped with given error is restarted
+func (policy RestartPolicy) shouldRestart(err error) bool {
+	switch policy.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// delay returns delay before the restart, which follows given count of recent restarts
+func (policy RestartPolicy) delay(recentRestarts int) time.Duration {
+	limit := maxRestartBackoff
+	if policy.Backoff > limit {
+		limit = policy.Backoff
+	}
+
+	delay := policy.Backoff
+	for i := 0; i < recentRestarts && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// Supervisor runs service and restarts it according to restart policy, when service stops by itself.
+// Proposal of the service is unregistered while it's down and registered again once it's restarted.
+type Supervisor struct {
+	service        RunnableService
+	eventPublisher Publisher
+	minBackoff     time.Duration
+
+	mutex    sync.Mutex
+	killed   chan struct{}
+	restarts []time.Time
+	total    int
+}
+
+// NewSupervisor creates supervisor of given service, which publishes restarts to event bus
+func NewSupervisor(service RunnableService, eventPublisher Publisher) *Supervisor {
+	return &Supervisor{
+		service:        service,
+		eventPublisher: eventPublisher,
+		minBackoff:     minRestartBackoff,
+		killed:         make(chan struct{}),
+	}
+}
+
+// Start starts service and restarts it according to restart policy of given options. The method blocks
+// until service is killed or it isn't restarted anymore, the last error of service is returned then.
+func (supervisor *Supervisor) Start(options Options) error {
+	for {
+		err := supervisor.service.Start(options)
+		if supervisor.isKilled() || !options.Restart.shouldRestart(err) {
+			return err
+		}
+
+		recent, allowed := supervisor.allowRestart(options.Restart, time.Now())
+		info := supervisor.service.Info()
+		if !allowed {
+			log.Error(supervisorLogPrefix, "Service ", info.ID, " stopped ", recent, " times recently, it won't be restarted anymore: ", err)
+			return err
+		}
+
+		delay := options.Restart.delay(recent - 1)
+		if delay < supervisor.minBackoff {
+			delay = supervisor.minBackoff
+		}
+		log.Warn(supervisorLogPrefix, "Service ", info.ID, " stopped, restarting it in ", delay, ": ", err)
+		supervisor.eventPublisher.Publish(RestartEventTopic, RestartEvent{
+			ServiceID:   info.ID,
+			ServiceType: options.Type,
+			ProviderID:  info.ProviderID,
+			Attempt:     recent,
+			Delay:       delay,
+			Error:       err,
+		})
+
+		select {
+		case <-supervisor.killed:
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Kill stops service and prevents it from being restarted
+func (supervisor *Supervisor) Kill() error {
+	supervisor.mutex.Lock()
+	select {
+	case <-supervisor.killed:
+	default:
+		close(supervisor.killed)
+	}
+	supervisor.mutex.Unlock()
+
+	return supervisor.service.Kill()
+}
+
+// Info returns current state of the service along with count of its restarts
+func (supervisor *Supervisor) Info() Info {
+	info := supervisor.service.Info()
+
+	supervisor.mutex.Lock()
+	info.Restarts = supervisor.total
+	supervisor.mutex.Unlock()
+
+	return info
+}
+
+// UpdateProposal replaces proposal of the service, updated terms are kept when service is restarted
+func (supervisor *Supervisor) UpdateProposal(proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	return supervisor.service.UpdateProposal(proposal)
+}
+
+func (supervisor *Supervisor) isKilled() bool {
+	select {
+	case <-supervisor.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+// allowRestart records restart, unless policy allows no more restarts in its window.
+// Count of recent restarts including this one is returned.
+func (supervisor *Supervisor) allowRestart(policy RestartPolicy, now time.Time) (int, bool) {
+	supervisor.mutex.Lock()
+	defer supervisor.mutex.Unlock()
+
+	recent := supervisor.restarts[:0]
+	for _, restarted := range supervisor.restarts {
+		if policy.Window == 0 || now.Sub(restarted) < policy.Window {
+			recent = append(recent, restarted)
+		}
+	}
+	supervisor.restarts = recent
+
+	if policy.MaxRestarts > 0 && len(supervisor.restarts) >= policy.MaxRestarts {
+		return len(supervisor.restarts), false
+	}
+
+	supervisor.restarts = append(supervisor.restarts, now)
+	supervisor.total++
+	return len(supervisor.restarts), true
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+// crashingRunnable stops by itself with given results of consecutive starts, once they run out it serves until killed
+type crashingRunnable struct {
+	results []error
+	starts  int
+	stop    chan struct{}
+	mutex   sync.Mutex
+}
+
+func newCrashingRunnable(results ...error) *crashingRunnable {
+	return &crashingRunnable{results: results, stop: make(chan struct{})}
+}
+
+func (cr *crashingRunnable) Start(options Options) error {
+	cr.mutex.Lock()
+	cr.starts++
+	if len(cr.results) > 0 {
+		result := cr.results[0]
+		cr.results = cr.results[1:]
+		cr.mutex.Unlock()
+		return result
+	}
+	cr.mutex.Unlock()
+
+	<-cr.stop
+	return nil
+}
+
+func (cr *crashingRunnable) Kill() error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	select {
+	case <-cr.stop:
+	default:
+		close(cr.stop)
+	}
+	return nil
+}
+
+func (cr *crashingRunnable) Info() Info {
+	return Info{ID: "service-1", Type: "test"}
+}
+
+func (cr *crashingRunnable) UpdateProposal(proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	return proposal, nil
+}
+
+func (cr *crashingRunnable) startCount() int {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	return cr.starts
+}
+
+type fakePublisher struct {
+	events []interface{}
+	mutex  sync.Mutex
+}
+
+func (fp *fakePublisher) Publish(topic string, args ...interface{}) {
+	fp.mutex.Lock()
+	defer fp.mutex.Unlock()
+
+	if topic == RestartEventTopic {
+		fp.events = append(fp.events, args...)
+	}
+}
+
+func (fp *fakePublisher) published() []interface{} {
+	fp.mutex.Lock()
+	defer fp.mutex.Unlock()
+
+	return fp.events
+}
+
+var errCrashed = errors.New("openvpn process exited")
+
+func TestSupervisorRestartsFailedService(t *testing.T) {
+	runnable := newCrashingRunnable(errCrashed, errCrashed)
+	publisher := &fakePublisher{}
+	supervisor := NewSupervisor(runnable, publisher)
+	supervisor.minBackoff = time.Millisecond
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- supervisor.Start(Options{
+			Type:    "test",
+			Restart: RestartPolicy{Mode: RestartOnFailure, Backoff: time.Millisecond},
+		})
+	}()
+	for i := 0; i < 100 && runnable.startCount() < 3; i++ {
+		wait()
+	}
+
+	assert.Equal(t, 3, runnable.startCount())
+	assert.Equal(t, 2, supervisor.Info().Restarts)
+	assert.Equal(
+		t,
+		[]interface{}{
+			RestartEvent{ServiceID: "service-1", ServiceType: "test", Attempt: 1, Delay: time.Millisecond, Error: errCrashed},
+			RestartEvent{ServiceID: "service-1", ServiceType: "test", Attempt: 2, Delay: 2 * time.Millisecond, Error: errCrashed},
+		},
+		publisher.published(),
+	)
+
+	assert.NoError(t, supervisor.Kill())
+	assert.NoError(t, <-stopped)
+	assert.Equal(t, 3, runnable.startCount())
+}
+
+func TestSupervisorRestartsServiceAccordingToMode(t *testing.T) {
+	tests := []struct {
+		mode          RestartMode
+		result        error
+		expectedStart int
+	}{
+		{RestartNever, errCrashed, 1},
+		{RestartMode(""), errCrashed, 1},
+		{RestartOnFailure, nil, 1},
+		{RestartOnFailure, errCrashed, 2},
+		{RestartAlways, nil, 2},
+	}
+
+	for _, test := range tests {
+		runnable := newCrashingRunnable(test.result)
+		supervisor := NewSupervisor(runnable, &fakePublisher{})
+		supervisor.minBackoff = time.Millisecond
+
+		stopped := make(chan error)
+		go func() {
+			stopped <- supervisor.Start(Options{Restart: RestartPolicy{Mode: test.mode}})
+		}()
+		if test.expectedStart == 1 {
+			assert.Equal(t, test.result, <-stopped, string(test.mode))
+		} else {
+			for i := 0; i < 100 && runnable.startCount() < test.expectedStart; i++ {
+				wait()
+			}
+			assert.NoError(t, supervisor.Kill())
+			assert.NoError(t, <-stopped, string(test.mode))
+		}
+		assert.Equal(t, test.expectedStart, runnable.startCount(), string(test.mode))
+	}
+}
+
+func TestSupervisorGivesUpAfterMaxRestartsInWindow(t *testing.T) {
+	runnable := newCrashingRunnable(errCrashed, errCrashed, errCrashed, errCrashed)
+	publisher := &fakePublisher{}
+	supervisor := NewSupervisor(runnable, publisher)
+	supervisor.minBackoff = time.Millisecond
+
+	err := supervisor.Start(Options{
+		Restart: RestartPolicy{Mode: RestartAlways, MaxRestarts: 2, Window: time.Minute},
+	})
+
+	assert.Equal(t, errCrashed, err)
+	assert.Equal(t, 3, runnable.startCount())
+	assert.Len(t, publisher.published(), 2)
+}
+
+func TestSupervisorDoesNotRestartSoonerThanMinBackoff(t *testing.T) {
+	runnable := newCrashingRunnable(errCrashed, errCrashed)
+	publisher := &fakePublisher{}
+	supervisor := NewSupervisor(runnable, publisher)
+	supervisor.minBackoff = 5 * time.Millisecond
+
+	err := supervisor.Start(Options{Restart: RestartPolicy{Mode: RestartAlways, MaxRestarts: 1}})
+
+	assert.Equal(t, errCrashed, err)
+	assert.Equal(t, 2, runnable.startCount())
+	events := publisher.published()
+	assert.Len(t, events, 1)
+	assert.Equal(t, 5*time.Millisecond, events[0].(RestartEvent).Delay)
+	assert.Equal(t, minRestartBackoff, NewSupervisor(runnable, publisher).minBackoff)
+}
+
+func TestSupervisorDoesNotRestartKilledService(t *testing.T) {
+	runnable := newCrashingRunnable(errCrashed)
+	supervisor := NewSupervisor(runnable, &fakePublisher{})
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- supervisor.Start(Options{Restart: RestartPolicy{Mode: RestartAlways, Backoff: time.Minute}})
+	}()
+	for i := 0; i < 100 && supervisor.Info().Restarts < 1; i++ {
+		wait()
+	}
+
+	assert.NoError(t, supervisor.Kill())
+	assert.Equal(t, errCrashed, <-stopped)
+	assert.Equal(t, 1, runnable.startCount())
+}
+
+func TestRestartPolicyDelayGrowsUpToLimit(t *testing.T) {
+	policy := RestartPolicy{Backoff: time.Second}
+	assert.Equal(t, time.Second, policy.delay(0))
+	assert.Equal(t, 2*time.Second, policy.delay(1))
+	assert.Equal(t, 8*time.Second, policy.delay(3))
+	assert.Equal(t, maxRestartBackoff, policy.delay(20))
+
+	policy = RestartPolicy{Backoff: 10 * time.Minute}
+	assert.Equal(t, 10*time.Minute, policy.delay(3))
+
+	assert.Equal(t, time.Duration(0), RestartPolicy{}.delay(3))
+}
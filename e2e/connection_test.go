@@ -125,9 +125,7 @@ func consumerConnectFlow(t *testing.T, tequilapi *tequilapi_client.Client, consu
 	})
 	assert.NoError(t, err)
 
-	connectionStatus, err = tequilapi.Connect(consumerID, proposal.ProviderID, serviceType, endpoints.ConnectOptions{
-		DisableKillSwitch: true,
-	})
+	connectionStatus, err = tequilapi.Connect(consumerID, proposal.ProviderID, serviceType, endpoints.ConnectOptions{})
 
 	assert.NoError(t, err)
 
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"sync"
+
+	log "github.com/cihub/seelog"
+)
+
+const (
+	dnsLeakLogPrefix = "[dns-leak-blocker] "
+	dnsLeakChain     = "MYST_DNS_LEAK"
+)
+
+type iptablesDNSLeakBlocker struct {
+	iptables func(args ...string) error
+
+	mutex   sync.Mutex
+	enabled bool
+}
+
+func newIptablesDNSLeakBlocker() *iptablesDNSLeakBlocker {
+	return &iptablesDNSLeakBlocker{iptables: sudoIptables}
+}
+
+// Enable rejects outgoing DNS queries except the ones sent through loopback or tunnel interface
+func (lb *iptablesDNSLeakBlocker) Enable(tunnel Tunnel) error {
+	if tunnel.Interface == "" {
+		return ErrTunnelNotDefined
+	}
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	rules := [][]string{
+		{"--out-interface", "lo", "--jump", "RETURN"},
+		{"--out-interface", tunnel.Interface, "--jump", "RETURN"},
+		{"--protocol", "udp", "--dport", "53", "--jump", "REJECT"},
+		{"--protocol", "tcp", "--dport", "53", "--jump", "REJECT"},
+	}
+	if err := replaceOutputChain(lb.iptables, dnsLeakChain, rules); err != nil {
+		log.Error(dnsLeakLogPrefix, "Failed to block DNS leaks: ", err)
+		return err
+	}
+
+	lb.enabled = true
+	log.Info(dnsLeakLogPrefix, "DNS queries restricted to interface '", tunnel.Interface, "'")
+	return nil
+}
+
+// Disable removes DNS leak blocking rules
+func (lb *iptablesDNSLeakBlocker) Disable() error {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if !lb.enabled {
+		return nil
+	}
+
+	removeOutputChain(lb.iptables, dnsLeakChain)
+	lb.enabled = false
+	log.Info(dnsLeakLogPrefix, "DNS query restrictions removed")
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIptablesDNSLeakBlocker_EnableRejectsQueriesOutsideTunnel(t *testing.T) {
+	fake := &fakeIptables{}
+	lb := &iptablesDNSLeakBlocker{iptables: fake.exec}
+
+	assert.NoError(t, lb.Enable(tunnel))
+	assert.Equal(
+		t,
+		[]string{
+			"--delete OUTPUT --jump MYST_DNS_LEAK",
+			"--flush MYST_DNS_LEAK",
+			"--delete-chain MYST_DNS_LEAK",
+			"--new-chain MYST_DNS_LEAK",
+			"--append MYST_DNS_LEAK --out-interface lo --jump RETURN",
+			"--append MYST_DNS_LEAK --out-interface tun+ --jump RETURN",
+			"--append MYST_DNS_LEAK --protocol udp --dport 53 --jump REJECT",
+			"--append MYST_DNS_LEAK --protocol tcp --dport 53 --jump REJECT",
+			"--insert OUTPUT --jump MYST_DNS_LEAK",
+		},
+		fake.calls,
+	)
+}
+
+func TestIptablesDNSLeakBlocker_DisableRemovesRulesOnlyWhenEnabled(t *testing.T) {
+	fake := &fakeIptables{}
+	lb := &iptablesDNSLeakBlocker{iptables: fake.exec}
+
+	assert.NoError(t, lb.Disable())
+	assert.Empty(t, fake.calls)
+
+	assert.NoError(t, lb.Enable(tunnel))
+	fake.calls = nil
+	assert.NoError(t, lb.Disable())
+	assert.Equal(
+		t,
+		[]string{
+			"--delete OUTPUT --jump MYST_DNS_LEAK",
+			"--flush MYST_DNS_LEAK",
+			"--delete-chain MYST_DNS_LEAK",
+		},
+		fake.calls,
+	)
+}
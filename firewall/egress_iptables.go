@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"strconv"
+
+	log "github.com/cihub/seelog"
+)
+
+const (
+	egressLogPrefix   = "[egress-policy] "
+	egressChainPrefix = "MYST_EGRESS_"
+)
+
+// ErrSubnetNotDefined indicates that egress policy can not be enforced without knowing service subnet
+var ErrSubnetNotDefined = errors.New("service subnet is required for egress policy")
+
+type iptablesEgressBlocker struct {
+	policy    EgressPolicy
+	iptables  func(args ...string) error
+	ip6tables func(args ...string) error
+}
+
+func newIptablesEgressBlocker(policy EgressPolicy) *iptablesEgressBlocker {
+	return &iptablesEgressBlocker{
+		policy:    policy,
+		iptables:  sudoIptables,
+		ip6tables: sudoIp6tables,
+	}
+}
+
+// Enable rejects traffic forwarded from given subnet to blocked networks and ports.
+// Rules of the subnet are kept in a chain of their own, so subnets of several services are restricted independently
+func (eb *iptablesEgressBlocker) Enable(subnet net.IPNet) error {
+	if subnet.IP == nil {
+		return ErrSubnetNotDefined
+	}
+
+	ipv6 := subnet.IP.To4() == nil
+	var rules [][]string
+	for _, network := range eb.policy.BlockedNetworks {
+		if (network.IP.To4() == nil) == ipv6 {
+			rules = append(rules, []string{"--destination", network.String(), "--jump", "REJECT"})
+		}
+	}
+	for _, port := range eb.policy.BlockedPorts {
+		for _, protocol := range []string{"tcp", "udp"} {
+			rules = append(rules, []string{"--protocol", protocol, "--dport", strconv.Itoa(port), "--jump", "REJECT"})
+		}
+	}
+
+	if err := replaceChain(eb.tool(subnet), egressHook(subnet), egressChain(subnet), rules); err != nil {
+		log.Error(egressLogPrefix, "Failed to restrict traffic of ", subnet.String(), ": ", err)
+		return err
+	}
+
+	log.Info(egressLogPrefix, "Traffic of ", subnet.String(), " restricted, blocked networks: ", eb.policy.BlockedNetworks, ", ports: ", eb.policy.BlockedPorts)
+	return nil
+}
+
+// Disable removes egress rules of given subnet
+func (eb *iptablesEgressBlocker) Disable(subnet net.IPNet) error {
+	if subnet.IP == nil {
+		return ErrSubnetNotDefined
+	}
+
+	removeChain(eb.tool(subnet), egressHook(subnet), egressChain(subnet))
+	log.Info(egressLogPrefix, "Traffic restrictions of ", subnet.String(), " removed")
+	return nil
+}
+
+func (eb *iptablesEgressBlocker) tool(subnet net.IPNet) func(args ...string) error {
+	if subnet.IP.To4() == nil {
+		return eb.ip6tables
+	}
+	return eb.iptables
+}
+
+func egressHook(subnet net.IPNet) []string {
+	return []string{"FORWARD", "--source", subnet.String()}
+}
+
+// egressChain names chain of the subnet, the name is stable across runs for stale rules to be cleaned up
+// and is short enough for iptables regardless of the subnet length
+func egressChain(subnet net.IPNet) string {
+	return fmt.Sprintf("%s%08X", egressChainPrefix, crc32.ChecksumIEEE([]byte(subnet.String())))
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseSubnet(cidr string) net.IPNet {
+	_, subnet, _ := net.ParseCIDR(cidr)
+	return *subnet
+}
+
+var egressPolicy = EgressPolicy{
+	BlockedPorts:    []int{25},
+	BlockedNetworks: []net.IPNet{parseSubnet("192.168.0.0/16"), parseSubnet("fe80::/10")},
+}
+
+func TestIptablesEgressBlocker_EnableRejectsBlockedDestinationsOfSubnet(t *testing.T) {
+	fake := &fakeIptables{}
+	fake6 := &fakeIptables{}
+	eb := &iptablesEgressBlocker{policy: egressPolicy, iptables: fake.exec, ip6tables: fake6.exec}
+	subnet := parseSubnet("10.8.0.0/24")
+	chain := egressChain(subnet)
+
+	assert.NoError(t, eb.Enable(subnet))
+	assert.Equal(
+		t,
+		[]string{
+			"--delete FORWARD --source 10.8.0.0/24 --jump " + chain,
+			"--flush " + chain,
+			"--delete-chain " + chain,
+			"--new-chain " + chain,
+			"--append " + chain + " --destination 192.168.0.0/16 --jump REJECT",
+			"--append " + chain + " --protocol tcp --dport 25 --jump REJECT",
+			"--append " + chain + " --protocol udp --dport 25 --jump REJECT",
+			"--insert FORWARD --source 10.8.0.0/24 --jump " + chain,
+		},
+		fake.calls,
+	)
+	assert.Empty(t, fake6.calls)
+}
+
+func TestIptablesEgressBlocker_EnableUsesIp6tablesForIPv6Subnet(t *testing.T) {
+	fake := &fakeIptables{}
+	fake6 := &fakeIptables{}
+	eb := &iptablesEgressBlocker{policy: egressPolicy, iptables: fake.exec, ip6tables: fake6.exec}
+	subnet := parseSubnet("fd6d:7973:7400:ffff::/64")
+	chain := egressChain(subnet)
+
+	assert.NoError(t, eb.Enable(subnet))
+	assert.Contains(t, fake6.calls, "--append "+chain+" --destination fe80::/10 --jump REJECT")
+	assert.NotContains(t, fake6.calls, "--append "+chain+" --destination 192.168.0.0/16 --jump REJECT")
+	assert.Empty(t, fake.calls)
+}
+
+func TestIptablesEgressBlocker_EnableCleansUpOnFailure(t *testing.T) {
+	fake := &fakeIptables{failOn: "--insert", failErr: errors.New("iptables failed")}
+	eb := &iptablesEgressBlocker{policy: egressPolicy, iptables: fake.exec}
+	subnet := parseSubnet("10.8.0.0/24")
+
+	assert.EqualError(t, eb.Enable(subnet), "iptables failed")
+	assert.Equal(t, "--delete-chain "+egressChain(subnet), fake.calls[len(fake.calls)-1])
+}
+
+func TestIptablesEgressBlocker_ChainsOfSubnetsDiffer(t *testing.T) {
+	assert.NotEqual(t, egressChain(parseSubnet("10.8.0.0/24")), egressChain(parseSubnet("10.182.0.0/16")))
+	assert.True(t, len(egressChain(parseSubnet("fd6d:7973:7400:ffff::/64"))) <= 28)
+}
+
+func TestNewEgressPolicy_AlwaysBlocksPrivateNetworks(t *testing.T) {
+	policy := NewEgressPolicy([]int{25}, []net.IPNet{parseSubnet("8.8.8.0/24")})
+
+	advertised := policy.Advertised()
+	assert.Equal(t, []int{25}, advertised.BlockedPorts)
+	assert.Contains(t, advertised.BlockedNetworks, "192.168.0.0/16")
+	assert.Contains(t, advertised.BlockedNetworks, "fe80::/10")
+	assert.Contains(t, advertised.BlockedNetworks, "8.8.8.0/24")
+	assert.Nil(t, EgressPolicy{}.Advertised())
+}
+
+func TestEgressPolicy_WithLocalNetworksBlocksNetworksOfOutboundAddresses(t *testing.T) {
+	defer func(original func() ([]net.Addr, error)) { interfaceAddrs = original }(interfaceAddrs)
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("100.64.12.34"), Mask: net.CIDRMask(22, 32)},
+			&net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)},
+			&net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)},
+		}, nil
+	}
+	policy := NewEgressPolicy(nil, nil)
+
+	local, err := policy.WithLocalNetworks("100.64.12.34", "192.168.1.10", "2001:db8::10")
+	assert.NoError(t, err)
+
+	advertised := local.Advertised()
+	assert.Contains(t, advertised.BlockedNetworks, "100.64.12.0/22")
+	assert.Contains(t, advertised.BlockedNetworks, "2001:db8::/64")
+	assert.NotContains(t, advertised.BlockedNetworks, "127.0.0.0/8")
+	assert.NotContains(t, advertised.BlockedNetworks, "192.168.1.0/24")
+	assert.Len(t, local.BlockedNetworks, len(policy.BlockedNetworks)+2)
+}
@@ -21,3 +21,18 @@ package firewall
 func NewKillSwitch() KillSwitch {
 	return &pfCtlKillSwitch{}
 }
+
+// NewDNSLeakBlocker returns mocked DNS leak blocker
+func NewDNSLeakBlocker() DNSLeakBlocker {
+	return &fakeDNSLeakBlocker{}
+}
+
+// NewIPv6LeakBlocker returns mocked IPv6 leak blocker
+func NewIPv6LeakBlocker() IPv6LeakBlocker {
+	return &fakeIPv6LeakBlocker{}
+}
+
+// NewEgressBlocker returns mocked egress blocker
+func NewEgressBlocker(_ EgressPolicy) EgressBlocker {
+	return &fakeEgressBlocker{}
+}
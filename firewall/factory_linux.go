@@ -17,7 +17,22 @@
 
 package firewall
 
-// NewKillSwitch returns mocked kill switch service
+// NewKillSwitch returns iptables based kill switch service
 func NewKillSwitch() KillSwitch {
-	return &iptablesKillSwitch{}
+	return newIptablesKillSwitch()
+}
+
+// NewDNSLeakBlocker returns iptables based DNS leak blocker
+func NewDNSLeakBlocker() DNSLeakBlocker {
+	return newIptablesDNSLeakBlocker()
+}
+
+// NewIPv6LeakBlocker returns ip6tables based IPv6 leak blocker
+func NewIPv6LeakBlocker() IPv6LeakBlocker {
+	return newIp6tablesIPv6LeakBlocker()
+}
+
+// NewEgressBlocker returns iptables based blocker enforcing given egress policy
+func NewEgressBlocker(policy EgressPolicy) EgressBlocker {
+	return newIptablesEgressBlocker(policy)
 }
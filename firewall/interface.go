@@ -17,8 +17,136 @@
 
 package firewall
 
+import (
+	"net"
+
+	"github.com/mysteriumnetwork/node/market"
+)
+
 // KillSwitch enables fw rules restricting all communication except via VPN
 type KillSwitch interface {
-	Enable()
-	Disable()
+	Enable(tunnel Tunnel) error
+	// Allow lets traffic to given destinations through enabled kill switch, replacing previously allowed ones.
+	// It's used to reach discovery, broker and providers while connection is re-established, enabling revokes it.
+	Allow(destinations []net.IP) error
+	Disable() error
+}
+
+// DNSLeakBlocker enables fw rules restricting DNS queries to VPN tunnel
+type DNSLeakBlocker interface {
+	Enable(tunnel Tunnel) error
+	Disable() error
+}
+
+// IPv6LeakBlocker enables fw rules restricting IPv6 traffic, which tunnel carrying only IPv4 would let leak
+type IPv6LeakBlocker interface {
+	Enable(tunnel Tunnel) error
+	Disable() error
+}
+
+// Tunnel describes the network path which stays open while kill switch is enabled
+type Tunnel struct {
+	// Interface is a name of tunnel network interface, iptables style wildcards (e.g. "tun+") are allowed
+	Interface string
+	// Endpoint is the address of remote tunnel peer (i.e. service provider)
+	Endpoint net.IP
+	// IPv6 tells if IPv6 traffic is routed through the tunnel too
+	IPv6 bool
+	// Excluded are networks routed outside of the tunnel by user's choice, traffic to them is not restricted
+	Excluded []net.IPNet
+}
+
+// EgressBlocker enables fw rules restricting destinations, which traffic of service subnets is forwarded to
+type EgressBlocker interface {
+	Enable(subnet net.IPNet) error
+	Disable(subnet net.IPNet) error
+}
+
+// alwaysBlockedNetworks are private and link-local networks, provider's LAN is usually in one of them
+var alwaysBlockedNetworks = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"169.254.0.0/16",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// EgressPolicy describes destinations, which consumers are not allowed to reach through provider
+type EgressPolicy struct {
+	// BlockedPorts are destination ports blocked for both TCP and UDP
+	BlockedPorts []int
+	// BlockedNetworks are destination networks blocked for all protocols
+	BlockedNetworks []net.IPNet
+}
+
+// NewEgressPolicy returns policy blocking given ports and networks, along with private and link-local networks
+func NewEgressPolicy(ports []int, networks []net.IPNet) EgressPolicy {
+	policy := EgressPolicy{BlockedPorts: ports}
+	for _, cidr := range alwaysBlockedNetworks {
+		_, network, _ := net.ParseCIDR(cidr)
+		policy.BlockedNetworks = append(policy.BlockedNetworks, *network)
+	}
+	policy.BlockedNetworks = append(policy.BlockedNetworks, networks...)
+	return policy
+}
+
+// interfaceAddrs lists addresses of network interfaces along with their networks
+var interfaceAddrs = net.InterfaceAddrs
+
+// WithLocalNetworks returns policy, which also blocks networks of interfaces holding given outbound addresses.
+// Provider's LAN isn't private always, e.g. it may be carrier-grade NAT range or public network.
+func (policy EgressPolicy) WithLocalNetworks(outboundIPs ...string) (EgressPolicy, error) {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return policy, err
+	}
+
+	blocked := append([]net.IPNet{}, policy.BlockedNetworks...)
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !holdsAnyOf(ipNet.IP, outboundIPs) {
+			continue
+		}
+		network := net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask}
+		if !coveredBy(network, blocked) {
+			blocked = append(blocked, network)
+		}
+	}
+	policy.BlockedNetworks = blocked
+	return policy, nil
+}
+
+func holdsAnyOf(ip net.IP, addresses []string) bool {
+	for _, address := range addresses {
+		if ip.Equal(net.ParseIP(address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// coveredBy tells if network is within any of given networks
+func coveredBy(network net.IPNet, networks []net.IPNet) bool {
+	ones, _ := network.Mask.Size()
+	for _, other := range networks {
+		otherOnes, _ := other.Mask.Size()
+		if otherOnes <= ones && other.Contains(network.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Advertised returns policy in the form it's shown to consumers in service proposal
+func (policy EgressPolicy) Advertised() *market.EgressPolicy {
+	if len(policy.BlockedPorts) == 0 && len(policy.BlockedNetworks) == 0 {
+		return nil
+	}
+
+	advertised := &market.EgressPolicy{BlockedPorts: policy.BlockedPorts}
+	for _, network := range policy.BlockedNetworks {
+		advertised.BlockedNetworks = append(advertised.BlockedNetworks, network.String())
+	}
+	return advertised
 }
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/utils"
+)
+
+const (
+	ipv6LeakLogPrefix = "[ipv6-leak-blocker] "
+	ipv6LeakChain     = "MYST_IPV6_LEAK"
+)
+
+type ip6tablesIPv6LeakBlocker struct {
+	ip6tables func(args ...string) error
+
+	mutex   sync.Mutex
+	enabled bool
+}
+
+func newIp6tablesIPv6LeakBlocker() *ip6tablesIPv6LeakBlocker {
+	return &ip6tablesIPv6LeakBlocker{ip6tables: sudoIp6tables}
+}
+
+// Enable rejects outgoing IPv6 traffic except the one sent through loopback
+func (lb *ip6tablesIPv6LeakBlocker) Enable(tunnel Tunnel) error {
+	if tunnel.Interface == "" {
+		return ErrTunnelNotDefined
+	}
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	rules := [][]string{
+		{"--out-interface", "lo", "--jump", "RETURN"},
+		{"--jump", "REJECT"},
+	}
+	if err := replaceOutputChain(lb.ip6tables, ipv6LeakChain, rules); err != nil {
+		log.Error(ipv6LeakLogPrefix, "Failed to block IPv6 leaks: ", err)
+		return err
+	}
+
+	lb.enabled = true
+	log.Info(ipv6LeakLogPrefix, "IPv6 traffic blocked, tunnel '", tunnel.Interface, "' carries IPv4 only")
+	return nil
+}
+
+// Disable removes IPv6 leak blocking rules
+func (lb *ip6tablesIPv6LeakBlocker) Disable() error {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if !lb.enabled {
+		return nil
+	}
+
+	removeOutputChain(lb.ip6tables, ipv6LeakChain)
+	lb.enabled = false
+	log.Info(ipv6LeakLogPrefix, "IPv6 traffic restrictions removed")
+	return nil
+}
+
+func sudoIp6tables(args ...string) error {
+	return utils.SudoExec(append([]string{"/sbin/ip6tables"}, args...)...)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIp6tablesIPv6LeakBlocker_EnableRejectsIPv6OutsideLoopback(t *testing.T) {
+	fake := &fakeIptables{}
+	lb := &ip6tablesIPv6LeakBlocker{ip6tables: fake.exec}
+
+	assert.NoError(t, lb.Enable(tunnel))
+	assert.Equal(
+		t,
+		[]string{
+			"--delete OUTPUT --jump MYST_IPV6_LEAK",
+			"--flush MYST_IPV6_LEAK",
+			"--delete-chain MYST_IPV6_LEAK",
+			"--new-chain MYST_IPV6_LEAK",
+			"--append MYST_IPV6_LEAK --out-interface lo --jump RETURN",
+			"--append MYST_IPV6_LEAK --jump REJECT",
+			"--insert OUTPUT --jump MYST_IPV6_LEAK",
+		},
+		fake.calls,
+	)
+}
+
+func TestIp6tablesIPv6LeakBlocker_EnableRequiresTunnel(t *testing.T) {
+	fake := &fakeIptables{}
+	lb := &ip6tablesIPv6LeakBlocker{ip6tables: fake.exec}
+
+	assert.Equal(t, ErrTunnelNotDefined, lb.Enable(Tunnel{}))
+	assert.Empty(t, fake.calls)
+}
+
+func TestIp6tablesIPv6LeakBlocker_DisableRemovesRulesOnlyWhenEnabled(t *testing.T) {
+	fake := &fakeIptables{}
+	lb := &ip6tablesIPv6LeakBlocker{ip6tables: fake.exec}
+
+	assert.NoError(t, lb.Disable())
+	assert.Empty(t, fake.calls)
+
+	assert.NoError(t, lb.Enable(tunnel))
+	fake.calls = nil
+	assert.NoError(t, lb.Disable())
+	assert.Equal(
+		t,
+		[]string{
+			"--delete OUTPUT --jump MYST_IPV6_LEAK",
+			"--flush MYST_IPV6_LEAK",
+			"--delete-chain MYST_IPV6_LEAK",
+		},
+		fake.calls,
+	)
+}
@@ -17,13 +17,66 @@
 
 package firewall
 
+import (
+	"net"
+
+	log "github.com/cihub/seelog"
+)
+
 type fakeKillSwitch struct {
 }
 
 // Enable enables kill switch mock
-func (ks *fakeKillSwitch) Enable() {
+func (ks *fakeKillSwitch) Enable(_ Tunnel) error {
+	return nil
+}
+
+// Allow allows destinations through kill switch mock
+func (ks *fakeKillSwitch) Allow(_ []net.IP) error {
+	return nil
+}
+
+// Disable disables kill switch mock
+func (ks *fakeKillSwitch) Disable() error {
+	return nil
+}
+
+type fakeDNSLeakBlocker struct {
+}
+
+// Enable enables DNS leak blocker mock
+func (lb *fakeDNSLeakBlocker) Enable(_ Tunnel) error {
+	return nil
+}
+
+// Disable disables DNS leak blocker mock
+func (lb *fakeDNSLeakBlocker) Disable() error {
+	return nil
+}
+
+type fakeIPv6LeakBlocker struct {
+}
+
+// Enable enables IPv6 leak blocker mock
+func (lb *fakeIPv6LeakBlocker) Enable(_ Tunnel) error {
+	return nil
+}
+
+// Disable disables IPv6 leak blocker mock
+func (lb *fakeIPv6LeakBlocker) Disable() error {
+	return nil
+}
+
+type fakeEgressBlocker struct {
+}
+
+// Enable warns that egress policy of the subnet is not enforced
+func (eb *fakeEgressBlocker) Enable(subnet net.IPNet) error {
+	log.Warn("[egress-policy] ", "Egress policy is not supported on this platform, traffic of ", subnet.String(), " is not restricted")
+	return nil
 }
 
-// Enable disables kill switch mock
-func (ks *fakeKillSwitch) Disable() {
+// Disable disables egress blocker mock
+func (eb *fakeEgressBlocker) Disable(_ net.IPNet) error {
+	return nil
 }
@@ -17,13 +17,222 @@
 
 package firewall
 
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/utils"
+)
+
+const (
+	killSwitchLogPrefix = "[kill-switch] "
+	killSwitchChain     = "MYST_KILL_SWITCH"
+)
+
+// ErrTunnelNotDefined indicates that kill switch can not be enabled without knowing tunnel interface and endpoint
+var ErrTunnelNotDefined = errors.New("tunnel interface and endpoint are required for kill switch")
+
 type iptablesKillSwitch struct {
+	iptables  func(args ...string) error
+	ip6tables func(args ...string) error
+
+	mutex   sync.Mutex
+	enabled bool
+	tunnel  Tunnel
+	// rules and rules6 are the rules of IPv4 and IPv6 chains, they are replaced in place while kill switch is enabled
+	rules  [][]string
+	rules6 [][]string
+}
+
+func newIptablesKillSwitch() *iptablesKillSwitch {
+	return &iptablesKillSwitch{iptables: sudoIptables, ip6tables: sudoIp6tables}
+}
+
+// Enable rejects all outgoing traffic except loopback, tunnel interface, tunnel endpoint and networks excluded from the tunnel.
+// IPv6 traffic is restricted the same way, it's let through tunnel interface only if the tunnel carries IPv6.
+// Enabled kill switch is switched to the new tunnel in place, so nothing leaks while rules are replaced.
+func (ks *iptablesKillSwitch) Enable(tunnel Tunnel) error {
+	if tunnel.Interface == "" || tunnel.Endpoint == nil {
+		return ErrTunnelNotDefined
+	}
+
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	rules, rules6 := killSwitchRules(tunnel, nil, false), killSwitchRules(tunnel, nil, true)
+	if ks.enabled {
+		if err := ks.replaceRules(rules, rules6); err != nil {
+			log.Error(killSwitchLogPrefix, "Failed to switch kill switch to new tunnel: ", err)
+			return err
+		}
+	} else {
+		if err := replaceOutputChain(ks.iptables, killSwitchChain, rules); err != nil {
+			log.Error(killSwitchLogPrefix, "Failed to enable kill switch: ", err)
+			return err
+		}
+		if err := replaceOutputChain(ks.ip6tables, killSwitchChain, rules6); err != nil {
+			log.Error(killSwitchLogPrefix, "Failed to enable kill switch for IPv6: ", err)
+			removeOutputChain(ks.iptables, killSwitchChain)
+			return err
+		}
+		ks.rules, ks.rules6 = rules, rules6
+	}
+
+	ks.enabled = true
+	ks.tunnel = tunnel
+	log.Info(killSwitchLogPrefix, "Traffic restricted to interface '", tunnel.Interface, "' and endpoint ", tunnel.Endpoint)
+	return nil
+}
+
+// Allow lets traffic to given destinations through enabled kill switch, besides the tunnel
+func (ks *iptablesKillSwitch) Allow(destinations []net.IP) error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	if !ks.enabled {
+		return nil
+	}
+
+	if err := ks.replaceRules(killSwitchRules(ks.tunnel, destinations, false), killSwitchRules(ks.tunnel, destinations, true)); err != nil {
+		log.Error(killSwitchLogPrefix, "Failed to allow destinations through kill switch: ", err)
+		return err
+	}
+	log.Info(killSwitchLogPrefix, "Traffic allowed to destinations: ", destinations)
+	return nil
+}
+
+// replaceRules replaces rules of IPv4 and IPv6 chains in place
+func (ks *iptablesKillSwitch) replaceRules(rules, rules6 [][]string) (err error) {
+	if ks.rules, err = replaceChainRules(ks.iptables, ks.rules, rules); err != nil {
+		return err
+	}
+	ks.rules6, err = replaceChainRules(ks.ip6tables, ks.rules6, rules6)
+	return err
+}
+
+// replaceChainRules puts given rules on top of the chain and deletes the previous ones found below them,
+// so that the chain restricts traffic all the time while its rules are being replaced. Rules of the chain are returned.
+func replaceChainRules(iptables func(args ...string) error, previous, rules [][]string) ([][]string, error) {
+	for i, rule := range rules {
+		command := append([]string{"--insert", killSwitchChain, strconv.Itoa(i + 1)}, rule...)
+		if err := iptables(command...); err != nil {
+			for ; i > 0; i-- {
+				if errDelete := iptables("--delete", killSwitchChain, "1"); errDelete != nil {
+					log.Warn(killSwitchLogPrefix, "Failed to delete inserted rule: ", errDelete)
+				}
+			}
+			return previous, err
+		}
+	}
+
+	for deleted := range previous {
+		if err := iptables("--delete", killSwitchChain, strconv.Itoa(len(rules)+1)); err != nil {
+			// rules which are left are shadowed by the new ones, so traffic is still restricted
+			log.Warn(killSwitchLogPrefix, "Failed to delete previous rule: ", err)
+			return append(rules, previous[deleted:]...), nil
+		}
+	}
+	return rules, nil
+}
+
+// killSwitchRules returns rules of IPv4 or IPv6 chain, only destinations of the chain's address family are accepted by it
+func killSwitchRules(tunnel Tunnel, allowed []net.IP, ipv6 bool) [][]string {
+	rules := [][]string{
+		{"--out-interface", "lo", "--jump", "ACCEPT"},
+	}
+	if !ipv6 || tunnel.IPv6 {
+		rules = append(rules, []string{"--out-interface", tunnel.Interface, "--jump", "ACCEPT"})
+	}
+	if isIPv6(tunnel.Endpoint) == ipv6 {
+		rules = append(rules, []string{"--destination", tunnel.Endpoint.String(), "--jump", "ACCEPT"})
+	}
+	for _, network := range tunnel.Excluded {
+		if isIPv6(network.IP) == ipv6 {
+			rules = append(rules, []string{"--destination", network.String(), "--jump", "ACCEPT"})
+		}
+	}
+	for _, destination := range allowed {
+		if isIPv6(destination) == ipv6 {
+			rules = append(rules, []string{"--destination", destination.String(), "--jump", "ACCEPT"})
+		}
+	}
+	return append(rules, []string{"--jump", "REJECT"})
+}
+
+func isIPv6(ip net.IP) bool {
+	return ip.To4() == nil
+}
+
+// Disable removes kill switch rules and allows all traffic again
+func (ks *iptablesKillSwitch) Disable() error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	if !ks.enabled {
+		return nil
+	}
+
+	removeOutputChain(ks.iptables, killSwitchChain)
+	removeOutputChain(ks.ip6tables, killSwitchChain)
+	ks.enabled = false
+	ks.rules, ks.rules6 = nil, nil
+	log.Info(killSwitchLogPrefix, "Traffic restrictions removed")
+	return nil
+}
+
+func sudoIptables(args ...string) error {
+	return utils.SudoExec(append([]string{"/sbin/iptables"}, args...)...)
+}
+
+// replaceOutputChain recreates the chain with given rules and hooks it at the top of OUTPUT chain.
+// Stale chain left from previous runs is removed first, partially created chain is removed on failure
+func replaceOutputChain(iptables func(args ...string) error, chain string, rules [][]string) error {
+	return replaceChain(iptables, []string{"OUTPUT"}, chain, rules)
+}
+
+// removeOutputChain unhooks the chain from OUTPUT chain and deletes it
+func removeOutputChain(iptables func(args ...string) error, chain string) {
+	removeChain(iptables, []string{"OUTPUT"}, chain)
+}
+
+// replaceChain recreates the chain with given rules and hooks it at the top of built-in chain by given hook,
+// which is the built-in chain name followed by optional match of packets jumping to the chain
+func replaceChain(iptables func(args ...string) error, hook []string, chain string, rules [][]string) error {
+	removeChain(iptables, hook, chain)
+
+	commands := [][]string{{"--new-chain", chain}}
+	for _, rule := range rules {
+		commands = append(commands, append([]string{"--append", chain}, rule...))
+	}
+	commands = append(commands, hookCommand("--insert", hook, chain))
+
+	for _, command := range commands {
+		if err := iptables(command...); err != nil {
+			removeChain(iptables, hook, chain)
+			return err
+		}
+	}
+	return nil
 }
 
-// Enable enables kill switch mock
-func (ks *iptablesKillSwitch) Enable() {
+// removeChain unhooks the chain from built-in chain and deletes it
+func removeChain(iptables func(args ...string) error, hook []string, chain string) {
+	commands := [][]string{
+		hookCommand("--delete", hook, chain),
+		{"--flush", chain},
+		{"--delete-chain", chain},
+	}
+	for _, command := range commands {
+		if err := iptables(command...); err != nil {
+			log.Trace("[iptables] ", "Nothing to clean: ", err)
+		}
+	}
 }
 
-// Enable disables kill switch mock
-func (ks *iptablesKillSwitch) Disable() {
+func hookCommand(action string, hook []string, chain string) []string {
+	command := append([]string{action}, hook...)
+	return append(command, "--jump", chain)
 }
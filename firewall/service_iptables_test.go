@@ -0,0 +1,245 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeIptables struct {
+	calls   []string
+	failOn  string
+	failErr error
+}
+
+func (fi *fakeIptables) exec(args ...string) error {
+	call := strings.Join(args, " ")
+	fi.calls = append(fi.calls, call)
+	if fi.failOn != "" && strings.HasPrefix(call, fi.failOn) {
+		return fi.failErr
+	}
+	return nil
+}
+
+var tunnel = Tunnel{Interface: "tun+", Endpoint: net.ParseIP("1.2.3.4")}
+
+func TestIptablesKillSwitch_EnableAddsRulesAfterStaleCleanup(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+
+	assert.NoError(t, ks.Enable(tunnel))
+	assert.Equal(
+		t,
+		[]string{
+			"--delete OUTPUT --jump MYST_KILL_SWITCH",
+			"--flush MYST_KILL_SWITCH",
+			"--delete-chain MYST_KILL_SWITCH",
+			"--new-chain MYST_KILL_SWITCH",
+			"--append MYST_KILL_SWITCH --out-interface lo --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --out-interface tun+ --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --destination 1.2.3.4 --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --jump REJECT",
+			"--insert OUTPUT --jump MYST_KILL_SWITCH",
+		},
+		fake.calls,
+	)
+}
+
+func TestIptablesKillSwitch_EnableAcceptsNetworksExcludedFromTunnel(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+
+	_, lan, _ := net.ParseCIDR("192.168.1.0/24")
+	excludingTunnel := tunnel
+	excludingTunnel.Excluded = []net.IPNet{*lan}
+	assert.NoError(t, ks.Enable(excludingTunnel))
+	assert.Equal(
+		t,
+		[]string{
+			"--append MYST_KILL_SWITCH --out-interface lo --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --out-interface tun+ --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --destination 1.2.3.4 --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --destination 192.168.1.0/24 --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --jump REJECT",
+		},
+		fake.calls[4:9],
+	)
+}
+
+func TestIptablesKillSwitch_EnableRejectsIPv6OutsideOfTunnel(t *testing.T) {
+	fake6 := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: (&fakeIptables{}).exec, ip6tables: fake6.exec}
+
+	_, lan, _ := net.ParseCIDR("fd00::/64")
+	excludingTunnel := tunnel
+	excludingTunnel.Excluded = []net.IPNet{*lan}
+	assert.NoError(t, ks.Enable(excludingTunnel))
+	assert.Equal(
+		t,
+		[]string{
+			"--append MYST_KILL_SWITCH --out-interface lo --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --destination fd00::/64 --jump ACCEPT",
+			"--append MYST_KILL_SWITCH --jump REJECT",
+			"--insert OUTPUT --jump MYST_KILL_SWITCH",
+		},
+		fake6.calls[4:],
+	)
+
+	fake6.calls = nil
+	assert.NoError(t, ks.Enable(Tunnel{Interface: "wg0", Endpoint: net.ParseIP("2001:db8::1"), IPv6: true}))
+	assert.Equal(
+		t,
+		[]string{
+			"--insert MYST_KILL_SWITCH 1 --out-interface lo --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 2 --out-interface wg0 --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 3 --destination 2001:db8::1 --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 4 --jump REJECT",
+			"--delete MYST_KILL_SWITCH 5",
+			"--delete MYST_KILL_SWITCH 5",
+			"--delete MYST_KILL_SWITCH 5",
+		},
+		fake6.calls,
+	)
+}
+
+func TestIptablesKillSwitch_EnableFailureForIPv6RemovesIPv4Rules(t *testing.T) {
+	fake := &fakeIptables{}
+	fake6 := &fakeIptables{failOn: "--new-chain", failErr: errors.New("boom")}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: fake6.exec}
+
+	assert.EqualError(t, ks.Enable(tunnel), "boom")
+	assert.Equal(t, "--delete-chain MYST_KILL_SWITCH", fake.calls[len(fake.calls)-1])
+}
+
+func TestIptablesKillSwitch_EnableRequiresTunnel(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+
+	assert.Equal(t, ErrTunnelNotDefined, ks.Enable(Tunnel{Interface: "tun+"}))
+	assert.Empty(t, fake.calls)
+}
+
+func TestIptablesKillSwitch_EnableFailureRemovesPartialRules(t *testing.T) {
+	fake := &fakeIptables{failOn: "--insert", failErr: errors.New("boom")}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+
+	assert.EqualError(t, ks.Enable(tunnel), "boom")
+	assert.Equal(t, "--delete-chain MYST_KILL_SWITCH", fake.calls[len(fake.calls)-1])
+
+	fake.calls = nil
+	assert.NoError(t, ks.Disable())
+	assert.Empty(t, fake.calls)
+}
+
+func TestIptablesKillSwitch_DisableRemovesRules(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+	assert.NoError(t, ks.Enable(tunnel))
+
+	fake.calls = nil
+	assert.NoError(t, ks.Disable())
+	assert.Equal(
+		t,
+		[]string{
+			"--delete OUTPUT --jump MYST_KILL_SWITCH",
+			"--flush MYST_KILL_SWITCH",
+			"--delete-chain MYST_KILL_SWITCH",
+		},
+		fake.calls,
+	)
+}
+
+func TestIptablesKillSwitch_EnableSwitchesTunnelInPlace(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+	assert.NoError(t, ks.Enable(tunnel))
+
+	fake.calls = nil
+	assert.NoError(t, ks.Enable(Tunnel{Interface: "wg0", Endpoint: net.ParseIP("5.6.7.8")}))
+	assert.Equal(
+		t,
+		[]string{
+			"--insert MYST_KILL_SWITCH 1 --out-interface lo --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 2 --out-interface wg0 --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 3 --destination 5.6.7.8 --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 4 --jump REJECT",
+			"--delete MYST_KILL_SWITCH 5",
+			"--delete MYST_KILL_SWITCH 5",
+			"--delete MYST_KILL_SWITCH 5",
+			"--delete MYST_KILL_SWITCH 5",
+		},
+		fake.calls,
+	)
+}
+
+func TestIptablesKillSwitch_AllowLetsDestinationsThroughUntilEnabledAgain(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+	assert.NoError(t, ks.Allow([]net.IP{net.ParseIP("9.9.9.9")}))
+	assert.Empty(t, fake.calls)
+
+	assert.NoError(t, ks.Enable(tunnel))
+	fake.calls = nil
+	assert.NoError(t, ks.Allow([]net.IP{net.ParseIP("9.9.9.9")}))
+	assert.Equal(
+		t,
+		[]string{
+			"--insert MYST_KILL_SWITCH 1 --out-interface lo --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 2 --out-interface tun+ --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 3 --destination 1.2.3.4 --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 4 --destination 9.9.9.9 --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 5 --jump REJECT",
+			"--delete MYST_KILL_SWITCH 6",
+			"--delete MYST_KILL_SWITCH 6",
+			"--delete MYST_KILL_SWITCH 6",
+			"--delete MYST_KILL_SWITCH 6",
+		},
+		fake.calls,
+	)
+
+	fake.calls = nil
+	assert.NoError(t, ks.Enable(tunnel))
+	assert.Len(t, fake.calls, 4+5)
+	assert.NotContains(t, fake.calls, "--insert MYST_KILL_SWITCH 4 --destination 9.9.9.9 --jump ACCEPT")
+}
+
+func TestIptablesKillSwitch_AllowFailureKeepsPreviousRules(t *testing.T) {
+	fake := &fakeIptables{}
+	ks := &iptablesKillSwitch{iptables: fake.exec, ip6tables: (&fakeIptables{}).exec}
+	assert.NoError(t, ks.Enable(tunnel))
+
+	fake.calls = nil
+	fake.failOn, fake.failErr = "--insert MYST_KILL_SWITCH 3", errors.New("boom")
+	assert.EqualError(t, ks.Allow([]net.IP{net.ParseIP("9.9.9.9")}), "boom")
+	assert.Equal(
+		t,
+		[]string{
+			"--insert MYST_KILL_SWITCH 1 --out-interface lo --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 2 --out-interface tun+ --jump ACCEPT",
+			"--insert MYST_KILL_SWITCH 3 --destination 1.2.3.4 --jump ACCEPT",
+			"--delete MYST_KILL_SWITCH 1",
+			"--delete MYST_KILL_SWITCH 1",
+		},
+		fake.calls,
+	)
+}
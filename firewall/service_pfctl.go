@@ -17,13 +17,22 @@
 
 package firewall
 
+import "net"
+
 type pfCtlKillSwitch struct {
 }
 
 // Enable enables kill switch mock
-func (ks *pfCtlKillSwitch) Enable() {
+func (ks *pfCtlKillSwitch) Enable(_ Tunnel) error {
+	return nil
+}
+
+// Allow allows destinations through kill switch mock
+func (ks *pfCtlKillSwitch) Allow(_ []net.IP) error {
+	return nil
 }
 
-// Enable disables kill switch mock
-func (ks *pfCtlKillSwitch) Disable() {
+// Disable disables kill switch mock
+func (ks *pfCtlKillSwitch) Disable() error {
+	return nil
 }
@@ -31,9 +31,10 @@ type NodeRegisterRequest struct {
 
 // NodeStatsRequest represents JSON request for the node session stats information
 type NodeStatsRequest struct {
-	NodeKey     string         `json:"node_key"`
-	ServiceType string         `json:"service_type"`
-	Sessions    []SessionStats `json:"sessions"`
+	NodeKey     string              `json:"node_key"`
+	ServiceType string              `json:"service_type"`
+	Sessions    []SessionStats      `json:"sessions"`
+	Load        *market.ServiceLoad `json:"load,omitempty"`
 }
 
 // ProposalUnregisterRequest represents request JSON for unregister a single proposal
@@ -120,6 +120,7 @@ func (mApi *MysteriumAPI) PingProposal(proposal market.ServiceProposal, signer i
 	req, err := requests.NewSignedPostRequest(mApi.discoveryAPIAddress, "ping_proposal", NodeStatsRequest{
 		NodeKey:     proposal.ProviderID,
 		ServiceType: proposal.ServiceType,
+		Load:        proposal.Load,
 	}, signer)
 	if err != nil {
 		return err
@@ -19,7 +19,9 @@ package market
 
 import (
 	"encoding/json"
+	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
 )
 
@@ -29,6 +31,20 @@ type PaymentMethod interface {
 	GetPrice() money.Money
 }
 
+// TimeMeteredPaymentMethod is payment method which price is paid for given duration of service
+type TimeMeteredPaymentMethod interface {
+	PaymentMethod
+	// Service duration provided for the price
+	GetDuration() time.Duration
+}
+
+// BytesMeteredPaymentMethod is payment method which price is paid for given amount of transferred data
+type BytesMeteredPaymentMethod interface {
+	PaymentMethod
+	// Amount of data provided for the price
+	GetBytes() datasize.BitSize
+}
+
 // UnsupportedPaymentMethod represents payment method which is unknown to node (i.e. not registered)
 type UnsupportedPaymentMethod struct {
 }
@@ -32,6 +32,11 @@ type ProposalRegistry interface {
 	UnregisterProposal(proposal market.ServiceProposal, signer identity.Signer) error
 }
 
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
 // Discovery structure holds discovery service state
 type Discovery struct {
 	identityRegistry            identity_registry.IdentityRegistry
@@ -39,8 +44,10 @@ type Discovery struct {
 	identityRegistration        identity_registry.RegistrationDataProvider
 	proposalRegistry            ProposalRegistry
 	signerCreate                identity.SignerFactory
+	eventPublisher              Publisher
 	signer                      identity.Signer
 	proposal                    market.ServiceProposal
+	loadProvider                LoadProvider
 	statusChan                  chan Status
 	status                      Status
 	proposalAnnouncementStopped *sync.WaitGroup
@@ -56,12 +63,14 @@ func NewService(
 	identityRegistration identity_registry.RegistrationDataProvider,
 	proposalRegistry ProposalRegistry,
 	signerCreate identity.SignerFactory,
+	eventPublisher Publisher,
 ) *Discovery {
 	return &Discovery{
 		identityRegistry:            identityRegistry,
 		identityRegistration:        identityRegistration,
 		proposalRegistry:            proposalRegistry,
 		signerCreate:                signerCreate,
+		eventPublisher:              eventPublisher,
 		statusChan:                  make(chan Status),
 		status:                      StatusUndefined,
 		proposalAnnouncementStopped: &sync.WaitGroup{},
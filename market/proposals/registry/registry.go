@@ -42,18 +42,43 @@ const (
 	StatusUndefined
 )
 
+var statusNames = map[Status]string{
+	IdentityUnregistered:     "IdentityUnregistered",
+	WaitingForRegistration:   "WaitingForRegistration",
+	IdentityRegisterFailed:   "IdentityRegisterFailed",
+	RegisterProposal:         "RegisterProposal",
+	PingProposal:             "PingProposal",
+	UnregisterProposal:       "UnregisterProposal",
+	UnregisterProposalFailed: "UnregisterProposalFailed",
+	ProposalUnregistered:     "ProposalUnregistered",
+	StatusUndefined:          "StatusUndefined",
+}
+
+// String returns name of the registration stage
+func (status Status) String() string {
+	if name, found := statusNames[status]; found {
+		return name
+	}
+	return statusNames[StatusUndefined]
+}
+
 const logPrefix = "[discovery] "
 
-// Start launches discovery service
-func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.ServiceProposal) {
+// LoadProvider tells current load of the service, which is advertised along with its proposal
+type LoadProvider func() market.ServiceLoad
+
+// Start launches discovery service, load of the service is advertised if load provider is given
+func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.ServiceProposal, loadProvider LoadProvider) {
 	d.RLock()
 	defer d.RUnlock()
 
 	d.ownIdentity = ownIdentity
 	d.signer = d.signerCreate(ownIdentity)
 	d.proposal = proposal
+	d.loadProvider = loadProvider
 
-	stopLoop := make(chan bool)
+	// stopping doesn't block even if the loop has already ended, so service can be restarted
+	stopLoop := make(chan bool, 1)
 	d.stop = func() {
 		// cancel (stop) discovery loop
 		stopLoop <- true
@@ -66,11 +91,33 @@ func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.Service
 	go d.mainDiscoveryLoop(stopLoop)
 }
 
+// UpdateProposal replaces the announced proposal, it's registered again right away if it has been registered already
+func (d *Discovery) UpdateProposal(proposal market.ServiceProposal) error {
+	d.Lock()
+	d.proposal = proposal
+	registered := d.status == PingProposal
+	d.Unlock()
+
+	if !registered {
+		// registration in progress picks the replaced proposal up
+		return nil
+	}
+	return d.proposalRegistry.RegisterProposal(d.proposalWithLoad(), d.signer)
+}
+
 // Wait wait for proposal announcements to stop / unregister
 func (d *Discovery) Wait() {
 	d.proposalAnnouncementStopped.Wait()
 }
 
+// Status returns current stage of proposal registration
+func (d *Discovery) Status() Status {
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.status
+}
+
 // Stop stops discovery loop
 func (d *Discovery) Stop() {
 	d.stop()
@@ -126,6 +173,7 @@ func (d *Discovery) registerIdentity() {
 		switch registerEvent {
 		case identity_registry.Registered:
 			log.Info(logPrefix, "identity registered, proceeding with proposal registration")
+			d.publishRegistration(true)
 			d.changeStatus(RegisterProposal)
 		case identity_registry.Cancelled:
 			log.Info(logPrefix, "cancelled identity registration")
@@ -135,7 +183,7 @@ func (d *Discovery) registerIdentity() {
 }
 
 func (d *Discovery) registerProposal() {
-	err := d.proposalRegistry.RegisterProposal(d.proposal, d.signer)
+	err := d.proposalRegistry.RegisterProposal(d.proposalWithLoad(), d.signer)
 	if err != nil {
 		log.Errorf("%s Failed to register proposal, retrying after 1 min. %s", logPrefix, err.Error())
 		time.Sleep(1 * time.Minute)
@@ -147,15 +195,32 @@ func (d *Discovery) registerProposal() {
 
 func (d *Discovery) pingProposal() {
 	time.Sleep(1 * time.Minute)
-	err := d.proposalRegistry.PingProposal(d.proposal, d.signer)
+	err := d.proposalRegistry.PingProposal(d.proposalWithLoad(), d.signer)
 	if err != nil {
 		log.Error(logPrefix, "Failed to ping proposal: ", err)
 	}
 	d.changeStatus(PingProposal)
 }
 
+// proposalWithLoad returns the proposal updated with current load of the service
+func (d *Discovery) proposalWithLoad() market.ServiceProposal {
+	d.RLock()
+	proposal := d.proposal
+	d.RUnlock()
+
+	if d.loadProvider != nil {
+		load := d.loadProvider()
+		proposal.Load = &load
+	}
+	return proposal
+}
+
 func (d *Discovery) unregisterProposal() {
-	err := d.proposalRegistry.UnregisterProposal(d.proposal, d.signer)
+	d.RLock()
+	proposal := d.proposal
+	d.RUnlock()
+
+	err := d.proposalRegistry.UnregisterProposal(proposal, d.signer)
 	if err != nil {
 		log.Error(logPrefix, "Failed to unregister proposal: ", err)
 		d.changeStatus(UnregisterProposalFailed)
@@ -180,13 +245,22 @@ func (d *Discovery) checkRegistration() {
 			return
 		}
 		identity_registry.PrintRegistrationData(registrationData)
+		d.publishRegistration(false)
 		log.Infof("%s identity %s not registered, delaying proposal registration until identity is registered", logPrefix, d.ownIdentity.Address)
 		d.changeStatus(IdentityUnregistered)
 		return
 	}
+	d.publishRegistration(true)
 	d.changeStatus(RegisterProposal)
 }
 
+func (d *Discovery) publishRegistration(registered bool) {
+	d.eventPublisher.Publish(identity_registry.RegistrationEventTopic, identity_registry.RegistrationStatusEvent{
+		ID:         d.ownIdentity,
+		Registered: registered,
+	})
+}
+
 func (d *Discovery) changeStatus(status Status) {
 	d.Lock()
 	defer d.Unlock()
@@ -44,6 +44,7 @@ func discoveryWithMockedDependencies() *Discovery {
 		},
 		identityRegistration: &identity_registry.FakeRegistrationDataProvider{},
 		proposalRegistry:     &mockedProposalRegistry{},
+		eventPublisher:       &mockedPublisher{},
 	}
 }
 
@@ -51,27 +52,79 @@ func TestStartRegistersProposal(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
 
-	d.Start(providerID, proposal)
+	d.Start(providerID, proposal, nil)
 
 	actualStatus := observeStatus(d, PingProposal)
 	assert.Equal(t, PingProposal, actualStatus)
 }
 
+func TestStartRegistersProposalWithLoad(t *testing.T) {
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
+	proposalRegistry := d.proposalRegistry.(*mockedProposalRegistry)
+
+	d.Start(providerID, proposal, func() market.ServiceLoad {
+		return market.ServiceLoad{Sessions: 1, MaxSessions: 10}
+	})
+
+	observeStatus(d, PingProposal)
+	registered := proposalRegistry.registeredProposals()
+	assert.Len(t, registered, 1)
+	assert.Equal(t, &market.ServiceLoad{Sessions: 1, MaxSessions: 10}, registered[0].Load)
+	assert.Nil(t, proposal.Load)
+}
+
+func TestUpdateProposalRegistersReplacedProposal(t *testing.T) {
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
+	proposalRegistry := d.proposalRegistry.(*mockedProposalRegistry)
+
+	d.Start(providerID, proposal, nil)
+	observeStatus(d, PingProposal)
+
+	updated := proposal
+	updated.Version = 2
+	err := d.UpdateProposal(updated)
+
+	assert.NoError(t, err)
+	registered := proposalRegistry.registeredProposals()
+	assert.Len(t, registered, 2)
+	assert.Equal(t, 2, registered[1].Version)
+}
+
 func TestStartRegistersIdentitySuccessfully(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: true, Registered: false}
 
-	d.Start(providerID, proposal)
+	d.Start(providerID, proposal, nil)
 
 	actualStatus := observeStatus(d, PingProposal)
 	assert.Equal(t, PingProposal, actualStatus)
 }
 
+func TestStartPublishesIdentityRegistrationStatus(t *testing.T) {
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: true, Registered: false}
+	publisher := d.eventPublisher.(*mockedPublisher)
+
+	d.Start(providerID, proposal, nil)
+
+	observeStatus(d, PingProposal)
+	assert.Equal(
+		t,
+		[]identity_registry.RegistrationStatusEvent{
+			{ID: providerID, Registered: false},
+			{ID: providerID, Registered: true},
+		},
+		publisher.registrationEvents(),
+	)
+}
+
 func TestStartRegisterIdentityCancelled(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: false}
 
-	d.Start(providerID, proposal)
+	d.Start(providerID, proposal, nil)
 
 	actualStatus := observeStatus(d, WaitingForRegistration)
 	assert.Equal(t, WaitingForRegistration, actualStatus)
@@ -86,7 +139,7 @@ func TestStartStopUnregisterProposal(t *testing.T) {
 	d := discoveryWithMockedDependencies()
 	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
 
-	d.Start(providerID, proposal)
+	d.Start(providerID, proposal, nil)
 
 	actualStatus := observeStatus(d, PingProposal)
 	assert.Equal(t, PingProposal, actualStatus)
@@ -110,18 +163,58 @@ func observeStatus(d *Discovery, status Status) Status {
 }
 
 type mockedProposalRegistry struct {
+	registered []market.ServiceProposal
+	sync.Mutex
 }
 
-func (mockedProposalRegistry) RegisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+func (mpr *mockedProposalRegistry) RegisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	mpr.Lock()
+	defer mpr.Unlock()
+	mpr.registered = append(mpr.registered, proposal)
 	return nil
 }
 
-func (mockedProposalRegistry) PingProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+func (mpr *mockedProposalRegistry) registeredProposals() []market.ServiceProposal {
+	mpr.Lock()
+	defer mpr.Unlock()
+	return mpr.registered
+}
+
+func (mpr *mockedProposalRegistry) PingProposal(proposal market.ServiceProposal, signer identity.Signer) error {
 	return nil
 }
 
-func (mockedProposalRegistry) UnregisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+func (mpr *mockedProposalRegistry) UnregisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
 	return nil
 }
 
 var _ ProposalRegistry = &mockedProposalRegistry{}
+
+type mockedPublisher struct {
+	events []interface{}
+	sync.Mutex
+}
+
+func (mp *mockedPublisher) Publish(topic string, args ...interface{}) {
+	mp.Lock()
+	defer mp.Unlock()
+	mp.events = append(mp.events, args...)
+}
+
+func (mp *mockedPublisher) registrationEvents() []identity_registry.RegistrationStatusEvent {
+	mp.Lock()
+	defer mp.Unlock()
+
+	var events []identity_registry.RegistrationStatusEvent
+	for _, event := range mp.events {
+		if registrationEvent, ok := event.(identity_registry.RegistrationStatusEvent); ok {
+			events = append(events, registrationEvent)
+		}
+	}
+	return events
+}
+
+func TestStatusString(t *testing.T) {
+	assert.Equal(t, "PingProposal", PingProposal.String())
+	assert.Equal(t, "StatusUndefined", Status(100).String())
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package selector
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/metrics"
+	"github.com/mysteriumnetwork/node/money"
+)
+
+const selectorLogPrefix = "[proposal-selector] "
+
+// unknownQuality is assigned to proposals which have no metrics in quality oracle
+const unknownQuality = -1
+
+// ErrNoMatchingProposals is returned when no proposal satisfies given criteria
+var ErrNoMatchingProposals = errors.New("no proposals match given criteria")
+
+// ProposalProvider allows to fetch proposals by specified params
+type ProposalProvider interface {
+	FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error)
+}
+
+// Criteria describes proposals acceptable for consumer, zero values do not restrict selection
+type Criteria struct {
+	ServiceType string
+	Country     string
+	// MaxPrice limits price per unit of metering, any price is accepted when nil
+	MaxPrice *money.Money
+	// MinQuality is the minimal share of successful connects to provider, from 0 to 1
+	MinQuality float64
+}
+
+// Selector picks proposals matching given criteria, best ones first
+type Selector struct {
+	proposalProvider ProposalProvider
+	qualityOracle    metrics.QualityOracle
+}
+
+// NewSelector creates proposal selector, quality oracle is optional
+func NewSelector(proposalProvider ProposalProvider, qualityOracle metrics.QualityOracle) *Selector {
+	return &Selector{
+		proposalProvider: proposalProvider,
+		qualityOracle:    qualityOracle,
+	}
+}
+
+type candidate struct {
+	proposal market.ServiceProposal
+	quality  float64
+}
+
+// Select returns proposals matching given criteria ordered by quality and price
+func (s *Selector) Select(criteria Criteria) ([]market.ServiceProposal, error) {
+	proposals, err := s.proposalProvider.FindProposals("", criteria.ServiceType)
+	if err != nil {
+		return nil, err
+	}
+
+	qualities := s.fetchQualities()
+	candidates := make([]candidate, 0, len(proposals))
+	for _, proposal := range proposals {
+		quality, ok := qualities[proposalKey(proposal.ProviderID, proposal.ServiceType)]
+		if !ok {
+			quality = unknownQuality
+		}
+		if matches(proposal, quality, criteria) {
+			candidates = append(candidates, candidate{proposal: proposal, quality: quality})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoMatchingProposals
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].quality != candidates[j].quality {
+			return candidates[i].quality > candidates[j].quality
+		}
+		return priceOf(candidates[i].proposal).Amount < priceOf(candidates[j].proposal).Amount
+	})
+
+	selected := make([]market.ServiceProposal, len(candidates))
+	for i, c := range candidates {
+		selected[i] = c.proposal
+	}
+	return selected, nil
+}
+
+// fetchQualities returns share of successful connects keyed by provider and service type
+func (s *Selector) fetchQualities() map[string]float64 {
+	qualities := make(map[string]float64)
+	if s.qualityOracle == nil {
+		return qualities
+	}
+
+	for _, msg := range s.qualityOracle.ProposalsMetrics() {
+		var proposalMetrics struct {
+			ProposalID struct {
+				ProviderID  string `json:"providerID"`
+				ServiceType string `json:"serviceType"`
+			} `json:"proposalID"`
+			ConnectCount struct {
+				Success uint64 `json:"success"`
+				Fail    uint64 `json:"fail"`
+				Timeout uint64 `json:"timeout"`
+			} `json:"connectCount"`
+		}
+		if err := json.Unmarshal(msg, &proposalMetrics); err != nil {
+			log.Warn(selectorLogPrefix, "Failed to parse proposal metrics: ", err)
+			continue
+		}
+
+		count := proposalMetrics.ConnectCount
+		total := count.Success + count.Fail + count.Timeout
+		if total == 0 {
+			continue
+		}
+		id := proposalMetrics.ProposalID
+		qualities[proposalKey(id.ProviderID, id.ServiceType)] = float64(count.Success) / float64(total)
+	}
+	return qualities
+}
+
+func matches(proposal market.ServiceProposal, quality float64, criteria Criteria) bool {
+	if proposal.ServiceDefinition == nil || proposal.PaymentMethod == nil {
+		return false
+	}
+	if proposal.Load != nil && proposal.Load.IsFull() {
+		return false
+	}
+	if criteria.Country != "" && proposal.ServiceDefinition.GetLocation().Country != criteria.Country {
+		return false
+	}
+	if criteria.MaxPrice != nil {
+		price := priceOf(proposal)
+		if price.Amount > 0 && (price.Currency != criteria.MaxPrice.Currency || price.Amount > criteria.MaxPrice.Amount) {
+			return false
+		}
+	}
+	if criteria.MinQuality > 0 && quality < criteria.MinQuality {
+		return false
+	}
+	return true
+}
+
+func priceOf(proposal market.ServiceProposal) money.Money {
+	if proposal.PaymentMethod == nil {
+		return money.Money{}
+	}
+	return proposal.PaymentMethod.GetPrice()
+}
+
+func proposalKey(providerID, serviceType string) string {
+	return providerID + "-" + serviceType
+}
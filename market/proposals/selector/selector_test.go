@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package selector
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceDefinition struct {
+	country string
+}
+
+func (sd fakeServiceDefinition) GetLocation() market.Location {
+	return market.Location{Country: sd.country}
+}
+
+type fakePaymentMethod struct {
+	price money.Money
+}
+
+func (pm fakePaymentMethod) GetPrice() money.Money {
+	return pm.price
+}
+
+type fakeProposalProvider struct {
+	requestedServiceType string
+	proposals            []market.ServiceProposal
+	err                  error
+}
+
+func (fpp *fakeProposalProvider) FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error) {
+	fpp.requestedServiceType = serviceType
+	return fpp.proposals, fpp.err
+}
+
+type fakeQualityOracle struct {
+	metrics []json.RawMessage
+}
+
+func (fqo *fakeQualityOracle) ProposalsMetrics() []json.RawMessage {
+	return fqo.metrics
+}
+
+func newProposal(providerID, country string, price float64) market.ServiceProposal {
+	return market.ServiceProposal{
+		ProviderID:        providerID,
+		ServiceType:       "wireguard",
+		ServiceDefinition: fakeServiceDefinition{country: country},
+		PaymentMethod:     fakePaymentMethod{price: money.NewMoney(price, money.CURRENCY_MYST)},
+	}
+}
+
+func connectMetrics(providerID string, success, fail int) json.RawMessage {
+	return json.RawMessage(`{
+		"proposalID": {"providerID": "` + providerID + `", "serviceType": "wireguard"},
+		"connectCount": {"success": ` + strconv.Itoa(success) + `, "fail": ` + strconv.Itoa(fail) + `, "timeout": 0}
+	}`)
+}
+
+func providersOf(proposals []market.ServiceProposal) []string {
+	providers := make([]string, len(proposals))
+	for i, proposal := range proposals {
+		providers[i] = proposal.ProviderID
+	}
+	return providers
+}
+
+func TestSelectFiltersByCountryAndPrice(t *testing.T) {
+	provider := &fakeProposalProvider{proposals: []market.ServiceProposal{
+		newProposal("0x1", "DE", 0.1),
+		newProposal("0x2", "LT", 0.1),
+		newProposal("0x3", "DE", 0.5),
+		newProposal("0x4", "DE", 0),
+	}}
+	maxPrice := money.NewMoney(0.2, money.CURRENCY_MYST)
+
+	proposals, err := NewSelector(provider, nil).Select(Criteria{ServiceType: "wireguard", Country: "DE", MaxPrice: &maxPrice})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "wireguard", provider.requestedServiceType)
+	assert.Equal(t, []string{"0x4", "0x1"}, providersOf(proposals))
+}
+
+func TestSelectOrdersByQualityAndFiltersLowQuality(t *testing.T) {
+	provider := &fakeProposalProvider{proposals: []market.ServiceProposal{
+		newProposal("0x1", "DE", 0.1),
+		newProposal("0x2", "DE", 0.1),
+		newProposal("0x3", "DE", 0.1),
+		newProposal("0x4", "DE", 0.1),
+	}}
+	oracle := &fakeQualityOracle{metrics: []json.RawMessage{
+		connectMetrics("0x1", 6, 4),
+		connectMetrics("0x2", 9, 1),
+		connectMetrics("0x3", 1, 9),
+	}}
+
+	proposals, err := NewSelector(provider, oracle).Select(Criteria{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x2", "0x1", "0x3", "0x4"}, providersOf(proposals))
+
+	proposals, err = NewSelector(provider, oracle).Select(Criteria{MinQuality: 0.5})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x2", "0x1"}, providersOf(proposals))
+}
+
+func TestSelectSkipsFullProviders(t *testing.T) {
+	full := newProposal("0x1", "DE", 0.1)
+	full.Load = &market.ServiceLoad{Sessions: 10, MaxSessions: 10}
+	busy := newProposal("0x2", "DE", 0.1)
+	busy.Load = &market.ServiceLoad{Sessions: 9, MaxSessions: 10}
+	provider := &fakeProposalProvider{proposals: []market.ServiceProposal{full, busy}}
+
+	proposals, err := NewSelector(provider, nil).Select(Criteria{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x2"}, providersOf(proposals))
+}
+
+func TestSelectReturnsErrorWhenNothingMatches(t *testing.T) {
+	provider := &fakeProposalProvider{proposals: []market.ServiceProposal{newProposal("0x1", "DE", 0.1)}}
+
+	_, err := NewSelector(provider, nil).Select(Criteria{Country: "US"})
+
+	assert.Equal(t, ErrNoMatchingProposals, err)
+}
+
+func TestSelectReturnsProviderError(t *testing.T) {
+	providerErr := errors.New("discovery unavailable")
+	provider := &fakeProposalProvider{err: providerErr}
+
+	_, err := NewSelector(provider, nil).Select(Criteria{})
+
+	assert.Equal(t, providerErr, err)
+}
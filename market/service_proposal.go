@@ -18,11 +18,19 @@
 package market
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 
 	"github.com/mysteriumnetwork/node/identity"
 )
 
+// ErrServiceDefinitionNotRelocatable is returned when location of service definition can't be changed
+var ErrServiceDefinitionNotRelocatable = errors.New("location of service definition can not be changed")
+
+// ErrServiceDefinitionNotEditable is returned when terms of service definition other than location are changed
+var ErrServiceDefinitionNotEditable = errors.New("only location of service definition can be changed, bandwidth and other terms are enforced by the running service")
+
 const (
 	proposalFormat = "service-proposal/v1"
 )
@@ -53,6 +61,38 @@ type ServiceProposal struct {
 
 	// Communication methods possible
 	ProviderContacts ContactList `json:"provider_contacts"`
+
+	// Current load of the service, if provider advertises it
+	Load *ServiceLoad `json:"load,omitempty"`
+
+	// Revision of proposal terms, it's increased each time provider updates proposal of running service
+	Version int `json:"version,omitempty"`
+
+	// Destinations consumers can't reach through the service, if provider restricts them
+	EgressPolicy *EgressPolicy `json:"egress_policy,omitempty"`
+}
+
+// ServiceLoad describes how busy provider's service is
+type ServiceLoad struct {
+	// Count of currently running sessions
+	Sessions int `json:"sessions"`
+
+	// Maximum count of concurrent sessions service takes, zero means no limit
+	MaxSessions int `json:"max_sessions,omitempty"`
+}
+
+// IsFull returns true if service doesn't take new sessions
+func (load ServiceLoad) IsFull() bool {
+	return load.MaxSessions > 0 && load.Sessions >= load.MaxSessions
+}
+
+// EgressPolicy describes destinations, traffic to which provider doesn't forward
+type EgressPolicy struct {
+	// Destination ports blocked for both TCP and UDP
+	BlockedPorts []int `json:"blocked_ports,omitempty"`
+
+	// Destination networks blocked in CIDR notation
+	BlockedNetworks []string `json:"blocked_networks,omitempty"`
 }
 
 // UnmarshalJSON is custom json unmarshaler to dynamically fill in ServiceProposal values
@@ -66,6 +106,9 @@ func (proposal *ServiceProposal) UnmarshalJSON(data []byte) error {
 		ServiceDefinition *json.RawMessage `json:"service_definition"`
 		PaymentMethod     *json.RawMessage `json:"payment_method"`
 		ProviderContacts  *json.RawMessage `json:"provider_contacts"`
+		Load              *ServiceLoad     `json:"load"`
+		Version           int              `json:"version"`
+		EgressPolicy      *EgressPolicy    `json:"egress_policy"`
 	}
 	if err := json.Unmarshal(data, &jsonData); err != nil {
 		return err
@@ -76,6 +119,9 @@ func (proposal *ServiceProposal) UnmarshalJSON(data []byte) error {
 	proposal.ServiceType = jsonData.ServiceType
 	proposal.ProviderID = jsonData.ProviderID
 	proposal.PaymentMethodType = jsonData.PaymentMethodType
+	proposal.Load = jsonData.Load
+	proposal.Version = jsonData.Version
+	proposal.EgressPolicy = jsonData.EgressPolicy
 
 	// run the service definition implementation from our registry
 	proposal.ServiceDefinition = unserializeServiceDefinition(
@@ -95,15 +141,92 @@ func (proposal *ServiceProposal) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// SetProviderContact updates service proposal description with general data
-func (proposal *ServiceProposal) SetProviderContact(providerID identity.Identity, providerContact Contact) {
+// SetProviderContact updates service proposal description with general data.
+// Each service instance run by provider is given a distinct proposal ID.
+func (proposal *ServiceProposal) SetProviderContact(id int, providerID identity.Identity, providerContact Contact) {
 	proposal.Format = proposalFormat
-	// TODO This will be generated later
-	proposal.ID = 1
+	proposal.ID = id
 	proposal.ProviderID = providerID.Address
 	proposal.ProviderContacts = ContactList{providerContact}
 }
 
+// WithEditableTerms returns copy of the proposal, which takes price and location from given proposal.
+// Other terms, e.g. bandwidth or egress policy, are enforced by the running service, so they are kept.
+// Service definition terms other than location may be omitted, but changing them is refused.
+func (proposal ServiceProposal) WithEditableTerms(edited ServiceProposal) (ServiceProposal, error) {
+	definition, err := relocateServiceDefinition(proposal.ServiceType, proposal.ServiceDefinition, edited.ServiceDefinition.GetLocation())
+	if err != nil {
+		return ServiceProposal{}, err
+	}
+	if err := checkEnforcedTerms(proposal.ServiceDefinition, edited.ServiceDefinition); err != nil {
+		return ServiceProposal{}, err
+	}
+
+	proposal.ServiceDefinition = definition
+	proposal.PaymentMethodType = edited.PaymentMethodType
+	proposal.PaymentMethod = edited.PaymentMethod
+	return proposal, nil
+}
+
+// checkEnforcedTerms makes sure that edited service definition gives the same terms as the current one, except location.
+// Terms left out of edited definition are kept as they are.
+func checkEnforcedTerms(current, edited ServiceDefinition) error {
+	currentFields, err := definitionFields(current)
+	if err != nil {
+		return err
+	}
+	editedFields, err := definitionFields(edited)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range editedFields {
+		if name == "location" || name == "location_originate" {
+			continue
+		}
+		if !bytes.Equal(currentFields[name], value) {
+			return ErrServiceDefinitionNotEditable
+		}
+	}
+	return nil
+}
+
+// definitionFields returns serialized fields of service definition by their names
+func definitionFields(definition ServiceDefinition) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// relocateServiceDefinition returns copy of service definition, which is provided from given location
+func relocateServiceDefinition(serviceType string, definition ServiceDefinition, location Location) (ServiceDefinition, error) {
+	fields, err := definitionFields(definition)
+	if err != nil {
+		return nil, err
+	}
+	fields["location"], err = json.Marshal(location)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	message := json.RawMessage(data)
+	relocated := unserializeServiceDefinition(serviceType, &message)
+	if _, unsupported := relocated.(UnsupportedServiceDefinition); unsupported {
+		return nil, ErrServiceDefinitionNotRelocatable
+	}
+	return relocated, nil
+}
+
 // IsSupported returns true if this service proposal can be used for connections by service consumer
 // can be used as a filter to filter out all proposals which are unsupported for any reason
 func (proposal *ServiceProposal) IsSupported() bool {
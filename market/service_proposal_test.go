@@ -37,12 +37,12 @@ var (
 
 func Test_ServiceProposal_SetProviderContact(t *testing.T) {
 	proposal := ServiceProposal{ID: 123, ProviderID: "123"}
-	proposal.SetProviderContact(providerID, providerContact)
+	proposal.SetProviderContact(2, providerID, providerContact)
 
 	assert.Exactly(
 		t,
 		ServiceProposal{
-			ID:               1,
+			ID:               2,
 			Format:           proposalFormat,
 			ProviderID:       providerID.Address,
 			ProviderContacts: ContactList{providerContact},
@@ -58,6 +58,15 @@ func (service mockServiceDefinition) GetLocation() Location {
 	return Location{}
 }
 
+type locatedServiceDefinition struct {
+	Location  Location `json:"location"`
+	Bandwidth int      `json:"bandwidth"`
+}
+
+func (service locatedServiceDefinition) GetLocation() Location {
+	return service.Location
+}
+
 type mockPaymentMethod struct {
 }
 
@@ -74,6 +83,14 @@ func init() {
 			return serviceDefinition, nil
 		},
 	)
+	RegisterServiceDefinitionUnserializer(
+		"located_service",
+		func(rawDefinition *json.RawMessage) (ServiceDefinition, error) {
+			var definition locatedServiceDefinition
+			err := json.Unmarshal(*rawDefinition, &definition)
+			return definition, err
+		},
+	)
 	RegisterPaymentMethodUnserializer(
 		"mock_payment",
 		func(rawDefinition *json.RawMessage) (PaymentMethod, error) {
@@ -152,6 +169,52 @@ func Test_ServiceProposal_Unserialize(t *testing.T) {
 	assert.True(t, actual.IsSupported())
 }
 
+func Test_ServiceProposal_UnserializeLoad(t *testing.T) {
+	jsonData := []byte(`{
+		"service_type": "mock_service",
+		"load": { "sessions": 3, "max_sessions": 3 }
+	}`)
+
+	var actual ServiceProposal
+	err := json.Unmarshal(jsonData, &actual)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &ServiceLoad{Sessions: 3, MaxSessions: 3}, actual.Load)
+	assert.True(t, actual.Load.IsFull())
+}
+
+func Test_ServiceProposal_UnserializeVersion(t *testing.T) {
+	jsonData := []byte(`{
+		"service_type": "mock_service",
+		"version": 3
+	}`)
+
+	var actual ServiceProposal
+	err := json.Unmarshal(jsonData, &actual)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, actual.Version)
+}
+
+func Test_ServiceProposal_UnserializeEgressPolicy(t *testing.T) {
+	jsonData := []byte(`{
+		"service_type": "mock_service",
+		"egress_policy": { "blocked_ports": [25], "blocked_networks": ["10.0.0.0/8"] }
+	}`)
+
+	var actual ServiceProposal
+	err := json.Unmarshal(jsonData, &actual)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &EgressPolicy{BlockedPorts: []int{25}, BlockedNetworks: []string{"10.0.0.0/8"}}, actual.EgressPolicy)
+}
+
+func Test_ServiceLoad_IsFull(t *testing.T) {
+	assert.False(t, ServiceLoad{Sessions: 100}.IsFull())
+	assert.False(t, ServiceLoad{Sessions: 1, MaxSessions: 2}.IsFull())
+	assert.True(t, ServiceLoad{Sessions: 2, MaxSessions: 2}.IsFull())
+}
+
 func Test_ServiceProposal_UnserializeUnknownService(t *testing.T) {
 	jsonData := []byte(`{
 		"service_type": "unknown",
@@ -190,3 +253,45 @@ func Test_ServiceProposal_RegisterPaymentMethodUnserializer(t *testing.T) {
 
 	assert.True(t, exists)
 }
+
+func Test_ServiceProposal_WithEditableTermsKeepsEnforcedTerms(t *testing.T) {
+	current := ServiceProposal{
+		ID:                1,
+		ServiceType:       "located_service",
+		ServiceDefinition: locatedServiceDefinition{Location: Location{Country: "LT"}, Bandwidth: 10},
+		EgressPolicy:      &EgressPolicy{BlockedPorts: []int{25}},
+		Version:           1,
+	}
+	edited := ServiceProposal{
+		ServiceType:       "located_service",
+		ServiceDefinition: locatedServiceDefinition{Location: Location{Country: "DE"}, Bandwidth: 10},
+		PaymentMethodType: "mock_payment",
+		PaymentMethod:     paymentMethod,
+	}
+
+	updated, err := current.WithEditableTerms(edited)
+	assert.NoError(t, err)
+	assert.Equal(t, locatedServiceDefinition{Location: Location{Country: "DE"}, Bandwidth: 10}, updated.ServiceDefinition)
+	assert.Equal(t, "mock_payment", updated.PaymentMethodType)
+	assert.Equal(t, paymentMethod, updated.PaymentMethod)
+	assert.Equal(t, &EgressPolicy{BlockedPorts: []int{25}}, updated.EgressPolicy)
+	assert.Equal(t, 1, updated.ID)
+	assert.Equal(t, 1, updated.Version)
+
+	_, err = ServiceProposal{ServiceType: "unknown", ServiceDefinition: serviceDefinition}.WithEditableTerms(edited)
+	assert.Equal(t, ErrServiceDefinitionNotRelocatable, err)
+}
+
+func Test_ServiceProposal_WithEditableTermsRefusesBandwidthChange(t *testing.T) {
+	current := ServiceProposal{
+		ServiceType:       "located_service",
+		ServiceDefinition: locatedServiceDefinition{Location: Location{Country: "LT"}, Bandwidth: 10},
+	}
+	edited := ServiceProposal{
+		ServiceType:       "located_service",
+		ServiceDefinition: locatedServiceDefinition{Location: Location{Country: "DE"}, Bandwidth: 1000},
+	}
+
+	_, err := current.WithEditableTerms(edited)
+	assert.Equal(t, ErrServiceDefinitionNotEditable, err)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/mysteriumnetwork/go-openvpn/openvpn3"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/session"
@@ -66,6 +67,11 @@ func (wrapper *sessionWrapper) GetConfig() (connection.ConsumerConfig, error) {
 	return nil, nil
 }
 
+// GetTunnel returns no tunnel, traffic restrictions on mobile devices are managed by the OS VPN service
+func (wrapper *sessionWrapper) GetTunnel() (firewall.Tunnel, error) {
+	return firewall.Tunnel{}, nil
+}
+
 func channelToCallbacks(stateChannel connection.StateChannel, statisticsChannel connection.StatisticsChannel) openvpn3.MobileSessionCallbacks {
 	return channelToCallbacksAdapter{
 		stateChannel:      stateChannel,
@@ -153,7 +159,7 @@ type OpenvpnConnectionFactory struct {
 // Create creates a new openvpn connection
 func (ocf *OpenvpnConnectionFactory) Create(stateChannel connection.StateChannel, statisticsChannel connection.StatisticsChannel) (connection.Connection, error) {
 	sessionFactory := func(options connection.ConnectOptions) (*openvpn3.Session, error) {
-		vpnClientConfig, err := openvpn.NewClientConfigFromSession(options.SessionConfig, "", "")
+		vpnClientConfig, err := openvpn.NewClientConfigFromSession(options, "", "")
 		if err != nil {
 			return nil, err
 		}
@@ -29,6 +29,7 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
 	"github.com/pkg/errors"
@@ -76,8 +77,9 @@ func (wcf *WireguardConnectionFactory) Create(stateChannel connection.StateChann
 
 		wcf.tunnelSetup.NewTunnel()
 		wcf.tunnelSetup.SetSessionName("wg-tun-session")
-		//TODO fetch from user connection options
-		wcf.tunnelSetup.AddDNS("8.8.8.8")
+		for _, server := range options.DNS {
+			wcf.tunnelSetup.AddDNS(server.String())
+		}
 
 		//TODO this heavy linfting might go to doInit
 		tun, err := newTunnDevice(wcf.tunnelSetup, &config)
@@ -255,6 +257,20 @@ func (wg *wireguardConnection) GetConfig() (connection.ConsumerConfig, error) {
 	}, nil
 }
 
+// GetTunnel returns no tunnel, traffic restrictions on mobile devices are managed by the OS VPN service
+func (wg *wireguardConnection) GetTunnel() (firewall.Tunnel, error) {
+	return firewall.Tunnel{}, nil
+}
+
+// LocateDNS returns DNS servers advertised by provider in session config
+func (wg *wireguardConnection) LocateDNS(sessionConfig json.RawMessage) ([]net.IP, error) {
+	var config wireguard.ServiceConfig
+	if err := json.Unmarshal(sessionConfig, &config); err != nil {
+		return nil, err
+	}
+	return config.Provider.DNS, nil
+}
+
 var _ connection.Connection = &wireguardConnection{}
 
 func (wg *wireguardConnection) updateStatistics() {
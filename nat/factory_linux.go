@@ -27,5 +27,10 @@ func NewService() NATService {
 			CommandDisable: exec.Command("sudo", "/sbin/sysctl", "-w", "net.ipv4.ip_forward=0"),
 			CommandRead:    exec.Command("/sbin/sysctl", "-n", "net.ipv4.ip_forward"),
 		},
+		ip6Forward: serviceIPForward{
+			CommandEnable:  exec.Command("sudo", "/sbin/sysctl", "-w", "net.ipv6.conf.all.forwarding=1"),
+			CommandDisable: exec.Command("sudo", "/sbin/sysctl", "-w", "net.ipv6.conf.all.forwarding=0"),
+			CommandRead:    exec.Command("/sbin/sysctl", "-n", "net.ipv6.conf.all.forwarding"),
+		},
 	}
 }
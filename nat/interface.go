@@ -17,15 +17,40 @@
 
 package nat
 
+import (
+	"net"
+	"strings"
+)
+
 // NATService describes fake nat service for darwin
 type NATService interface {
 	Add(rule RuleForwarding)
+	Remove(rule RuleForwarding)
+	// RemoveStale removes rules of sources within given networks, which were left by previous run of the node.
+	// Rules of sources still in use are kept.
+	RemoveStale(within []net.IPNet, inUse func(source net.IPNet) bool)
 	Start() error
 	Stop()
 }
 
-// RuleForwarding describes fake nat rule
+// RuleForwarding describes fake nat rule.
+// Addresses are either both IPv4 or both IPv6, IPv6 rules are applied with IPv6 tools.
 type RuleForwarding struct {
 	SourceAddress string
 	TargetIP      string
 }
+
+// IsIPv6 tells if rule forwards IPv6 traffic
+func (rule RuleForwarding) IsIPv6() bool {
+	return strings.Contains(rule.TargetIP, ":")
+}
+
+func withoutRule(rules []RuleForwarding, rule RuleForwarding) []RuleForwarding {
+	result := make([]RuleForwarding, 0, len(rules))
+	for _, existing := range rules {
+		if existing != rule {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
@@ -17,12 +17,20 @@
 
 package nat
 
+import "net"
+
 type serviceFake struct {
 }
 
 func (service *serviceFake) Add(rule RuleForwarding) {
 }
 
+func (service *serviceFake) Remove(rule RuleForwarding) {
+}
+
+func (service *serviceFake) RemoveStale(within []net.IPNet, inUse func(source net.IPNet) bool) {
+}
+
 func (service *serviceFake) Start() error {
 	return nil
 }
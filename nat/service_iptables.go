@@ -18,6 +18,9 @@
 package nat
 
 import (
+	"net"
+	"strings"
+
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/utils"
 )
@@ -25,19 +28,52 @@ import (
 const natLogPrefix = "[nat] "
 
 type serviceIPTables struct {
-	rules     []RuleForwarding
-	ipForward serviceIPForward
+	rules      []RuleForwarding
+	ipForward  serviceIPForward
+	ip6Forward serviceIPForward
 }
 
 func (service *serviceIPTables) Add(rule RuleForwarding) {
 	service.rules = append(service.rules, rule)
 }
 
+// Remove stops forwarding of given rule, even if it was left by previous run of the node
+func (service *serviceIPTables) Remove(rule RuleForwarding) {
+	service.rules = withoutRule(service.rules, rule)
+	service.disableRule(rule)
+}
+
+// RemoveStale removes SNAT rules of sources within given networks, which aren't in use by this or other services
+func (service *serviceIPTables) RemoveStale(within []net.IPNet, inUse func(source net.IPNet) bool) {
+	for _, binary := range []string{"/sbin/iptables", "/sbin/ip6tables"} {
+		cmd := utils.SplitCommand("sudo", binary+" --table nat --list-rules POSTROUTING")
+		output, err := cmd.Output()
+		if err != nil {
+			log.Warn(natLogPrefix, "Failed to list NAT rules: ", cmd.Args, " Returned exit error: ", err)
+			continue
+		}
+
+		for _, rule := range parseSNATRules(string(output)) {
+			_, source, err := net.ParseCIDR(rule.SourceAddress)
+			if err != nil || !containsNetwork(within, *source) || inUse(*source) {
+				continue
+			}
+			log.Info(natLogPrefix, "Removing stale forwarding rule of '", rule.SourceAddress, "'")
+			service.disableRule(rule)
+		}
+	}
+}
+
 func (service *serviceIPTables) Start() error {
 	err := service.ipForward.Enable()
 	if err != nil {
 		log.Warn(natLogPrefix, "Failed to enable IP forwarding: ", err)
 	}
+	if service.hasIPv6Rules() {
+		if err := service.ip6Forward.Enable(); err != nil {
+			log.Warn(natLogPrefix, "Failed to enable IPv6 forwarding: ", err)
+		}
+	}
 
 	service.clearStaleRules()
 	return service.enableRules()
@@ -46,11 +82,30 @@ func (service *serviceIPTables) Start() error {
 func (service *serviceIPTables) Stop() {
 	service.disableRules()
 	service.ipForward.Disable()
+	if service.hasIPv6Rules() {
+		service.ip6Forward.Disable()
+	}
+}
+
+func (service *serviceIPTables) hasIPv6Rules() bool {
+	for _, rule := range service.rules {
+		if rule.IsIPv6() {
+			return true
+		}
+	}
+	return false
+}
+
+func iptablesBinary(rule RuleForwarding) string {
+	if rule.IsIPv6() {
+		return "/sbin/ip6tables"
+	}
+	return "/sbin/iptables"
 }
 
 func (service *serviceIPTables) enableRules() error {
 	for _, rule := range service.rules {
-		arguments := "/sbin/iptables --table nat --append POSTROUTING --source " +
+		arguments := iptablesBinary(rule) + " --table nat --append POSTROUTING --source " +
 			rule.SourceAddress + " ! --destination " +
 			rule.SourceAddress + " --jump SNAT --to " +
 			rule.TargetIP
@@ -67,19 +122,47 @@ func (service *serviceIPTables) enableRules() error {
 
 func (service *serviceIPTables) disableRules() {
 	for _, rule := range service.rules {
-		arguments := "/sbin/iptables --table nat --delete POSTROUTING --source " +
-			rule.SourceAddress + " ! --destination " +
-			rule.SourceAddress + " --jump SNAT --to " +
-			rule.TargetIP
-		cmd := utils.SplitCommand("sudo", arguments)
-		if output, err := cmd.CombinedOutput(); err != nil {
-			log.Warn("Failed to delete ip forwarding rule: ", cmd.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
-		} else {
-			log.Info(natLogPrefix, "Stopped forwarding packets from '", rule.SourceAddress, "' to IP: ", rule.TargetIP)
-		}
+		service.disableRule(rule)
+	}
+}
+
+func (service *serviceIPTables) disableRule(rule RuleForwarding) {
+	arguments := iptablesBinary(rule) + " --table nat --delete POSTROUTING --source " +
+		rule.SourceAddress + " ! --destination " +
+		rule.SourceAddress + " --jump SNAT --to " +
+		rule.TargetIP
+	cmd := utils.SplitCommand("sudo", arguments)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Warn("Failed to delete ip forwarding rule: ", cmd.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
+	} else {
+		log.Info(natLogPrefix, "Stopped forwarding packets from '", rule.SourceAddress, "' to IP: ", rule.TargetIP)
 	}
 }
 
 func (service *serviceIPTables) clearStaleRules() {
 	service.disableRules()
 }
+
+// parseSNATRules picks forwarding rules out of iptables rule listing,
+// e.g. "-A POSTROUTING -s 10.182.1.0/24 ! -d 10.182.1.0/24 -j SNAT --to-source 1.2.3.4"
+func parseSNATRules(listing string) []RuleForwarding {
+	var rules []RuleForwarding
+	for _, line := range strings.Split(listing, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 11 || fields[0] != "-A" || fields[2] != "-s" || fields[4] != "!" || fields[5] != "-d" ||
+			fields[3] != fields[6] || fields[8] != "SNAT" || fields[9] != "--to-source" {
+			continue
+		}
+		rules = append(rules, RuleForwarding{SourceAddress: fields[3], TargetIP: fields[10]})
+	}
+	return rules
+}
+
+func containsNetwork(networks []net.IPNet, subnet net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(subnet.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSNATRules(t *testing.T) {
+	listing := `-P POSTROUTING ACCEPT
+-A POSTROUTING -s 10.182.1.0/24 ! -d 10.182.1.0/24 -j SNAT --to-source 1.2.3.4
+-A POSTROUTING -o eth0 -j MASQUERADE
+-A POSTROUTING -s 10.8.0.0/24 ! -d 10.8.0.0/24 -j SNAT --to-source 1.2.3.4
+`
+
+	assert.Equal(
+		t,
+		[]RuleForwarding{
+			{SourceAddress: "10.182.1.0/24", TargetIP: "1.2.3.4"},
+			{SourceAddress: "10.8.0.0/24", TargetIP: "1.2.3.4"},
+		},
+		parseSNATRules(listing),
+	)
+}
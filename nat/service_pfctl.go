@@ -37,6 +37,19 @@ func (service *servicePFCtl) Add(rule RuleForwarding) {
 	service.rules = append(service.rules, rule)
 }
 
+// Remove stops forwarding of given rule, remaining rules are reapplied as pfctl flushes all of them at once
+func (service *servicePFCtl) Remove(rule RuleForwarding) {
+	service.rules = withoutRule(service.rules, rule)
+	service.clearStaleRules()
+	if err := service.enableRules(); err != nil {
+		log.Warn(natLogPrefix, "Failed to reapply NAT rules: ", err)
+	}
+}
+
+// RemoveStale does nothing, rules left by previous run of the node are flushed by the first start
+func (service *servicePFCtl) RemoveStale(within []net.IPNet, inUse func(source net.IPNet) bool) {
+}
+
 func (service *servicePFCtl) Start() error {
 	err := service.ipForward.Enable()
 	if err != nil {
@@ -78,7 +91,11 @@ func (service *servicePFCtl) enableRules() error {
 		if err != nil {
 			return err
 		}
-		natRule := fmt.Sprintf("nat on %v inet from %v to any -> %v", iface, rule.SourceAddress, rule.TargetIP)
+		family := "inet"
+		if rule.IsIPv6() {
+			family = "inet6"
+		}
+		natRule := fmt.Sprintf("nat on %v %v from %v to any -> %v", iface, family, rule.SourceAddress, rule.TargetIP)
 		arguments := fmt.Sprintf(`echo "%v" | /sbin/pfctl -vEf -`, natRule)
 		cmd := exec.Command(
 			"sh",
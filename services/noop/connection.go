@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/firewall"
 )
 
 // Connection which does no real tunneling
@@ -69,3 +70,8 @@ func (c *Connection) Stop() {
 func (c *Connection) GetConfig() (connection.ConsumerConfig, error) {
 	return nil, nil
 }
+
+// GetTunnel returns no tunnel, noop connection does not route any traffic
+func (c *Connection) GetTunnel() (firewall.Tunnel, error) {
+	return firewall.Tunnel{}, nil
+}
@@ -57,6 +57,11 @@ func (manager *Manager) Serve(providerID identity.Identity) error {
 	return nil
 }
 
+// RecoverSession re-adopts session which outlived the node, noop sessions hold no resources
+func (manager *Manager) RecoverSession(session.Session) (session.DestroyCallback, error) {
+	return nil, nil
+}
+
 // Stop stops service
 func (manager *Manager) Stop() error {
 	manager.process.Done()
@@ -18,10 +18,14 @@
 package openvpn
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/firewall"
 )
 
 // ErrProcessNotStarted represents the error we return when the process is not started yet
@@ -34,6 +38,7 @@ type processFactory func(options connection.ConnectOptions) (openvpn.Process, er
 type Client struct {
 	process        openvpn.Process
 	processFactory processFactory
+	sessionConfig  []byte
 }
 
 // Start starts the connection
@@ -43,6 +48,7 @@ func (c *Client) Start(options connection.ConnectOptions) error {
 		return err
 	}
 	c.process = proc
+	c.sessionConfig = options.SessionConfig
 	return c.process.Start()
 }
 
@@ -66,6 +72,57 @@ func (c *Client) GetConfig() (connection.ConsumerConfig, error) {
 	return nil, nil
 }
 
+// LocateEndpoint returns address of openvpn server given in session config
+func (c *Client) LocateEndpoint(sessionConfig json.RawMessage) (net.IP, error) {
+	vpnConfig := &VPNConfig{}
+	if err := json.Unmarshal(sessionConfig, vpnConfig); err != nil {
+		return nil, err
+	}
+
+	remote, err := net.ResolveIPAddr("ip4", vpnConfig.RemoteIP)
+	if err != nil {
+		return nil, err
+	}
+	return remote.IP, nil
+}
+
+// LocateDNS returns DNS servers advertised by provider in session config
+func (c *Client) LocateDNS(sessionConfig json.RawMessage) ([]net.IP, error) {
+	vpnConfig := &VPNConfig{}
+	if err := json.Unmarshal(sessionConfig, vpnConfig); err != nil {
+		return nil, err
+	}
+
+	var servers []net.IP
+	for _, address := range vpnConfig.DNS {
+		server := net.ParseIP(address)
+		if server == nil {
+			return nil, fmt.Errorf("invalid DNS server address %q", address)
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// GetTunnel returns openvpn tunnel description. Tun device is named by openvpn itself, so any tun interface is matched
+func (c *Client) GetTunnel() (firewall.Tunnel, error) {
+	vpnConfig := &VPNConfig{}
+	if err := json.Unmarshal(c.sessionConfig, vpnConfig); err != nil {
+		return firewall.Tunnel{}, err
+	}
+
+	remote, err := net.ResolveIPAddr("ip4", vpnConfig.RemoteIP)
+	if err != nil {
+		return firewall.Tunnel{}, err
+	}
+
+	return firewall.Tunnel{
+		Interface: "tun+",
+		Endpoint:  remote.IP,
+		IPv6:      vpnConfig.IPv6,
+	}, nil
+}
+
 //VPNConfig structure represents VPN configuration options for given session
 type VPNConfig struct {
 	RemoteIP        string `json:"remote"`
@@ -73,4 +130,7 @@ type VPNConfig struct {
 	RemoteProtocol  string `json:"protocol"`
 	TLSPresharedKey string `json:"TLSPresharedKey"`
 	CACertificate   string `json:"CACertificate"`
+	IPv6            bool   `json:"ipv6,omitempty"`
+	// DNS are servers advertised by provider, which consumer queries through the tunnel
+	DNS []string `json:"dns,omitempty"`
 }
@@ -19,8 +19,11 @@ package openvpn
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/config"
+	"github.com/mysteriumnetwork/node/core/connection"
 )
 
 // ClientConfig represents specific "openvpn as client" configuration
@@ -41,6 +44,31 @@ func (c *ClientConfig) SetClientMode(serverIP string, serverPort int) {
 	c.SetFlag("management-query-passwords")
 }
 
+// SetRoutes routes all traffic through the tunnel, or only the included networks if any are given.
+// Excluded networks are routed through the original gateway. IPv6 traffic is redirected too if tunnel carries it
+func (c *ClientConfig) SetRoutes(routes connection.Routes, ipv6 bool) {
+	if len(routes.Include) == 0 {
+		if ipv6 {
+			c.SetParam("redirect-gateway", "def1", "bypass-dhcp", "ipv6")
+		} else {
+			c.SetParam("redirect-gateway", "def1", "bypass-dhcp")
+		}
+	}
+	for _, network := range routes.Include {
+		c.SetParam("route", network.IP.String(), net.IP(network.Mask).String(), "vpn_gateway")
+	}
+	for _, network := range routes.Exclude {
+		c.SetParam("route", network.IP.String(), net.IP(network.Mask).String(), "net_gateway")
+	}
+}
+
+// SetDNS makes openvpn configure given DNS servers, system resolvers stay untouched if none are given
+func (c *ClientConfig) SetDNS(servers []net.IP) {
+	for _, server := range servers {
+		c.SetParam("dhcp-option", "DNS", server.String())
+	}
+}
+
 // SetProtocol specifies openvpn connection protocol type (tcp or udp)
 func (c *ClientConfig) SetProtocol(protocol string) {
 	if protocol == "tcp" {
@@ -63,19 +91,21 @@ func defaultClientConfig(runtimeDir string, scriptSearchPath string) *ClientConf
 
 	clientConfig.SetParam("reneg-sec", "60")
 	clientConfig.SetParam("resolv-retry", "infinite")
-	clientConfig.SetParam("redirect-gateway", "def1", "bypass-dhcp")
-	clientConfig.SetParam("dhcp-option", "DNS", "208.67.222.222")
-	clientConfig.SetParam("dhcp-option", "DNS", "208.67.220.220")
 
 	return &clientConfig
 }
 
-// NewClientConfigFromSession creates client configuration structure for given VPNConfig, configuration dir to store serialized file args, and
-// configuration filename to store other args
+// NewClientConfigFromSession creates client configuration structure for given connect options with VPNConfig of session,
+// configuration dir to store serialized file args, and configuration filename to store other args
 // TODO this will become the part of openvpn service consumer separate package
-func NewClientConfigFromSession(sessionConfig []byte, configDir string, runtimeDir string) (*ClientConfig, error) {
+func NewClientConfigFromSession(options connection.ConnectOptions, configDir string, runtimeDir string) (*ClientConfig, error) {
+	// openvpn routes its remote via default gateway, it can't be chained through tunnel of previous hop
+	if options.Routes.Via != "" {
+		return nil, errors.New("openvpn connection can not be chained through another hop")
+	}
+
 	vpnConfig := &VPNConfig{}
-	err := json.Unmarshal(sessionConfig, vpnConfig)
+	err := json.Unmarshal(options.SessionConfig, vpnConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +121,8 @@ func NewClientConfigFromSession(sessionConfig []byte, configDir string, runtimeD
 	clientFileConfig.SetProtocol(vpnConfig.RemoteProtocol)
 	clientFileConfig.SetTLSCACertificate(vpnConfig.CACertificate)
 	clientFileConfig.SetTLSCrypt(vpnConfig.TLSPresharedKey)
+	clientFileConfig.SetRoutes(options.Routes, vpnConfig.IPv6)
+	clientFileConfig.SetDNS(options.DNS)
 
 	return clientFileConfig, nil
 }
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package openvpn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/config"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRoutesRedirectsAllTrafficByDefault(t *testing.T) {
+	clientConfig := ClientConfig{config.NewConfig("", "")}
+	clientConfig.SetRoutes(connection.Routes{}, false)
+
+	arguments, err := clientConfig.ToArguments()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--redirect-gateway", "def1", "bypass-dhcp"}, arguments)
+}
+
+func TestSetRoutesRedirectsIPv6TrafficIfTunnelled(t *testing.T) {
+	clientConfig := ClientConfig{config.NewConfig("", "")}
+	clientConfig.SetRoutes(connection.Routes{}, true)
+
+	arguments, err := clientConfig.ToArguments()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--redirect-gateway", "def1", "bypass-dhcp", "ipv6"}, arguments)
+}
+
+func TestSetRoutesRoutesOnlyIncludedNetworksAndBypassesExcluded(t *testing.T) {
+	clientConfig := ClientConfig{config.NewConfig("", "")}
+	clientConfig.SetRoutes(connection.Routes{
+		Include: []net.IPNet{parseNetwork("10.0.0.0/8")},
+		Exclude: []net.IPNet{parseNetwork("10.1.0.0/16")},
+	}, true)
+
+	arguments, err := clientConfig.ToArguments()
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"--route", "10.0.0.0", "255.0.0.0", "vpn_gateway",
+			"--route", "10.1.0.0", "255.255.0.0", "net_gateway",
+		},
+		arguments,
+	)
+}
+
+func TestSetDNSConfiguresGivenServers(t *testing.T) {
+	clientConfig := ClientConfig{config.NewConfig("", "")}
+	clientConfig.SetDNS([]net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("8.8.8.8")})
+
+	arguments, err := clientConfig.ToArguments()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--dhcp-option", "DNS", "1.1.1.1", "--dhcp-option", "DNS", "8.8.8.8"}, arguments)
+}
+
+func parseNetwork(cidr string) net.IPNet {
+	_, network, _ := net.ParseCIDR(cidr)
+	return *network
+}
+
+func TestNewClientConfigFromSessionRejectsChainedConnection(t *testing.T) {
+	options := connection.ConnectOptions{Routes: connection.Routes{Via: "wg0"}}
+
+	_, err := NewClientConfigFromSession(options, "config-dir", "runtime-dir")
+
+	assert.EqualError(t, err, "openvpn connection can not be chained through another hop")
+}
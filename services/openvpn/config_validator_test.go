@@ -67,6 +67,8 @@ func TestValidatorReturnsNilErrorOnValidVPNConfig(t *testing.T) {
 		"tcp",
 		tlsTestKey,
 		caCertificate,
+		false,
+		nil,
 	}
 	assert.NoError(t, NewDefaultValidator().IsValid(vpnConfig))
 }
@@ -75,7 +75,7 @@ func (op *ProcessBasedConnectionFactory) newStateMiddleware(session session.ID,
 // Create creates a new openvpnn connection
 func (op *ProcessBasedConnectionFactory) Create(stateChannel connection.StateChannel, statisticsChannel connection.StatisticsChannel) (connection.Connection, error) {
 	procFactory := func(options connection.ConnectOptions) (openvpn.Process, error) {
-		vpnClientConfig, err := NewClientConfigFromSession(options.SessionConfig, op.configDirectory, op.runtimeDirectory)
+		vpnClientConfig, err := NewClientConfigFromSession(options, op.configDirectory, op.runtimeDirectory)
 		if err != nil {
 			return nil, err
 		}
@@ -18,6 +18,8 @@
 package openvpn
 
 import (
+	"encoding/json"
+	"net"
 	"reflect"
 	"testing"
 
@@ -89,3 +91,18 @@ func TestOpenVpnStateCallbackToConnectionState(t *testing.T) {
 		})
 	}
 }
+
+func TestClientLocatesDNSAdvertisedInSessionConfig(t *testing.T) {
+	client := &Client{}
+
+	servers, err := client.LocateDNS(json.RawMessage(`{"remote": "1.2.3.4", "dns": ["8.8.8.8"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("8.8.8.8")}, servers)
+
+	servers, err = client.LocateDNS(json.RawMessage(`{"remote": "1.2.3.4"}`))
+	assert.NoError(t, err)
+	assert.Empty(t, servers)
+
+	_, err = client.LocateDNS(json.RawMessage(`{"remote": "1.2.3.4", "dns": ["resolver"]}`))
+	assert.EqualError(t, err, `invalid DNS server address "resolver"`)
+}
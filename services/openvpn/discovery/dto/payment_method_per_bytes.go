@@ -37,3 +37,8 @@ type PaymentPerBytes struct {
 func (method PaymentPerBytes) GetPrice() money.Money {
 	return method.Price
 }
+
+// GetBytes returns amount of data provided for the price
+func (method PaymentPerBytes) GetBytes() datasize.BitSize {
+	return method.Bytes
+}
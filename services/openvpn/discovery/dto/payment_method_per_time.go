@@ -38,3 +38,8 @@ type PaymentPerTime struct {
 func (method PaymentPerTime) GetPrice() money.Money {
 	return method.Price
 }
+
+// GetDuration returns service duration provided for the price
+func (method PaymentPerTime) GetDuration() time.Duration {
+	return method.Duration
+}
@@ -30,6 +30,12 @@ type ServiceDefinition struct {
 	// Available per session bandwidth
 	SessionBandwidth Bandwidth `json:"session_bandwidth,omitempty"`
 
+	// Upload rate limit of each session, upload is not limited if empty
+	SessionUploadBandwidth Bandwidth `json:"session_upload_bandwidth,omitempty"`
+
+	// Download rate limit of each session, download is not limited if empty
+	SessionDownloadBandwidth Bandwidth `json:"session_download_bandwidth,omitempty"`
+
 	// Transport protocol used by service
 	Protocol string `json:"protocol,omitempty"`
 }
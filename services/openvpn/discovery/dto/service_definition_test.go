@@ -40,10 +40,12 @@ func TestServiceDefinitionSerialize(t *testing.T) {
 	}{
 		{
 			ServiceDefinition{
-				Location:          locationUS,
-				LocationOriginate: locationUS,
-				SessionBandwidth:  Bandwidth(10 * datasize.Bit),
-				Protocol:          protocol,
+				Location:                 locationUS,
+				LocationOriginate:        locationUS,
+				SessionBandwidth:         Bandwidth(10 * datasize.Bit),
+				SessionUploadBandwidth:   Bandwidth(1 * datasize.Byte),
+				SessionDownloadBandwidth: Bandwidth(2 * datasize.Byte),
+				Protocol:                 protocol,
 			},
 			`{
 				"location": {
@@ -53,6 +55,8 @@ func TestServiceDefinitionSerialize(t *testing.T) {
 					"country": "US"
 				},
 				"session_bandwidth": 10,
+				"session_upload_bandwidth": 8,
+				"session_download_bandwidth": 16,
 				"protocol": "tcp"
 			}`,
 		},
@@ -88,13 +92,17 @@ func TestServiceDefinitionUnserialize(t *testing.T) {
 					"country": "US"
 				},
 				"session_bandwidth": 8,
+				"session_upload_bandwidth": 8,
+				"session_download_bandwidth": 16,
 				"protocol": "tcp"
 			}`,
 			ServiceDefinition{
-				Location:          locationUS,
-				LocationOriginate: locationUS,
-				SessionBandwidth:  Bandwidth(1 * datasize.Byte),
-				Protocol:          protocol,
+				Location:                 locationUS,
+				LocationOriginate:        locationUS,
+				SessionBandwidth:         Bandwidth(1 * datasize.Byte),
+				SessionUploadBandwidth:   Bandwidth(1 * datasize.Byte),
+				SessionDownloadBandwidth: Bandwidth(2 * datasize.Byte),
+				Protocol:                 protocol,
 			},
 			nil,
 		},
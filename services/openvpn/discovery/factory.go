@@ -21,24 +21,30 @@ import (
 	"time"
 
 	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/shaper"
 )
 
 // NewServiceProposalWithLocation creates service proposal description for openvpn service
 func NewServiceProposalWithLocation(
 	serviceLocation market.Location,
 	protocol string,
+	bandwidth shaper.Limits,
+	egress firewall.EgressPolicy,
 ) market.ServiceProposal {
 	return market.ServiceProposal{
 		ServiceType: openvpn.ServiceType,
 		ServiceDefinition: dto.ServiceDefinition{
-			Location:          serviceLocation,
-			LocationOriginate: serviceLocation,
-			SessionBandwidth:  dto.Bandwidth(10 * datasize.MB),
-			Protocol:          protocol,
+			Location:                 serviceLocation,
+			LocationOriginate:        serviceLocation,
+			SessionBandwidth:         dto.Bandwidth(10 * datasize.MB),
+			SessionUploadBandwidth:   dto.Bandwidth(bandwidth.Upload),
+			SessionDownloadBandwidth: dto.Bandwidth(bandwidth.Download),
+			Protocol:                 protocol,
 		},
 		PaymentMethodType: dto.PaymentMethodPerTime,
 		PaymentMethod: dto.PaymentPerTime{
@@ -46,5 +52,6 @@ func NewServiceProposalWithLocation(
 			Price:    money.NewMoney(0.125, money.CURRENCY_MYST),
 			Duration: 1 * time.Hour,
 		},
+		EgressPolicy: egress.Advertised(),
 	}
 }
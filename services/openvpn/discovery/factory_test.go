@@ -21,9 +21,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+	"github.com/mysteriumnetwork/node/shaper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,17 +36,23 @@ var (
 )
 
 func Test_NewServiceProposalWithLocation(t *testing.T) {
-	proposal := NewServiceProposalWithLocation(locationLTTelia, protocol)
+	proposal := NewServiceProposalWithLocation(
+		locationLTTelia,
+		protocol,
+		shaper.Limits{Download: 1 * datasize.MB},
+		firewall.EgressPolicy{BlockedPorts: []int{25}},
+	)
 
 	assert.Exactly(
 		t,
 		market.ServiceProposal{
 			ServiceType: "openvpn",
 			ServiceDefinition: dto.ServiceDefinition{
-				Location:          locationLTTelia,
-				LocationOriginate: locationLTTelia,
-				SessionBandwidth:  83886080,
-				Protocol:          "tcp",
+				Location:                 locationLTTelia,
+				LocationOriginate:        locationLTTelia,
+				SessionBandwidth:         83886080,
+				SessionDownloadBandwidth: 8388608,
+				Protocol:                 "tcp",
 			},
 
 			PaymentMethodType: "PER_TIME",
@@ -51,6 +60,7 @@ func Test_NewServiceProposalWithLocation(t *testing.T) {
 				Price:    money.Money{12500000, money.Currency("MYST")},
 				Duration: 60 * time.Minute,
 			},
+			EgressPolicy: &market.EgressPolicy{BlockedPorts: []int{25}},
 		},
 		proposal,
 	)
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bandwidth
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/shaper"
+)
+
+const logPrefix = "[openvpn-bandwidth] "
+
+var clientEventLine = regexp.MustCompile(`^>CLIENT:(\w+),(.*)$`)
+
+// NewMiddleware creates middleware, which limits bandwidth of every client connected to openvpn server
+func NewMiddleware(trafficShaper shaper.Shaper, limits shaper.Limits) management.Middleware {
+	return &middleware{
+		shaper:  trafficShaper,
+		limits:  limits,
+		clients: make(map[int]client),
+	}
+}
+
+type middleware struct {
+	shaper shaper.Shaper
+	limits shaper.Limits
+
+	// event is the client event, environment of which is being received
+	event *clientEvent
+
+	mutex   sync.Mutex
+	clients map[int]client
+}
+
+type clientEvent struct {
+	name     string
+	clientID int
+	env      map[string]string
+}
+
+type client struct {
+	iface     string
+	addresses []net.IP
+}
+
+// Start does nothing, client events are sent by openvpn server in management-client-auth mode anyway
+func (m *middleware) Start(management.Connection) error {
+	return nil
+}
+
+// Stop removes limits of clients, which are still connected
+func (m *middleware) Stop(management.Connection) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for clientID, connected := range m.clients {
+		m.unshape(connected)
+		delete(m.clients, clientID)
+	}
+	return nil
+}
+
+// ConsumeLine collects environment of established and disconnected clients to shape and unshape their traffic
+func (m *middleware) ConsumeLine(line string) (bool, error) {
+	match := clientEventLine.FindStringSubmatch(line)
+	if match == nil {
+		return false, nil
+	}
+	name, args := match[1], match[2]
+
+	switch name {
+	case "ESTABLISHED", "DISCONNECT":
+		clientID, err := strconv.Atoi(strings.Split(args, ",")[0])
+		if err != nil {
+			return true, err
+		}
+		m.event = &clientEvent{name: name, clientID: clientID, env: make(map[string]string)}
+		return true, nil
+	case "ENV":
+		if m.event == nil {
+			return false, nil
+		}
+		if args != "END" {
+			keyValue := strings.SplitN(args, "=", 2)
+			if len(keyValue) == 2 {
+				m.event.env[keyValue[0]] = keyValue[1]
+			}
+			return true, nil
+		}
+		event := m.event
+		m.event = nil
+		m.handle(*event)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (m *middleware) handle(event clientEvent) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch event.name {
+	case "ESTABLISHED":
+		connected := client{iface: event.env["dev"]}
+		for _, key := range []string{"ifconfig_pool_remote_ip", "ifconfig_pool_remote_ip6"} {
+			if address := net.ParseIP(event.env[key]); address != nil {
+				connected.addresses = append(connected.addresses, address)
+			}
+		}
+		if connected.iface == "" || len(connected.addresses) == 0 {
+			log.Warn(logPrefix, "Tunnel of client ", event.clientID, " is unknown, its bandwidth is not limited")
+			return
+		}
+
+		for _, address := range connected.addresses {
+			if err := m.shaper.Shape(connected.iface, address, m.limits); err != nil {
+				log.Error(logPrefix, "Failed to limit bandwidth of client ", event.clientID, ": ", err)
+			}
+		}
+		m.clients[event.clientID] = connected
+	case "DISCONNECT":
+		if connected, found := m.clients[event.clientID]; found {
+			m.unshape(connected)
+			delete(m.clients, event.clientID)
+		}
+	}
+}
+
+func (m *middleware) unshape(connected client) {
+	for _, address := range connected.addresses {
+		if err := m.shaper.Unshape(connected.iface, address); err != nil {
+			log.Warn(logPrefix, "Failed to remove bandwidth limits of ", address, ": ", err)
+		}
+	}
+}
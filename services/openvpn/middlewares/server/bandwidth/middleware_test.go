@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bandwidth
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/shaper"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeShaper struct {
+	shaped map[string]shaper.Limits
+}
+
+func (fs *fakeShaper) Shape(iface string, address net.IP, limits shaper.Limits) error {
+	fs.shaped[iface+" "+address.String()] = limits
+	return nil
+}
+
+func (fs *fakeShaper) Unshape(iface string, address net.IP) error {
+	delete(fs.shaped, iface+" "+address.String())
+	return nil
+}
+
+var limits = shaper.Limits{Upload: 1 * datasize.MB, Download: 2 * datasize.MB}
+
+func feedLines(t *testing.T, m *middleware, lines ...string) {
+	for _, line := range lines {
+		_, err := m.ConsumeLine(line)
+		assert.NoError(t, err)
+	}
+}
+
+func TestMiddleware_ShapesEstablishedClientUntilDisconnect(t *testing.T) {
+	fake := &fakeShaper{shaped: make(map[string]shaper.Limits)}
+	m := NewMiddleware(fake, limits).(*middleware)
+
+	feedLines(t, m,
+		">CLIENT:ESTABLISHED,7",
+		">CLIENT:ENV,username=session-id",
+		">CLIENT:ENV,dev=tun0",
+		">CLIENT:ENV,ifconfig_pool_remote_ip=10.8.0.6",
+		">CLIENT:ENV,ifconfig_pool_remote_ip6=fd6d:7973:7400:ffff::1000",
+		">CLIENT:ENV,END",
+	)
+	assert.Equal(
+		t,
+		map[string]shaper.Limits{
+			"tun0 10.8.0.6":                  limits,
+			"tun0 fd6d:7973:7400:ffff::1000": limits,
+		},
+		fake.shaped,
+	)
+
+	feedLines(t, m,
+		">CLIENT:DISCONNECT,7",
+		">CLIENT:ENV,username=session-id",
+		">CLIENT:ENV,END",
+	)
+	assert.Empty(t, fake.shaped)
+}
+
+func TestMiddleware_IgnoresOtherLines(t *testing.T) {
+	fake := &fakeShaper{shaped: make(map[string]shaper.Limits)}
+	m := NewMiddleware(fake, limits).(*middleware)
+
+	consumed, err := m.ConsumeLine(">CLIENT:CONNECT,7,1")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+
+	consumed, err = m.ConsumeLine(">CLIENT:ENV,dev=tun0")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+
+	consumed, err = m.ConsumeLine(">STATE:1553072395,CONNECTED,SUCCESS,10.8.0.1,,,,")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+	assert.Empty(t, fake.shaped)
+}
+
+func TestMiddleware_StopUnshapesConnectedClients(t *testing.T) {
+	fake := &fakeShaper{shaped: make(map[string]shaper.Limits)}
+	m := NewMiddleware(fake, limits).(*middleware)
+	feedLines(t, m,
+		">CLIENT:ESTABLISHED,7",
+		">CLIENT:ENV,dev=tun0",
+		">CLIENT:ENV,ifconfig_pool_remote_ip=10.8.0.6",
+		">CLIENT:ENV,END",
+	)
+
+	assert.NoError(t, m.Stop(nil))
+	assert.Empty(t, fake.shaped)
+}
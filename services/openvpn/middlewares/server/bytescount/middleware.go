@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bytescount
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+)
+
+const logPrefix = "[openvpn-server-bytescount] "
+
+var (
+	clientBytecountLine  = regexp.MustCompile(`^>BYTECOUNT_CLI:(\d+),(\d+),(\d+)$`)
+	clientDisconnectLine = regexp.MustCompile(`^>CLIENT:DISCONNECT,(\d+)$`)
+	clientEnvLine        = regexp.MustCompile(`^>CLIENT:ENV,(\w+)=(.*)$`)
+)
+
+const (
+	clientEventPrefix = ">CLIENT:"
+	clientEnvEnd      = ">CLIENT:ENV,END"
+)
+
+// ClientStatsHandler is called with traffic totals of the client connection, as seen by openvpn server:
+// bytesIn are received from the client, bytesOut are sent to it
+type ClientStatsHandler func(clientID int, bytesIn, bytesOut uint64)
+
+// NewMiddleware creates middleware, which makes openvpn server report traffic of each connected client periodically.
+// Final traffic of the client is taken from environment of its disconnect event, so middleware has to be placed
+// before the one which ends session on disconnect.
+func NewMiddleware(handler ClientStatsHandler, interval time.Duration) management.Middleware {
+	return &middleware{
+		handler:  handler,
+		interval: interval,
+	}
+}
+
+type middleware struct {
+	handler  ClientStatsHandler
+	interval time.Duration
+
+	// disconnect is the event of the client, environment of which is being received
+	disconnect *clientDisconnect
+}
+
+type clientDisconnect struct {
+	clientID int
+	env      map[string]string
+}
+
+// Start asks openvpn server to report traffic of clients every interval
+func (m *middleware) Start(connection management.Connection) error {
+	_, err := connection.SingleLineCommand("bytecount %d", int(m.interval.Seconds()))
+	return err
+}
+
+// Stop turns traffic reports off
+func (m *middleware) Stop(connection management.Connection) error {
+	_, err := connection.SingleLineCommand("bytecount %d", 0)
+	return err
+}
+
+// ConsumeLine passes traffic of the client to the handler.
+// Client events are only looked at and left for other middlewares to consume.
+func (m *middleware) ConsumeLine(line string) (bool, error) {
+	if strings.HasPrefix(line, clientEventPrefix) {
+		return false, m.watchClientEvent(line)
+	}
+
+	match := clientBytecountLine.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return false, nil
+	}
+
+	clientID, err := strconv.Atoi(match[1])
+	if err != nil {
+		return true, err
+	}
+	bytesIn, err := strconv.ParseUint(match[2], 10, 64)
+	if err != nil {
+		return true, err
+	}
+	bytesOut, err := strconv.ParseUint(match[3], 10, 64)
+	if err != nil {
+		return true, err
+	}
+
+	log.Trace(logPrefix, "Client ", clientID, " traffic in: ", bytesIn, ", out: ", bytesOut)
+	m.handler(clientID, bytesIn, bytesOut)
+	return true, nil
+}
+
+// watchClientEvent collects environment of client disconnect event and passes final traffic of the client to the handler
+func (m *middleware) watchClientEvent(line string) error {
+	if match := clientDisconnectLine.FindStringSubmatch(line); len(match) > 0 {
+		clientID, err := strconv.Atoi(match[1])
+		if err != nil {
+			return err
+		}
+		m.disconnect = &clientDisconnect{clientID: clientID, env: make(map[string]string)}
+		return nil
+	}
+
+	if m.disconnect == nil {
+		return nil
+	}
+	if match := clientEnvLine.FindStringSubmatch(line); len(match) > 0 {
+		m.disconnect.env[match[1]] = match[2]
+		return nil
+	}
+
+	disconnect := m.disconnect
+	m.disconnect = nil
+	if line != clientEnvEnd {
+		return nil
+	}
+
+	bytesIn, err := strconv.ParseUint(disconnect.env["bytes_received"], 10, 64)
+	if err != nil {
+		return err
+	}
+	bytesOut, err := strconv.ParseUint(disconnect.env["bytes_sent"], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	log.Trace(logPrefix, "Client ", disconnect.clientID, " disconnected, traffic in: ", bytesIn, ", out: ", bytesOut)
+	m.handler(disconnect.clientID, bytesIn, bytesOut)
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bytescount
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConnection struct {
+	commands []string
+}
+
+func (conn *fakeConnection) SingleLineCommand(template string, args ...interface{}) (string, error) {
+	conn.commands = append(conn.commands, fmt.Sprintf(template, args...))
+	return "", nil
+}
+
+func (conn *fakeConnection) MultiLineCommand(template string, args ...interface{}) (string, []string, error) {
+	return "", nil, nil
+}
+
+type clientStats struct {
+	clientID          int
+	bytesIn, bytesOut uint64
+}
+
+func TestMiddleware_TogglesBytecountReports(t *testing.T) {
+	connection := &fakeConnection{}
+	m := NewMiddleware(func(int, uint64, uint64) {}, 5*time.Second)
+
+	assert.NoError(t, m.Start(connection))
+	assert.NoError(t, m.Stop(connection))
+	assert.Equal(t, []string{"bytecount 5", "bytecount 0"}, connection.commands)
+}
+
+func TestMiddleware_ConsumesClientBytecountLines(t *testing.T) {
+	var reported []clientStats
+	m := NewMiddleware(func(clientID int, bytesIn, bytesOut uint64) {
+		reported = append(reported, clientStats{clientID, bytesIn, bytesOut})
+	}, time.Second)
+
+	consumed, err := m.ConsumeLine(">BYTECOUNT_CLI:7,1024,4096")
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+
+	consumed, err = m.ConsumeLine(">BYTECOUNT:1024,4096")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+
+	consumed, err = m.ConsumeLine(">CLIENT:ESTABLISHED,7")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+
+	assert.Equal(t, []clientStats{{7, 1024, 4096}}, reported)
+}
+
+func TestMiddleware_ReportsFinalTrafficOfDisconnectedClient(t *testing.T) {
+	var reported []clientStats
+	m := NewMiddleware(func(clientID int, bytesIn, bytesOut uint64) {
+		reported = append(reported, clientStats{clientID, bytesIn, bytesOut})
+	}, time.Second)
+
+	lines := []string{
+		">CLIENT:CONNECT,3,0",
+		">CLIENT:ENV,bytes_received=1",
+		">CLIENT:ENV,END",
+		">CLIENT:DISCONNECT,7",
+		">CLIENT:ENV,bytes_received=2048",
+		">CLIENT:ENV,bytes_sent=8192",
+		">CLIENT:ENV,username=session-id",
+		">CLIENT:ENV,END",
+	}
+	for _, line := range lines {
+		consumed, err := m.ConsumeLine(line)
+		assert.NoError(t, err)
+		assert.False(t, consumed)
+	}
+
+	assert.Equal(t, []clientStats{{7, 2048, 8192}}, reported)
+}
@@ -34,6 +34,11 @@ func (c *ServerConfig) SetServerMode(port int, network, netmask string) {
 	c.SetParam("topology", "subnet")
 }
 
+// SetServerIPv6 makes openvpn server hand out IPv6 addresses of given network to clients
+func (c *ServerConfig) SetServerIPv6(network string) {
+	c.SetParam("server-ipv6", network)
+}
+
 // SetTLSServer add tls-server option to config, also sets dh to none
 func (c *ServerConfig) SetTLSServer() {
 	c.SetFlag("tls-server")
@@ -20,18 +20,25 @@ package service
 import (
 	"crypto/x509/pkix"
 	"encoding/json"
+	"net"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/server/auth"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/state"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
 	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/nat"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	openvpn_bandwidth "github.com/mysteriumnetwork/node/services/openvpn/middlewares/server/bandwidth"
+	openvpn_bytescount "github.com/mysteriumnetwork/node/services/openvpn/middlewares/server/bytescount"
 	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
 	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/shaper"
 )
 
 // NewManager creates new instance of Openvpn service
@@ -40,8 +47,12 @@ func NewManager(
 	serviceOptions Options,
 	publicIP string,
 	outboundIP string,
+	outboundIPv6 string,
 	currentLocation string,
 	sessionMap openvpn_session.SessionMap,
+	bandwidth shaper.Limits,
+	egress firewall.EgressPolicy,
+	dns []net.IP,
 ) *Manager {
 	natService := nat.NewService()
 	sessionValidator := openvpn_session.NewValidator(sessionMap, identity.NewExtractor())
@@ -49,42 +60,63 @@ func NewManager(
 	return &Manager{
 		publicIP:                       publicIP,
 		outboundIP:                     outboundIP,
+		outboundIPv6:                   outboundIPv6,
 		currentLocation:                currentLocation,
 		natService:                     natService,
-		sessionConfigNegotiatorFactory: newSessionConfigNegotiatorFactory(nodeOptions.OptionsNetwork, serviceOptions),
-		vpnServerConfigFactory:         newServerConfigFactory(nodeOptions, serviceOptions),
-		vpnServerFactory:               newServerFactory(nodeOptions, sessionValidator),
+		egressBlocker:                  firewall.NewEgressBlocker(egress),
+		sessionValidator:               sessionValidator,
+		sessionConfigNegotiatorFactory: newSessionConfigNegotiatorFactory(nodeOptions.OptionsNetwork, serviceOptions, outboundIPv6 != "", dns),
+		vpnServerConfigFactory:         newServerConfigFactory(nodeOptions, serviceOptions, outboundIPv6 != ""),
+		vpnServerFactory:               newServerFactory(nodeOptions, sessionValidator, bandwidth),
 	}
 }
 
 // newServerConfigFactory returns function generating server config and generates required security primitives
-func newServerConfigFactory(nodeOptions node.Options, serviceOptions Options) ServerConfigFactory {
-	return func(secPrimitives *tls.Primitives) *openvpn_service.ServerConfig {
+func newServerConfigFactory(nodeOptions node.Options, serviceOptions Options, ipv6 bool) ServerConfigFactory {
+	return func(secPrimitives *tls.Primitives, subnets serverSubnets) *openvpn_service.ServerConfig {
 		// TODO: check nodeOptions for --openvpn-transport option
-		return openvpn_service.NewServerConfig(
+		serverConfig := openvpn_service.NewServerConfig(
 			nodeOptions.Directories.Runtime,
 			nodeOptions.Directories.Config,
-			"10.8.0.0", "255.255.255.0",
+			subnets.ipv4.IP.String(), net.IP(subnets.ipv4.Mask).String(),
 			secPrimitives,
 			serviceOptions.OpenvpnPort,
 			serviceOptions.OpenvpnProtocol,
 		)
+		if ipv6 {
+			serverConfig.SetServerIPv6(subnets.ipv6.String())
+		}
+		return serverConfig
 	}
 }
 
-func newServerFactory(nodeOptions node.Options, sessionValidator *openvpn_session.Validator) ServerFactory {
+func newServerFactory(nodeOptions node.Options, sessionValidator *openvpn_session.Validator, bandwidth shaper.Limits) ServerFactory {
 	return func(config *openvpn_service.ServerConfig) openvpn.Process {
+		middlewares := []management.Middleware{
+			// final traffic of the client is recorded before its session is removed on disconnect
+			openvpn_bytescount.NewMiddleware(sessionValidator.UpdateTraffic, 5*time.Second),
+			auth.NewMiddleware(sessionValidator.Validate, sessionValidator.Cleanup),
+			state.NewMiddleware(vpnStateCallback),
+		}
+		if !bandwidth.IsUnlimited() {
+			middlewares = append(middlewares, openvpn_bandwidth.NewMiddleware(shaper.NewShaper(), bandwidth))
+		}
+
 		return openvpn.CreateNewProcess(
 			nodeOptions.Openvpn.BinaryPath(),
 			config.GenericConfig,
-			auth.NewMiddleware(sessionValidator.Validate, sessionValidator.Cleanup),
-			state.NewMiddleware(vpnStateCallback),
+			middlewares...,
 		)
 	}
 }
 
 // newSessionConfigNegotiatorFactory returns function generating session config for remote client
-func newSessionConfigNegotiatorFactory(networkOptions node.OptionsNetwork, serviceOptions Options) SessionConfigNegotiatorFactory {
+func newSessionConfigNegotiatorFactory(networkOptions node.OptionsNetwork, serviceOptions Options, ipv6 bool, dns []net.IP) SessionConfigNegotiatorFactory {
+	var dnsServers []string
+	for _, server := range dns {
+		dnsServers = append(dnsServers, server.String())
+	}
+
 	return func(secPrimitives *tls.Primitives, outboundIP, publicIP string) session.ConfigNegotiator {
 		serverIP := vpnServerIP(serviceOptions, outboundIP, publicIP, networkOptions.Localnet)
 		return &OpenvpnConfigNegotiator{
@@ -94,6 +126,8 @@ func newSessionConfigNegotiatorFactory(networkOptions node.OptionsNetwork, servi
 				RemoteProtocol:  serviceOptions.OpenvpnProtocol,
 				TLSPresharedKey: secPrimitives.PresharedKey.ToPEMFormat(),
 				CACertificate:   secPrimitives.CertificateAuthority.ToPEMFormat(),
+				IPv6:            ipv6,
+				DNS:             dnsServers,
 			},
 		}
 	}
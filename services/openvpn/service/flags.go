@@ -18,13 +18,15 @@
 package service
 
 import (
+	"encoding/json"
+
 	"github.com/urfave/cli"
 )
 
 // Options describes options which are required to start Openvpn service
 type Options struct {
-	OpenvpnProtocol string
-	OpenvpnPort     int
+	OpenvpnProtocol string `json:"protocol"`
+	OpenvpnPort     int    `json:"port"`
 }
 
 var (
@@ -52,3 +54,17 @@ func ParseFlags(ctx *cli.Context) Options {
 		OpenvpnPort:     ctx.Int(portFlag.Name),
 	}
 }
+
+// ParseJSONOptions function fills in Openvpn options from JSON request, defaults of flags are taken for missing values
+func ParseJSONOptions(request *json.RawMessage) (Options, error) {
+	options := Options{
+		OpenvpnProtocol: protocolFlag.Value,
+		OpenvpnPort:     portFlag.Value,
+	}
+	if request == nil || len(*request) == 0 {
+		return options, nil
+	}
+
+	err := json.Unmarshal(*request, &options)
+	return options, err
+}
@@ -20,21 +20,25 @@ package service
 import (
 	"encoding/json"
 	"errors"
+	"net"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/nat"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
 	"github.com/mysteriumnetwork/node/session"
 )
 
 const logPrefix = "[service-openvpn] "
 
-// ServerConfigFactory callback generates session config for remote client
-type ServerConfigFactory func(*tls.Primitives) *openvpn_service.ServerConfig
+// ServerConfigFactory callback generates config of the server, which hands out addresses of given subnets to clients
+type ServerConfigFactory func(*tls.Primitives, serverSubnets) *openvpn_service.ServerConfig
 
 // ServerFactory initiates Openvpn server instance during runtime
 type ServerFactory func(*openvpn_service.ServerConfig) openvpn.Process
@@ -47,7 +51,10 @@ type SessionConfigNegotiatorFactory func(secPrimitives *tls.Primitives, outbound
 
 // Manager represents entrypoint for Openvpn service with top level components
 type Manager struct {
-	natService nat.NATService
+	natService       nat.NATService
+	egressBlocker    firewall.EgressBlocker
+	egressSubnets    []net.IPNet
+	sessionValidator *openvpn_session.Validator
 
 	sessionConfigNegotiatorFactory SessionConfigNegotiatorFactory
 
@@ -58,21 +65,38 @@ type Manager struct {
 
 	publicIP        string
 	outboundIP      string
+	outboundIPv6    string
 	currentLocation string
 }
 
 // Serve starts service - does block
 func (manager *Manager) Serve(providerID identity.Identity) (err error) {
+	clientSubnets, err := subnets.allocate()
+	if err != nil {
+		return
+	}
+	defer subnets.release(clientSubnets)
+
 	manager.natService.Add(nat.RuleForwarding{
-		SourceAddress: "10.8.0.0/24",
+		SourceAddress: clientSubnets.ipv4.String(),
 		TargetIP:      manager.outboundIP,
 	})
+	if manager.outboundIPv6 != "" {
+		manager.natService.Add(nat.RuleForwarding{
+			SourceAddress: clientSubnets.ipv6.String(),
+			TargetIP:      manager.outboundIPv6,
+		})
+	}
 
 	err = manager.natService.Start()
 	if err != nil {
 		log.Warn(logPrefix, "received nat service error: ", err, " trying to proceed.")
 	}
 
+	if err = manager.restrictEgress(clientSubnets); err != nil {
+		return
+	}
+
 	primitives, err := primitiveFactory(manager.currentLocation, providerID.Address)
 	if err != nil {
 		return
@@ -80,7 +104,7 @@ func (manager *Manager) Serve(providerID identity.Identity) (err error) {
 
 	manager.vpnServiceConfigProvider = manager.sessionConfigNegotiatorFactory(primitives, manager.outboundIP, manager.publicIP)
 
-	vpnServerConfig := manager.vpnServerConfigFactory(primitives)
+	vpnServerConfig := manager.vpnServerConfigFactory(primitives, clientSubnets)
 	manager.vpnServer = manager.vpnServerFactory(vpnServerConfig)
 
 	if err = manager.vpnServer.Start(); err != nil {
@@ -94,6 +118,7 @@ func (manager *Manager) Stop() error {
 	if manager.natService != nil {
 		manager.natService.Stop()
 	}
+	manager.liftEgress()
 
 	if manager.vpnServer != nil {
 		manager.vpnServer.Stop()
@@ -102,6 +127,31 @@ func (manager *Manager) Stop() error {
 	return nil
 }
 
+// restrictEgress enforces egress policy on subnets, which addresses of clients are handed out from
+func (manager *Manager) restrictEgress(clientSubnets serverSubnets) error {
+	networks := []net.IPNet{clientSubnets.ipv4}
+	if manager.outboundIPv6 != "" {
+		networks = append(networks, clientSubnets.ipv6)
+	}
+	for _, subnet := range networks {
+		if err := manager.egressBlocker.Enable(subnet); err != nil {
+			manager.liftEgress()
+			return err
+		}
+		manager.egressSubnets = append(manager.egressSubnets, subnet)
+	}
+	return nil
+}
+
+func (manager *Manager) liftEgress() {
+	for _, subnet := range manager.egressSubnets {
+		if err := manager.egressBlocker.Disable(subnet); err != nil {
+			log.Warn(logPrefix, "Failed to remove egress restrictions of ", subnet.String(), ": ", err)
+		}
+	}
+	manager.egressSubnets = nil
+}
+
 // ProvideConfig provides the configuration to end consumer
 func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
 	if manager.vpnServiceConfigProvider == nil {
@@ -112,6 +162,27 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 	return manager.vpnServiceConfigProvider.ProvideConfig(publicKey)
 }
 
+// RecoverSession refuses sessions which outlived the node, as openvpn server and its certificates are recreated on every start
+func (manager *Manager) RecoverSession(session.Session) (session.DestroyCallback, error) {
+	return nil, errors.New("openvpn sessions do not outlive the service")
+}
+
+// LastActivity tells when client of given session was last connected to openvpn server
+func (manager *Manager) LastActivity(sessionInstance session.Session) (time.Time, bool) {
+	if manager.sessionValidator == nil {
+		return time.Time{}, false
+	}
+	return manager.sessionValidator.LastActivity(sessionInstance)
+}
+
+// SessionStats returns amount of data moved by client of given session
+func (manager *Manager) SessionStats(sessionInstance session.Session) (session.DataTransfer, error) {
+	if manager.sessionValidator == nil {
+		return session.DataTransfer{}, nil
+	}
+	return manager.sessionValidator.SessionStats(sessionInstance)
+}
+
 func vpnStateCallback(state openvpn.State) {
 	switch state {
 	case openvpn.ProcessStarted:
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+const maxSubnets = 256
+
+// ipv4SubnetFormat is a private prefix which IPv4 addresses of clients of each server are handed out from
+const ipv4SubnetFormat = "10.8.%d.0/24"
+
+// ipv6SubnetFormat is a unique local prefix which IPv6 addresses of clients of each server are handed out from
+const ipv6SubnetFormat = "fd6d:7973:7400:%x::/64"
+
+// serverSubnets are networks which addresses of clients of a single openvpn server are handed out from
+type serverSubnets struct {
+	index int
+	ipv4  net.IPNet
+	ipv6  net.IPNet
+}
+
+// subnetPool hands out distinct subnets to openvpn servers, so that multiple instances of the service can run at once
+type subnetPool struct {
+	allocated map[int]struct{}
+	mutex     sync.Mutex
+}
+
+var subnets = newSubnetPool()
+
+func newSubnetPool() *subnetPool {
+	return &subnetPool{allocated: make(map[int]struct{})}
+}
+
+// allocate reserves subnets for openvpn server
+func (pool *subnetPool) allocate() (serverSubnets, error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for i := 0; i < maxSubnets; i++ {
+		if _, ok := pool.allocated[i]; ok {
+			continue
+		}
+
+		_, ipv4, err := net.ParseCIDR(fmt.Sprintf(ipv4SubnetFormat, i))
+		if err != nil {
+			return serverSubnets{}, err
+		}
+		// IPv6 subnets are taken from the end of the range, the beginning of it is used by wireguard
+		_, ipv6, err := net.ParseCIDR(fmt.Sprintf(ipv6SubnetFormat, 0xffff-i))
+		if err != nil {
+			return serverSubnets{}, err
+		}
+
+		pool.allocated[i] = struct{}{}
+		return serverSubnets{index: i, ipv4: *ipv4, ipv6: *ipv6}, nil
+	}
+
+	return serverSubnets{}, errors.New("no more unused subnets")
+}
+
+// release returns subnets of stopped openvpn server back to the pool
+func (pool *subnetPool) release(released serverSubnets) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	delete(pool.allocated, released.index)
+}
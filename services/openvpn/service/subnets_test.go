@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubnetPoolAllocatesDistinctSubnets(t *testing.T) {
+	pool := newSubnetPool()
+
+	first, err := pool.allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.0.0/24", first.ipv4.String())
+	assert.Equal(t, "fd6d:7973:7400:ffff::/64", first.ipv6.String())
+
+	second, err := pool.allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.1.0/24", second.ipv4.String())
+	assert.Equal(t, "fd6d:7973:7400:fffe::/64", second.ipv6.String())
+
+	pool.release(first)
+	third, err := pool.allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, first, third)
+}
+
+func TestSubnetPoolRunsOut(t *testing.T) {
+	pool := newSubnetPool()
+	for i := 0; i < maxSubnets; i++ {
+		_, err := pool.allocate()
+		assert.NoError(t, err)
+	}
+
+	_, err := pool.allocate()
+	assert.EqualError(t, err, "no more unused subnets")
+}
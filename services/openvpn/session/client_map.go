@@ -20,6 +20,7 @@ package session
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/mysteriumnetwork/node/session"
 )
@@ -36,7 +37,9 @@ type clientMap struct {
 	sessions SessionMap
 	// TODO: use clientID to kill OpenVPN session (client-kill {clientID}) when promise processor instructs so
 	sessionClientIDs map[session.ID]int
-	sessionMapLock   sync.Mutex
+	// sessionTraffic keeps traffic of the session, as last reported by openvpn server
+	sessionTraffic map[session.ID]session.DataTransfer
+	sessionMapLock sync.Mutex
 }
 
 // FindClientSession returns OpenVPN session instance by given session id
@@ -66,15 +69,51 @@ func (cm *clientMap) UpdateClientSession(clientID int, id session.ID) bool {
 
 // RemoveSession removes given session from underlying session managers
 func (cm *clientMap) RemoveSession(id session.ID) error {
-	cm.sessionMapLock.Lock()
-	defer cm.sessionMapLock.Unlock()
-
-	_, clientIDExist := cm.sessions.Find(id)
-	if !clientIDExist {
+	_, sessionExist := cm.sessions.Find(id)
+	if !sessionExist {
 		return errors.New("no underlying session exists: " + string(id))
 	}
 
+	// removal archives traffic of the session, so it's done before the traffic is forgotten and outside of the lock
 	cm.sessions.Remove(id)
+
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
 	delete(cm.sessionClientIDs, id)
+	delete(cm.sessionTraffic, id)
 	return nil
 }
+
+// UpdateTraffic sets traffic totals of current client connection, bytesIn are received from the client and bytesOut are sent to it
+func (cm *clientMap) UpdateTraffic(clientID int, bytesIn, bytesOut uint64) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	for id, sessionClientID := range cm.sessionClientIDs {
+		if sessionClientID != clientID {
+			continue
+		}
+		cm.sessionTraffic[id] = session.DataTransfer{BytesSent: bytesOut, BytesReceived: bytesIn}
+		return
+	}
+}
+
+// Traffic returns traffic of given session
+func (cm *clientMap) Traffic(id session.ID) session.DataTransfer {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	return cm.sessionTraffic[id]
+}
+
+// LastActivity returns current time for sessions with connected client and zero time for the others
+func (cm *clientMap) LastActivity(id session.ID) time.Time {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	if _, connected := cm.sessionClientIDs[id]; connected {
+		return time.Now()
+	}
+	return time.Time{}
+}
@@ -19,6 +19,7 @@ package session
 
 import (
 	"sync"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/session"
@@ -39,6 +40,7 @@ func NewValidator(sessionMap SessionMap, extractor identity.Extractor) *Validato
 		clientMap: &clientMap{
 			sessions:         sessionMap,
 			sessionClientIDs: make(map[session.ID]int),
+			sessionTraffic:   make(map[session.ID]session.DataTransfer),
 			sessionMapLock:   sync.Mutex{},
 		},
 		identityExtractor: extractor,
@@ -73,3 +75,18 @@ func (v *Validator) Cleanup(sessionString string) error {
 
 	return v.clientMap.RemoveSession(sessionID)
 }
+
+// LastActivity tells when client of the session was last connected to openvpn server
+func (v *Validator) LastActivity(sessionInstance session.Session) (time.Time, bool) {
+	return v.clientMap.LastActivity(sessionInstance.ID), true
+}
+
+// UpdateTraffic records traffic of the client, as reported by openvpn server
+func (v *Validator) UpdateTraffic(clientID int, bytesIn, bytesOut uint64) {
+	v.clientMap.UpdateTraffic(clientID, bytesIn, bytesOut)
+}
+
+// SessionStats returns amount of data moved by client of the session
+func (v *Validator) SessionStats(sessionInstance session.Session) (session.DataTransfer, error) {
+	return v.clientMap.Traffic(sessionInstance.ID), nil
+}
@@ -19,6 +19,7 @@ package session
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/session"
@@ -103,3 +104,47 @@ func TestCleanupReturnsErrorIfSessionNotExists(t *testing.T) {
 
 	assert.Errorf(t, err, "no underlying session exists: nonexistent_session")
 }
+
+func TestCleanupRemovesSession(t *testing.T) {
+	validator := mockValidatorWithSession(identityExisting, sessionExisting)
+
+	validator.Validate(1, sessionExistingString, "not important")
+	assert.NoError(t, validator.Cleanup(sessionExistingString))
+
+	authenticated, err := validator.Validate(2, sessionExistingString, "not important")
+	assert.Errorf(t, err, "no underlying session exists, possible break-in attempt")
+	assert.False(t, authenticated)
+}
+
+func TestLastActivityFollowsClientConnection(t *testing.T) {
+	validator := mockValidatorWithSession(identityExisting, sessionExisting)
+
+	activity, known := validator.LastActivity(sessionExisting)
+	assert.True(t, known)
+	assert.True(t, activity.IsZero())
+
+	validator.Validate(1, sessionExistingString, "not important")
+	activity, _ = validator.LastActivity(sessionExisting)
+	assert.WithinDuration(t, time.Now(), activity, time.Second)
+
+	validator.Cleanup(sessionExistingString)
+	activity, _ = validator.LastActivity(sessionExisting)
+	assert.True(t, activity.IsZero())
+}
+
+func TestSessionStatsReturnsLastTrafficOfClient(t *testing.T) {
+	validator := mockValidatorWithSession(identityExisting, sessionExisting)
+
+	validator.Validate(1, sessionExistingString, "not important")
+	validator.UpdateTraffic(1, 10, 20)
+	validator.UpdateTraffic(1, 15, 30)
+	validator.UpdateTraffic(5, 1000, 1000)
+	stats, err := validator.SessionStats(sessionExisting)
+	assert.NoError(t, err)
+	assert.Equal(t, session.DataTransfer{BytesSent: 30, BytesReceived: 15}, stats)
+
+	validator.Cleanup(sessionExistingString)
+	stats, err = validator.SessionStats(sessionExisting)
+	assert.NoError(t, err)
+	assert.Equal(t, session.DataTransfer{}, stats)
+}
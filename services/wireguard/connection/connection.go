@@ -20,12 +20,14 @@ package connection
 import (
 	"encoding/json"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/firewall"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	endpoint "github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
@@ -45,10 +47,16 @@ type Connection struct {
 
 	config             wg.ServiceConfig
 	connectionEndpoint wg.ConnectionEndpoint
+	resourceAllocator  *resources.Allocator
 }
 
 // Start establish wireguard connection to the service provider.
 func (c *Connection) Start(options connection.ConnectOptions) (err error) {
+	// provider endpoint is routed through the exact interface of previous hop, wildcards (e.g. "tun+" of openvpn) can't be used
+	if strings.Contains(options.Routes.Via, "+") {
+		return errors.Errorf("wireguard can not be chained through tunnel interface %q", options.Routes.Via)
+	}
+
 	var config wg.ServiceConfig
 	if err := json.Unmarshal(options.SessionConfig, &config); err != nil {
 		return err
@@ -56,8 +64,7 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 	c.config.Provider = config.Provider
 	c.config.Consumer.IPAddress = config.Consumer.IPAddress
 
-	resourceAllocator := resources.NewAllocator()
-	c.connectionEndpoint, err = endpoint.NewConnectionEndpoint("", &resourceAllocator)
+	c.connectionEndpoint, err = endpoint.NewConnectionEndpoint("", false, c.resourceAllocator)
 	if err != nil {
 		return err
 	}
@@ -77,12 +84,20 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 		return err
 	}
 
-	if err := c.connectionEndpoint.ConfigureRoutes(c.config.Provider.Endpoint.IP); err != nil {
+	if err := c.connectionEndpoint.ConfigureRoutes(c.config.Provider.Endpoint.IP, options.Routes); err != nil {
 		c.stateChannel <- connection.NotConnected
 		c.connection.Done()
 		return err
 	}
 
+	if len(options.DNS) > 0 {
+		if err := c.connectionEndpoint.ConfigureDNS(options.DNS); err != nil {
+			c.stateChannel <- connection.NotConnected
+			c.connection.Done()
+			return err
+		}
+	}
+
 	if err := c.waitHandshake(); err != nil {
 		c.stateChannel <- connection.NotConnected
 		c.connection.Done()
@@ -112,6 +127,33 @@ func (c *Connection) GetConfig() (connection.ConsumerConfig, error) {
 	}, nil
 }
 
+// LocateEndpoint returns address of provider's wireguard endpoint given in session config
+func (c *Connection) LocateEndpoint(sessionConfig json.RawMessage) (net.IP, error) {
+	var config wg.ServiceConfig
+	if err := json.Unmarshal(sessionConfig, &config); err != nil {
+		return nil, err
+	}
+	return config.Provider.Endpoint.IP, nil
+}
+
+// LocateDNS returns DNS servers advertised by provider in session config
+func (c *Connection) LocateDNS(sessionConfig json.RawMessage) ([]net.IP, error) {
+	var config wg.ServiceConfig
+	if err := json.Unmarshal(sessionConfig, &config); err != nil {
+		return nil, err
+	}
+	return config.Provider.DNS, nil
+}
+
+// GetTunnel returns wireguard network interface and provider endpoint of established connection
+func (c *Connection) GetTunnel() (firewall.Tunnel, error) {
+	return firewall.Tunnel{
+		Interface: c.connectionEndpoint.InterfaceName(),
+		Endpoint:  c.config.Provider.Endpoint.IP,
+		IPv6:      c.config.Consumer.IPv6Address != nil,
+	}, nil
+}
+
 // Stop stops wireguard connection and closes connection endpoint.
 func (c *Connection) Stop() {
 	c.stateChannel <- connection.Disconnecting
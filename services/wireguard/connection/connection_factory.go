@@ -21,10 +21,13 @@ import (
 	"github.com/mysteriumnetwork/node/core/connection"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
+	"github.com/mysteriumnetwork/node/services/wireguard/resources"
 )
 
 // Factory is the wireguard connection factory
-type Factory struct{}
+type Factory struct {
+	resourceAllocator *resources.Allocator
+}
 
 // Create creates a new wireguard connection
 func (f *Factory) Create(stateChannel connection.StateChannel, statisticsChannel connection.StatisticsChannel) (connection.Connection, error) {
@@ -40,10 +43,11 @@ func (f *Factory) Create(stateChannel connection.StateChannel, statisticsChannel
 		stateChannel:      stateChannel,
 		statisticsChannel: statisticsChannel,
 		config:            config,
+		resourceAllocator: f.resourceAllocator,
 	}, nil
 }
 
-// NewConnectionCreator creates wireguard connections
-func NewConnectionCreator() connection.Factory {
-	return &Factory{}
+// NewConnectionCreator creates wireguard connections, which allocate their resources from given allocator
+func NewConnectionCreator(resourceAllocator *resources.Allocator) connection.Factory {
+	return &Factory{resourceAllocator: resourceAllocator}
 }
@@ -26,11 +26,12 @@ import (
 )
 
 // NewConnectionEndpoint creates new wireguard connection endpoint.
-func NewConnectionEndpoint(publicIP string, resourceAllocator *resources.Allocator) (wg.ConnectionEndpoint, error) {
+func NewConnectionEndpoint(publicIP string, ipv6 bool, resourceAllocator *resources.Allocator) (wg.ConnectionEndpoint, error) {
 	client, err := userspace.NewWireguardClient()
 	return &connectionEndpoint{
 		wgClient:          client,
 		publicIP:          publicIP,
+		ipv6:              ipv6,
 		resourceAllocator: resourceAllocator,
 	}, err
 }
@@ -29,7 +29,7 @@ import (
 )
 
 // NewConnectionEndpoint creates new wireguard connection endpoint.
-func NewConnectionEndpoint(publicIP string, resourceAllocator *resources.Allocator) (wg.ConnectionEndpoint, error) {
+func NewConnectionEndpoint(publicIP string, ipv6 bool, resourceAllocator *resources.Allocator) (wg.ConnectionEndpoint, error) {
 	wgClient, err := getWGClient()
 	if err != nil {
 		return nil, err
@@ -38,6 +38,7 @@ func NewConnectionEndpoint(publicIP string, resourceAllocator *resources.Allocat
 	return &connectionEndpoint{
 		wgClient:          wgClient,
 		publicIP:          publicIP,
+		ipv6:              ipv6,
 		resourceAllocator: resourceAllocator,
 	}, nil
 }
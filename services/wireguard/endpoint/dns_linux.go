@@ -0,0 +1,41 @@
+// +build linux,!android
+
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoint
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/mysteriumnetwork/node/utils"
+)
+
+// configureDNS registers interface nameservers with resolvconf, same way as wg-quick does
+func configureDNS(iface string, servers []net.IP) error {
+	var config strings.Builder
+	for _, server := range servers {
+		fmt.Fprintf(&config, "nameserver %s\n", server)
+	}
+	return utils.SudoExecWithInput(config.String(), "resolvconf", "-a", iface, "-m", "0", "-x")
+}
+
+func removeDNS(iface string) error {
+	return utils.SudoExec("resolvconf", "-d", iface, "-f")
+}
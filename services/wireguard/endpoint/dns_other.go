@@ -0,0 +1,35 @@
+// +build !linux linux,android
+
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoint
+
+import (
+	"net"
+
+	log "github.com/cihub/seelog"
+)
+
+func configureDNS(iface string, servers []net.IP) error {
+	log.Warn(logPrefix, "DNS configuration is not supported on this platform, system resolvers are used")
+	return nil
+}
+
+func removeDNS(iface string) error {
+	return nil
+}
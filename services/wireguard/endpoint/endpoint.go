@@ -18,10 +18,11 @@
 package endpoint
 
 import (
-	"fmt"
+	"errors"
 	"net"
 
 	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/connection"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
 	"github.com/mysteriumnetwork/node/services/wireguard/resources"
@@ -30,8 +31,8 @@ import (
 const logPrefix = "[wireguard-connection-endpoint] "
 
 type wgClient interface {
-	ConfigureDevice(name string, config wg.DeviceConfig, subnet net.IPNet) error
-	ConfigureRoutes(iface string, ip net.IP) error
+	ConfigureDevice(name string, config wg.DeviceConfig, subnets []net.IPNet) error
+	ConfigureRoutes(iface string, ip net.IP, routes connection.Routes, ipv6 bool) error
 	DestroyDevice(name string) error
 	AddPeer(name string, peer wg.PeerInfo) error
 	PeerStats() (wg.Stats, error)
@@ -43,18 +44,17 @@ type connectionEndpoint struct {
 	privateKey        string
 	publicIP          string
 	ipAddr            net.IPNet
+	ipv6              bool
+	ipv6Addr          *net.IPNet
 	endpoint          net.UDPAddr
 	resourceAllocator *resources.Allocator
 	wgClient          wgClient
+	dnsConfigured     bool
 }
 
 // Start starts and configure wireguard network interface for providing service.
 // If config is nil, required options will be generated automatically.
 func (ce *connectionEndpoint) Start(config *wg.ServiceConfig) error {
-	if err := ce.cleanAbandonedInterfaces(); err != nil {
-		return err
-	}
-
 	iface, err := ce.resourceAllocator.AllocateInterface()
 	if err != nil {
 		return err
@@ -81,15 +81,100 @@ func (ce *connectionEndpoint) Start(config *wg.ServiceConfig) error {
 		ce.ipAddr = ipAddr
 		ce.ipAddr.IP = providerIP(ce.ipAddr)
 		ce.privateKey = privateKey
+
+		if ce.ipv6 {
+			ipv6Addr, err := ce.resourceAllocator.AllocateIPv6Net()
+			if err != nil {
+				return err
+			}
+			ipv6Addr.IP = providerIP(ipv6Addr)
+			ce.ipv6Addr = &ipv6Addr
+		}
 	} else {
 		ce.ipAddr = config.Consumer.IPAddress
+		ce.ipv6Addr = config.Consumer.IPv6Address
 		ce.privateKey = config.Consumer.PrivateKey
 	}
 
+	subnets := []net.IPNet{ce.ipAddr}
+	if ce.ipv6Addr != nil {
+		subnets = append(subnets, *ce.ipv6Addr)
+	}
+
 	var deviceConfig deviceConfig
 	deviceConfig.listenPort = ce.endpoint.Port
 	deviceConfig.privateKey = ce.privateKey
-	return ce.wgClient.ConfigureDevice(ce.iface, deviceConfig, ce.ipAddr)
+	return ce.wgClient.ConfigureDevice(ce.iface, deviceConfig, subnets)
+}
+
+// Recover takes over wireguard network interface which outlived the node, if it still serves given configuration.
+// Interface which can not be taken over is destroyed.
+func (ce *connectionEndpoint) Recover(config wg.ServiceConfig) error {
+	ipAddr := config.Consumer.IPAddress
+	ipAddr.IP = providerIP(ipAddr)
+	iface, err := interfaceByIP(ipAddr.IP)
+	if err != nil {
+		return err
+	}
+	if err := ce.resourceAllocator.ReserveInterface(iface); err != nil {
+		return err
+	}
+
+	ce.iface = iface
+	ce.endpoint = config.Provider.Endpoint
+	ce.ipAddr = ipAddr
+	ce.ipv6Addr = nil
+	if config.Consumer.IPv6Address != nil {
+		ipv6Addr := *config.Consumer.IPv6Address
+		ipv6Addr.IP = providerIP(ipv6Addr)
+		ce.ipv6Addr = &ipv6Addr
+	}
+	ce.ipv6 = ce.ipv6Addr != nil
+
+	if err := ce.takeOver(); err != nil {
+		if err := ce.wgClient.DestroyDevice(iface); err != nil {
+			log.Warn(logPrefix, "failed to destroy interface which can't be recovered: ", iface, ", error: ", err)
+		}
+		_ = ce.resourceAllocator.ReleaseInterface(iface)
+		return err
+	}
+
+	log.Info(logPrefix, "interface recovered: ", iface)
+	return nil
+}
+
+// takeOver reserves resources of recovered interface and attaches wireguard client to it
+func (ce *connectionEndpoint) takeOver() (err error) {
+	var rollback []func() error
+	defer func() {
+		if err != nil {
+			for _, release := range rollback {
+				_ = release()
+			}
+		}
+	}()
+
+	if err = ce.resourceAllocator.ReservePort(ce.endpoint.Port); err != nil {
+		return err
+	}
+	rollback = append(rollback, func() error { return ce.resourceAllocator.ReleasePort(ce.endpoint.Port) })
+
+	if err = ce.resourceAllocator.ReserveIPNet(ce.ipAddr); err != nil {
+		return err
+	}
+	rollback = append(rollback, func() error { return ce.resourceAllocator.ReleaseIPNet(ce.ipAddr) })
+
+	subnets := []net.IPNet{ce.ipAddr}
+	if ce.ipv6Addr != nil {
+		if err = ce.resourceAllocator.ReserveIPv6Net(*ce.ipv6Addr); err != nil {
+			return err
+		}
+		rollback = append(rollback, func() error { return ce.resourceAllocator.ReleaseIPv6Net(*ce.ipv6Addr) })
+		subnets = append(subnets, *ce.ipv6Addr)
+	}
+
+	// device keeps its keys and peer, so it is only brought up with the same addresses
+	return ce.wgClient.ConfigureDevice(ce.iface, nil, subnets)
 }
 
 // AddPeer adds new wireguard peer to the wireguard network interface.
@@ -113,15 +198,41 @@ func (ce *connectionEndpoint) Config() (wg.ServiceConfig, error) {
 	config.Provider.Endpoint = ce.endpoint
 	config.Consumer.IPAddress = ce.ipAddr
 	config.Consumer.IPAddress.IP = consumerIP(ce.ipAddr)
+	if ce.ipv6Addr != nil {
+		ipv6Addr := *ce.ipv6Addr
+		ipv6Addr.IP = consumerIP(ipv6Addr)
+		config.Consumer.IPv6Address = &ipv6Addr
+	}
 	return config, nil
 }
 
-func (ce *connectionEndpoint) ConfigureRoutes(ip net.IP) error {
-	return ce.wgClient.ConfigureRoutes(ce.iface, ip)
+// InterfaceName returns the name of wireguard network interface allocated for the connection endpoint.
+func (ce *connectionEndpoint) InterfaceName() string {
+	return ce.iface
+}
+
+func (ce *connectionEndpoint) ConfigureRoutes(ip net.IP, routes connection.Routes) error {
+	return ce.wgClient.ConfigureRoutes(ce.iface, ip, routes, ce.ipv6Addr != nil)
+}
+
+// ConfigureDNS makes system resolve names using given DNS servers while the interface is up.
+func (ce *connectionEndpoint) ConfigureDNS(servers []net.IP) error {
+	if err := configureDNS(ce.iface, servers); err != nil {
+		return err
+	}
+	ce.dnsConfigured = true
+	return nil
 }
 
 // Stop closes wireguard client and destroys wireguard network interface.
 func (ce *connectionEndpoint) Stop() error {
+	if ce.dnsConfigured {
+		if err := removeDNS(ce.iface); err != nil {
+			log.Warn(logPrefix, "Failed to remove DNS configuration: ", err)
+		}
+		ce.dnsConfigured = false
+	}
+
 	if err := ce.wgClient.Close(); err != nil {
 		return err
 	}
@@ -134,22 +245,33 @@ func (ce *connectionEndpoint) Stop() error {
 		return err
 	}
 
+	if ce.ipv6 && ce.ipv6Addr != nil {
+		if err := ce.resourceAllocator.ReleaseIPv6Net(*ce.ipv6Addr); err != nil {
+			return err
+		}
+	}
+
 	return ce.resourceAllocator.ReleaseInterface(ce.iface)
 }
 
-func (ce *connectionEndpoint) cleanAbandonedInterfaces() error {
+// Sweep destroys wireguard network interfaces left by previous run of the node,
+// except the ones with addresses within given subnets of stored sessions, as they may still be recovered.
+func (ce *connectionEndpoint) Sweep(recoverable []net.IPNet) error {
 	ifaces, err := ce.resourceAllocator.AbandonedInterfaces()
 	if err != nil {
 		return err
 	}
 
 	for _, iface := range ifaces {
+		if hasAddressWithin(iface, recoverable) {
+			continue
+		}
 		if err := ce.wgClient.DestroyDevice(iface.Name); err != nil {
-			log.Warn(logPrefix, fmt.Sprintf("failed to destroy abandoned interface: %s, error: %v", iface.Name, err))
+			log.Warn(logPrefix, "failed to destroy orphaned interface: ", iface.Name, ", error: ", err)
+			continue
 		}
-		log.Info(logPrefix, "abandoned interface destroyed: ", iface.Name)
+		log.Info(logPrefix, "orphaned interface destroyed: ", iface.Name)
 	}
-
 	return nil
 }
 
@@ -178,12 +300,56 @@ func (p peerInfo) PublicKey() string {
 	return p.publicKey
 }
 
+func interfaceByIP(ip net.IP) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", errors.New("no network interface has address " + ip.String())
+}
+
+func hasAddressWithin(iface net.Interface, subnets []net.IPNet) bool {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		for _, subnet := range subnets {
+			if subnet.Contains(ipNet.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func providerIP(subnet net.IPNet) net.IP {
-	subnet.IP[len(subnet.IP)-1] = byte(1)
-	return subnet.IP
+	return hostIP(subnet, 1)
 }
 
 func consumerIP(subnet net.IPNet) net.IP {
-	subnet.IP[len(subnet.IP)-1] = byte(2)
-	return subnet.IP
+	return hostIP(subnet, 2)
+}
+
+func hostIP(subnet net.IPNet, host byte) net.IP {
+	ip := make(net.IP, len(subnet.IP))
+	copy(ip, subnet.IP)
+	ip[len(ip)-1] = host
+	return ip
 }
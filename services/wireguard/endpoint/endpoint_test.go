@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoint
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConnectionEndpoint_StartKeepsInterfaceOfPreviousHop(t *testing.T) {
+	resourceAllocator := resources.NewAllocator()
+	devices := make(map[string]bool)
+
+	entry := &connectionEndpoint{resourceAllocator: &resourceAllocator, wgClient: &fakeWGClient{devices: devices}}
+	err := entry.Start(consumerConfig("10.182.0.2/24"))
+	assert.NoError(t, err)
+
+	exit := &connectionEndpoint{resourceAllocator: &resourceAllocator, wgClient: &fakeWGClient{devices: devices}}
+	err = exit.Start(consumerConfig("10.182.1.2/24"))
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, entry.InterfaceName(), exit.InterfaceName())
+	assert.NotEqual(t, entry.endpoint.Port, exit.endpoint.Port)
+	assert.True(t, devices[entry.InterfaceName()])
+	assert.True(t, devices[exit.InterfaceName()])
+}
+
+func consumerConfig(cidr string) *wg.ServiceConfig {
+	ip, ipNet, _ := net.ParseCIDR(cidr)
+	ipNet.IP = ip
+
+	var config wg.ServiceConfig
+	config.Consumer.IPAddress = *ipNet
+	config.Consumer.PrivateKey = "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="
+	return &config
+}
+
+type fakeWGClient struct {
+	devices map[string]bool
+}
+
+func (fwc *fakeWGClient) ConfigureDevice(name string, config wg.DeviceConfig, subnets []net.IPNet) error {
+	fwc.devices[name] = true
+	return nil
+}
+
+func (fwc *fakeWGClient) ConfigureRoutes(iface string, ip net.IP, routes connection.Routes, ipv6 bool) error {
+	return nil
+}
+
+func (fwc *fakeWGClient) DestroyDevice(name string) error {
+	delete(fwc.devices, name)
+	return nil
+}
+
+func (fwc *fakeWGClient) AddPeer(name string, peer wg.PeerInfo) error {
+	return nil
+}
+
+func (fwc *fakeWGClient) PeerStats() (wg.Stats, error) {
+	return wg.Stats{}, nil
+}
+
+func (fwc *fakeWGClient) Close() error {
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/jackpal/gateway"
 	"github.com/mdlayher/wireguardctrl"
 	"github.com/mdlayher/wireguardctrl/wgtypes"
+	"github.com/mysteriumnetwork/node/core/connection"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/utils"
 )
@@ -38,6 +39,8 @@ var allowedIPs = []net.IPNet{
 type client struct {
 	iface    string
 	wgClient *wireguardctrl.Client
+	// excluded are destinations routed via original gateway, those routes outlive the device
+	excluded []string
 }
 
 // NewWireguardClient creates new wireguard kernel space client.
@@ -49,7 +52,7 @@ func NewWireguardClient() (*client, error) {
 	return &client{wgClient: wgClient}, nil
 }
 
-func (c *client) ConfigureDevice(iface string, config wg.DeviceConfig, ipAddr net.IPNet) error {
+func (c *client) ConfigureDevice(iface string, config wg.DeviceConfig, subnets []net.IPNet) error {
 	var deviceConfig wgtypes.Config
 	if config != nil {
 		port := config.ListenPort()
@@ -61,7 +64,7 @@ func (c *client) ConfigureDevice(iface string, config wg.DeviceConfig, ipAddr ne
 		deviceConfig.ListenPort = &port
 	}
 
-	if err := c.up(iface, ipAddr); err != nil {
+	if err := c.up(iface, subnets); err != nil {
 		return err
 	}
 	c.iface = iface
@@ -105,34 +108,76 @@ func (c *client) DestroyDevice(name string) error {
 	return utils.SudoExec("ip", "link", "del", "dev", name)
 }
 
-func (c *client) up(iface string, ipAddr net.IPNet) error {
+func (c *client) up(iface string, subnets []net.IPNet) error {
 	if d, err := c.wgClient.Device(iface); err != nil || d.Name != iface {
 		if err := utils.SudoExec("ip", "link", "add", "dev", iface, "type", "wireguard"); err != nil {
 			return err
 		}
 	}
 
-	if err := utils.SudoExec("ip", "address", "replace", "dev", iface, ipAddr.String()); err != nil {
-		return err
+	for _, subnet := range subnets {
+		if err := utils.SudoExec("ip", "address", "replace", "dev", iface, subnet.String()); err != nil {
+			return err
+		}
 	}
 
 	return utils.SudoExec("ip", "link", "set", "dev", iface, "up")
 }
 
-func (c *client) ConfigureRoutes(iface string, ip net.IP) error {
-	if err := excludeRoute(ip); err != nil {
+func (c *client) ConfigureRoutes(iface string, ip net.IP, routes connection.Routes, ipv6 bool) error {
+	if err := c.routeEndpoint(ip, routes.Via); err != nil {
 		return err
 	}
-	return addDefaultRoute(iface)
+	for _, network := range routes.Exclude {
+		if err := c.excludeRoute(network.String()); err != nil {
+			return err
+		}
+	}
+
+	if len(routes.Include) == 0 {
+		if ipv6 {
+			if err := addDefaultIPv6Route(iface); err != nil {
+				return err
+			}
+		}
+		return addDefaultRoute(iface)
+	}
+	for _, network := range routes.Include {
+		if err := utils.SudoExec("ip", "route", "replace", network.String(), "dev", iface); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func excludeRoute(ip net.IP) error {
+func (c *client) excludeRoute(destination string) error {
 	gw, err := gateway.DiscoverGateway()
 	if err != nil {
 		return err
 	}
 
-	return utils.SudoExec("ip", "route", "replace", ip.String(), "via", gw.String())
+	if err := utils.SudoExec("ip", "route", "replace", destination, "via", gw.String()); err != nil {
+		return err
+	}
+	c.excluded = append(c.excluded, destination)
+	return nil
+}
+
+func (c *client) removeExcludedRoutes() {
+	for _, destination := range c.excluded {
+		if err := utils.SudoExec("ip", "route", "del", destination); err != nil {
+			log.Warn("failed to remove route excluded from wireguard tunnel: ", err)
+		}
+	}
+	c.excluded = nil
+}
+
+// routeEndpoint keeps provider endpoint outside of the tunnel, via previous hop tunnel if connection is chained
+func (c *client) routeEndpoint(ip net.IP, via string) error {
+	if via == "" {
+		return c.excludeRoute(ip.String())
+	}
+	return utils.SudoExec("ip", "route", "replace", ip.String(), "dev", via)
 }
 
 func addDefaultRoute(iface string) error {
@@ -142,6 +187,13 @@ func addDefaultRoute(iface string) error {
 	return utils.SudoExec("ip", "route", "replace", "128.0.0.0/1", "dev", iface)
 }
 
+func addDefaultIPv6Route(iface string) error {
+	if err := utils.SudoExec("ip", "-6", "route", "replace", "::/1", "dev", iface); err != nil {
+		return err
+	}
+	return utils.SudoExec("ip", "-6", "route", "replace", "8000::/1", "dev", iface)
+}
+
 func (c *client) Close() (err error) {
 	var errs []error
 	defer func() {
@@ -153,6 +205,8 @@ func (c *client) Close() (err error) {
 		}
 	}()
 
+	c.removeExcludedRoutes()
+
 	if err := c.DestroyDevice(c.iface); err != nil {
 		errs = append(errs, err)
 	}
@@ -25,12 +25,17 @@ import (
 
 	"git.zx2c4.com/wireguard-go/device"
 	"git.zx2c4.com/wireguard-go/tun"
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/connection"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 )
 
 type client struct {
 	tun    tun.TUNDevice
 	devAPI *device.DeviceApi
+	// excludedEndpoint and excludedNetworks are routed via original gateway, those routes outlive the device
+	excludedEndpoint net.IP
+	excludedNetworks []net.IPNet
 }
 
 // NewWireguardClient creates new wireguard user space client.
@@ -38,12 +43,17 @@ func NewWireguardClient() (*client, error) {
 	return &client{}, nil
 }
 
-func (c *client) ConfigureDevice(name string, config wg.DeviceConfig, subnet net.IPNet) (err error) {
+func (c *client) ConfigureDevice(name string, config wg.DeviceConfig, subnets []net.IPNet) (err error) {
+	if config == nil {
+		return errors.New("user space device can not be configured without keys")
+	}
 	if c.tun, err = tun.CreateTUN(name, device.DefaultMTU); err != nil {
 		return err
 	}
-	if err := assignIP(name, subnet); err != nil {
-		return err
+	for _, subnet := range subnets {
+		if err := assignIP(name, subnet); err != nil {
+			return err
+		}
 	}
 
 	c.devAPI = device.UserspaceDeviceApi(c.tun)
@@ -72,7 +82,7 @@ func (c *client) AddPeer(name string, peer wg.PeerInfo) error {
 
 	extPeer := device.ExternalPeer{
 		PublicKey:  device.NoisePublicKey(key),
-		AllowedIPs: []string{"0.0.0.0/0"},
+		AllowedIPs: []string{"0.0.0.0/0", "::/0"},
 	}
 
 	if ep := peer.Endpoint(); ep != nil {
@@ -86,15 +96,63 @@ func (c *client) AddPeer(name string, peer wg.PeerInfo) error {
 }
 
 func (c *client) Close() error {
+	c.removeExcludedRoutes()
 	c.devAPI.Close() // c.devAPI.Close() closes c.tun too
 	return nil
 }
 
-func (c *client) ConfigureRoutes(iface string, ip net.IP) error {
+func (c *client) ConfigureRoutes(iface string, ip net.IP, routes connection.Routes, ipv6 bool) error {
+	if err := c.routeEndpoint(ip, routes.Via); err != nil {
+		return err
+	}
+	for _, network := range routes.Exclude {
+		if err := excludeNetwork(network); err != nil {
+			return err
+		}
+		c.excludedNetworks = append(c.excludedNetworks, network)
+	}
+
+	if len(routes.Include) == 0 {
+		if ipv6 {
+			if err := addDefaultIPv6Route(iface); err != nil {
+				return err
+			}
+		}
+		return addDefaultRoute(iface)
+	}
+	for _, network := range routes.Include {
+		if err := addRoute(network, iface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeEndpoint keeps provider endpoint outside of the tunnel, via previous hop tunnel if connection is chained
+func (c *client) routeEndpoint(ip net.IP, via string) error {
+	if via != "" {
+		return addHostRoute(ip, via)
+	}
 	if err := excludeRoute(ip); err != nil {
 		return err
 	}
-	return addDefaultRoute(iface)
+	c.excludedEndpoint = ip
+	return nil
+}
+
+func (c *client) removeExcludedRoutes() {
+	if c.excludedEndpoint != nil {
+		if err := removeExcludedRoute(c.excludedEndpoint); err != nil {
+			log.Warn("failed to remove route to wireguard endpoint: ", err)
+		}
+		c.excludedEndpoint = nil
+	}
+	for _, network := range c.excludedNetworks {
+		if err := removeExcludedNetwork(network); err != nil {
+			log.Warn("failed to remove route excluded from wireguard tunnel: ", err)
+		}
+	}
+	c.excludedNetworks = nil
 }
 
 func (c *client) PeerStats() (wg.Stats, error) {
@@ -25,6 +25,9 @@ import (
 )
 
 func assignIP(iface string, subnet net.IPNet) error {
+	if subnet.IP.To4() == nil {
+		return utils.SudoExec("ifconfig", iface, "inet6", subnet.String(), "alias")
+	}
 	return utils.SudoExec("ifconfig", iface, subnet.String(), peerIP(subnet).String())
 }
 
@@ -37,6 +40,31 @@ func excludeRoute(ip net.IP) error {
 	return utils.SudoExec("route", "add", "-host", ip.String(), gw.String())
 }
 
+func excludeNetwork(network net.IPNet) error {
+	gw, err := gateway.DiscoverGateway()
+	if err != nil {
+		return err
+	}
+
+	return utils.SudoExec("route", "add", "-net", network.String(), gw.String())
+}
+
+func removeExcludedRoute(ip net.IP) error {
+	return utils.SudoExec("route", "delete", "-host", ip.String())
+}
+
+func removeExcludedNetwork(network net.IPNet) error {
+	return utils.SudoExec("route", "delete", "-net", network.String())
+}
+
+func addHostRoute(ip net.IP, iface string) error {
+	return utils.SudoExec("route", "add", "-host", ip.String(), "-interface", iface)
+}
+
+func addRoute(network net.IPNet, iface string) error {
+	return utils.SudoExec("route", "add", "-net", network.String(), "-interface", iface)
+}
+
 func addDefaultRoute(iface string) error {
 	if err := utils.SudoExec("route", "add", "-net", "0.0.0.0/1", "-interface", iface); err != nil {
 		return err
@@ -45,6 +73,14 @@ func addDefaultRoute(iface string) error {
 	return utils.SudoExec("route", "add", "-net", "128.0.0.0/1", "-interface", iface)
 }
 
+func addDefaultIPv6Route(iface string) error {
+	if err := utils.SudoExec("route", "add", "-inet6", "-net", "::/1", "-interface", iface); err != nil {
+		return err
+	}
+
+	return utils.SudoExec("route", "add", "-inet6", "-net", "8000::/1", "-interface", iface)
+}
+
 func peerIP(subnet net.IPNet) net.IP {
 	lastOctetID := len(subnet.IP) - 1
 	if subnet.IP[lastOctetID] == byte(1) {
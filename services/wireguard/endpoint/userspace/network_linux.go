@@ -37,7 +37,32 @@ func excludeRoute(ip net.IP) error {
 		return err
 	}
 
-	return utils.SudoExec("route", "add", "-host", ip.String(), gw.String())
+	return utils.SudoExec("route", "add", "-host", ip.String(), "gw", gw.String())
+}
+
+func excludeNetwork(network net.IPNet) error {
+	gw, err := gateway.DiscoverGateway()
+	if err != nil {
+		return err
+	}
+
+	return utils.SudoExec("route", "add", "-net", network.String(), "gw", gw.String())
+}
+
+func removeExcludedRoute(ip net.IP) error {
+	return utils.SudoExec("route", "del", "-host", ip.String())
+}
+
+func removeExcludedNetwork(network net.IPNet) error {
+	return utils.SudoExec("route", "del", "-net", network.String())
+}
+
+func addHostRoute(ip net.IP, iface string) error {
+	return utils.SudoExec("route", "add", "-host", ip.String(), "dev", iface)
+}
+
+func addRoute(network net.IPNet, iface string) error {
+	return utils.SudoExec("route", "add", "-net", network.String(), "dev", iface)
 }
 
 func addDefaultRoute(iface string) error {
@@ -48,6 +73,14 @@ func addDefaultRoute(iface string) error {
 	return utils.SudoExec("route", "add", "-net", "128.0.0.0/1", "-interface", iface)
 }
 
+func addDefaultIPv6Route(iface string) error {
+	if err := utils.SudoExec("ip", "-6", "route", "replace", "::/1", "dev", iface); err != nil {
+		return err
+	}
+
+	return utils.SudoExec("ip", "-6", "route", "replace", "8000::/1", "dev", iface)
+}
+
 func destroyDevice(name string) error {
 	return utils.SudoExec("ip", "link", "del", "dev", name)
 }
@@ -28,21 +28,33 @@ import (
 
 const maxResources = 255
 
+// ipv6SubnetFormat is a unique local prefix which IPv6 subnets of connections are allocated from
+const ipv6SubnetFormat = "fd6d:7973:7400:%x::/64"
+
+// Networks returns ranges which IPv4 and IPv6 subnets of connections are allocated from
+func Networks() []net.IPNet {
+	_, ipv4, _ := net.ParseCIDR("10.182.0.0/16")
+	_, ipv6, _ := net.ParseCIDR("fd6d:7973:7400::/48")
+	return []net.IPNet{*ipv4, *ipv6}
+}
+
 // Allocator is mock wireguard resource handler.
 // It will manage lists of network interfaces names, IP addresses and port for endpoints.
 type Allocator struct {
-	Ifaces      map[int]struct{}
-	IPAddresses map[int]struct{}
-	Ports       map[int]struct{}
-	mu          sync.Mutex
+	Ifaces        map[int]struct{}
+	IPAddresses   map[int]struct{}
+	IPv6Addresses map[int]struct{}
+	Ports         map[int]struct{}
+	mu            sync.Mutex
 }
 
 // NewAllocator creates new resource pool for wireguard connection.
 func NewAllocator() Allocator {
 	return Allocator{
-		Ifaces:      make(map[int]struct{}),
-		IPAddresses: make(map[int]struct{}),
-		Ports:       make(map[int]struct{}),
+		Ifaces:        make(map[int]struct{}),
+		IPAddresses:   make(map[int]struct{}),
+		IPv6Addresses: make(map[int]struct{}),
+		Ports:         make(map[int]struct{}),
 	}
 }
 
@@ -101,17 +113,55 @@ func (a *Allocator) AllocateIPNet() (net.IPNet, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	var s string
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return net.IPNet{}, err
+	}
+
 	for i := 0; i < maxResources; i++ {
 		if _, ok := a.IPAddresses[i]; !ok {
+			_, subnet, err := net.ParseCIDR(fmt.Sprintf("10.182.%d.0/24", i))
+			if err != nil {
+				return net.IPNet{}, err
+			}
+			if subnetInUse(addrs, *subnet) {
+				continue
+			}
+
 			a.IPAddresses[i] = struct{}{}
-			s = fmt.Sprintf("10.182.%d.0/24", i)
-			break
+			return *subnet, nil
+		}
+	}
+
+	return net.IPNet{}, errors.New("no more unused subnets")
+}
+
+// AllocateIPv6Net provides available IPv6 subnet for the wireguard connection.
+func (a *Allocator) AllocateIPv6Net() (net.IPNet, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return net.IPNet{}, err
+	}
+
+	for i := 0; i < maxResources; i++ {
+		if _, ok := a.IPv6Addresses[i]; !ok {
+			_, subnet, err := net.ParseCIDR(fmt.Sprintf(ipv6SubnetFormat, i))
+			if err != nil {
+				return net.IPNet{}, err
+			}
+			if subnetInUse(addrs, *subnet) {
+				continue
+			}
+
+			a.IPv6Addresses[i] = struct{}{}
+			return *subnet, nil
 		}
 	}
 
-	_, subnet, err := net.ParseCIDR(s)
-	return *subnet, err
+	return net.IPNet{}, errors.New("no more unused IPv6 subnets")
 }
 
 // AllocatePort provides available UDP port for the wireguard endpoint.
@@ -121,6 +171,10 @@ func (a *Allocator) AllocatePort() (int, error) {
 
 	for i := 52820; i < 52820+maxResources; i++ {
 		if _, ok := a.Ports[i]; !ok {
+			if portInUse(i) {
+				continue
+			}
+
 			a.Ports[i] = struct{}{}
 			return i, nil
 		}
@@ -152,12 +206,11 @@ func (a *Allocator) ReleaseIPNet(ipnet net.IPNet) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	ip4 := ipnet.IP.To4()
-	if len(ip4) != net.IPv4len {
+	i, err := ipNetIndex(ipnet)
+	if err != nil {
 		return errors.New("allocated subnet not found")
 	}
 
-	i := int(ip4[2])
 	if _, ok := a.IPAddresses[i]; !ok {
 		return errors.New("allocated subnet not found")
 	}
@@ -166,6 +219,24 @@ func (a *Allocator) ReleaseIPNet(ipnet net.IPNet) error {
 	return nil
 }
 
+// ReleaseIPv6Net releases IPv6 subnet.
+func (a *Allocator) ReleaseIPv6Net(ipnet net.IPNet) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i, err := ipv6NetIndex(ipnet)
+	if err != nil {
+		return errors.New("allocated IPv6 subnet not found")
+	}
+
+	if _, ok := a.IPv6Addresses[i]; !ok {
+		return errors.New("allocated IPv6 subnet not found")
+	}
+
+	delete(a.IPv6Addresses, i)
+	return nil
+}
+
 // ReleasePort releases UDP port.
 func (a *Allocator) ReleasePort(port int) error {
 	a.mu.Lock()
@@ -179,6 +250,96 @@ func (a *Allocator) ReleasePort(port int) error {
 	return nil
 }
 
+// ReserveInterface marks name of already existing network interface as allocated, so it can be taken over.
+func (a *Allocator) ReserveInterface(iface string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i, err := strconv.Atoi(strings.TrimPrefix(iface, interfacePrefix))
+	if err != nil {
+		return err
+	}
+
+	return reserve(a.Ifaces, i)
+}
+
+// ReserveIPNet marks given IP subnet as allocated, so it can be taken over.
+func (a *Allocator) ReserveIPNet(ipnet net.IPNet) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i, err := ipNetIndex(ipnet)
+	if err != nil {
+		return err
+	}
+
+	return reserve(a.IPAddresses, i)
+}
+
+// ReserveIPv6Net marks given IPv6 subnet as allocated, so it can be taken over.
+func (a *Allocator) ReserveIPv6Net(ipnet net.IPNet) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i, err := ipv6NetIndex(ipnet)
+	if err != nil {
+		return err
+	}
+
+	return reserve(a.IPv6Addresses, i)
+}
+
+// ReservePort marks given UDP port as allocated, so it can be taken over.
+func (a *Allocator) ReservePort(port int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return reserve(a.Ports, port)
+}
+
+// IsAllocated tells if given IPv4 or IPv6 subnet is allocated to a connection
+func (a *Allocator) IsAllocated(ipnet net.IPNet) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if i, err := ipNetIndex(ipnet); err == nil {
+		_, ok := a.IPAddresses[i]
+		return ok
+	}
+	if i, err := ipv6NetIndex(ipnet); err == nil {
+		_, ok := a.IPv6Addresses[i]
+		return ok
+	}
+	return false
+}
+
+func reserve(allocated map[int]struct{}, i int) error {
+	if _, ok := allocated[i]; ok {
+		return errors.New("resource is already allocated")
+	}
+
+	allocated[i] = struct{}{}
+	return nil
+}
+
+func ipNetIndex(ipnet net.IPNet) (int, error) {
+	ip4 := ipnet.IP.To4()
+	if len(ip4) != net.IPv4len {
+		return 0, errors.New("not an IPv4 subnet")
+	}
+
+	return int(ip4[2]), nil
+}
+
+func ipv6NetIndex(ipnet net.IPNet) (int, error) {
+	ip6 := ipnet.IP.To16()
+	if len(ip6) != net.IPv6len || ip6.To4() != nil {
+		return 0, errors.New("not an IPv6 subnet")
+	}
+
+	return int(ip6[6])<<8 | int(ip6[7]), nil
+}
+
 func interfaceExists(ifaces []net.Interface, name string) bool {
 	for _, iface := range ifaces {
 		if iface.Name == name {
@@ -187,3 +348,23 @@ func interfaceExists(ifaces []net.Interface, name string) bool {
 	}
 	return false
 }
+
+// subnetInUse tells if any of given addresses of network interfaces is within the subnet,
+// e.g. the one of interface which is kept to be recovered by another instance of the service
+func subnetInUse(addrs []net.Addr, subnet net.IPNet) bool {
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && subnet.Contains(ipNet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func portInUse(port int) bool {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		return true
+	}
+	_ = conn.Close()
+	return false
+}
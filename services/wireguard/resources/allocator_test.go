@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package resources
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Allocator_AllocatePortSkipsPortInUse(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 52820})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	allocator := NewAllocator()
+	port, err := allocator.AllocatePort()
+	assert.NoError(t, err)
+	assert.Equal(t, 52821, port)
+
+	assert.Error(t, allocator.ReleasePort(52820))
+	assert.NoError(t, allocator.ReservePort(52820))
+}
+
+func Test_SubnetInUse(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.182.0.0/24")
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.ParseIP("10.182.0.1"), Mask: net.CIDRMask(24, 32)},
+	}
+
+	assert.True(t, subnetInUse(addrs, *subnet))
+	assert.False(t, subnetInUse(addrs[:1], *subnet))
+}
@@ -19,33 +19,54 @@ package service
 
 import (
 	"encoding/json"
+	"net"
 	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/services/wireguard/resources"
 	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/shaper"
 )
 
 const logPrefix = "[service-wireguard] "
 
-// NewManager creates new instance of Wireguard service
-func NewManager(publicIP, outIP, country string) *Manager {
-	resourceAllocator := resources.NewAllocator()
+// NewManager creates new instance of Wireguard service, bandwidth of its sessions is limited by given limits
+// and their traffic is restricted by given egress policy. Resources of connections are taken from given allocator,
+// which is shared by all instances of the service.
+func NewManager(
+	resourceAllocator *resources.Allocator,
+	publicIP, outIP, outIPv6, country string,
+	bandwidth shaper.Limits,
+	egress firewall.EgressPolicy,
+	dns []net.IP,
+) *Manager {
 	return &Manager{
-		natService: nat.NewService(),
+		natService:    nat.NewService(),
+		shaper:        shaper.NewShaper(),
+		bandwidth:     bandwidth,
+		egressBlocker: firewall.NewEgressBlocker(egress),
+		egressSubnets: make(map[string]net.IPNet),
 
 		publicIP:        publicIP,
 		outboundIP:      outIP,
+		outboundIPv6:    outIPv6,
 		currentLocation: country,
+		dns:             dns,
 
+		resourceAllocator: resourceAllocator,
+		endpoints:         make(map[string]wg.ConnectionEndpoint),
+		peerActivity:      make(map[string]peerActivity),
 		connectionEndpointFactory: func() (wg.ConnectionEndpoint, error) {
-			return endpoint.NewConnectionEndpoint(publicIP, &resourceAllocator)
+			return endpoint.NewConnectionEndpoint(publicIP, outIPv6 != "", resourceAllocator)
 		},
 	}
 }
@@ -54,12 +75,31 @@ func NewManager(publicIP, outIP, country string) *Manager {
 type Manager struct {
 	wg         sync.WaitGroup
 	natService nat.NATService
+	shaper     shaper.Shaper
+	bandwidth  shaper.Limits
 
+	egressBlocker firewall.EgressBlocker
+	egressSubnets map[string]net.IPNet
+	egressMutex   sync.Mutex
+
+	resourceAllocator         *resources.Allocator
 	connectionEndpointFactory func() (wg.ConnectionEndpoint, error)
+	endpoints                 map[string]wg.ConnectionEndpoint
+	peerActivity              map[string]peerActivity
+	endpointsMutex            sync.Mutex
 
 	publicIP        string
 	outboundIP      string
+	outboundIPv6    string
 	currentLocation string
+	// dns are servers advertised to consumers
+	dns []net.IP
+}
+
+// peerActivity keeps amount of data received from the peer, when it was seen to grow the last time
+type peerActivity struct {
+	bytesReceived uint64
+	seenAt        time.Time
 }
 
 // ProvideConfig provides the config for consumer
@@ -87,16 +127,273 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 	if err != nil {
 		return nil, nil, err
 	}
+	config.Provider.DNS = manager.dns
 
-	manager.natService.Add(nat.RuleForwarding{
-		SourceAddress: config.Consumer.IPAddress.String(),
-		TargetIP:      manager.outboundIP,
+	destroy, err := manager.forward(config, connectionEndpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return config, destroy, nil
+}
+
+// RecoverSession takes over connection endpoint of the session which outlived the node.
+// NAT rules of the session which can't be recovered are removed.
+func (manager *Manager) RecoverSession(sessionInstance session.Session) (session.DestroyCallback, error) {
+	config, err := serviceConfig(sessionInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	connectionEndpoint, err := manager.connectionEndpointFactory()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := connectionEndpoint.Recover(config); err != nil {
+		for _, rule := range manager.natRules(config) {
+			manager.natService.Remove(rule)
+		}
+		return nil, err
+	}
+
+	return manager.forward(config, connectionEndpoint)
+}
+
+// Sweep destroys interfaces and NAT rules, which were left by previous run of the node without stored session.
+// Resources of given stored sessions are kept, as other instances of the service may still recover them.
+func (manager *Manager) Sweep(stored []session.Session) {
+	var recoverable []net.IPNet
+	for _, sessionInstance := range stored {
+		config, err := serviceConfig(sessionInstance)
+		if err != nil {
+			log.Warn(logPrefix, "Failed to read config of stored session ", sessionInstance.ID, ": ", err)
+			continue
+		}
+		recoverable = append(recoverable, consumerSubnets(config)...)
+	}
+
+	connectionEndpoint, err := manager.connectionEndpointFactory()
+	if err != nil {
+		log.Warn(logPrefix, "Failed to sweep orphaned interfaces: ", err)
+	} else if err := connectionEndpoint.Sweep(recoverable); err != nil {
+		log.Warn(logPrefix, "Failed to sweep orphaned interfaces: ", err)
+	}
+
+	manager.natService.RemoveStale(resources.Networks(), func(source net.IPNet) bool {
+		return manager.resourceAllocator.IsAllocated(source) || containsSubnet(recoverable, source)
 	})
+}
+
+// forward makes traffic of the connection endpoint leave via outbound IP, returned callback stops it and the endpoint
+func (manager *Manager) forward(config wg.ServiceConfig, connectionEndpoint wg.ConnectionEndpoint) (session.DestroyCallback, error) {
+	subnets := consumerSubnets(config)
+	if err := manager.restrictEgress(subnets); err != nil {
+		return nil, err
+	}
+
+	rules := manager.natRules(config)
+	for _, rule := range rules {
+		manager.natService.Add(rule)
+	}
 	if err := manager.natService.Start(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return config, connectionEndpoint.Stop, nil
+	iface := connectionEndpoint.InterfaceName()
+	addresses := consumerAddresses(config)
+	manager.shape(iface, addresses)
+
+	consumerIP := config.Consumer.IPAddress.String()
+	manager.endpointsMutex.Lock()
+	manager.endpoints[consumerIP] = connectionEndpoint
+	manager.endpointsMutex.Unlock()
+
+	return func() error {
+		manager.endpointsMutex.Lock()
+		delete(manager.endpoints, consumerIP)
+		delete(manager.peerActivity, consumerIP)
+		manager.endpointsMutex.Unlock()
+
+		manager.unshape(iface, addresses)
+		for _, rule := range rules {
+			manager.natService.Remove(rule)
+		}
+		manager.liftEgress(subnets)
+		return connectionEndpoint.Stop()
+	}, nil
+}
+
+// restrictEgress enforces egress policy on subnets of the connection, before its traffic is forwarded.
+// Subnets of connections are distinct even across service instances, so each instance enforces its own policy
+func (manager *Manager) restrictEgress(subnets []net.IPNet) error {
+	manager.egressMutex.Lock()
+	defer manager.egressMutex.Unlock()
+
+	for i, subnet := range subnets {
+		if err := manager.egressBlocker.Enable(subnet); err != nil {
+			manager.disableEgress(subnets[:i])
+			return err
+		}
+		manager.egressSubnets[subnet.String()] = subnet
+	}
+	return nil
+}
+
+func (manager *Manager) liftEgress(subnets []net.IPNet) {
+	manager.egressMutex.Lock()
+	defer manager.egressMutex.Unlock()
+
+	manager.disableEgress(subnets)
+}
+
+func (manager *Manager) disableEgress(subnets []net.IPNet) {
+	for _, subnet := range subnets {
+		if _, ok := manager.egressSubnets[subnet.String()]; !ok {
+			continue
+		}
+		delete(manager.egressSubnets, subnet.String())
+		if err := manager.egressBlocker.Disable(subnet); err != nil {
+			log.Warn(logPrefix, "Failed to remove egress restrictions of ", subnet.String(), ": ", err)
+		}
+	}
+}
+
+// shape limits bandwidth of consumer addresses, session is served even if limits can't be applied
+func (manager *Manager) shape(iface string, addresses []net.IP) {
+	if manager.bandwidth.IsUnlimited() {
+		return
+	}
+	for _, address := range addresses {
+		if err := manager.shaper.Shape(iface, address, manager.bandwidth); err != nil {
+			log.Error(logPrefix, "Failed to limit bandwidth of ", address, ": ", err)
+		}
+	}
+}
+
+func (manager *Manager) unshape(iface string, addresses []net.IP) {
+	if manager.bandwidth.IsUnlimited() {
+		return
+	}
+	for _, address := range addresses {
+		if err := manager.shaper.Unshape(iface, address); err != nil {
+			log.Warn(logPrefix, "Failed to remove bandwidth limits of ", address, ": ", err)
+		}
+	}
+}
+
+func consumerSubnets(config wg.ServiceConfig) []net.IPNet {
+	subnets := []net.IPNet{network(config.Consumer.IPAddress)}
+	if config.Consumer.IPv6Address != nil {
+		subnets = append(subnets, network(*config.Consumer.IPv6Address))
+	}
+	return subnets
+}
+
+func containsSubnet(subnets []net.IPNet, subnet net.IPNet) bool {
+	for _, existing := range subnets {
+		if existing.String() == subnet.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// network strips host part of the address
+func network(address net.IPNet) net.IPNet {
+	return net.IPNet{IP: address.IP.Mask(address.Mask), Mask: address.Mask}
+}
+
+func consumerAddresses(config wg.ServiceConfig) []net.IP {
+	addresses := []net.IP{config.Consumer.IPAddress.IP}
+	if config.Consumer.IPv6Address != nil {
+		addresses = append(addresses, config.Consumer.IPv6Address.IP)
+	}
+	return addresses
+}
+
+// SessionStats returns amount of data moved by the peer of given session
+func (manager *Manager) SessionStats(sessionInstance session.Session) (session.DataTransfer, error) {
+	config, err := serviceConfig(sessionInstance)
+	if err != nil {
+		return session.DataTransfer{}, err
+	}
+
+	manager.endpointsMutex.Lock()
+	connectionEndpoint, found := manager.endpoints[config.Consumer.IPAddress.String()]
+	manager.endpointsMutex.Unlock()
+	if !found {
+		return session.DataTransfer{}, nil
+	}
+
+	stats, err := connectionEndpoint.PeerStats()
+	if err != nil {
+		return session.DataTransfer{}, err
+	}
+	return session.DataTransfer{BytesSent: stats.BytesSent, BytesReceived: stats.BytesReceived}, nil
+}
+
+// LastActivity returns time when traffic from the peer of given session was last seen, or time of the latest handshake
+// with it if that's later. Handshakes alone aren't enough, since they aren't repeated while the peer is idle.
+func (manager *Manager) LastActivity(sessionInstance session.Session) (time.Time, bool) {
+	config, err := serviceConfig(sessionInstance)
+	if err != nil {
+		return time.Time{}, false
+	}
+	consumerIP := config.Consumer.IPAddress.String()
+
+	manager.endpointsMutex.Lock()
+	defer manager.endpointsMutex.Unlock()
+
+	connectionEndpoint, found := manager.endpoints[consumerIP]
+	if !found {
+		return time.Time{}, false
+	}
+
+	stats, err := connectionEndpoint.PeerStats()
+	if err != nil {
+		log.Warn(logPrefix, "Failed to get peer stats of session ", sessionInstance.ID, ": ", err)
+		return time.Time{}, false
+	}
+
+	activity, seen := manager.peerActivity[consumerIP]
+	if !seen || stats.BytesReceived != activity.bytesReceived {
+		activity = peerActivity{bytesReceived: stats.BytesReceived, seenAt: time.Now()}
+		if !seen && stats.BytesReceived == 0 {
+			activity.seenAt = time.Time{}
+		}
+		manager.peerActivity[consumerIP] = activity
+	}
+
+	if stats.LastHandshake.After(activity.seenAt) {
+		return stats.LastHandshake, true
+	}
+	return activity.seenAt, true
+}
+
+// serviceConfig restores wireguard config of the session, regardless of it being kept as struct or as raw json
+func serviceConfig(sessionInstance session.Session) (wg.ServiceConfig, error) {
+	var config wg.ServiceConfig
+	data, err := json.Marshal(sessionInstance.Config)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+func (manager *Manager) natRules(config wg.ServiceConfig) []nat.RuleForwarding {
+	rules := []nat.RuleForwarding{{
+		SourceAddress: config.Consumer.IPAddress.String(),
+		TargetIP:      manager.outboundIP,
+	}}
+	if config.Consumer.IPv6Address != nil && manager.outboundIPv6 != "" {
+		rules = append(rules, nat.RuleForwarding{
+			SourceAddress: config.Consumer.IPv6Address.String(),
+			TargetIP:      manager.outboundIPv6,
+		})
+	}
+	return rules
 }
 
 // Serve starts service - does block
@@ -109,16 +406,19 @@ func (manager *Manager) Serve(providerID identity.Identity) error {
 }
 
 // GetProposal returns the proposal for wireguard service
-func GetProposal(country string) market.ServiceProposal {
+func GetProposal(country string, bandwidth shaper.Limits, egress firewall.EgressPolicy) market.ServiceProposal {
 	return market.ServiceProposal{
 		ServiceType: wg.ServiceType,
 		ServiceDefinition: wg.ServiceDefinition{
-			Location: market.Location{Country: country},
+			Location:                 market.Location{Country: country},
+			SessionUploadBandwidth:   dto.Bandwidth(bandwidth.Upload),
+			SessionDownloadBandwidth: dto.Bandwidth(bandwidth.Download),
 		},
 		PaymentMethodType: wg.PaymentMethod,
 		PaymentMethod: wg.Payment{
 			Price: money.NewMoney(0, money.CURRENCY_MYST),
 		},
+		EgressPolicy: egress.Advertised(),
 	}
 }
 
@@ -127,6 +427,14 @@ func (manager *Manager) Stop() error {
 	manager.wg.Done()
 	manager.natService.Stop()
 
+	manager.egressMutex.Lock()
+	subnets := make([]net.IPNet, 0, len(manager.egressSubnets))
+	for _, subnet := range manager.egressSubnets {
+		subnets = append(subnets, subnet)
+	}
+	manager.disableEgress(subnets)
+	manager.egressMutex.Unlock()
+
 	log.Info(logPrefix, "Wireguard service stopped")
 	return nil
 }
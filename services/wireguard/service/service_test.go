@@ -19,15 +19,22 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/resources"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/shaper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,7 +53,8 @@ func Test_GetProposal(t *testing.T) {
 		market.ServiceProposal{
 			ServiceType: "wireguard",
 			ServiceDefinition: wg.ServiceDefinition{
-				Location: market.Location{Country: country},
+				Location:                 market.Location{Country: country},
+				SessionDownloadBandwidth: 8388608,
 			},
 			PaymentMethodType: "WG",
 			PaymentMethod: wg.Payment{
@@ -55,8 +63,9 @@ func Test_GetProposal(t *testing.T) {
 					Currency: money.Currency("MYST"),
 				},
 			},
+			EgressPolicy: &market.EgressPolicy{BlockedPorts: []int{25}},
 		},
-		GetProposal(country),
+		GetProposal(country, shaper.Limits{Download: 1 * datasize.MB}, firewall.EgressPolicy{BlockedPorts: []int{25}}),
 	)
 }
 
@@ -86,20 +95,219 @@ func Test_Manager_Stop(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Manager_RestrictsEgressOfEachConnectionUntilStopped(t *testing.T) {
+	egressBlocker := &fakeEgressBlocker{}
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.outboundIPv6 = "2001:db8::1"
+	manager.egressBlocker = egressBlocker
+
+	var config wg.ServiceConfig
+	config.Consumer.IPAddress = net.IPNet{IP: net.ParseIP("10.182.1.2"), Mask: net.CIDRMask(24, 32)}
+	config.Consumer.IPv6Address = &net.IPNet{IP: net.ParseIP("fd6d:7973:7400:1::2"), Mask: net.CIDRMask(64, 128)}
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return &fakeConnectionEndpoint{config: config}, nil
+	}
+
+	_, destroy, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.182.1.0/24", "fd6d:7973:7400:1::/64"}, egressBlocker.enabled)
+
+	assert.NoError(t, destroy())
+	assert.Equal(t, []string{"10.182.1.0/24", "fd6d:7973:7400:1::/64"}, egressBlocker.disabled)
+
+	config.Consumer.IPAddress = net.IPNet{IP: net.ParseIP("10.182.2.2"), Mask: net.CIDRMask(24, 32)}
+	config.Consumer.IPv6Address = nil
+	_, _, err = manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.182.1.0/24", "fd6d:7973:7400:1::/64", "10.182.2.0/24"}, egressBlocker.enabled)
+
+	go manager.Serve(providerID)
+	waitABit()
+	assert.NoError(t, manager.Stop())
+	assert.Equal(t, []string{"10.182.1.0/24", "fd6d:7973:7400:1::/64", "10.182.2.0/24"}, egressBlocker.disabled)
+}
+
+func Test_Manager_ProvideConfigFailsWhenEgressCanNotBeRestricted(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.egressBlocker = &fakeEgressBlocker{err: errors.New("iptables failed")}
+
+	_, _, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.EqualError(t, err, "iptables failed")
+}
+
 // usually time.Sleep call gives a chance for other goroutines to kick in important when testing async code
 func waitABit() {
 	time.Sleep(10 * time.Millisecond)
 }
 
-type fakeConnectionEndpoint struct{}
+func Test_Manager_RecoverSessionForwardsTrafficOfRecoveredEndpoint(t *testing.T) {
+	natService := &serviceFake{}
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.natService = natService
+	endpoint := &fakeConnectionEndpoint{}
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return endpoint, nil
+	}
+
+	destroy, err := manager.RecoverSession(session.Session{Config: json.RawMessage(recoveredConfig)})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.1.2/24", endpoint.recovered.Consumer.IPAddress.String())
+	assert.Equal(t, []nat.RuleForwarding{{SourceAddress: "10.182.1.2/24", TargetIP: outIP}}, natService.rules)
+
+	assert.NoError(t, destroy())
+	assert.Len(t, natService.rules, 0)
+	assert.True(t, endpoint.stopped)
+}
+
+func Test_Manager_LimitsBandwidthOfSessionUntilItIsDestroyed(t *testing.T) {
+	trafficShaper := &fakeShaper{shaped: make(map[string]shaper.Limits)}
+	limits := shaper.Limits{Upload: 1 * datasize.MB}
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.shaper = trafficShaper
+	manager.bandwidth = limits
+
+	destroy, err := manager.RecoverSession(session.Session{Config: json.RawMessage(recoveredConfig)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]shaper.Limits{"wg0 10.182.1.2": limits}, trafficShaper.shaped)
+
+	assert.NoError(t, destroy())
+	assert.Empty(t, trafficShaper.shaped)
+}
+
+func Test_Manager_RecoverSessionRemovesNATRulesOfDeadSession(t *testing.T) {
+	natService := &serviceFake{}
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.natService = natService
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return &fakeConnectionEndpoint{recoverErr: errors.New("interface is gone")}, nil
+	}
+
+	_, err := manager.RecoverSession(session.Session{Config: json.RawMessage(recoveredConfig)})
+	assert.EqualError(t, err, "interface is gone")
+	assert.Equal(t, []nat.RuleForwarding{{SourceAddress: "10.182.1.2/24", TargetIP: outIP}}, natService.removed)
+}
+
+const recoveredConfig = `{
+	"provider": {"public_key": "wg1", "endpoint": "1.2.3.4:52820"},
+	"consumer": {"ip_address": "10.182.1.2/24"}
+}`
+
+func Test_Manager_SessionStatsReportsTrafficOfSessionPeer(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	endpoint := &fakeConnectionEndpoint{stats: wg.Stats{BytesSent: 10, BytesReceived: 20}}
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return endpoint, nil
+	}
+	sessionInstance := session.Session{Config: json.RawMessage(recoveredConfig)}
+
+	destroy, err := manager.RecoverSession(sessionInstance)
+	assert.NoError(t, err)
+	stats, err := manager.SessionStats(sessionInstance)
+	assert.NoError(t, err)
+	assert.Equal(t, session.DataTransfer{BytesSent: 10, BytesReceived: 20}, stats)
+
+	assert.NoError(t, destroy())
+	stats, err = manager.SessionStats(sessionInstance)
+	assert.NoError(t, err)
+	assert.Equal(t, session.DataTransfer{}, stats)
+}
+
+func Test_Manager_LastActivityIsLatestHandshakeOfSessionPeer(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	handshake := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return &fakeConnectionEndpoint{stats: wg.Stats{LastHandshake: handshake}}, nil
+	}
+	sessionInstance := session.Session{Config: json.RawMessage(recoveredConfig)}
 
-func (fce *fakeConnectionEndpoint) Stop() error                            { return nil }
-func (fce *fakeConnectionEndpoint) Start(_ *wg.ServiceConfig) error        { return nil }
-func (fce *fakeConnectionEndpoint) Config() (wg.ServiceConfig, error)      { return wg.ServiceConfig{}, nil }
-func (fce *fakeConnectionEndpoint) AddPeer(_ string, _ *net.UDPAddr) error { return nil }
-func (fce *fakeConnectionEndpoint) ConfigureRoutes(_ net.IP) error         { return nil }
+	_, known := manager.LastActivity(sessionInstance)
+	assert.False(t, known)
+
+	_, err := manager.RecoverSession(sessionInstance)
+	assert.NoError(t, err)
+	activity, known := manager.LastActivity(sessionInstance)
+	assert.True(t, known)
+	assert.Equal(t, handshake, activity)
+}
+
+func Test_Manager_LastActivityFollowsTrafficReceivedFromSessionPeer(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	handshake := time.Now().Add(-time.Hour)
+	endpoint := &fakeConnectionEndpoint{stats: wg.Stats{LastHandshake: handshake}}
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return endpoint, nil
+	}
+	sessionInstance := session.Session{Config: json.RawMessage(recoveredConfig)}
+	_, err := manager.RecoverSession(sessionInstance)
+	assert.NoError(t, err)
+
+	activity, _ := manager.LastActivity(sessionInstance)
+	assert.Equal(t, handshake, activity)
+
+	endpoint.stats.BytesReceived = 100
+	activity, _ = manager.LastActivity(sessionInstance)
+	assert.WithinDuration(t, time.Now(), activity, time.Second)
+
+	seenAt := activity
+	time.Sleep(time.Millisecond)
+	activity, _ = manager.LastActivity(sessionInstance)
+	assert.Equal(t, seenAt, activity)
+}
+
+func Test_Manager_SweepKeepsResourcesOfStoredAndAllocatedSessions(t *testing.T) {
+	allocator := resources.NewAllocator()
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.resourceAllocator = &allocator
+	allocated, err := allocator.AllocateIPNet()
+	assert.NoError(t, err)
+
+	natService := &serviceFake{}
+	for _, source := range []string{"10.182.1.0/24", allocated.String(), "10.182.9.0/24"} {
+		_, subnet, _ := net.ParseCIDR(source)
+		natService.existing = append(natService.existing, *subnet)
+	}
+	manager.natService = natService
+	connectionEndpoint := &fakeConnectionEndpoint{}
+	manager.connectionEndpointFactory = func() (wg.ConnectionEndpoint, error) {
+		return connectionEndpoint, nil
+	}
+
+	manager.Sweep([]session.Session{{Config: json.RawMessage(recoveredConfig)}})
+
+	assert.Equal(t, []string{"10.182.9.0/24"}, natService.stale)
+	_, stored, _ := net.ParseCIDR("10.182.1.0/24")
+	assert.Equal(t, []net.IPNet{*stored}, connectionEndpoint.swept)
+}
+
+type fakeConnectionEndpoint struct {
+	config     wg.ServiceConfig
+	recoverErr error
+	recovered  wg.ServiceConfig
+	stopped    bool
+	stats      wg.Stats
+	swept      []net.IPNet
+}
+
+func (fce *fakeConnectionEndpoint) Stop() error {
+	fce.stopped = true
+	return nil
+}
+func (fce *fakeConnectionEndpoint) Start(_ *wg.ServiceConfig) error { return nil }
+func (fce *fakeConnectionEndpoint) Recover(config wg.ServiceConfig) error {
+	fce.recovered = config
+	return fce.recoverErr
+}
+func (fce *fakeConnectionEndpoint) Sweep(recoverable []net.IPNet) error {
+	fce.swept = recoverable
+	return nil
+}
+func (fce *fakeConnectionEndpoint) Config() (wg.ServiceConfig, error)                   { return fce.config, nil }
+func (fce *fakeConnectionEndpoint) AddPeer(_ string, _ *net.UDPAddr) error              { return nil }
+func (fce *fakeConnectionEndpoint) ConfigureRoutes(_ net.IP, _ connection.Routes) error { return nil }
+func (fce *fakeConnectionEndpoint) ConfigureDNS(_ []net.IP) error                       { return nil }
+func (fce *fakeConnectionEndpoint) InterfaceName() string                               { return "wg0" }
 func (fce *fakeConnectionEndpoint) PeerStats() (wg.Stats, error) {
-	return wg.Stats{LastHandshake: time.Now()}, nil
+	return fce.stats, nil
 }
 
 func newManagerStub(pub, out, country string) *Manager {
@@ -108,14 +316,75 @@ func newManagerStub(pub, out, country string) *Manager {
 		publicIP:        pub,
 		outboundIP:      out,
 		natService:      &serviceFake{},
+		egressBlocker:   &fakeEgressBlocker{},
+		egressSubnets:   make(map[string]net.IPNet),
+		endpoints:       make(map[string]wg.ConnectionEndpoint),
+		peerActivity:    make(map[string]peerActivity),
 		connectionEndpointFactory: func() (wg.ConnectionEndpoint, error) {
 			return connectionEndpointStub, nil
 		},
 	}
 }
 
-type serviceFake struct{}
+type fakeEgressBlocker struct {
+	enabled  []string
+	disabled []string
+	err      error
+}
+
+func (eb *fakeEgressBlocker) Enable(subnet net.IPNet) error {
+	if eb.err != nil {
+		return eb.err
+	}
+	eb.enabled = append(eb.enabled, subnet.String())
+	return nil
+}
+
+func (eb *fakeEgressBlocker) Disable(subnet net.IPNet) error {
+	eb.disabled = append(eb.disabled, subnet.String())
+	return nil
+}
+
+type fakeShaper struct {
+	shaped map[string]shaper.Limits
+}
+
+func (fs *fakeShaper) Shape(iface string, address net.IP, limits shaper.Limits) error {
+	fs.shaped[iface+" "+address.String()] = limits
+	return nil
+}
+
+func (fs *fakeShaper) Unshape(iface string, address net.IP) error {
+	delete(fs.shaped, iface+" "+address.String())
+	return nil
+}
+
+type serviceFake struct {
+	rules   []nat.RuleForwarding
+	removed []nat.RuleForwarding
+	// existing are sources of rules found in the system
+	existing []net.IPNet
+	stale    []string
+}
 
-func (service *serviceFake) Add(rule nat.RuleForwarding) {}
-func (service *serviceFake) Start() error                { return nil }
-func (service *serviceFake) Stop()                       {}
+func (service *serviceFake) Add(rule nat.RuleForwarding) {
+	service.rules = append(service.rules, rule)
+}
+func (service *serviceFake) Remove(rule nat.RuleForwarding) {
+	service.removed = append(service.removed, rule)
+	for i := range service.rules {
+		if service.rules[i] == rule {
+			service.rules = append(service.rules[:i], service.rules[i+1:]...)
+			break
+		}
+	}
+}
+func (service *serviceFake) RemoveStale(within []net.IPNet, inUse func(source net.IPNet) bool) {
+	for _, source := range service.existing {
+		if !inUse(source) {
+			service.stale = append(service.stale, source.String())
+		}
+	}
+}
+func (service *serviceFake) Start() error { return nil }
+func (service *serviceFake) Stop()        {}
@@ -22,8 +22,10 @@ import (
 	"net"
 	"time"
 
+	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 )
 
 // ServiceType indicates "wireguard" service type
@@ -33,6 +35,12 @@ const ServiceType = "wireguard"
 type ServiceDefinition struct {
 	// Approximate information on location where the service is provided from
 	Location market.Location `json:"location"`
+
+	// Upload rate limit of each session, upload is not limited if empty
+	SessionUploadBandwidth dto.Bandwidth `json:"session_upload_bandwidth,omitempty"`
+
+	// Download rate limit of each session, download is not limited if empty
+	SessionDownloadBandwidth dto.Bandwidth `json:"session_download_bandwidth,omitempty"`
 }
 
 // GetLocation returns geographic location of service definition provider
@@ -57,10 +65,14 @@ func (method Payment) GetPrice() money.Money {
 // required for establishing connection between service provider and consumer.
 type ConnectionEndpoint interface {
 	Start(config *ServiceConfig) error
+	Recover(config ServiceConfig) error
+	Sweep(recoverable []net.IPNet) error
 	AddPeer(publicKey string, endpoint *net.UDPAddr) error
 	PeerStats() (Stats, error)
-	ConfigureRoutes(ip net.IP) error
+	ConfigureRoutes(ip net.IP, routes connection.Routes) error
+	ConfigureDNS(servers []net.IP) error
 	Config() (ServiceConfig, error)
+	InterfaceName() string
 	Stop() error
 }
 
@@ -98,22 +110,32 @@ type ServiceConfig struct {
 	Provider struct {
 		PublicKey string
 		Endpoint  net.UDPAddr
+		// DNS are servers advertised by provider, which consumer queries through the tunnel
+		DNS []net.IP
 	}
 	Consumer struct {
-		PrivateKey string `json:"-"`
-		IPAddress  net.IPNet
+		PrivateKey  string `json:"-"`
+		IPAddress   net.IPNet
+		IPv6Address *net.IPNet
 	}
 }
 
 // MarshalJSON implements json.Marshaler interface to provide human readable configuration.
 func (s ServiceConfig) MarshalJSON() ([]byte, error) {
 	type provider struct {
-		PublicKey string `json:"public_key"`
-		Endpoint  string `json:"endpoint"`
+		PublicKey string   `json:"public_key"`
+		Endpoint  string   `json:"endpoint"`
+		DNS       []net.IP `json:"dns,omitempty"`
 	}
 	type consumer struct {
-		PrivateKey string `json:"private_key"`
-		IPAddress  string `json:"ip_address"`
+		PrivateKey  string `json:"private_key"`
+		IPAddress   string `json:"ip_address"`
+		IPv6Address string `json:"ipv6_address,omitempty"`
+	}
+
+	var ipv6Address string
+	if s.Consumer.IPv6Address != nil {
+		ipv6Address = s.Consumer.IPv6Address.String()
 	}
 
 	return json.Marshal(&struct {
@@ -123,9 +145,11 @@ func (s ServiceConfig) MarshalJSON() ([]byte, error) {
 		provider{
 			s.Provider.PublicKey,
 			s.Provider.Endpoint.String(),
+			s.Provider.DNS,
 		},
 		consumer{
-			IPAddress: s.Consumer.IPAddress.String(),
+			IPAddress:   s.Consumer.IPAddress.String(),
+			IPv6Address: ipv6Address,
 		},
 	})
 }
@@ -133,12 +157,14 @@ func (s ServiceConfig) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON implements json.Unmarshaler interface to receive human readable configuration.
 func (s *ServiceConfig) UnmarshalJSON(data []byte) error {
 	type provider struct {
-		PublicKey string `json:"public_key"`
-		Endpoint  string `json:"endpoint"`
+		PublicKey string   `json:"public_key"`
+		Endpoint  string   `json:"endpoint"`
+		DNS       []net.IP `json:"dns,omitempty"`
 	}
 	type consumer struct {
-		PrivateKey string `json:"private_key"`
-		IPAddress  string `json:"ip_address"`
+		PrivateKey  string `json:"private_key"`
+		IPAddress   string `json:"ip_address"`
+		IPv6Address string `json:"ipv6_address,omitempty"`
 	}
 	var config struct {
 		Provider provider `json:"provider"`
@@ -160,8 +186,18 @@ func (s *ServiceConfig) UnmarshalJSON(data []byte) error {
 
 	s.Provider.Endpoint = *endpoint
 	s.Provider.PublicKey = config.Provider.PublicKey
+	s.Provider.DNS = config.Provider.DNS
 	s.Consumer.IPAddress = *ipnet
 	s.Consumer.IPAddress.IP = ip
 
+	if config.Consumer.IPv6Address != "" {
+		ip, ipnet, err := net.ParseCIDR(config.Consumer.IPv6Address)
+		if err != nil {
+			return err
+		}
+		ipnet.IP = ip
+		s.Consumer.IPv6Address = ipnet
+	}
+
 	return nil
 }
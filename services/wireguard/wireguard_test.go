@@ -19,6 +19,7 @@ package wireguard
 
 import (
 	"encoding/json"
+	"net"
 	"testing"
 
 	"github.com/mysteriumnetwork/node/money"
@@ -101,3 +102,53 @@ func Test_PaymentMethod_Unserialize(t *testing.T) {
 		assert.Equal(t, test.expectedError, err)
 	}
 }
+
+func Test_ServiceConfig_SerializeIPv6(t *testing.T) {
+	config := ServiceConfig{}
+	config.Provider.PublicKey = "wg1"
+	config.Provider.Endpoint = net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: 51820}
+	config.Consumer.IPAddress = net.IPNet{IP: net.ParseIP("10.182.0.2").To4(), Mask: net.CIDRMask(24, 32)}
+
+	jsonBytes, err := json.Marshal(config)
+	assert.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+			"provider": {"public_key": "wg1", "endpoint": "1.2.3.4:51820"},
+			"consumer": {"private_key": "", "ip_address": "10.182.0.2/24"}
+		}`,
+		string(jsonBytes),
+	)
+
+	config.Consumer.IPv6Address = &net.IPNet{IP: net.ParseIP("fd6d:7973:7400:1::2"), Mask: net.CIDRMask(64, 128)}
+	jsonBytes, err = json.Marshal(config)
+	assert.NoError(t, err)
+
+	var restored ServiceConfig
+	assert.NoError(t, json.Unmarshal(jsonBytes, &restored))
+	assert.Equal(t, "10.182.0.2/24", restored.Consumer.IPAddress.String())
+	assert.Equal(t, "fd6d:7973:7400:1::2/64", restored.Consumer.IPv6Address.String())
+}
+
+func Test_ServiceConfig_SerializeDNS(t *testing.T) {
+	config := ServiceConfig{}
+	config.Provider.PublicKey = "wg1"
+	config.Provider.Endpoint = net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: 51820}
+	config.Provider.DNS = []net.IP{net.ParseIP("8.8.8.8")}
+	config.Consumer.IPAddress = net.IPNet{IP: net.ParseIP("10.182.0.2").To4(), Mask: net.CIDRMask(24, 32)}
+
+	jsonBytes, err := json.Marshal(config)
+	assert.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+			"provider": {"public_key": "wg1", "endpoint": "1.2.3.4:51820", "dns": ["8.8.8.8"]},
+			"consumer": {"private_key": "", "ip_address": "10.182.0.2/24"}
+		}`,
+		string(jsonBytes),
+	)
+
+	var restored ServiceConfig
+	assert.NoError(t, json.Unmarshal(jsonBytes, &restored))
+	assert.Equal(t, config.Provider.DNS, restored.Provider.DNS)
+}
@@ -20,10 +20,13 @@ package session
 import (
 	"encoding/json"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 )
 
+const createConsumerLogPrefix = "[session-create-consumer] "
+
 // createConsumer processes session create requests from communication channel.
 type createConsumer struct {
 	sessionCreator Creator
@@ -57,11 +60,19 @@ func (consumer *createConsumer) Consume(requestPtr interface{}) (response interf
 	}
 
 	sessionInstance, err := consumer.sessionCreator.Create(consumer.peerID, request.ProposalId, config, destroyCallback)
+	if err != nil && destroyCallback != nil {
+		if errDestroy := destroyCallback(); errDestroy != nil {
+			log.Warn(createConsumerLogPrefix, "Failed to clean up config of rejected session: ", errDestroy)
+		}
+	}
+
 	switch err {
 	case nil:
 		return responseWithSession(sessionInstance), nil
 	case ErrorInvalidProposal:
 		return responseInvalidProposal, nil
+	case ErrorCapacityExceeded:
+		return responseCapacityExceeded, nil
 	default:
 		return responseInternalError, nil
 	}
@@ -98,6 +98,29 @@ func TestConsumer_ErrorFatal(t *testing.T) {
 	assert.Exactly(t, responseInternalError, sessionResponse)
 }
 
+func TestConsumer_ErrorCapacityExceeded(t *testing.T) {
+	mockManager := &managerFake{
+		returnError: ErrorCapacityExceeded,
+	}
+	destroyed := false
+	consumer := createConsumer{
+		sessionCreator: mockManager,
+		configProvider: func(json.RawMessage) (ServiceConfiguration, DestroyCallback, error) {
+			return nil, func() error {
+				destroyed = true
+				return nil
+			}, nil
+		},
+	}
+
+	request := consumer.NewRequest().(*CreateRequest)
+	sessionResponse, err := consumer.Consume(request)
+
+	assert.NoError(t, err)
+	assert.Exactly(t, responseCapacityExceeded, sessionResponse)
+	assert.True(t, destroyed)
+}
+
 // managerFake represents fake Manager usually useful in tests
 type managerFake struct {
 	lastConsumerID identity.Identity
@@ -25,9 +25,13 @@ import (
 
 const endpointSessionCreate = communication.RequestEndpoint("session-create")
 
+// CodeCapacityExceeded tells consumer that provider doesn't take more sessions and another provider should be tried
+const CodeCapacityExceeded = "capacity_exceeded"
+
 var (
-	responseInvalidProposal = CreateResponse{Success: false, Message: "Invalid Proposal"}
-	responseInternalError   = CreateResponse{Success: false, Message: "Internal Error"}
+	responseInvalidProposal  = CreateResponse{Success: false, Message: "Invalid Proposal"}
+	responseInternalError    = CreateResponse{Success: false, Message: "Internal Error"}
+	responseCapacityExceeded = CreateResponse{Success: false, Message: "Capacity Exceeded", Code: CodeCapacityExceeded}
 )
 
 // CreateRequest structure represents message from service consumer to initiate session for given proposal id
@@ -40,6 +44,7 @@ type CreateRequest struct {
 type CreateResponse struct {
 	Success bool       `json:"success"`
 	Message string     `json:"message"`
+	Code    string     `json:"code,omitempty"`
 	Session SessionDto `json:"session"`
 }
 
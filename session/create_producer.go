@@ -60,6 +60,10 @@ func RequestSessionCreate(sender communication.Sender, proposalID int, config in
 	}
 
 	response := responsePtr.(*CreateResponse)
+	if !response.Success && response.Code == CodeCapacityExceeded {
+		err = ErrorCapacityExceeded
+		return
+	}
 	if !response.Success {
 		err = errors.New("Session create failed. " + response.Message)
 		return
@@ -51,8 +51,15 @@ func TestProducer_RequestSessionCreate(t *testing.T) {
 	assert.Exactly(t, succesfullSessionConfig, config)
 }
 
+func TestProducer_RequestSessionCreateCapacityExceeded(t *testing.T) {
+	sender := &fakeSender{response: &responseCapacityExceeded}
+	_, _, err := RequestSessionCreate(sender, 123, []byte{})
+	assert.Exactly(t, ErrorCapacityExceeded, err)
+}
+
 type fakeSender struct {
 	lastRequest communication.RequestProducer
+	response    *CreateResponse
 }
 
 func (sender *fakeSender) Send(producer communication.MessageProducer) error {
@@ -61,6 +68,9 @@ func (sender *fakeSender) Send(producer communication.MessageProducer) error {
 
 func (sender *fakeSender) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
 	sender.lastRequest = producer
+	if sender.response != nil {
+		return sender.response, nil
+	}
 	return &CreateResponse{
 		Success: true,
 		Message: "Everything is great!",
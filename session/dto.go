@@ -17,7 +17,12 @@
 
 package session
 
-import "github.com/mysteriumnetwork/node/identity"
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+)
 
 // ID represents session id type
 type ID string
@@ -25,9 +30,76 @@ type ID string
 // Session structure holds all required information about current session between service consumer and provider
 type Session struct {
 	ID              ID
+	ServiceType     string
 	Config          ServiceConfiguration
 	ConsumerID      identity.Identity
+	CreatedAt       time.Time
 	DestroyCallback DestroyCallback
+
+	// session is created by service instance offered with proposal of ProposalID, on terms of its ProposalVersion
+	ProposalID      int
+	ProposalVersion int
+
+	// these are known only for sessions created since the node started
+	promiseProcessor PromiseProcessor
+	dialog           communication.Dialog
+	statsProvider    StatsProvider
+	activityProvider ActivityProvider
+}
+
+// DataTransferred tells how much data session has moved so far, it's zero if service doesn't account traffic
+func (s Session) DataTransferred() (DataTransfer, error) {
+	if s.statsProvider == nil {
+		return DataTransfer{}, nil
+	}
+	return s.statsProvider.SessionStats(s)
+}
+
+// PromisesReceived tells how many promises consumer has sent for the session
+func (s Session) PromisesReceived() int {
+	if s.promiseProcessor == nil {
+		return 0
+	}
+	return s.promiseProcessor.PromisesReceived()
+}
+
+// LastSeen tells when consumer of the session was last seen alive, false if neither service nor promises can tell it
+func (s Session) LastSeen() (time.Time, bool) {
+	lastSeen, known := s.CreatedAt, false
+	if s.activityProvider != nil {
+		if activity, ok := s.activityProvider.LastActivity(s); ok {
+			known = true
+			if activity.After(lastSeen) {
+				lastSeen = activity
+			}
+		}
+	}
+	if s.promiseProcessor != nil {
+		if promised, ok := s.promiseProcessor.LastPromiseReceived(); ok {
+			known = true
+			if promised.After(lastSeen) {
+				lastSeen = promised
+			}
+		}
+	}
+	return lastSeen, known
+}
+
+// DataTransfer is the amount of data session has moved, from provider's point of view
+type DataTransfer struct {
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// StatsProvider knows how much data sessions of the service have moved
+type StatsProvider interface {
+	SessionStats(sessionInstance Session) (DataTransfer, error)
+}
+
+// ActivityProvider knows when consumers of the service were last seen alive
+type ActivityProvider interface {
+	// LastActivity returns false if service can't tell about activity of the session
+	LastActivity(sessionInstance Session) (time.Time, bool)
 }
 
 // ServiceConfiguration defines service configuration from underlying transport mechanism to be passed to remote party
@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+// ErrorCapacityExceeded returned when provider's service can't take more sessions and consumer should try another provider
+var ErrorCapacityExceeded = errors.New("service capacity exceeded")
+
+// Limits defines how many sessions service takes, zero value of each limit means no limit
+type Limits struct {
+	MaxSessions             int
+	MaxSessionsPerConsumer  int
+	MaxNewSessionsPerMinute int
+}
+
+// NewLimiter creates limiter, which keeps sessions of service instance with given proposal id in storage within limits
+func NewLimiter(proposalID int, limits Limits, storage Storage) *Limiter {
+	return &Limiter{
+		proposalID: proposalID,
+		limits:     limits,
+		storage:    storage,
+		now:        time.Now,
+	}
+}
+
+// Limiter admits new sessions of single service while its limits aren't reached
+type Limiter struct {
+	proposalID int
+	limits     Limits
+	storage    Storage
+	now        func() time.Time
+
+	mutex   sync.Mutex
+	created []time.Time
+}
+
+// Admit runs session creation if consumer's session fits into limits, ErrorCapacityExceeded is returned otherwise.
+// Admissions are serialized, so that concurrent sessions can't exceed limits together.
+func (limiter *Limiter) Admit(consumerID identity.Identity, create func() error) error {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	limiter.forgetCreatedBefore(now.Add(-time.Minute))
+	if !limiter.fits(consumerID) {
+		return ErrorCapacityExceeded
+	}
+
+	if err := create(); err != nil {
+		return err
+	}
+	if limiter.limits.MaxNewSessionsPerMinute > 0 {
+		limiter.created = append(limiter.created, now)
+	}
+	return nil
+}
+
+// Load tells how many sessions service currently has
+func (limiter *Limiter) Load() market.ServiceLoad {
+	sessions, _ := limiter.count(identity.Identity{})
+	return market.ServiceLoad{
+		Sessions:    sessions,
+		MaxSessions: limiter.limits.MaxSessions,
+	}
+}
+
+func (limiter *Limiter) fits(consumerID identity.Identity) bool {
+	sessions, consumerSessions := limiter.count(consumerID)
+
+	if limiter.limits.MaxSessions > 0 && sessions >= limiter.limits.MaxSessions {
+		return false
+	}
+	if limiter.limits.MaxSessionsPerConsumer > 0 && consumerSessions >= limiter.limits.MaxSessionsPerConsumer {
+		return false
+	}
+	if limiter.limits.MaxNewSessionsPerMinute > 0 && len(limiter.created) >= limiter.limits.MaxNewSessionsPerMinute {
+		return false
+	}
+	return true
+}
+
+func (limiter *Limiter) count(consumerID identity.Identity) (sessions, consumerSessions int) {
+	for _, sessionInstance := range limiter.storage.GetAll() {
+		if sessionInstance.ProposalID != limiter.proposalID {
+			continue
+		}
+		sessions++
+		if sessionInstance.ConsumerID == consumerID {
+			consumerSessions++
+		}
+	}
+	return sessions, consumerSessions
+}
+
+func (limiter *Limiter) forgetCreatedBefore(since time.Time) {
+	i := 0
+	for i < len(limiter.created) && limiter.created[i].Before(since) {
+		i++
+	}
+	limiter.created = limiter.created[i:]
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	limitedConsumer = identity.FromAddress("0x1")
+	otherConsumer   = identity.FromAddress("0x2")
+)
+
+const (
+	limitedProposalID = 1
+	otherProposalID   = 2
+)
+
+func admitSession(limiter *Limiter, storage Storage, id ID, consumerID identity.Identity) error {
+	return limiter.Admit(consumerID, func() error {
+		storage.Add(Session{ID: id, ServiceType: "limited", ProposalID: limitedProposalID, ConsumerID: consumerID})
+		return nil
+	})
+}
+
+func TestLimiter_AdmitsEverythingWithoutLimits(t *testing.T) {
+	storage := NewStorageMemory()
+	limiter := NewLimiter(limitedProposalID, Limits{}, storage)
+
+	for _, id := range []ID{"1", "2", "3"} {
+		assert.NoError(t, admitSession(limiter, storage, id, limitedConsumer))
+	}
+	assert.Len(t, storage.GetAll(), 3)
+}
+
+func TestLimiter_RejectsSessionsOverMaxSessions(t *testing.T) {
+	storage := NewStorageMemory()
+	storage.Add(Session{ID: "other-instance", ServiceType: "limited", ProposalID: otherProposalID, ConsumerID: otherConsumer})
+	limiter := NewLimiter(limitedProposalID, Limits{MaxSessions: 2}, storage)
+
+	assert.NoError(t, admitSession(limiter, storage, "1", limitedConsumer))
+	assert.NoError(t, admitSession(limiter, storage, "2", otherConsumer))
+	assert.Exactly(t, ErrorCapacityExceeded, admitSession(limiter, storage, "3", otherConsumer))
+
+	storage.Remove("1")
+	assert.NoError(t, admitSession(limiter, storage, "3", otherConsumer))
+}
+
+func TestLimiter_RejectsSessionsOverMaxSessionsPerConsumer(t *testing.T) {
+	storage := NewStorageMemory()
+	limiter := NewLimiter(limitedProposalID, Limits{MaxSessionsPerConsumer: 1}, storage)
+
+	assert.NoError(t, admitSession(limiter, storage, "1", limitedConsumer))
+	assert.Exactly(t, ErrorCapacityExceeded, admitSession(limiter, storage, "2", limitedConsumer))
+	assert.NoError(t, admitSession(limiter, storage, "3", otherConsumer))
+}
+
+func TestLimiter_RejectsSessionsOverMaxNewSessionsPerMinute(t *testing.T) {
+	storage := NewStorageMemory()
+	limiter := NewLimiter(limitedProposalID, Limits{MaxNewSessionsPerMinute: 2}, storage)
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	assert.NoError(t, admitSession(limiter, storage, "1", limitedConsumer))
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, admitSession(limiter, storage, "2", limitedConsumer))
+	assert.Exactly(t, ErrorCapacityExceeded, admitSession(limiter, storage, "3", limitedConsumer))
+
+	now = now.Add(31 * time.Second)
+	assert.NoError(t, admitSession(limiter, storage, "3", limitedConsumer))
+}
+
+func TestLimiter_DoesNotCountFailedCreation(t *testing.T) {
+	storage := NewStorageMemory()
+	limiter := NewLimiter(limitedProposalID, Limits{MaxNewSessionsPerMinute: 1}, storage)
+	createErr := errors.New("failed to create")
+
+	err := limiter.Admit(limitedConsumer, func() error { return createErr })
+	assert.Exactly(t, createErr, err)
+	assert.NoError(t, admitSession(limiter, storage, "1", limitedConsumer))
+}
+
+func TestLimiter_Load(t *testing.T) {
+	storage := NewStorageMemory()
+	storage.Add(Session{ID: "other-instance", ServiceType: "limited", ProposalID: otherProposalID})
+	limiter := NewLimiter(limitedProposalID, Limits{MaxSessions: 5}, storage)
+
+	assert.NoError(t, admitSession(limiter, storage, "1", limitedConsumer))
+	assert.NoError(t, admitSession(limiter, storage, "2", otherConsumer))
+	assert.Exactly(t, market.ServiceLoad{Sessions: 2, MaxSessions: 5}, limiter.Load())
+}
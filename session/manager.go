@@ -21,7 +21,9 @@ import (
 	"encoding/json"
 	"errors"
 	"sync"
+	"time"
 
+	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 )
@@ -55,27 +57,42 @@ type DestroyCallback func() error
 type PromiseProcessor interface {
 	Start(proposal market.ServiceProposal) error
 	Stop() error
+	PromisesReceived() int
+	// LastPromiseReceived tells when consumer has sent the last promise, false if promises aren't checked
+	LastPromiseReceived() (time.Time, bool)
 }
 
+// ProposalProvider returns the proposal, terms of which new sessions are created on
+type ProposalProvider func() market.ServiceProposal
+
 // Storage interface to session storage
 type Storage interface {
 	Add(sessionInstance Session)
 	Find(id ID) (Session, bool)
 	Remove(id ID)
+	GetAll() []Session
 }
 
 // NewManager returns new session Manager
 func NewManager(
-	currentProposal market.ServiceProposal,
+	currentProposal ProposalProvider,
 	idGenerator IDGenerator,
 	sessionStorage Storage,
 	promiseProcessor PromiseProcessor,
+	statsProvider StatsProvider,
+	activityProvider ActivityProvider,
+	limiter *Limiter,
+	dialog communication.Dialog,
 ) *Manager {
 	return &Manager{
 		currentProposal:  currentProposal,
 		generateID:       idGenerator,
 		sessionStorage:   sessionStorage,
 		promiseProcessor: promiseProcessor,
+		statsProvider:    statsProvider,
+		activityProvider: activityProvider,
+		limiter:          limiter,
+		dialog:           dialog,
 
 		creationLock: sync.Mutex{},
 	}
@@ -83,11 +100,15 @@ func NewManager(
 
 // Manager knows how to start and provision session
 type Manager struct {
-	currentProposal  market.ServiceProposal
+	currentProposal  ProposalProvider
 	generateID       IDGenerator
 	provideConfig    ConfigProvider
 	sessionStorage   Storage
 	promiseProcessor PromiseProcessor
+	statsProvider    StatsProvider
+	activityProvider ActivityProvider
+	limiter          *Limiter
+	dialog           communication.Dialog
 
 	creationLock sync.Mutex
 }
@@ -97,24 +118,22 @@ func (manager *Manager) Create(consumerID identity.Identity, proposalID int, con
 	manager.creationLock.Lock()
 	defer manager.creationLock.Unlock()
 
-	if manager.currentProposal.ID != proposalID {
+	// proposal may be updated at any time, the session keeps terms it's created on
+	proposal := manager.currentProposal()
+	if proposal.ID != proposalID {
 		err = ErrorInvalidProposal
 		return
 	}
 
-	sessionInstance, err = manager.createSession(consumerID, config)
-	if err != nil {
-		return
-	}
-
-	err = manager.promiseProcessor.Start(manager.currentProposal)
-	if err != nil {
-		return
+	if manager.limiter == nil {
+		return manager.startSession(proposal, consumerID, config, destroyCallback)
 	}
 
-	sessionInstance.DestroyCallback = destroyCallback
-	manager.sessionStorage.Add(sessionInstance)
-	return sessionInstance, nil
+	err = manager.limiter.Admit(consumerID, func() error {
+		sessionInstance, err = manager.startSession(proposal, consumerID, config, destroyCallback)
+		return err
+	})
+	return sessionInstance, err
 }
 
 // Destroy destroys session by given sessionID
@@ -145,12 +164,36 @@ func (manager *Manager) Destroy(consumerID identity.Identity, sessionID string)
 	return nil
 }
 
-func (manager *Manager) createSession(consumerID identity.Identity, config ServiceConfiguration) (sessionInstance Session, err error) {
+func (manager *Manager) startSession(proposal market.ServiceProposal, consumerID identity.Identity, config ServiceConfiguration, destroyCallback DestroyCallback) (sessionInstance Session, err error) {
+	sessionInstance, err = manager.createSession(proposal, consumerID, config)
+	if err != nil {
+		return
+	}
+
+	err = manager.promiseProcessor.Start(proposal)
+	if err != nil {
+		return
+	}
+
+	sessionInstance.DestroyCallback = destroyCallback
+	sessionInstance.promiseProcessor = manager.promiseProcessor
+	sessionInstance.dialog = manager.dialog
+	sessionInstance.statsProvider = manager.statsProvider
+	sessionInstance.activityProvider = manager.activityProvider
+	manager.sessionStorage.Add(sessionInstance)
+	return sessionInstance, nil
+}
+
+func (manager *Manager) createSession(proposal market.ServiceProposal, consumerID identity.Identity, config ServiceConfiguration) (sessionInstance Session, err error) {
 	sessionInstance.ID, err = manager.generateID()
 	if err != nil {
 		return
 	}
+	sessionInstance.ServiceType = proposal.ServiceType
+	sessionInstance.ProposalID = proposal.ID
+	sessionInstance.ProposalVersion = proposal.Version
 	sessionInstance.ConsumerID = consumerID
 	sessionInstance.Config = config
+	sessionInstance.CreatedAt = time.Now().UTC()
 	return
 }
@@ -19,6 +19,7 @@ package session
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
@@ -28,7 +29,8 @@ import (
 var (
 	currentProposalID = 68
 	currentProposal   = market.ServiceProposal{
-		ID: currentProposalID,
+		ID:      currentProposalID,
+		Version: 3,
 	}
 	expectedID      = ID("mocked-id")
 	expectedSession = Session{
@@ -41,13 +43,19 @@ var (
 
 const expectedSessionConfig = "config_string"
 
+func provideCurrentProposal() market.ServiceProposal {
+	return currentProposal
+}
+
 func generateSessionID() (ID, error) {
 	return expectedID, nil
 }
 
 type fakePromiseProcessor struct {
-	started  bool
-	proposal market.ServiceProposal
+	started      bool
+	proposal     market.ServiceProposal
+	lastPromise  time.Time
+	checkPromise bool
 }
 
 func (processor *fakePromiseProcessor) Start(proposal market.ServiceProposal) error {
@@ -61,18 +69,35 @@ func (processor *fakePromiseProcessor) Stop() error {
 	return nil
 }
 
+func (processor *fakePromiseProcessor) PromisesReceived() int {
+	return 0
+}
+
+func (processor *fakePromiseProcessor) LastPromiseReceived() (time.Time, bool) {
+	return processor.lastPromise, processor.checkPromise
+}
+
 func TestManager_Create_StoresSession(t *testing.T) {
 	sessionStore := NewStorageMemory()
-	manager := NewManager(currentProposal, generateSessionID, sessionStore, &fakePromiseProcessor{})
+	manager := NewManager(provideCurrentProposal, generateSessionID, sessionStore, &fakePromiseProcessor{}, nil, nil, nil, nil)
 
 	sessionInstance, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID, expectedSessionConfig, nil)
 	assert.NoError(t, err)
-	assert.Exactly(t, expectedSession, sessionInstance)
+	assert.Exactly(t, expectedSession.ID, sessionInstance.ID)
+	assert.Exactly(t, expectedSession.Config, sessionInstance.Config)
+	assert.Exactly(t, expectedSession.ConsumerID, sessionInstance.ConsumerID)
+	assert.Exactly(t, currentProposalID, sessionInstance.ProposalID)
+	assert.Exactly(t, currentProposal.Version, sessionInstance.ProposalVersion)
+	assert.False(t, sessionInstance.CreatedAt.IsZero())
+
+	storedSession, found := sessionStore.Find(expectedID)
+	assert.True(t, found)
+	assert.Exactly(t, sessionInstance.CreatedAt, storedSession.CreatedAt)
 }
 
 func TestManager_Create_RejectsUnknownProposal(t *testing.T) {
 	sessionStore := NewStorageMemory()
-	manager := NewManager(currentProposal, generateSessionID, sessionStore, &fakePromiseProcessor{})
+	manager := NewManager(provideCurrentProposal, generateSessionID, sessionStore, &fakePromiseProcessor{}, nil, nil, nil, nil)
 
 	sessionInstance, err := manager.Create(identity.FromAddress("deadbeef"), 69, expectedSessionConfig, nil)
 	assert.Exactly(t, err, ErrorInvalidProposal)
@@ -82,10 +107,40 @@ func TestManager_Create_RejectsUnknownProposal(t *testing.T) {
 func TestManager_Create_StartsPromiseProcessor(t *testing.T) {
 	promiseProcessor := &fakePromiseProcessor{}
 	sessionStore := NewStorageMemory()
-	manager := NewManager(currentProposal, generateSessionID, sessionStore, promiseProcessor)
+	manager := NewManager(provideCurrentProposal, generateSessionID, sessionStore, promiseProcessor, nil, nil, nil, nil)
 
 	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID, expectedSessionConfig, nil)
 	assert.NoError(t, err)
 	assert.True(t, promiseProcessor.started)
 	assert.Exactly(t, currentProposal, promiseProcessor.proposal)
 }
+
+func TestManager_Create_StartsSessionOnTermsOfUpdatedProposal(t *testing.T) {
+	proposal := market.ServiceProposal{ID: currentProposalID, Version: 1}
+	promiseProcessor := &fakePromiseProcessor{}
+	sessionStore := NewStorageMemory()
+	manager := NewManager(func() market.ServiceProposal { return proposal }, generateSessionID, sessionStore, promiseProcessor, nil, nil, nil, nil)
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID, expectedSessionConfig, nil)
+	assert.NoError(t, err)
+	assert.Exactly(t, 1, promiseProcessor.proposal.Version)
+
+	proposal = market.ServiceProposal{ID: currentProposalID, Version: 2}
+	_, err = manager.Create(identity.FromAddress("beefdead"), currentProposalID, expectedSessionConfig, nil)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, promiseProcessor.proposal.Version)
+}
+
+func TestManager_Create_RejectsSessionOverLimits(t *testing.T) {
+	sessionStore := NewStorageMemory()
+	limiter := NewLimiter(currentProposal.ID, Limits{MaxSessions: 1}, sessionStore)
+	manager := NewManager(provideCurrentProposal, generateSessionID, sessionStore, &fakePromiseProcessor{}, nil, nil, limiter, nil)
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID, expectedSessionConfig, nil)
+	assert.NoError(t, err)
+
+	sessionInstance, err := manager.Create(identity.FromAddress("beefdead"), currentProposalID, expectedSessionConfig, nil)
+	assert.Exactly(t, ErrorCapacityExceeded, err)
+	assert.Exactly(t, Session{}, sessionInstance)
+	assert.Len(t, sessionStore.GetAll(), 1)
+}
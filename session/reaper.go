@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+const reaperLogPrefix = "[session-reaper] "
+
+// NewReaper creates reaper, which ends sessions of consumers silent for longer than grace period
+func NewReaper(storage Storage, killer *Killer, gracePeriod time.Duration) *Reaper {
+	return &Reaper{
+		storage:     storage,
+		killer:      killer,
+		gracePeriod: gracePeriod,
+		interval:    time.Minute,
+		now:         time.Now,
+		done:        make(chan struct{}),
+	}
+}
+
+// Reaper ends sessions, consumers of which have vanished without destroying them
+type Reaper struct {
+	storage     Storage
+	killer      *Killer
+	gracePeriod time.Duration
+	interval    time.Duration
+	now         func() time.Time
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Start periodically looks for abandoned sessions - does not block
+func (reaper *Reaper) Start() {
+	go func() {
+		for {
+			select {
+			case <-reaper.done:
+				return
+			case <-time.After(reaper.interval):
+				reaper.reap()
+			}
+		}
+	}()
+}
+
+// Stop stops looking for abandoned sessions
+func (reaper *Reaper) Stop() {
+	reaper.stopOnce.Do(func() {
+		close(reaper.done)
+	})
+}
+
+// reap ends sessions which weren't seen alive during grace period.
+// Sessions, activity of which can't be told, are left to their consumers.
+func (reaper *Reaper) reap() {
+	deadline := reaper.now().Add(-reaper.gracePeriod)
+	for _, sessionInstance := range reaper.storage.GetAll() {
+		lastSeen, known := sessionInstance.LastSeen()
+		if !known || lastSeen.After(deadline) {
+			continue
+		}
+
+		log.Info(reaperLogPrefix, "Consumer of session ", sessionInstance.ID, " was last seen at ", lastSeen, ", ending session")
+		if err := reaper.killer.Kill(sessionInstance.ID); err != nil && err != ErrorSessionNotExists {
+			log.Warn(reaperLogPrefix, "Failed to end session ", sessionInstance.ID, ": ", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var reaperNow = time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeActivityProvider struct {
+	activity map[ID]time.Time
+}
+
+func (provider *fakeActivityProvider) LastActivity(sessionInstance Session) (time.Time, bool) {
+	activity, found := provider.activity[sessionInstance.ID]
+	return activity, found
+}
+
+func newTestReaper(storage Storage) *Reaper {
+	reaper := NewReaper(storage, NewKiller(storage), 10*time.Minute)
+	reaper.now = func() time.Time { return reaperNow }
+	return reaper
+}
+
+func TestReaper_EndsSessionsSilentForLongerThanGracePeriod(t *testing.T) {
+	activityProvider := &fakeActivityProvider{activity: map[ID]time.Time{
+		"alive": reaperNow.Add(-time.Minute),
+		"dead":  reaperNow.Add(-time.Hour),
+	}}
+	destroyed := make(map[ID]bool)
+	storage := NewStorageMemory()
+	for _, id := range []ID{"alive", "dead", "never-connected"} {
+		id := id
+		storage.Add(Session{
+			ID:               id,
+			CreatedAt:        reaperNow.Add(-2 * time.Hour),
+			DestroyCallback:  func() error { destroyed[id] = true; return nil },
+			activityProvider: activityProvider,
+		})
+	}
+	activityProvider.activity["never-connected"] = time.Time{}
+
+	newTestReaper(storage).reap()
+
+	_, found := storage.Find("alive")
+	assert.True(t, found)
+	assert.Equal(t, map[ID]bool{"dead": true, "never-connected": true}, destroyed)
+}
+
+func TestReaper_KeepsSessionsWithinGracePeriodSinceCreation(t *testing.T) {
+	storage := NewStorageMemory()
+	storage.Add(Session{
+		ID:               "fresh",
+		CreatedAt:        reaperNow.Add(-time.Minute),
+		activityProvider: &fakeActivityProvider{activity: map[ID]time.Time{"fresh": {}}},
+	})
+
+	newTestReaper(storage).reap()
+
+	_, found := storage.Find("fresh")
+	assert.True(t, found)
+}
+
+func TestReaper_ConsidersPromisesAsActivity(t *testing.T) {
+	storage := NewStorageMemory()
+	storage.Add(Session{
+		ID:               "paying",
+		CreatedAt:        reaperNow.Add(-time.Hour),
+		promiseProcessor: &fakePromiseProcessor{lastPromise: reaperNow.Add(-time.Minute), checkPromise: true},
+	})
+	storage.Add(Session{
+		ID:               "not-paying",
+		CreatedAt:        reaperNow.Add(-time.Hour),
+		promiseProcessor: &fakePromiseProcessor{checkPromise: true},
+	})
+
+	newTestReaper(storage).reap()
+
+	_, found := storage.Find("paying")
+	assert.True(t, found)
+	_, found = storage.Find("not-paying")
+	assert.False(t, found)
+}
+
+func TestReaper_KeepsSessionsWhichActivityIsUnknown(t *testing.T) {
+	storage := NewStorageMemory()
+	storage.Add(Session{
+		ID:               "unknown",
+		CreatedAt:        reaperNow.Add(-time.Hour),
+		promiseProcessor: &fakePromiseProcessor{},
+	})
+
+	newTestReaper(storage).reap()
+
+	_, found := storage.Find("unknown")
+	assert.True(t, found)
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const sessionKillerLogPrefix = "[session-killer] "
+
+// NewKiller creates killer of sessions kept in given storage
+func NewKiller(storage Storage) *Killer {
+	return &Killer{storage: storage}
+}
+
+// AccessChecker tells if consumer is allowed to use services of the provider
+type AccessChecker interface {
+	IsAllowed(consumerID identity.Identity) bool
+}
+
+// Killer ends sessions on provider's own initiative
+type Killer struct {
+	storage Storage
+}
+
+// Kill notifies consumer that session is ended, stops processing its promises, closes its dialog and frees its resources
+func (killer *Killer) Kill(id ID) error {
+	sessionInstance, found := killer.storage.Find(id)
+	if !found {
+		return ErrorSessionNotExists
+	}
+	killer.storage.Remove(id)
+
+	if sessionInstance.dialog != nil {
+		if err := sessionInstance.dialog.Send(&endedProducer{SessionID: id}); err != nil {
+			log.Warn(sessionKillerLogPrefix, "Failed to notify consumer about ended session ", id, ": ", err)
+		}
+		// consumer is not served anymore, the same as after it destroys session itself
+		sessionInstance.dialog.Unsubscribe()
+		if err := sessionInstance.dialog.Close(); err != nil {
+			log.Warn(sessionKillerLogPrefix, "Failed to close dialog of session ", id, ": ", err)
+		}
+	}
+
+	if sessionInstance.promiseProcessor != nil {
+		if err := sessionInstance.promiseProcessor.Stop(); err != nil {
+			log.Warn(sessionKillerLogPrefix, "Failed to stop processing promises of session ", id, ": ", err)
+		}
+	}
+
+	log.Info(sessionKillerLogPrefix, "Session ", id, " killed")
+	if sessionInstance.DestroyCallback != nil {
+		return sessionInstance.DestroyCallback()
+	}
+	return nil
+}
+
+// KillDenied ends sessions of consumers, which aren't allowed to use services anymore
+func (killer *Killer) KillDenied(checker AccessChecker) {
+	for _, sessionInstance := range killer.storage.GetAll() {
+		if checker.IsAllowed(sessionInstance.ConsumerID) {
+			continue
+		}
+
+		log.Info(sessionKillerLogPrefix, "Consumer ", sessionInstance.ConsumerID.Address, " of session ", sessionInstance.ID, " is denied access, ending session")
+		if err := killer.Kill(sessionInstance.ID); err != nil && err != ErrorSessionNotExists {
+			log.Warn(sessionKillerLogPrefix, "Failed to end session ", sessionInstance.ID, ": ", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSessionDialog struct {
+	sent         []communication.MessageProducer
+	unsubscribed bool
+	closed       bool
+}
+
+func (dialog *fakeSessionDialog) PeerID() identity.Identity {
+	return identity.FromAddress("consumer")
+}
+
+func (dialog *fakeSessionDialog) Receive(consumer communication.MessageConsumer) error {
+	return nil
+}
+
+func (dialog *fakeSessionDialog) Respond(consumer communication.RequestConsumer) error {
+	return nil
+}
+
+func (dialog *fakeSessionDialog) Unsubscribe() {
+	dialog.unsubscribed = true
+}
+
+func (dialog *fakeSessionDialog) Close() error {
+	dialog.closed = true
+	return nil
+}
+
+func (dialog *fakeSessionDialog) Send(producer communication.MessageProducer) error {
+	dialog.sent = append(dialog.sent, producer)
+	return nil
+}
+
+func (dialog *fakeSessionDialog) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	return nil, nil
+}
+
+func TestKiller_KillEndsSession(t *testing.T) {
+	dialog := &fakeSessionDialog{}
+	processor := &fakePromiseProcessor{started: true}
+	destroyed := false
+	storage := NewStorageMemory()
+	storage.Add(Session{
+		ID:               expectedID,
+		DestroyCallback:  func() error { destroyed = true; return nil },
+		promiseProcessor: processor,
+		dialog:           dialog,
+	})
+
+	err := NewKiller(storage).Kill(expectedID)
+	assert.NoError(t, err)
+
+	_, found := storage.Find(expectedID)
+	assert.False(t, found)
+	assert.True(t, destroyed)
+	assert.False(t, processor.started)
+	assert.Len(t, dialog.sent, 1)
+	assert.Equal(t, endpointSessionEnded, dialog.sent[0].GetMessageEndpoint())
+	assert.Equal(t, &EndedMessage{SessionID: string(expectedID)}, dialog.sent[0].Produce())
+	assert.True(t, dialog.unsubscribed)
+	assert.True(t, dialog.closed)
+}
+
+func TestKiller_KillRejectsUnknownSession(t *testing.T) {
+	err := NewKiller(NewStorageMemory()).Kill(expectedID)
+	assert.Equal(t, ErrorSessionNotExists, err)
+}
+
+type fakeAccessChecker struct {
+	denied identity.Identity
+}
+
+func (checker *fakeAccessChecker) IsAllowed(consumerID identity.Identity) bool {
+	return consumerID != checker.denied
+}
+
+func TestKiller_KillDeniedEndsSessionsOfDeniedConsumersOnly(t *testing.T) {
+	denied := identity.FromAddress("denied")
+	storage := NewStorageMemory()
+	storage.Add(Session{ID: "denied-session", ConsumerID: denied})
+	storage.Add(Session{ID: "allowed-session", ConsumerID: identity.FromAddress("allowed")})
+
+	NewKiller(storage).KillDenied(&fakeAccessChecker{denied: denied})
+
+	_, found := storage.Find("denied-session")
+	assert.False(t, found)
+	_, found = storage.Find("allowed-session")
+	assert.True(t, found)
+}
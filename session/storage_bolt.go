@@ -0,0 +1,251 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const (
+	storageBoltLogPrefix = "[session-storage-bolt] "
+	storageBoltBucket    = "provider-sessions"
+	historyBoltBucket    = "provider-session-history"
+
+	// historyRetention is how long totals of ended sessions are kept
+	historyRetention = 30 * 24 * time.Hour
+)
+
+// Storer allows to save, get and delete stored objects
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	Delete(bucket string, object interface{}) error
+	GetAllFrom(bucket string, array interface{}) error
+}
+
+// Recoverer takes over resources of sessions which outlived provider restart
+type Recoverer interface {
+	// RecoverSession re-adopts resources of stored session and returns callback which cleans them up.
+	// If session can't be re-adopted, whatever is left of its resources is cleaned up and error returned.
+	RecoverSession(sessionInstance Session) (DestroyCallback, error)
+}
+
+// Sweeper removes resources which were left by previous run of the node without stored session, e.g. after crash
+type Sweeper interface {
+	// Sweep removes orphaned resources of the service, resources of given stored sessions are kept
+	Sweep(stored []Session)
+}
+
+// StoredSession is the record of provider session, which is kept until session is destroyed
+type StoredSession struct {
+	ID              ID `storm:"id"`
+	ServiceType     string
+	ProposalID      int
+	ProposalVersion int
+	ConsumerID      identity.Identity
+	Config          json.RawMessage
+	Created         time.Time
+}
+
+// EndedSession is the record of provider session, which is kept after session has ended
+type EndedSession struct {
+	ID               ID `storm:"id"`
+	ServiceType      string
+	ConsumerID       identity.Identity
+	Created          time.Time
+	Ended            time.Time
+	BytesSent        uint64
+	BytesReceived    uint64
+	PromisesReceived int
+}
+
+// NewStorageBolt initiates new durable session storage
+func NewStorageBolt(storage Storer) *StorageBolt {
+	return &StorageBolt{
+		storage:   storage,
+		memory:    NewStorageMemory(),
+		retention: historyRetention,
+	}
+}
+
+// StorageBolt keeps the record of every session in BoltDB, so sessions can be recovered after provider restart.
+// Live sessions are served from memory, as their destroy callbacks can not be persisted.
+type StorageBolt struct {
+	storage   Storer
+	memory    *StorageMemory
+	retention time.Duration
+}
+
+// Add puts given session to storage. Multiple sessions per peerID is possible in case different services are used
+func (storage *StorageBolt) Add(sessionInstance Session) {
+	storage.memory.Add(sessionInstance)
+
+	config, err := json.Marshal(sessionInstance.Config)
+	if err != nil {
+		log.Error(storageBoltLogPrefix, "Failed to serialize config of session ", sessionInstance.ID, ": ", err)
+		return
+	}
+
+	record := StoredSession{
+		ID:              sessionInstance.ID,
+		ServiceType:     sessionInstance.ServiceType,
+		ProposalID:      sessionInstance.ProposalID,
+		ProposalVersion: sessionInstance.ProposalVersion,
+		ConsumerID:      sessionInstance.ConsumerID,
+		Config:          config,
+		Created:         sessionInstance.CreatedAt,
+	}
+	if err := storage.storage.Store(storageBoltBucket, &record); err != nil {
+		log.Error(storageBoltLogPrefix, "Failed to store session ", sessionInstance.ID, ": ", err)
+	}
+}
+
+// Find returns underlying session instance
+func (storage *StorageBolt) Find(id ID) (Session, bool) {
+	return storage.memory.Find(id)
+}
+
+// GetAll returns all live sessions
+func (storage *StorageBolt) GetAll() []Session {
+	return storage.memory.GetAll()
+}
+
+// Remove removes given session from underlying storage, totals of removed session are kept in history
+func (storage *StorageBolt) Remove(id ID) {
+	if sessionInstance, found := storage.memory.Find(id); found {
+		storage.archive(sessionInstance)
+	}
+	storage.memory.Remove(id)
+	storage.forget(id)
+}
+
+// GetEnded returns records of all sessions which have ended
+func (storage *StorageBolt) GetEnded() ([]EndedSession, error) {
+	var records []EndedSession
+	err := storage.storage.GetAllFrom(historyBoltBucket, &records)
+	return records, err
+}
+
+// archive stores totals of the session, which is being removed. It has to be done before
+// the session is destroyed, as services stop accounting traffic of destroyed sessions
+func (storage *StorageBolt) archive(sessionInstance Session) {
+	dataTransferred, err := sessionInstance.DataTransferred()
+	if err != nil {
+		log.Warn(storageBoltLogPrefix, "Failed to get traffic of session ", sessionInstance.ID, ": ", err)
+	}
+
+	record := EndedSession{
+		ID:               sessionInstance.ID,
+		ServiceType:      sessionInstance.ServiceType,
+		ConsumerID:       sessionInstance.ConsumerID,
+		Created:          sessionInstance.CreatedAt,
+		Ended:            time.Now().UTC(),
+		BytesSent:        dataTransferred.BytesSent,
+		BytesReceived:    dataTransferred.BytesReceived,
+		PromisesReceived: sessionInstance.PromisesReceived(),
+	}
+	if err := storage.storage.Store(historyBoltBucket, &record); err != nil {
+		log.Error(storageBoltLogPrefix, "Failed to store totals of session ", sessionInstance.ID, ": ", err)
+	}
+	storage.expireHistory(record.Ended)
+}
+
+// expireHistory removes totals of sessions, which have ended longer than retention period ago
+func (storage *StorageBolt) expireHistory(now time.Time) {
+	records, err := storage.GetEnded()
+	if err != nil {
+		log.Warn(storageBoltLogPrefix, "Failed to get totals of ended sessions: ", err)
+		return
+	}
+
+	deadline := now.Add(-storage.retention)
+	for _, record := range records {
+		if !record.Ended.Before(deadline) {
+			continue
+		}
+		if err := storage.storage.Delete(historyBoltBucket, &EndedSession{ID: record.ID}); err != nil {
+			log.Warn(storageBoltLogPrefix, "Failed to delete totals of session ", record.ID, ": ", err)
+		}
+	}
+}
+
+// Recover reconciles stored sessions of service instance, which is offered with given proposal id, with its real resources.
+// Sessions which are still valid are re-adopted, records of dead ones are removed. Sessions stored before instances
+// were told apart are taken by the first instance of their service type.
+// Resources left without stored session of the service type are swept afterwards, if recoverer is able to.
+func (storage *StorageBolt) Recover(serviceType string, proposalID int, recoverer Recoverer) error {
+	var records []StoredSession
+	if err := storage.storage.GetAllFrom(storageBoltBucket, &records); err != nil {
+		return err
+	}
+
+	var stored []Session
+	for _, record := range records {
+		if record.ServiceType != serviceType {
+			continue
+		}
+
+		sessionInstance := Session{
+			ID:              record.ID,
+			ServiceType:     record.ServiceType,
+			ProposalID:      record.ProposalID,
+			ProposalVersion: record.ProposalVersion,
+			ConsumerID:      record.ConsumerID,
+			Config:          record.Config,
+			CreatedAt:       record.Created,
+		}
+		// resources of other instances are kept, they are recovered by the instances themselves
+		if _, found := storage.memory.Find(record.ID); found || (record.ProposalID != proposalID && record.ProposalID != 0) {
+			stored = append(stored, sessionInstance)
+			continue
+		}
+		sessionInstance.ProposalID = proposalID
+
+		destroyCallback, err := recoverer.RecoverSession(sessionInstance)
+		if err != nil {
+			log.Info(storageBoltLogPrefix, "Session ", record.ID, " is dead: ", err)
+			storage.forget(record.ID)
+			continue
+		}
+		stored = append(stored, sessionInstance)
+
+		sessionInstance.DestroyCallback = destroyCallback
+		if statsProvider, ok := recoverer.(StatsProvider); ok {
+			sessionInstance.statsProvider = statsProvider
+		}
+		if activityProvider, ok := recoverer.(ActivityProvider); ok {
+			sessionInstance.activityProvider = activityProvider
+		}
+		storage.memory.Add(sessionInstance)
+		log.Info(storageBoltLogPrefix, "Session ", record.ID, " recovered")
+	}
+
+	if sweeper, ok := recoverer.(Sweeper); ok {
+		sweeper.Sweep(stored)
+	}
+	return nil
+}
+
+func (storage *StorageBolt) forget(id ID) {
+	if err := storage.storage.Delete(storageBoltBucket, &StoredSession{ID: id}); err != nil {
+		log.Warn(storageBoltLogPrefix, "Failed to delete stored session ", id, ": ", err)
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorer struct {
+	records map[ID]StoredSession
+	history []EndedSession
+}
+
+func newFakeStorer() *fakeStorer {
+	return &fakeStorer{records: make(map[ID]StoredSession)}
+}
+
+func (storer *fakeStorer) Store(bucket string, object interface{}) error {
+	if record, ok := object.(*EndedSession); ok {
+		storer.history = append(storer.history, *record)
+		return nil
+	}
+	record := object.(*StoredSession)
+	storer.records[record.ID] = *record
+	return nil
+}
+
+func (storer *fakeStorer) Delete(bucket string, object interface{}) error {
+	if ended, ok := object.(*EndedSession); ok {
+		for i, record := range storer.history {
+			if record.ID == ended.ID {
+				storer.history = append(storer.history[:i], storer.history[i+1:]...)
+				return nil
+			}
+		}
+		return errors.New("not found")
+	}
+	record := object.(*StoredSession)
+	if _, found := storer.records[record.ID]; !found {
+		return errors.New("not found")
+	}
+	delete(storer.records, record.ID)
+	return nil
+}
+
+func (storer *fakeStorer) GetAllFrom(bucket string, array interface{}) error {
+	if history, ok := array.(*[]EndedSession); ok {
+		*history = append(*history, storer.history...)
+		return nil
+	}
+	records := array.(*[]StoredSession)
+	for _, record := range storer.records {
+		*records = append(*records, record)
+	}
+	return nil
+}
+
+type fakeStatsProvider struct {
+	transfer DataTransfer
+}
+
+func (provider *fakeStatsProvider) SessionStats(sessionInstance Session) (DataTransfer, error) {
+	return provider.transfer, nil
+}
+
+type fakeRecoverer struct {
+	alive     map[ID]bool
+	recovered []Session
+	// kept are sessions which resources were kept by the last sweep
+	kept []ID
+}
+
+func (recoverer *fakeRecoverer) RecoverSession(sessionInstance Session) (DestroyCallback, error) {
+	recoverer.recovered = append(recoverer.recovered, sessionInstance)
+	if !recoverer.alive[sessionInstance.ID] {
+		return nil, errors.New("resources are gone")
+	}
+	return func() error { return nil }, nil
+}
+
+func (recoverer *fakeRecoverer) Sweep(stored []Session) {
+	recoverer.kept = []ID{}
+	for _, sessionInstance := range stored {
+		recoverer.kept = append(recoverer.kept, sessionInstance.ID)
+	}
+}
+
+func TestStorageBolt_AddKeepsRecordOfSession(t *testing.T) {
+	createdAt := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	storer := newFakeStorer()
+	storage := NewStorageBolt(storer)
+
+	storage.Add(Session{
+		ID:              ID("session-1"),
+		ServiceType:     "wireguard",
+		ProposalID:      2,
+		ProposalVersion: 3,
+		ConsumerID:      identity.FromAddress("0x1"),
+		Config:          map[string]string{"key": "value"},
+		CreatedAt:       createdAt,
+	})
+
+	sessionInstance, found := storage.Find(ID("session-1"))
+	assert.True(t, found)
+	assert.Equal(t, "wireguard", sessionInstance.ServiceType)
+
+	record := storer.records[ID("session-1")]
+	assert.Equal(t, "wireguard", record.ServiceType)
+	assert.Equal(t, 2, record.ProposalID)
+	assert.Equal(t, 3, record.ProposalVersion)
+	assert.Equal(t, identity.FromAddress("0x1"), record.ConsumerID)
+	assert.JSONEq(t, `{"key": "value"}`, string(record.Config))
+	assert.Equal(t, createdAt, record.Created)
+}
+
+func TestStorageBolt_RemoveForgetsSession(t *testing.T) {
+	storer := newFakeStorer()
+	storage := NewStorageBolt(storer)
+	storage.Add(Session{ID: ID("session-1")})
+
+	storage.Remove(ID("session-1"))
+
+	_, found := storage.Find(ID("session-1"))
+	assert.False(t, found)
+	assert.Len(t, storer.records, 0)
+}
+
+func TestStorageBolt_RemoveKeepsTotalsOfSession(t *testing.T) {
+	createdAt := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	storer := newFakeStorer()
+	storage := NewStorageBolt(storer)
+	storage.Add(Session{
+		ID:            ID("session-1"),
+		ServiceType:   "openvpn",
+		ConsumerID:    identity.FromAddress("0x1"),
+		CreatedAt:     createdAt,
+		statsProvider: &fakeStatsProvider{transfer: DataTransfer{BytesSent: 20, BytesReceived: 10}},
+	})
+
+	storage.Remove(ID("session-1"))
+
+	history, err := storage.GetEnded()
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, ID("session-1"), history[0].ID)
+	assert.Equal(t, "openvpn", history[0].ServiceType)
+	assert.Equal(t, identity.FromAddress("0x1"), history[0].ConsumerID)
+	assert.Equal(t, createdAt, history[0].Created)
+	assert.False(t, history[0].Ended.Before(createdAt))
+	assert.Equal(t, uint64(20), history[0].BytesSent)
+	assert.Equal(t, uint64(10), history[0].BytesReceived)
+}
+
+func TestStorageBolt_RemoveExpiresTotalsOfSessionsEndedBeforeRetention(t *testing.T) {
+	storer := newFakeStorer()
+	storer.history = []EndedSession{
+		{ID: ID("expired"), Ended: time.Now().UTC().Add(-2 * time.Hour)},
+		{ID: ID("retained"), Ended: time.Now().UTC().Add(-30 * time.Minute)},
+	}
+	storage := NewStorageBolt(storer)
+	storage.retention = time.Hour
+	storage.Add(Session{ID: ID("session-1")})
+
+	storage.Remove(ID("session-1"))
+
+	history, err := storage.GetEnded()
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, ID("retained"), history[0].ID)
+	assert.Equal(t, ID("session-1"), history[1].ID)
+}
+
+func TestStorageBolt_RecoverReadoptsAliveSessionsAndForgetsDeadOnes(t *testing.T) {
+	storer := newFakeStorer()
+	storer.records[ID("alive")] = StoredSession{
+		ID:          ID("alive"),
+		ServiceType: "wireguard",
+		ConsumerID:  identity.FromAddress("0x1"),
+		Config:      json.RawMessage(`{"key":"value"}`),
+		Created:     time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	storer.records[ID("dead")] = StoredSession{ID: ID("dead"), ServiceType: "wireguard"}
+	storer.records[ID("other")] = StoredSession{ID: ID("other"), ServiceType: "openvpn"}
+	recoverer := &fakeRecoverer{alive: map[ID]bool{ID("alive"): true}}
+	storage := NewStorageBolt(storer)
+
+	err := storage.Recover("wireguard", 1, recoverer)
+	assert.NoError(t, err)
+	assert.Len(t, recoverer.recovered, 2)
+
+	sessionInstance, found := storage.Find(ID("alive"))
+	assert.True(t, found)
+	assert.Equal(t, identity.FromAddress("0x1"), sessionInstance.ConsumerID)
+	assert.Equal(t, json.RawMessage(`{"key":"value"}`), sessionInstance.Config)
+	assert.NotNil(t, sessionInstance.DestroyCallback)
+	assert.Equal(t, time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC), sessionInstance.CreatedAt)
+
+	_, found = storage.Find(ID("dead"))
+	assert.False(t, found)
+	assert.NotContains(t, storer.records, ID("dead"))
+	assert.Contains(t, storer.records, ID("alive"))
+	assert.Contains(t, storer.records, ID("other"))
+}
+
+func TestStorageBolt_RecoverReadoptsSessionsOfGivenServiceInstanceOnly(t *testing.T) {
+	storer := newFakeStorer()
+	storer.records[ID("own")] = StoredSession{ID: ID("own"), ServiceType: "wireguard", ProposalID: 1}
+	storer.records[ID("legacy")] = StoredSession{ID: ID("legacy"), ServiceType: "wireguard"}
+	storer.records[ID("other-instance")] = StoredSession{ID: ID("other-instance"), ServiceType: "wireguard", ProposalID: 2}
+	recoverer := &fakeRecoverer{alive: map[ID]bool{ID("own"): true, ID("legacy"): true, ID("other-instance"): true}}
+	storage := NewStorageBolt(storer)
+
+	assert.NoError(t, storage.Recover("wireguard", 1, recoverer))
+	assert.Len(t, recoverer.recovered, 2)
+	assert.ElementsMatch(t, []ID{ID("own"), ID("legacy"), ID("other-instance")}, recoverer.kept)
+
+	sessionInstance, found := storage.Find(ID("legacy"))
+	assert.True(t, found)
+	assert.Equal(t, 1, sessionInstance.ProposalID)
+	_, found = storage.Find(ID("other-instance"))
+	assert.False(t, found)
+	assert.Contains(t, storer.records, ID("other-instance"))
+}
+
+func TestStorageBolt_RecoverSweepsResourcesExceptOnesOfStoredSessions(t *testing.T) {
+	storer := newFakeStorer()
+	storer.records[ID("alive")] = StoredSession{ID: ID("alive"), ServiceType: "wireguard"}
+	storer.records[ID("dead")] = StoredSession{ID: ID("dead"), ServiceType: "wireguard"}
+	storer.records[ID("other")] = StoredSession{ID: ID("other"), ServiceType: "openvpn"}
+	recoverer := &fakeRecoverer{alive: map[ID]bool{ID("alive"): true}}
+	storage := NewStorageBolt(storer)
+
+	assert.NoError(t, storage.Recover("wireguard", 1, recoverer))
+	assert.Equal(t, []ID{ID("alive")}, recoverer.kept)
+}
@@ -45,10 +45,25 @@ func (storage *StorageMemory) Add(sessionInstance Session) {
 
 // Find returns underlying session instance
 func (storage *StorageMemory) Find(id ID) (Session, bool) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
 	sessionInstance, found := storage.sessionMap[id]
 	return sessionInstance, found
 }
 
+// GetAll returns all sessions in storage
+func (storage *StorageMemory) GetAll() []Session {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	sessions := make([]Session, 0, len(storage.sessionMap))
+	for _, sessionInstance := range storage.sessionMap {
+		sessions = append(sessions, sessionInstance)
+	}
+	return sessions
+}
+
 // Remove removes given session from underlying storage
 func (storage *StorageMemory) Remove(id ID) {
 	storage.lock.Lock()
@@ -0,0 +1,43 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"net"
+
+	"github.com/mysteriumnetwork/node/datasize"
+)
+
+// Limits defines rate limits of session traffic per second, zero means no limit
+type Limits struct {
+	// Upload limits traffic sent by consumer
+	Upload datasize.BitSize
+	// Download limits traffic received by consumer
+	Download datasize.BitSize
+}
+
+// IsUnlimited tells if traffic isn't limited at all
+func (limits Limits) IsUnlimited() bool {
+	return limits.Upload == 0 && limits.Download == 0
+}
+
+// Shaper limits bandwidth of consumer's traffic on tunnel interface
+type Shaper interface {
+	Shape(iface string, address net.IP, limits Limits) error
+	Unshape(iface string, address net.IP) error
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"net"
+
+	log "github.com/cihub/seelog"
+)
+
+type shaperFake struct {
+}
+
+// Shape only warns that traffic can't be limited on this platform
+func (shaper *shaperFake) Shape(_ string, address net.IP, limits Limits) error {
+	if !limits.IsUnlimited() {
+		log.Warn(shaperLogPrefix, "Bandwidth limits are not supported on this platform, traffic of ", address, " is not limited")
+	}
+	return nil
+}
+
+// Unshape does nothing
+func (shaper *shaperFake) Unshape(_ string, _ net.IP) error {
+	return nil
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/utils"
+)
+
+const (
+	shaperLogPrefix = "[shaper] "
+
+	// minBurst is the least amount of bytes policer lets through at once, it must fit the largest packet
+	minBurst = 16 * 1024
+	// maxShapedAddresses is the count of classes and filter priorities, which can be handed out per interface
+	maxShapedAddresses = 0xffff
+)
+
+// ErrTooManyAddresses indicates that traffic of no more addresses can be limited on the interface
+var ErrTooManyAddresses = errors.New("too many shaped addresses on interface")
+
+// tcShaper limits download of each address by its own HTB class and upload by ingress policer.
+// Classes and filters of an address share the same id, which is used as filter priority too,
+// so that they can be removed without knowing handles given by kernel.
+type tcShaper struct {
+	tc func(args ...string) error
+
+	mutex      sync.Mutex
+	interfaces map[string]*shapedInterface
+}
+
+type shapedInterface struct {
+	addresses map[string]shapedAddress
+}
+
+type shapedAddress struct {
+	id     uint16
+	limits Limits
+	ipv6   bool
+}
+
+func newTCShaper() *tcShaper {
+	return &tcShaper{
+		tc:         sudoTC,
+		interfaces: make(map[string]*shapedInterface),
+	}
+}
+
+func sudoTC(args ...string) error {
+	return utils.SudoExec(append([]string{"/sbin/tc"}, args...)...)
+}
+
+// Shape limits traffic from and to given address on the interface, traffic of unlimited address is left untouched
+func (shaper *tcShaper) Shape(iface string, address net.IP, limits Limits) error {
+	if limits.IsUnlimited() {
+		return nil
+	}
+
+	shaper.mutex.Lock()
+	defer shaper.mutex.Unlock()
+
+	shaped, found := shaper.interfaces[iface]
+	if !found {
+		if err := shaper.setupInterface(iface); err != nil {
+			return err
+		}
+		shaped = &shapedInterface{addresses: make(map[string]shapedAddress)}
+		shaper.interfaces[iface] = shaped
+	}
+
+	if _, exists := shaped.addresses[address.String()]; exists {
+		shaper.removeAddress(iface, shaped, address)
+	}
+
+	id, err := shaped.nextID()
+	if err != nil {
+		return err
+	}
+	entry := shapedAddress{id: id, limits: limits, ipv6: address.To4() == nil}
+	if err := shaper.addAddress(iface, address, entry); err != nil {
+		shaper.deleteAddressRules(iface, entry)
+		shaper.teardownUnused(iface, shaped)
+		return err
+	}
+	shaped.addresses[address.String()] = entry
+
+	log.Info(shaperLogPrefix, "Limited traffic of ", address, " on ", iface, " to upload ", limits.Upload, "/s, download ", limits.Download, "/s")
+	return nil
+}
+
+// Unshape removes limits of given address on the interface
+func (shaper *tcShaper) Unshape(iface string, address net.IP) error {
+	shaper.mutex.Lock()
+	defer shaper.mutex.Unlock()
+
+	shaped, found := shaper.interfaces[iface]
+	if !found {
+		return nil
+	}
+	if _, exists := shaped.addresses[address.String()]; !exists {
+		return nil
+	}
+
+	shaper.removeAddress(iface, shaped, address)
+	shaper.teardownUnused(iface, shaped)
+	log.Info(shaperLogPrefix, "Removed traffic limits of ", address, " on ", iface)
+	return nil
+}
+
+// setupInterface replaces qdiscs, which could be left by previous run of the node, with empty ones
+func (shaper *tcShaper) setupInterface(iface string) error {
+	shaper.teardownInterface(iface)
+
+	if err := shaper.tc("qdisc", "add", "dev", iface, "root", "handle", "1:", "htb"); err != nil {
+		log.Error(shaperLogPrefix, "Failed to set up traffic shaping on ", iface, ": ", err)
+		return err
+	}
+	if err := shaper.tc("qdisc", "add", "dev", iface, "handle", "ffff:", "ingress"); err != nil {
+		log.Error(shaperLogPrefix, "Failed to set up traffic policing on ", iface, ": ", err)
+		shaper.teardownInterface(iface)
+		return err
+	}
+	return nil
+}
+
+// teardownInterface removes all shaping from the interface, interface might be gone already, so errors are ignored
+func (shaper *tcShaper) teardownInterface(iface string) {
+	shaper.tc("qdisc", "del", "dev", iface, "root")
+	shaper.tc("qdisc", "del", "dev", iface, "ingress")
+}
+
+func (shaper *tcShaper) teardownUnused(iface string, shaped *shapedInterface) {
+	if len(shaped.addresses) > 0 {
+		return
+	}
+	shaper.teardownInterface(iface)
+	delete(shaper.interfaces, iface)
+}
+
+func (shaper *tcShaper) addAddress(iface string, address net.IP, entry shapedAddress) error {
+	protocol, match := "ip", "ip"
+	prefix := "/32"
+	if entry.ipv6 {
+		protocol, match = "ipv6", "ip6"
+		prefix = "/128"
+	}
+	prio, classID := tcIDs(entry.id)
+
+	if entry.limits.Download > 0 {
+		rate := tcRate(entry.limits.Download)
+		if err := shaper.tc("class", "add", "dev", iface, "parent", "1:", "classid", classID, "htb", "rate", rate, "ceil", rate); err != nil {
+			return err
+		}
+		err := shaper.tc(
+			"filter", "add", "dev", iface, "parent", "1:", "protocol", protocol, "prio", prio,
+			"u32", "match", match, "dst", address.String()+prefix, "flowid", classID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if entry.limits.Upload > 0 {
+		err := shaper.tc(
+			"filter", "add", "dev", iface, "parent", "ffff:", "protocol", protocol, "prio", prio,
+			"u32", "match", match, "src", address.String()+prefix,
+			"police", "rate", tcRate(entry.limits.Upload), "burst", tcBurst(entry.limits.Upload), "drop", "flowid", ":1",
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (shaper *tcShaper) removeAddress(iface string, shaped *shapedInterface, address net.IP) {
+	shaper.deleteAddressRules(iface, shaped.addresses[address.String()])
+	delete(shaped.addresses, address.String())
+}
+
+// deleteAddressRules removes filters and class of the address, the ones which were not created are skipped
+func (shaper *tcShaper) deleteAddressRules(iface string, entry shapedAddress) {
+	protocol := "ip"
+	if entry.ipv6 {
+		protocol = "ipv6"
+	}
+	prio, classID := tcIDs(entry.id)
+
+	if entry.limits.Download > 0 {
+		shaper.tc("filter", "del", "dev", iface, "parent", "1:", "protocol", protocol, "prio", prio)
+		shaper.tc("class", "del", "dev", iface, "classid", classID)
+	}
+	if entry.limits.Upload > 0 {
+		shaper.tc("filter", "del", "dev", iface, "parent", "ffff:", "protocol", protocol, "prio", prio)
+	}
+}
+
+// nextID returns the lowest id, which isn't used by any address of the interface, so that ids of removed addresses are reused
+func (shaped *shapedInterface) nextID() (uint16, error) {
+	if len(shaped.addresses) >= maxShapedAddresses {
+		return 0, ErrTooManyAddresses
+	}
+
+	used := make(map[uint16]bool, len(shaped.addresses))
+	for _, entry := range shaped.addresses {
+		used[entry.id] = true
+	}
+	id := uint16(1)
+	for used[id] {
+		id++
+	}
+	return id, nil
+}
+
+// tcIDs formats id of the address as filter priority, which tc takes in decimal, and as class id, minor of which tc takes in hex
+func tcIDs(id uint16) (prio, classID string) {
+	return strconv.FormatUint(uint64(id), 10), "1:" + strconv.FormatUint(uint64(id), 16)
+}
+
+func tcRate(rate datasize.BitSize) string {
+	return fmt.Sprintf("%dbit", rate.Bits())
+}
+
+// tcBurst lets through traffic of 100ms at once, but no less than a single large packet
+func tcBurst(rate datasize.BitSize) string {
+	burst := rate.Bits() / 8 / 10
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return strconv.FormatUint(burst, 10)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTC struct {
+	calls   []string
+	failOn  string
+	failErr error
+}
+
+func (ft *fakeTC) exec(args ...string) error {
+	call := strings.Join(args, " ")
+	ft.calls = append(ft.calls, call)
+	if ft.failOn != "" && strings.HasPrefix(call, ft.failOn) {
+		return ft.failErr
+	}
+	return nil
+}
+
+func newTestShaper() (*tcShaper, *fakeTC) {
+	fake := &fakeTC{}
+	shaper := newTCShaper()
+	shaper.tc = fake.exec
+	return shaper, fake
+}
+
+var limits = Limits{Upload: 1 * datasize.MB, Download: 2 * datasize.MB}
+
+func TestTCShaper_ShapeSetsUpInterfaceAndLimitsAddress(t *testing.T) {
+	shaper, fake := newTestShaper()
+
+	assert.NoError(t, shaper.Shape("tun0", net.ParseIP("10.8.0.6"), limits))
+	assert.Equal(
+		t,
+		[]string{
+			"qdisc del dev tun0 root",
+			"qdisc del dev tun0 ingress",
+			"qdisc add dev tun0 root handle 1: htb",
+			"qdisc add dev tun0 handle ffff: ingress",
+			"class add dev tun0 parent 1: classid 1:1 htb rate 16777216bit ceil 16777216bit",
+			"filter add dev tun0 parent 1: protocol ip prio 1 u32 match ip dst 10.8.0.6/32 flowid 1:1",
+			"filter add dev tun0 parent ffff: protocol ip prio 1 u32 match ip src 10.8.0.6/32 police rate 8388608bit burst 104857 drop flowid :1",
+		},
+		fake.calls,
+	)
+}
+
+func TestTCShaper_ShapeLimitsOnlyGivenDirection(t *testing.T) {
+	shaper, fake := newTestShaper()
+	assert.NoError(t, shaper.Shape("wg0", net.ParseIP("10.182.0.2"), Limits{Upload: 1 * datasize.KB}))
+	fake.calls = nil
+
+	assert.NoError(t, shaper.Shape("wg0", net.ParseIP("fd00::2"), Limits{Download: 1 * datasize.KB}))
+	assert.Equal(
+		t,
+		[]string{
+			"class add dev wg0 parent 1: classid 1:2 htb rate 8192bit ceil 8192bit",
+			"filter add dev wg0 parent 1: protocol ipv6 prio 2 u32 match ip6 dst fd00::2/128 flowid 1:2",
+		},
+		fake.calls,
+	)
+}
+
+func TestTCShaper_ShapeSkipsUnlimitedAddress(t *testing.T) {
+	shaper, fake := newTestShaper()
+
+	assert.NoError(t, shaper.Shape("tun0", net.ParseIP("10.8.0.6"), Limits{}))
+	assert.Empty(t, fake.calls)
+}
+
+func TestTCShaper_UnshapeRemovesInterfaceSetupWithLastAddress(t *testing.T) {
+	shaper, fake := newTestShaper()
+	assert.NoError(t, shaper.Shape("tun0", net.ParseIP("10.8.0.6"), limits))
+	assert.NoError(t, shaper.Shape("tun0", net.ParseIP("10.8.0.10"), limits))
+	fake.calls = nil
+
+	assert.NoError(t, shaper.Unshape("tun0", net.ParseIP("10.8.0.6")))
+	assert.Equal(
+		t,
+		[]string{
+			"filter del dev tun0 parent 1: protocol ip prio 1",
+			"class del dev tun0 classid 1:1",
+			"filter del dev tun0 parent ffff: protocol ip prio 1",
+		},
+		fake.calls,
+	)
+	fake.calls = nil
+
+	assert.NoError(t, shaper.Unshape("tun0", net.ParseIP("10.8.0.10")))
+	assert.Equal(
+		t,
+		[]string{
+			"filter del dev tun0 parent 1: protocol ip prio 2",
+			"class del dev tun0 classid 1:2",
+			"filter del dev tun0 parent ffff: protocol ip prio 2",
+			"qdisc del dev tun0 root",
+			"qdisc del dev tun0 ingress",
+		},
+		fake.calls,
+	)
+	assert.Empty(t, shaper.interfaces)
+}
+
+func TestTCShaper_UnshapeIgnoresUnknownAddress(t *testing.T) {
+	shaper, fake := newTestShaper()
+
+	assert.NoError(t, shaper.Unshape("tun0", net.ParseIP("10.8.0.6")))
+	assert.Empty(t, fake.calls)
+}
+
+func TestTCShaper_ShapeCleansUpOnFailure(t *testing.T) {
+	shaper, fake := newTestShaper()
+	fake.failOn = "filter add dev tun0 parent ffff:"
+	fake.failErr = errors.New("tc failure")
+
+	err := shaper.Shape("tun0", net.ParseIP("10.8.0.6"), limits)
+
+	assert.Equal(t, fake.failErr, err)
+	assert.Equal(
+		t,
+		[]string{
+			"filter del dev tun0 parent 1: protocol ip prio 1",
+			"class del dev tun0 classid 1:1",
+			"filter del dev tun0 parent ffff: protocol ip prio 1",
+			"qdisc del dev tun0 root",
+			"qdisc del dev tun0 ingress",
+		},
+		fake.calls[len(fake.calls)-5:],
+	)
+	assert.Empty(t, shaper.interfaces)
+}
+
+func TestTCShaper_ShapeReusesFreedIDs(t *testing.T) {
+	shaped := &shapedInterface{addresses: map[string]shapedAddress{"a": {id: 2}}}
+
+	id, err := shaped.nextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1), id)
+
+	shaped.addresses["b"] = shapedAddress{id: id}
+	id, err = shaped.nextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(3), id)
+
+	delete(shaped.addresses, "a")
+	id, err = shaped.nextID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2), id)
+}
+
+func TestTCShaper_ShapeFormatsClassIDInHex(t *testing.T) {
+	prio, classID := tcIDs(26)
+	assert.Equal(t, "26", prio)
+	assert.Equal(t, "1:1a", classID)
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/tequilapi/endpoints"
 )
 
@@ -143,6 +144,19 @@ func (client *Client) ConnectionStatistics() (StatisticsDTO, error) {
 	return statistics, err
 }
 
+// ConnectionDiagnostics checks current connection for leaks
+func (client *Client) ConnectionDiagnostics() (DiagnosticsDTO, error) {
+	response, err := client.http.Get("connection/diagnostics", url.Values{})
+	if err != nil {
+		return DiagnosticsDTO{}, err
+	}
+	defer response.Body.Close()
+
+	var report DiagnosticsDTO
+	err = parseResponseJSON(response, &report)
+	return report, err
+}
+
 // Status returns connection status
 func (client *Client) Status() (StatusDTO, error) {
 	response, err := client.http.Get("connection", url.Values{})
@@ -293,3 +307,103 @@ func (client *Client) GetSessionsByStatus(status string) (endpoints.SessionsDTO,
 	sessions = filterSessionsByStatus(status, sessions)
 	return sessions, err
 }
+
+// GetServiceSessions returns sessions which this node is serving to consumers
+func (client *Client) GetServiceSessions() (endpoints.ServiceSessionsDTO, error) {
+	sessions := endpoints.ServiceSessionsDTO{}
+	response, err := client.http.Get("service-sessions", url.Values{})
+	if err != nil {
+		return sessions, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &sessions)
+	return sessions, err
+}
+
+// GetEndedServiceSessions returns sessions which this node has served, with their traffic totals
+func (client *Client) GetEndedServiceSessions() (endpoints.ServiceSessionsDTO, error) {
+	sessions := endpoints.ServiceSessionsDTO{}
+	response, err := client.http.Get("service-sessions/history", url.Values{})
+	if err != nil {
+		return sessions, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &sessions)
+	return sessions, err
+}
+
+// EndServiceSession ends session which this node is serving to consumer
+func (client *Client) EndServiceSession(sessionID string) error {
+	response, err := client.http.Delete("service-sessions/"+sessionID, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
+
+// Services returns services running on this node
+func (client *Client) Services() (endpoints.ServiceListDTO, error) {
+	services := endpoints.ServiceListDTO{}
+	response, err := client.http.Get("services", url.Values{})
+	if err != nil {
+		return services, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &services)
+	return services, err
+}
+
+// Service returns state of the service instance running on this node
+func (client *Client) Service(id string) (endpoints.ServiceInfoDTO, error) {
+	service := endpoints.ServiceInfoDTO{}
+	response, err := client.http.Get("services/"+id, url.Values{})
+	if err != nil {
+		return service, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &service)
+	return service, err
+}
+
+// ServiceStart starts a new service instance on this node
+func (client *Client) ServiceStart(request endpoints.ServiceStartRequestDTO) (endpoints.ServiceStartResponseDTO, error) {
+	started := endpoints.ServiceStartResponseDTO{}
+	response, err := client.http.Post("services", request)
+	if err != nil {
+		return started, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &started)
+	return started, err
+}
+
+// ServiceUpdateProposal replaces proposal of the service instance running on this node
+func (client *Client) ServiceUpdateProposal(id string, proposal market.ServiceProposal) (endpoints.ServiceInfoDTO, error) {
+	service := endpoints.ServiceInfoDTO{}
+	response, err := client.http.Put("services/"+id+"/proposal", proposal)
+	if err != nil {
+		return service, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &service)
+	return service, err
+}
+
+// ServiceStop stops service instance running on this node
+func (client *Client) ServiceStop(id string) error {
+	response, err := client.http.Delete("services/"+id, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
@@ -32,6 +32,20 @@ type StatisticsDTO struct {
 	Duration      int    `json:"duration"`
 }
 
+// DiagnosticsDTO holds pass/fail report of connection leak checks
+type DiagnosticsDTO struct {
+	Passed bool                  `json:"passed"`
+	Checks []DiagnosticsCheckDTO `json:"checks"`
+}
+
+// DiagnosticsCheckDTO holds outcome of single leak check
+type DiagnosticsCheckDTO struct {
+	Name    string            `json:"name"`
+	Passed  bool              `json:"passed"`
+	Message string            `json:"message"`
+	Values  map[string]string `json:"values"`
+}
+
 // ProposalList describes list of proposals
 type ProposalList struct {
 	Proposals []ProposalDTO `json:"proposals"`
@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/policy"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+// AccessPolicyDTO lists consumer identities, which are allowed or denied to use services of this node
+// swagger:model AccessPolicyDTO
+type AccessPolicyDTO struct {
+	// consumers allowed to use services, everyone not denied is allowed if empty
+	// example: ["0x0000000000000000000000000000000000000001"]
+	Allow []string `json:"allow"`
+
+	// consumers refused to use services, even if allowed
+	// example: ["0x0000000000000000000000000000000000000002"]
+	Deny []string `json:"deny"`
+}
+
+// AccessPolicy keeps rules of consumer access to services
+type AccessPolicy interface {
+	Rules() policy.Rules
+	SetRules(rules policy.Rules) error
+}
+
+type accessPolicyEndpoint struct {
+	policy AccessPolicy
+}
+
+// NewAccessPolicyEndpoint creates and returns access policy endpoint
+func NewAccessPolicyEndpoint(accessPolicy AccessPolicy) *accessPolicyEndpoint {
+	return &accessPolicyEndpoint{policy: accessPolicy}
+}
+
+// swagger:operation GET /access-policy AccessPolicy getAccessPolicy
+// ---
+// summary: Returns access policy
+// description: Returns consumer identities, which are allowed or denied to use services of this node
+// responses:
+//   200:
+//     description: Access policy
+//     schema:
+//       "$ref": "#/definitions/AccessPolicyDTO"
+func (endpoint *accessPolicyEndpoint) Get(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	utils.WriteAsJSON(toAccessPolicyDTO(endpoint.policy.Rules()), resp)
+}
+
+// swagger:operation PUT /access-policy AccessPolicy setAccessPolicy
+// ---
+// summary: Replaces access policy
+// description: Replaces consumer identities, which are allowed or denied to use services of this node. Rules are saved to policy file, if node uses one.
+// parameters:
+//   - in: body
+//     name: body
+//     description: New access policy
+//     schema:
+//       $ref: "#/definitions/AccessPolicyDTO"
+// responses:
+//   200:
+//     description: Access policy in force
+//     schema:
+//       "$ref": "#/definitions/AccessPolicyDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   409:
+//     description: Conflict. Access policy is managed by remote source
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *accessPolicyEndpoint) Set(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var request AccessPolicyDTO
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validation.NewErrorMap()
+	validateIdentities(errorMap, "allow", request.Allow)
+	validateIdentities(errorMap, "deny", request.Deny)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	err := endpoint.policy.SetRules(policy.Rules{Allow: request.Allow, Deny: request.Deny})
+	switch err {
+	case nil:
+		utils.WriteAsJSON(toAccessPolicyDTO(endpoint.policy.Rules()), resp)
+	case policy.ErrReadOnly:
+		utils.SendError(resp, err, http.StatusConflict)
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
+
+// AddRoutesForAccessPolicy attaches access policy endpoints to router
+func AddRoutesForAccessPolicy(router *httprouter.Router, accessPolicy AccessPolicy) {
+	accessPolicyEndpoint := NewAccessPolicyEndpoint(accessPolicy)
+	router.GET("/access-policy", accessPolicyEndpoint.Get)
+	router.PUT("/access-policy", accessPolicyEndpoint.Set)
+}
+
+func validateIdentities(errors *validation.FieldErrorMap, field string, addresses []string) {
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			errors.ForField(field).AddError("invalid", "Invalid identity: "+address)
+		}
+	}
+}
+
+func toAccessPolicyDTO(rules policy.Rules) AccessPolicyDTO {
+	dto := AccessPolicyDTO{Allow: rules.Allow, Deny: rules.Deny}
+	if dto.Allow == nil {
+		dto.Allow = []string{}
+	}
+	if dto.Deny == nil {
+		dto.Deny = []string{}
+	}
+	return dto
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAccessPolicy struct {
+	rules  policy.Rules
+	setErr error
+}
+
+func (fap *fakeAccessPolicy) Rules() policy.Rules {
+	return fap.rules
+}
+
+func (fap *fakeAccessPolicy) SetRules(rules policy.Rules) error {
+	if fap.setErr != nil {
+		return fap.setErr
+	}
+	fap.rules = rules
+	return nil
+}
+
+func TestAccessPolicyGetReturnsRules(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForAccessPolicy(router, &fakeAccessPolicy{
+		rules: policy.Rules{Deny: []string{"0x0000000000000000000000000000000000000002"}},
+	})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/access-policy", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"allow": [], "deny": ["0x0000000000000000000000000000000000000002"]}`, resp.Body.String())
+}
+
+func TestAccessPolicySetReplacesRules(t *testing.T) {
+	accessPolicy := &fakeAccessPolicy{}
+	router := httprouter.New()
+	AddRoutesForAccessPolicy(router, accessPolicy)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(
+		http.MethodPut,
+		"/access-policy",
+		strings.NewReader(`{"allow": ["0x0000000000000000000000000000000000000001"]}`),
+	))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"allow": ["0x0000000000000000000000000000000000000001"], "deny": []}`, resp.Body.String())
+	assert.Equal(t, policy.Rules{Allow: []string{"0x0000000000000000000000000000000000000001"}}, accessPolicy.rules)
+}
+
+func TestAccessPolicySetValidatesIdentities(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForAccessPolicy(router, &fakeAccessPolicy{})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/access-policy", strings.NewReader(`{"deny": ["bob"]}`)))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message": "validation_error",
+			"errors": {
+				"deny": [{"code": "invalid", "message": "Invalid identity: bob"}]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestAccessPolicySetReturnsConflictForReadOnlyPolicy(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForAccessPolicy(router, &fakeAccessPolicy{setErr: policy.ErrReadOnly})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/access-policy", strings.NewReader(`{}`)))
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.JSONEq(t, `{"message": "access policy rules are managed by remote source"}`, resp.Body.String())
+}
@@ -18,9 +18,13 @@
 package endpoints
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	log "github.com/cihub/seelog"
@@ -29,6 +33,10 @@ import (
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/proposals/selector"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
 	"github.com/mysteriumnetwork/node/tequilapi/validation"
 )
@@ -43,7 +51,86 @@ type ConnectOptions struct {
 	// kill switch option restricting communication only through VPN
 	// required: false
 	// example: true
-	DisableKillSwitch bool `json:"killSwitch"`
+	EnableKillSwitch bool `json:"killSwitch"`
+	// reconnect option restoring lost connection automatically
+	// required: false
+	Reconnect ReconnectOptions `json:"reconnect"`
+	// networks routed through VPN as CIDRs, IP addresses or host names, all traffic is routed if empty
+	// required: false
+	// example: ["10.0.0.0/8", "intranet.example.com"]
+	IncludeRoutes []string `json:"includeRoutes"`
+	// networks kept outside of VPN as CIDRs, IP addresses or host names
+	// required: false
+	// example: ["192.168.0.0/16"]
+	ExcludeRoutes []string `json:"excludeRoutes"`
+	// DNS servers used while connected: "provider" resolves names through VPN using servers advertised by provider,
+	// "system" keeps system resolvers, or comma separated list of server IP addresses.
+	// By default provider's servers are used if it advertises any, system resolvers are kept otherwise.
+	// DNS queries are kept in the tunnel on Linux, connection fails if it can't be done for explicitly chosen servers only
+	// required: false
+	// example: 1.1.1.1,8.8.8.8
+	DNS string `json:"dns"`
+	// deadlines of connect phases, defaults are used for the ones not given
+	// required: false
+	Timeouts ConnectTimeouts `json:"timeouts"`
+	// usage limits, connection is closed once any of them is reached
+	// required: false
+	Limits ConnectLimits `json:"limits"`
+}
+
+// ConnectLimits holds tequilapi connection usage limits, zero value means no limit
+// swagger:model ConnectLimitsDTO
+type ConnectLimits struct {
+	// maximum bytes sent and received
+	// required: false
+	// example: 1073741824
+	MaxBytes uint64 `json:"maxBytes"`
+	// maximum connection duration in seconds
+	// required: false
+	// example: 3600
+	MaxDurationSeconds int `json:"maxDurationSeconds"`
+	// maximum spend in MYST
+	// required: false
+	// example: 1.5
+	MaxSpend float64 `json:"maxSpend"`
+}
+
+// ConnectTimeouts holds tequilapi deadlines of connect phases
+// swagger:model ConnectTimeoutsDTO
+type ConnectTimeouts struct {
+	// seconds to establish dialog with provider
+	// required: false
+	// example: 30
+	DialogSeconds int `json:"dialogSeconds"`
+	// seconds for provider to create session
+	// required: false
+	// example: 30
+	SessionCreateSeconds int `json:"sessionCreateSeconds"`
+	// seconds to bring tunnel up
+	// required: false
+	// example: 60
+	TunnelUpSeconds int `json:"tunnelUpSeconds"`
+	// seconds to wait for the first handshake through the tunnel
+	// required: false
+	// example: 60
+	HandshakeSeconds int `json:"handshakeSeconds"`
+}
+
+// ReconnectOptions holds tequilapi reconnect options
+// swagger:model ReconnectOptionsDTO
+type ReconnectOptions struct {
+	// number of attempts to restore lost connection, 0 disables reconnect
+	// required: false
+	// example: 3
+	Attempts int `json:"attempts"`
+	// delay in seconds before the first attempt, doubled after every failed attempt up to a minute
+	// required: false
+	// example: 2
+	BackoffSeconds int `json:"backoffSeconds"`
+	// allow reconnecting to another provider with the same service type and country
+	// required: false
+	// example: true
+	Failover bool `json:"failover"`
 }
 
 // swagger:model ConnectionRequestDTO
@@ -53,11 +140,21 @@ type connectionRequest struct {
 	// example: 0x0000000000000000000000000000000000000001
 	ConsumerID string `json:"consumerId"`
 
-	// provider identity
-	// required: true
+	// provider identity, required unless criteria are given
+	// required: false
 	// example: 0x0000000000000000000000000000000000000002
 	ProviderID string `json:"providerId"`
 
+	// criteria for choosing provider automatically instead of providerId
+	// required: false
+	Criteria *ConnectCriteria `json:"criteria,omitempty"`
+
+	// ordered provider identities to chain connection through instead of providerId,
+	// traffic enters via the first provider and exits from the last one
+	// required: false
+	// example: ["0x0000000000000000000000000000000000000002", "0x0000000000000000000000000000000000000003"]
+	Hops []string `json:"hops,omitempty"`
+
 	// service type. Possible values are "openvpn" and "noop"
 	// required: false
 	// default: openvpn
@@ -69,6 +166,23 @@ type connectionRequest struct {
 	ConnectOptions ConnectOptions `json:"connectOptions,omitempty"`
 }
 
+// ConnectCriteria describes provider acceptable for consumer
+// swagger:model ConnectCriteriaDTO
+type ConnectCriteria struct {
+	// provider country code
+	// required: false
+	// example: DE
+	Country string `json:"country"`
+	// maximum price in MYST per unit of metering
+	// required: false
+	// example: 0.5
+	MaxPrice *float64 `json:"maxPrice"`
+	// minimum share of successful connects to provider reported by quality oracle, from 0 to 1
+	// required: false
+	// example: 0.8
+	MinQuality float64 `json:"minQuality"`
+}
+
 // swagger:model ConnectionStatusDTO
 type statusResponse struct {
 	// example: Connected
@@ -96,6 +210,32 @@ type statisticsResponse struct {
 	// connection duration in seconds
 	// example: 60
 	Duration int `json:"duration"`
+
+	// limits of connection and remaining quota, given for limited connection only
+	Quota *quotaResponse `json:"quota,omitempty"`
+}
+
+// swagger:model ConnectionQuotaDTO
+type quotaResponse struct {
+	// example: 1073741824
+	MaxBytes uint64 `json:"maxBytes,omitempty"`
+
+	// bytes left until connection is closed, given when maxBytes is set
+	// example: 1048576
+	BytesLeft *uint64 `json:"bytesLeft,omitempty"`
+
+	// in seconds
+	// example: 3600
+	MaxDuration int `json:"maxDuration,omitempty"`
+
+	// seconds left until connection is closed, given when maxDuration is set
+	// example: 60
+	DurationLeft *int `json:"durationLeft,omitempty"`
+
+	MaxSpend *money.Money `json:"maxSpend,omitempty"`
+
+	// money left to spend until connection is closed, given when maxSpend is set
+	SpendLeft *money.Money `json:"spendLeft,omitempty"`
 }
 
 // SessionStatisticsTracker represents the session stat keeper
@@ -104,24 +244,37 @@ type SessionStatisticsTracker interface {
 	GetSessionDuration() time.Duration
 }
 
+// ProposalSelector picks proposals matching given criteria, best ones first
+type ProposalSelector interface {
+	Select(criteria selector.Criteria) ([]market.ServiceProposal, error)
+}
+
+// LastConnectionKeeper remembers the last successful connection request, so it can be restored after restart
+type LastConnectionKeeper interface {
+	Remember(request json.RawMessage) error
+	Forget() error
+}
+
 // ConnectionEndpoint struct represents /connection resource and it's subresources
 type ConnectionEndpoint struct {
 	manager           connection.Manager
 	ipResolver        ip.Resolver
 	statisticsTracker SessionStatisticsTracker
-	//TODO connection should use concrete proposal from connection params and avoid going to marketplace
-	proposalProvider ProposalProvider
+	lastConnection    LastConnectionKeeper
+	connector
 }
 
 const connectionLogPrefix = "[Connection] "
 
 // NewConnectionEndpoint creates and returns connection endpoint
-func NewConnectionEndpoint(manager connection.Manager, ipResolver ip.Resolver, statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider) *ConnectionEndpoint {
+// lastConnection may be nil if connections shouldn't be remembered
+func NewConnectionEndpoint(manager connection.Manager, ipResolver ip.Resolver, statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider, proposalSelector ProposalSelector, lastConnection LastConnectionKeeper) *ConnectionEndpoint {
 	return &ConnectionEndpoint{
 		manager:           manager,
 		ipResolver:        ipResolver,
 		statisticsTracker: statsKeeper,
-		proposalProvider:  proposalProvider,
+		lastConnection:    lastConnection,
+		connector:         newConnector(proposalProvider, proposalSelector),
 	}
 }
 
@@ -148,11 +301,11 @@ func (ce *ConnectionEndpoint) Status(resp http.ResponseWriter, _ *http.Request,
 // swagger:operation PUT /connection Connection createConnection
 // ---
 // summary: Starts new connection
-// description: Consumer opens connection to provider
+// description: Consumer opens connection to given provider or to the best provider matching given criteria
 // parameters:
 //   - in: body
 //     name: body
-//     description: Parameters in body (consumerId, providerId, serviceType) required for creating new connection
+//     description: Parameters in body (consumerId, providerId, criteria or hops, serviceType) required for creating new connection
 //     schema:
 //       $ref: "#/definitions/ConnectionRequestDTO"
 // responses:
@@ -164,6 +317,10 @@ func (ce *ConnectionEndpoint) Status(resp http.ResponseWriter, _ *http.Request,
 //     description: Bad request
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
+//   404:
+//     description: No provider matches given criteria
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
 //   409:
 //     description: Conflict. Connection already exists
 //     schema:
@@ -180,48 +337,158 @@ func (ce *ConnectionEndpoint) Status(resp http.ResponseWriter, _ *http.Request,
 //     description: Internal server error
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
+//   503:
+//     description: Provider doesn't take more sessions
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   504:
+//     description: Connect phase named in the message timed out
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
 func (ce *ConnectionEndpoint) Create(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
-	cr, err := toConnectionRequest(req)
+	cr, ok := ce.connect(resp, req, ce.manager.Connect)
+	if !ok {
+		return
+	}
+	ce.remember(cr)
+	resp.WriteHeader(http.StatusCreated)
+	ce.Status(resp, req, params)
+}
+
+// connectFunc connects consumer to provider of given proposal
+type connectFunc func(consumerID identity.Identity, proposal market.ServiceProposal, params connection.ConnectParams) error
+
+const (
+	// candidatesMaxAttempts is the count of candidate providers, which single connection request tries at most
+	candidatesMaxAttempts = 3
+	// candidatesDeadline is the time after which no more candidate providers are tried, the attempt in progress is not interrupted
+	candidatesDeadline = 2 * time.Minute
+)
+
+// connector finds proposals of connection request and connects to them
+type connector struct {
+	//TODO connection should use concrete proposal from connection params and avoid going to marketplace
+	proposalProvider ProposalProvider
+	proposalSelector ProposalSelector
+	maxAttempts      int
+	deadline         time.Duration
+}
+
+func newConnector(proposalProvider ProposalProvider, proposalSelector ProposalSelector) connector {
+	return connector{
+		proposalProvider: proposalProvider,
+		proposalSelector: proposalSelector,
+		maxAttempts:      candidatesMaxAttempts,
+		deadline:         candidatesDeadline,
+	}
+}
+
+// connect parses connection request and connects to one of its candidate proposals, failures are written to response
+func (c *connector) connect(resp http.ResponseWriter, req *http.Request, connect connectFunc) (*connectionRequest, bool) {
+	cr, err := toConnectionRequest(req.Body)
 	if err != nil {
 		utils.SendError(resp, err, http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	errorMap := validateConnectionRequest(cr)
 	if errorMap.HasErrors() {
 		utils.SendValidationErrorMessage(resp, errorMap)
-		return
+		return nil, false
+	}
+
+	if err := c.connectRequest(cr, connect); err != nil {
+		sendConnectError(resp, err)
+		return nil, false
 	}
+	return cr, true
+}
 
-	proposals, err := ce.proposalProvider.FindProposals(cr.ProviderID, cr.ServiceType)
+// connectRequest tries candidate proposals of the request until one of them is connected
+func (c *connector) connectRequest(cr *connectionRequest, connect connectFunc) error {
+	connectOptions := getConnectOptions(cr)
+	var candidates []market.ServiceProposal
+	var err error
+	switch {
+	case cr.Criteria != nil:
+		candidates, err = c.proposalSelector.Select(toSelectorCriteria(cr))
+	case len(cr.Hops) > 0:
+		// the last hop is the exit, all the others are entered in given order before it
+		var hops []market.ServiceProposal
+		hops, err = c.findHopProposals(cr)
+		if err == nil {
+			candidates = hops[len(hops)-1:]
+			connectOptions.EntryHops = hops[:len(hops)-1]
+		}
+	default:
+		var proposal market.ServiceProposal
+		proposal, err = c.findProviderProposal(cr.ProviderID, cr.ServiceType)
+		candidates = []market.ServiceProposal{proposal}
+	}
 	if err != nil {
-		utils.SendError(resp, err, http.StatusInternalServerError)
-		return
+		return err
 	}
-	if len(proposals) == 0 {
-		utils.SendError(resp, errors.New("provider has no service proposals"), http.StatusBadRequest)
-		return
+
+	deadline := time.Now().Add(c.deadline)
+	for attempt, proposal := range candidates {
+		if attempt > 0 && (attempt >= c.maxAttempts || time.Now().After(deadline)) {
+			log.Warn(connectionLogPrefix, "Giving up connecting after ", attempt, " candidate providers")
+			break
+		}
+		err = connect(identity.FromAddress(cr.ConsumerID), proposal, connectOptions)
+		if err == nil || err == connection.ErrAlreadyExists || err == connection.ErrConnectionCancelled {
+			break
+		}
+		log.Warn(connectionLogPrefix, "Failed to connect to provider ", proposal.ProviderID, ": ", err)
 	}
+	return err
+}
 
-	proposal := proposals[0]
+func sendConnectError(resp http.ResponseWriter, err error) {
+	switch err {
+	case selector.ErrNoMatchingProposals:
+		utils.SendError(resp, err, http.StatusNotFound)
+	case errNoProviderProposals:
+		utils.SendError(resp, err, http.StatusBadRequest)
+	case connection.ErrAlreadyExists:
+		utils.SendError(resp, err, http.StatusConflict)
+	case connection.ErrConnectionCancelled:
+		utils.SendError(resp, err, statusConnectCancelled)
+	case connection.ErrConcurrentKillSwitch:
+		utils.SendError(resp, err, http.StatusBadRequest)
+	case session.ErrorCapacityExceeded:
+		utils.SendError(resp, err, http.StatusServiceUnavailable)
+	case connection.ErrDialogTimeout, connection.ErrSessionCreateTimeout, connection.ErrTunnelTimeout, connection.ErrHandshakeTimeout:
+		utils.SendError(resp, err, http.StatusGatewayTimeout)
+	default:
+		log.Error(connectionLogPrefix, err)
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
 
-	connectOptions := getConnectOptions(cr)
-	err = ce.manager.Connect(identity.FromAddress(cr.ConsumerID), proposal, connectOptions)
+var errNoProviderProposals = errors.New("provider has no service proposals")
 
+func (c *connector) findProviderProposal(providerID, serviceType string) (market.ServiceProposal, error) {
+	proposals, err := c.proposalProvider.FindProposals(providerID, serviceType)
 	if err != nil {
-		switch err {
-		case connection.ErrAlreadyExists:
-			utils.SendError(resp, err, http.StatusConflict)
-		case connection.ErrConnectionCancelled:
-			utils.SendError(resp, err, statusConnectCancelled)
-		default:
-			log.Error(connectionLogPrefix, err)
-			utils.SendError(resp, err, http.StatusInternalServerError)
+		return market.ServiceProposal{}, err
+	}
+	if len(proposals) == 0 {
+		return market.ServiceProposal{}, errNoProviderProposals
+	}
+	return proposals[0], nil
+}
+
+func (c *connector) findHopProposals(cr *connectionRequest) ([]market.ServiceProposal, error) {
+	hops := make([]market.ServiceProposal, len(cr.Hops))
+	for i, providerID := range cr.Hops {
+		proposal, err := c.findProviderProposal(providerID, cr.ServiceType)
+		if err != nil {
+			return nil, err
 		}
-		return
+		hops[i] = proposal
 	}
-	resp.WriteHeader(http.StatusCreated)
-	ce.Status(resp, req, params)
+	return hops, nil
 }
 
 // Kill stops connection
@@ -241,6 +508,8 @@ func (ce *ConnectionEndpoint) Create(resp http.ResponseWriter, req *http.Request
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
 func (ce *ConnectionEndpoint) Kill(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	// connection is forgotten even if it's already lost, user doesn't want it back anymore
+	ce.forget()
 	err := ce.manager.Disconnect()
 	if err != nil {
 		switch err {
@@ -309,15 +578,57 @@ func (ce *ConnectionEndpoint) GetStatistics(writer http.ResponseWriter, request
 		BytesSent:     st.BytesSent,
 		BytesReceived: st.BytesReceived,
 		Duration:      int(duration.Seconds()),
+		Quota:         toQuotaResponse(ce.manager.Quota()),
 	}
 
 	utils.WriteAsJSON(response, writer)
 }
 
+func (ce *ConnectionEndpoint) remember(cr *connectionRequest) {
+	if ce.lastConnection == nil {
+		return
+	}
+	request, err := json.Marshal(cr)
+	if err == nil {
+		err = ce.lastConnection.Remember(request)
+	}
+	if err != nil {
+		log.Warn(connectionLogPrefix, "Failed to remember connection: ", err)
+	}
+}
+
+func (ce *ConnectionEndpoint) forget() {
+	if ce.lastConnection == nil {
+		return
+	}
+	if err := ce.lastConnection.Forget(); err != nil {
+		log.Warn(connectionLogPrefix, "Failed to forget connection: ", err)
+	}
+}
+
+// RestoreConnection connects manager with connection request remembered by LastConnectionKeeper.
+// Consumer identity of the request is unlocked before connecting.
+func RestoreConnection(manager connection.Manager, proposalProvider ProposalProvider, proposalSelector ProposalSelector,
+	unlock func(consumerID identity.Identity) error, request json.RawMessage) error {
+	cr, err := toConnectionRequest(bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	if errorMap := validateConnectionRequest(cr); errorMap.HasErrors() {
+		return errors.New("remembered connection request is invalid")
+	}
+	if err := unlock(identity.FromAddress(cr.ConsumerID)); err != nil {
+		return err
+	}
+
+	c := newConnector(proposalProvider, proposalSelector)
+	return c.connectRequest(cr, manager.Connect)
+}
+
 // AddRoutesForConnection adds connections routes to given router
 func AddRoutesForConnection(router *httprouter.Router, manager connection.Manager, ipResolver ip.Resolver,
-	statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider) {
-	connectionEndpoint := NewConnectionEndpoint(manager, ipResolver, statsKeeper, proposalProvider)
+	statsKeeper SessionStatisticsTracker, proposalProvider ProposalProvider, proposalSelector ProposalSelector, lastConnection LastConnectionKeeper) {
+	connectionEndpoint := NewConnectionEndpoint(manager, ipResolver, statsKeeper, proposalProvider, proposalSelector, lastConnection)
 	router.GET("/connection", connectionEndpoint.Status)
 	router.PUT("/connection", connectionEndpoint.Create)
 	router.DELETE("/connection", connectionEndpoint.Kill)
@@ -325,13 +636,13 @@ func AddRoutesForConnection(router *httprouter.Router, manager connection.Manage
 	router.GET("/connection/statistics", connectionEndpoint.GetStatistics)
 }
 
-func toConnectionRequest(req *http.Request) (*connectionRequest, error) {
+func toConnectionRequest(body io.Reader) (*connectionRequest, error) {
 	var connectionRequest = connectionRequest{
 		// This defaults the service type to openvpn, for backward compatibility
 		// If specified in the request, the value will get overridden
 		ServiceType: "openvpn",
 	}
-	err := json.NewDecoder(req.Body).Decode(&connectionRequest)
+	err := json.NewDecoder(body).Decode(&connectionRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -339,7 +650,85 @@ func toConnectionRequest(req *http.Request) (*connectionRequest, error) {
 }
 
 func getConnectOptions(cr *connectionRequest) connection.ConnectParams {
-	return connection.ConnectParams{DisableKillSwitch: cr.ConnectOptions.DisableKillSwitch}
+	reconnect := cr.ConnectOptions.Reconnect
+	return connection.ConnectParams{
+		EnableKillSwitch: cr.ConnectOptions.EnableKillSwitch,
+		Reconnect: connection.ReconnectPolicy{
+			MaxAttempts: reconnect.Attempts,
+			Backoff:     time.Duration(reconnect.BackoffSeconds) * time.Second,
+			Failover:    reconnect.Failover,
+		},
+		IncludeRoutes: cr.ConnectOptions.IncludeRoutes,
+		ExcludeRoutes: cr.ConnectOptions.ExcludeRoutes,
+		DNS:           connection.DNSOption(cr.ConnectOptions.DNS),
+		Timeouts:      toConnectTimeouts(cr.ConnectOptions.Timeouts),
+		Limits:        toConnectionLimits(cr.ConnectOptions.Limits),
+	}
+}
+
+func toConnectionLimits(limits ConnectLimits) connection.Limits {
+	connectionLimits := connection.Limits{
+		MaxBytes:    limits.MaxBytes,
+		MaxDuration: time.Duration(limits.MaxDurationSeconds) * time.Second,
+	}
+	if limits.MaxSpend > 0 {
+		connectionLimits.MaxSpend = money.NewMoney(limits.MaxSpend, money.CURRENCY_MYST)
+	}
+	return connectionLimits
+}
+
+func toQuotaResponse(quota connection.Quota) *quotaResponse {
+	if !quota.HasLimits() {
+		return nil
+	}
+
+	response := &quotaResponse{}
+	if quota.Limits.MaxBytes > 0 {
+		bytesLeft := quota.BytesLeft()
+		response.MaxBytes = quota.Limits.MaxBytes
+		response.BytesLeft = &bytesLeft
+	}
+	if quota.Limits.MaxDuration > 0 {
+		durationLeft := int(quota.DurationLeft().Seconds())
+		response.MaxDuration = int(quota.Limits.MaxDuration.Seconds())
+		response.DurationLeft = &durationLeft
+	}
+	if quota.Limits.MaxSpend.Amount > 0 {
+		maxSpend, spendLeft := quota.Limits.MaxSpend, quota.SpendLeft()
+		response.MaxSpend = &maxSpend
+		response.SpendLeft = &spendLeft
+	}
+	return response
+}
+
+func toConnectTimeouts(timeouts ConnectTimeouts) connection.ConnectTimeouts {
+	orDefault := func(seconds int, defaultTimeout time.Duration) time.Duration {
+		if seconds == 0 {
+			return defaultTimeout
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	defaults := connection.DefaultConnectTimeouts
+	return connection.ConnectTimeouts{
+		Dialog:        orDefault(timeouts.DialogSeconds, defaults.Dialog),
+		SessionCreate: orDefault(timeouts.SessionCreateSeconds, defaults.SessionCreate),
+		TunnelUp:      orDefault(timeouts.TunnelUpSeconds, defaults.TunnelUp),
+		Handshake:     orDefault(timeouts.HandshakeSeconds, defaults.Handshake),
+	}
+}
+
+func toSelectorCriteria(cr *connectionRequest) selector.Criteria {
+	criteria := selector.Criteria{
+		ServiceType: cr.ServiceType,
+		Country:     cr.Criteria.Country,
+		MinQuality:  cr.Criteria.MinQuality,
+	}
+	if cr.Criteria.MaxPrice != nil {
+		maxPrice := money.NewMoney(*cr.Criteria.MaxPrice, money.CURRENCY_MYST)
+		criteria.MaxPrice = &maxPrice
+	}
+	return criteria
 }
 
 func validateConnectionRequest(cr *connectionRequest) *validation.FieldErrorMap {
@@ -347,12 +736,89 @@ func validateConnectionRequest(cr *connectionRequest) *validation.FieldErrorMap
 	if len(cr.ConsumerID) == 0 {
 		errors.ForField("consumerId").AddError("required", "Field is required")
 	}
-	if len(cr.ProviderID) == 0 {
+	if cr.Criteria == nil && len(cr.Hops) == 0 && len(cr.ProviderID) == 0 {
 		errors.ForField("providerId").AddError("required", "Field is required")
 	}
+	if cr.Criteria != nil {
+		validateCriteria(errors, cr)
+	}
+	if len(cr.Hops) > 0 {
+		validateHops(errors, cr)
+	}
+	if cr.ConnectOptions.Reconnect.Attempts < 0 {
+		errors.ForField("connectOptions.reconnect.attempts").AddError("invalid", "Value can not be negative")
+	}
+	if cr.ConnectOptions.Reconnect.BackoffSeconds < 0 {
+		errors.ForField("connectOptions.reconnect.backoffSeconds").AddError("invalid", "Value can not be negative")
+	}
+	timeouts := map[string]int{
+		"dialogSeconds":        cr.ConnectOptions.Timeouts.DialogSeconds,
+		"sessionCreateSeconds": cr.ConnectOptions.Timeouts.SessionCreateSeconds,
+		"tunnelUpSeconds":      cr.ConnectOptions.Timeouts.TunnelUpSeconds,
+		"handshakeSeconds":     cr.ConnectOptions.Timeouts.HandshakeSeconds,
+	}
+	if cr.ConnectOptions.Limits.MaxDurationSeconds < 0 {
+		errors.ForField("connectOptions.limits.maxDurationSeconds").AddError("invalid", "Value can not be negative")
+	}
+	if cr.ConnectOptions.Limits.MaxSpend < 0 {
+		errors.ForField("connectOptions.limits.maxSpend").AddError("invalid", "Value can not be negative")
+	}
+	for field, seconds := range timeouts {
+		if seconds < 0 {
+			errors.ForField("connectOptions.timeouts."+field).AddError("invalid", "Value can not be negative")
+		}
+	}
+	validateRoutes(errors, "connectOptions.includeRoutes", cr.ConnectOptions.IncludeRoutes)
+	validateRoutes(errors, "connectOptions.excludeRoutes", cr.ConnectOptions.ExcludeRoutes)
+	if err := connection.DNSOption(cr.ConnectOptions.DNS).Validate(); err != nil {
+		errors.ForField("connectOptions.dns").AddError("invalid", err.Error())
+	}
 	return errors
 }
 
+func validateRoutes(errors *validation.FieldErrorMap, field string, routes []string) {
+	for _, route := range routes {
+		if strings.TrimSpace(route) == "" {
+			errors.ForField(field).AddError("invalid", "Route can not be empty")
+			continue
+		}
+		if strings.Contains(route, "/") {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(route)); err != nil {
+				errors.ForField(field).AddError("invalid", "Invalid CIDR: "+route)
+			}
+		}
+	}
+}
+
+func validateCriteria(errors *validation.FieldErrorMap, cr *connectionRequest) {
+	if len(cr.ProviderID) != 0 {
+		errors.ForField("criteria").AddError("invalid", "Criteria can not be used together with providerId")
+	}
+	if cr.Criteria.MaxPrice != nil && *cr.Criteria.MaxPrice < 0 {
+		errors.ForField("criteria.maxPrice").AddError("invalid", "Value can not be negative")
+	}
+	if cr.Criteria.MinQuality < 0 || cr.Criteria.MinQuality > 1 {
+		errors.ForField("criteria.minQuality").AddError("invalid", "Value must be between 0 and 1")
+	}
+}
+
+func validateHops(errors *validation.FieldErrorMap, cr *connectionRequest) {
+	if len(cr.ProviderID) != 0 || cr.Criteria != nil {
+		errors.ForField("hops").AddError("invalid", "Hops can not be used together with providerId or criteria")
+	}
+	if len(cr.Hops) < 2 {
+		errors.ForField("hops").AddError("invalid", "At least two providers are required")
+	}
+	for _, providerID := range cr.Hops {
+		if strings.TrimSpace(providerID) == "" {
+			errors.ForField("hops").AddError("invalid", "Provider can not be empty")
+		}
+	}
+	if cr.ConnectOptions.EnableKillSwitch {
+		errors.ForField("connectOptions.killSwitch").AddError("invalid", "Kill switch is not supported for multi-hop connections")
+	}
+}
+
 func toStatusResponse(status connection.ConnectionStatus) statusResponse {
 	return statusResponse{
 		Status:    string(status.State),
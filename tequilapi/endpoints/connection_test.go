@@ -18,7 +18,9 @@
 package endpoints
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -31,6 +33,9 @@ import (
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/proposals/selector"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,16 +43,25 @@ type fakeManager struct {
 	onConnectReturn      error
 	onDisconnectReturn   error
 	onStatusReturn       connection.ConnectionStatus
+	onQuotaReturn        connection.Quota
 	disconnectCount      int
 	requestedConsumerID  identity.Identity
 	requestedProvider    identity.Identity
 	requestedServiceType string
+	requestedParams      connection.ConnectParams
+	dialedProviders      []string
+	providerErrors       map[string]error
 }
 
 func (fm *fakeManager) Connect(consumerID identity.Identity, proposal market.ServiceProposal, options connection.ConnectParams) error {
 	fm.requestedConsumerID = consumerID
 	fm.requestedProvider = identity.FromAddress(proposal.ProviderID)
 	fm.requestedServiceType = proposal.ServiceType
+	fm.requestedParams = options
+	fm.dialedProviders = append(fm.dialedProviders, proposal.ProviderID)
+	if err, ok := fm.providerErrors[proposal.ProviderID]; ok {
+		return err
+	}
 	return fm.onConnectReturn
 }
 
@@ -56,6 +70,10 @@ func (fm *fakeManager) Status() connection.ConnectionStatus {
 	return fm.onStatusReturn
 }
 
+func (fm *fakeManager) Quota() connection.Quota {
+	return fm.onQuotaReturn
+}
+
 func (fm *fakeManager) Disconnect() error {
 	fm.disconnectCount++
 	return fm.onDisconnectReturn
@@ -100,7 +118,7 @@ func TestAddRoutesForConnectionAddsRoutes(t *testing.T) {
 	ipResolver := ip.NewResolverFake("123.123.123.123")
 
 	mockedProposalProvider := getMockProposalProviderWithSpecifiedProposal("node1", "noop")
-	AddRoutesForConnection(router, &fakeManager, ipResolver, statsKeeper, mockedProposalProvider)
+	AddRoutesForConnection(router, &fakeManager, ipResolver, statsKeeper, mockedProposalProvider, nil, nil)
 
 	tests := []struct {
 		method         string
@@ -155,7 +173,7 @@ func TestDisconnectingState(t *testing.T) {
 		SessionID: "",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -177,7 +195,7 @@ func TestNotConnectedStateIsReturnedWhenNoConnection(t *testing.T) {
 		SessionID: "",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -199,7 +217,7 @@ func TestStateConnectingIsReturnedWhenIsConnectionInProgress(t *testing.T) {
 		State: connection.Connecting,
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -222,7 +240,7 @@ func TestConnectedStateAndSessionIdIsReturnedWhenIsConnected(t *testing.T) {
 		SessionID: "My-super-session",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -242,7 +260,7 @@ func TestConnectedStateAndSessionIdIsReturnedWhenIsConnected(t *testing.T) {
 func TestPutReturns400ErrorIfRequestBodyIsNotJSON(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("a"))
 	resp := httptest.NewRecorder()
 
@@ -261,7 +279,7 @@ func TestPutReturns400ErrorIfRequestBodyIsNotJSON(t *testing.T) {
 func TestPutReturns422ErrorIfRequestBodyIsMissingFieldValues(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("{}"))
 	resp := httptest.NewRecorder()
 
@@ -284,7 +302,7 @@ func TestPutWithValidBodyCreatesConnection(t *testing.T) {
 	fakeManager := fakeManager{}
 
 	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -304,11 +322,442 @@ func TestPutWithValidBodyCreatesConnection(t *testing.T) {
 	assert.Equal(t, "openvpn", fakeManager.requestedServiceType)
 }
 
+type fakeProposalSelector struct {
+	requestedCriteria selector.Criteria
+	proposals         []market.ServiceProposal
+	err               error
+}
+
+func (fps *fakeProposalSelector) Select(criteria selector.Criteria) ([]market.ServiceProposal, error) {
+	fps.requestedCriteria = criteria
+	return fps.proposals, fps.err
+}
+
+func TestPutWithCriteriaConnectsToNextCandidateWhenConnectFails(t *testing.T) {
+	fakeManager := fakeManager{providerErrors: map[string]error{"node-1": errors.New("session creation failed")}}
+	proposalSelector := &fakeProposalSelector{proposals: []market.ServiceProposal{
+		{ProviderID: "node-1", ServiceType: "wireguard"},
+		{ProviderID: "node-2", ServiceType: "wireguard"},
+		{ProviderID: "node-3", ServiceType: "wireguard"},
+	}}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, proposalSelector, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"serviceType": "wireguard",
+				"criteria": {"country": "DE", "maxPrice": 0.5, "minQuality": 0.8}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	maxPrice := money.NewMoney(0.5, money.CURRENCY_MYST)
+	assert.Equal(
+		t,
+		selector.Criteria{ServiceType: "wireguard", Country: "DE", MaxPrice: &maxPrice, MinQuality: 0.8},
+		proposalSelector.requestedCriteria,
+	)
+	assert.Equal(t, []string{"node-1", "node-2"}, fakeManager.dialedProviders)
+}
+
+func TestPutWithCriteriaTriesLimitedCountOfCandidates(t *testing.T) {
+	connectErr := errors.New("session creation failed")
+	fakeManager := fakeManager{onConnectReturn: connectErr}
+	proposalSelector := &fakeProposalSelector{}
+	for i := 1; i <= 5; i++ {
+		proposalSelector.proposals = append(proposalSelector.proposals, market.ServiceProposal{ProviderID: fmt.Sprintf("node-%d", i)})
+	}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, proposalSelector, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(`{"consumerId" : "my-identity", "criteria": {"country": "DE"}}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, []string{"node-1", "node-2", "node-3"}, fakeManager.dialedProviders)
+}
+
+func TestPutWithCriteriaStopsTryingCandidatesAfterDeadline(t *testing.T) {
+	fakeManager := fakeManager{onConnectReturn: errors.New("session creation failed")}
+	proposalSelector := &fakeProposalSelector{proposals: []market.ServiceProposal{
+		{ProviderID: "node-1"},
+		{ProviderID: "node-2"},
+	}}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, proposalSelector, nil)
+	connEndpoint.deadline = 0
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(`{"consumerId" : "my-identity", "criteria": {"country": "DE"}}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, []string{"node-1"}, fakeManager.dialedProviders)
+}
+
+func TestPutWithCriteriaReturns404WhenNoProposalsMatch(t *testing.T) {
+	fakeManager := fakeManager{}
+	proposalSelector := &fakeProposalSelector{err: selector.ErrNoMatchingProposals}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, proposalSelector, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(`{"consumerId" : "my-identity", "criteria": {"country": "DE"}}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Empty(t, fakeManager.dialedProviders)
+}
+
+func TestPutReturns422ErrorIfCriteriaAreInvalid(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, &fakeProposalSelector{}, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"criteria": {"maxPrice": -1, "minQuality": 2}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"criteria" : [ { "code" : "invalid" , "message" : "Criteria can not be used together with providerId" } ],
+				"criteria.maxPrice" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ],
+				"criteria.minQuality" : [ { "code" : "invalid" , "message" : "Value must be between 0 and 1" } ]
+			}
+		}`, resp.Body.String())
+}
+
+type proposalsByProvider map[string]market.ServiceProposal
+
+func (pbp proposalsByProvider) FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error) {
+	if proposal, ok := pbp[providerID]; ok {
+		return []market.ServiceProposal{proposal}, nil
+	}
+	return nil, nil
+}
+
+func TestPutWithHopsChainsConnectionThroughEntryProviders(t *testing.T) {
+	fakeManager := fakeManager{}
+	proposalProvider := proposalsByProvider{
+		"entry-node":  {ProviderID: "entry-node", ServiceType: "noop"},
+		"middle-node": {ProviderID: "middle-node", ServiceType: "noop"},
+		"exit-node":   {ProviderID: "exit-node", ServiceType: "noop"},
+	}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"serviceType": "noop",
+				"hops": ["entry-node", "middle-node", "exit-node"]
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, identity.FromAddress("exit-node"), fakeManager.requestedProvider)
+	assert.Equal(
+		t,
+		[]market.ServiceProposal{proposalProvider["entry-node"], proposalProvider["middle-node"]},
+		fakeManager.requestedParams.EntryHops,
+	)
+}
+
+func TestPutWithHopsReturns400WhenHopHasNoProposals(t *testing.T) {
+	fakeManager := fakeManager{}
+	proposalProvider := proposalsByProvider{"exit-node": {ProviderID: "exit-node", ServiceType: "noop"}}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(`{"consumerId" : "my-identity", "hops": ["entry-node", "exit-node"]}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Empty(t, fakeManager.dialedProviders)
+}
+
+func TestPutReturns422ErrorIfHopsAreInvalid(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"hops": [" "],
+				"connectOptions": {"killSwitch": true}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"hops" : [
+					{ "code" : "invalid" , "message" : "Hops can not be used together with providerId or criteria" },
+					{ "code" : "invalid" , "message" : "At least two providers are required" },
+					{ "code" : "invalid" , "message" : "Provider can not be empty" }
+				],
+				"connectOptions.killSwitch" : [
+					{ "code" : "invalid" , "message" : "Kill switch is not supported for multi-hop connections" }
+				]
+			}
+		}`, resp.Body.String())
+}
+
+func TestPutWithReconnectOptionsPassesReconnectPolicy(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"reconnect": { "attempts": 3, "backoffSeconds": 2, "failover": true }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(
+		t,
+		connection.ReconnectPolicy{MaxAttempts: 3, Backoff: 2 * time.Second, Failover: true},
+		fakeManager.requestedParams.Reconnect,
+	)
+}
+
+func TestPutWithRoutesAndDNSPassesThemToManager(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"includeRoutes": ["10.0.0.0/8", "intranet.example.com"],
+					"excludeRoutes": ["10.1.0.0/16"],
+					"dns": "system"
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, []string{"10.0.0.0/8", "intranet.example.com"}, fakeManager.requestedParams.IncludeRoutes)
+	assert.Equal(t, []string{"10.1.0.0/16"}, fakeManager.requestedParams.ExcludeRoutes)
+	assert.Equal(t, connection.DNSOptionSystem, fakeManager.requestedParams.DNS)
+}
+
+func TestPutReturns422ErrorIfRoutesOrDNSAreInvalid(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"includeRoutes": ["10.0.0.0/33"],
+					"excludeRoutes": [" "],
+					"dns": "1.1.1.1,resolver"
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.includeRoutes" : [ { "code" : "invalid" , "message" : "Invalid CIDR: 10.0.0.0/33" } ],
+				"connectOptions.excludeRoutes" : [ { "code" : "invalid" , "message" : "Route can not be empty" } ],
+				"connectOptions.dns" : [ { "code" : "invalid" , "message" : "invalid DNS server address \"resolver\"" } ]
+			}
+		}`, resp.Body.String())
+}
+
+func TestPutReturns422ErrorIfReconnectOptionsAreNegative(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"reconnect": { "attempts": -1, "backoffSeconds": -1 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.reconnect.attempts" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ],
+				"connectOptions.reconnect.backoffSeconds" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ]
+			}
+		}`, resp.Body.String())
+}
+
+func TestPutWithTimeoutsPassesThemToManagerWithDefaultsForMissingOnes(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"timeouts": { "dialogSeconds": 5, "handshakeSeconds": 10 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(
+		t,
+		connection.ConnectTimeouts{
+			Dialog:        5 * time.Second,
+			SessionCreate: connection.DefaultConnectTimeouts.SessionCreate,
+			TunnelUp:      connection.DefaultConnectTimeouts.TunnelUp,
+			Handshake:     10 * time.Second,
+		},
+		fakeManager.requestedParams.Timeouts,
+	)
+}
+
+func TestPutReturns422ErrorIfTimeoutsAreNegative(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"timeouts": { "sessionCreateSeconds": -1, "tunnelUpSeconds": -1 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.timeouts.sessionCreateSeconds" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ],
+				"connectOptions.timeouts.tunnelUpSeconds" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ]
+			}
+		}`, resp.Body.String())
+}
+
+func TestPutReturnsGatewayTimeoutNamingPhaseWhichTimedOut(t *testing.T) {
+	fakeManager := fakeManager{onConnectReturn: connection.ErrSessionCreateTimeout}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node"
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+	assert.JSONEq(t, `{"message": "connect timed out while creating session"}`, resp.Body.String())
+}
+
 func TestPutWithServiceTypeOverridesDefault(t *testing.T) {
 	fakeManager := fakeManager{}
 
 	mystAPI := getMockProposalProviderWithSpecifiedProposal("required-node", "noop")
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, mystAPI)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, mystAPI, nil, nil)
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -332,7 +781,7 @@ func TestPutWithServiceTypeOverridesDefault(t *testing.T) {
 func TestDeleteCallsDisconnect(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
 	req := httptest.NewRequest(http.MethodDelete, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -346,7 +795,7 @@ func TestDeleteCallsDisconnect(t *testing.T) {
 func TestGetIPEndpointSucceeds(t *testing.T) {
 	manager := fakeManager{}
 	ipResolver := ip.NewResolverFake("123.123.123.123")
-	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{}, nil, nil)
 	resp := httptest.NewRecorder()
 
 	connEndpoint.GetIP(resp, nil, nil)
@@ -364,7 +813,7 @@ func TestGetIPEndpointSucceeds(t *testing.T) {
 func TestGetIPEndpointReturnsErrorWhenIPDetectionFails(t *testing.T) {
 	manager := fakeManager{}
 	ipResolver := ip.NewResolverFakeFailing(errors.New("fake error"))
-	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, &mockProposalProvider{}, nil, nil)
 	resp := httptest.NewRecorder()
 
 	connEndpoint.GetIP(resp, nil, nil)
@@ -386,7 +835,7 @@ func TestGetStatisticsEndpointReturnsStatistics(t *testing.T) {
 	}
 
 	manager := fakeManager{}
-	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{}, nil, nil)
 
 	resp := httptest.NewRecorder()
 	connEndpoint.GetStatistics(resp, nil, nil)
@@ -401,13 +850,116 @@ func TestGetStatisticsEndpointReturnsStatistics(t *testing.T) {
 	)
 }
 
+func TestGetStatisticsEndpointReturnsQuotaOfLimitedConnection(t *testing.T) {
+	statsKeeper := &StubStatisticsTracker{
+		duration: time.Minute,
+		stats:    consumer.SessionStatistics{BytesSent: 1, BytesReceived: 2},
+	}
+
+	manager := fakeManager{
+		onQuotaReturn: connection.Quota{
+			Limits: connection.Limits{
+				MaxBytes: 10,
+				MaxSpend: money.Money{Amount: 100, Currency: money.CURRENCY_MYST},
+			},
+			Used: connection.Usage{
+				Bytes:    3,
+				Duration: time.Minute,
+				Spend:    money.Money{Amount: 40, Currency: money.CURRENCY_MYST},
+			},
+		},
+	}
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{}, nil, nil)
+
+	resp := httptest.NewRecorder()
+	connEndpoint.GetStatistics(resp, nil, nil)
+	assert.JSONEq(
+		t,
+		`{
+			"bytesSent": 1,
+			"bytesReceived": 2,
+			"duration": 60,
+			"quota": {
+				"maxBytes": 10,
+				"bytesLeft": 7,
+				"maxSpend": {"amount": 100, "currency": "MYST"},
+				"spendLeft": {"amount": 60, "currency": "MYST"}
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestPutWithLimitsPassesThemToManager(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"limits": { "maxBytes": 1024, "maxDurationSeconds": 3600, "maxSpend": 0.5 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(
+		t,
+		connection.Limits{
+			MaxBytes:    1024,
+			MaxDuration: time.Hour,
+			MaxSpend:    money.NewMoney(0.5, money.CURRENCY_MYST),
+		},
+		fakeManager.requestedParams.Limits,
+	)
+}
+
+func TestPutReturns422ErrorIfLimitsAreNegative(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {
+					"limits": { "maxDurationSeconds": -1, "maxSpend": -0.1 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.limits.maxDurationSeconds" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ],
+				"connectOptions.limits.maxSpend" : [ { "code" : "invalid" , "message" : "Value can not be negative" } ]
+			}
+		}`, resp.Body.String())
+}
+
 func TestGetStatisticsEndpointReturnsStatisticsWhenSessionIsNotStarted(t *testing.T) {
 	statsKeeper := &StubStatisticsTracker{
 		stats: consumer.SessionStatistics{BytesSent: 1, BytesReceived: 2},
 	}
 
 	manager := fakeManager{}
-	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{})
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, &mockProposalProvider{}, nil, nil)
 
 	resp := httptest.NewRecorder()
 	connEndpoint.GetStatistics(resp, nil, nil)
@@ -427,7 +979,7 @@ func TestEndpointReturnsConflictStatusIfConnectionAlreadyExists(t *testing.T) {
 	manager.onConnectReturn = connection.ErrAlreadyExists
 
 	mystAPI := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mystAPI)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mystAPI, nil, nil)
 
 	req := httptest.NewRequest(
 		http.MethodPut,
@@ -455,7 +1007,7 @@ func TestDisconnectReturnsConflictStatusIfConnectionDoesNotExist(t *testing.T) {
 	manager := fakeManager{}
 	manager.onDisconnectReturn = connection.ErrNoConnection
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{})
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{}, nil, nil)
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -481,7 +1033,7 @@ func TestConnectReturnsConnectCancelledStatusWhenErrConnectionCancelledIsEncount
 	manager.onConnectReturn = connection.ErrConnectionCancelled
 
 	mockProposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mockProposalProvider)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mockProposalProvider, nil, nil)
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -504,11 +1056,39 @@ func TestConnectReturnsConnectCancelledStatusWhenErrConnectionCancelledIsEncount
 	)
 }
 
+func TestConnectReturnsServiceUnavailableWhenProviderCapacityIsExceeded(t *testing.T) {
+	manager := fakeManager{}
+	manager.onConnectReturn = session.ErrorCapacityExceeded
+
+	mockProposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, mockProposalProvider, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node"
+			}`))
+	resp := httptest.NewRecorder()
+
+	connectionEndpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "service capacity exceeded"
+		}`,
+		resp.Body.String(),
+	)
+}
+
 func TestConnectReturnsErrorIfNoProposals(t *testing.T) {
 	manager := fakeManager{}
 	manager.onConnectReturn = connection.ErrConnectionCancelled
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{proposals: make([]market.ServiceProposal, 0)})
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &mockProposalProvider{proposals: make([]market.ServiceProposal, 0)}, nil, nil)
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -530,3 +1110,121 @@ func TestConnectReturnsErrorIfNoProposals(t *testing.T) {
 		resp.Body.String(),
 	)
 }
+
+type fakeLastConnectionKeeper struct {
+	remembered json.RawMessage
+	forgotten  bool
+}
+
+func (flck *fakeLastConnectionKeeper) Remember(request json.RawMessage) error {
+	flck.remembered = request
+	return nil
+}
+
+func (flck *fakeLastConnectionKeeper) Forget() error {
+	flck.forgotten = true
+	return nil
+}
+
+func TestCreateRemembersConnectedRequest(t *testing.T) {
+	fakeManager := fakeManager{}
+	lastConnection := &fakeLastConnectionKeeper{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, lastConnection)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {"killSwitch": true}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var remembered connectionRequest
+	assert.NoError(t, json.Unmarshal(lastConnection.remembered, &remembered))
+	assert.Equal(t, "my-identity", remembered.ConsumerID)
+	assert.Equal(t, "required-node", remembered.ProviderID)
+	assert.Equal(t, "openvpn", remembered.ServiceType)
+	assert.True(t, remembered.ConnectOptions.EnableKillSwitch)
+}
+
+func TestCreateDoesNotRememberFailedConnection(t *testing.T) {
+	fakeManager := fakeManager{onConnectReturn: errors.New("session creation failed")}
+	lastConnection := &fakeLastConnectionKeeper{}
+
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, proposalProvider, nil, lastConnection)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node"
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Nil(t, lastConnection.remembered)
+}
+
+func TestKillForgetsConnectionEvenIfItIsLost(t *testing.T) {
+	fakeManager := fakeManager{onDisconnectReturn: connection.ErrNoConnection}
+	lastConnection := &fakeLastConnectionKeeper{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, &mockProposalProvider{}, nil, lastConnection)
+	req := httptest.NewRequest(http.MethodDelete, "/irrelevant", nil)
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Kill(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.True(t, lastConnection.forgotten)
+}
+
+func TestRestoreConnectionUnlocksConsumerAndConnects(t *testing.T) {
+	fakeManager := fakeManager{}
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	var unlocked identity.Identity
+	unlock := func(consumerID identity.Identity) error {
+		unlocked = consumerID
+		return nil
+	}
+
+	err := RestoreConnection(&fakeManager, proposalProvider, nil, unlock, json.RawMessage(
+		`{
+			"consumerId" : "my-identity",
+			"providerId" : "required-node",
+			"serviceType": "openvpn"
+		}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, identity.FromAddress("my-identity"), unlocked)
+	assert.Equal(t, identity.FromAddress("my-identity"), fakeManager.requestedConsumerID)
+	assert.Equal(t, identity.FromAddress("required-node"), fakeManager.requestedProvider)
+}
+
+func TestRestoreConnectionDoesNotConnectWhenUnlockFails(t *testing.T) {
+	fakeManager := fakeManager{}
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("required-node", "openvpn")
+	unlock := func(consumerID identity.Identity) error {
+		return errors.New("wrong passphrase")
+	}
+
+	err := RestoreConnection(&fakeManager, proposalProvider, nil, unlock, json.RawMessage(
+		`{
+			"consumerId" : "my-identity",
+			"providerId" : "required-node"
+		}`))
+
+	assert.EqualError(t, err, "wrong passphrase")
+	assert.Empty(t, fakeManager.dialedProviders)
+}
@@ -0,0 +1,288 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// ConnectionPool keeps concurrent connections of the node
+type ConnectionPool interface {
+	Connect(consumerID identity.Identity, proposal market.ServiceProposal, params connection.ConnectParams) (connection.ID, error)
+	Connections() map[connection.ID]connection.ConnectionStatus
+	Status(id connection.ID) (connection.ConnectionStatus, error)
+	Statistics(id connection.ID) (consumer.SessionStatistics, time.Duration, error)
+	Quota(id connection.ID) (connection.Quota, error)
+	Disconnect(id connection.ID) error
+}
+
+// swagger:model ConnectionDTO
+type connectionResponse struct {
+	// example: 9ee6e5a5-1d8c-4a6b-9a34-3a1a5e5a4a9f
+	ID string `json:"id"`
+
+	// example: Connected
+	Status string `json:"status"`
+
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// swagger:model ConnectionListDTO
+type connectionsResponse struct {
+	Connections []connectionResponse `json:"connections"`
+}
+
+// ConnectionsEndpoint struct represents /connections resource and it's subresources
+type ConnectionsEndpoint struct {
+	pool ConnectionPool
+	connector
+}
+
+// NewConnectionsEndpoint creates and returns connections endpoint
+func NewConnectionsEndpoint(pool ConnectionPool, proposalProvider ProposalProvider, proposalSelector ProposalSelector) *ConnectionsEndpoint {
+	return &ConnectionsEndpoint{
+		pool:      pool,
+		connector: newConnector(proposalProvider, proposalSelector),
+	}
+}
+
+// List returns all open connections
+// swagger:operation GET /connections Connection listConnections
+// ---
+// summary: Returns open connections
+// description: Returns all open connections including the default one managed through /connection
+// responses:
+//   200:
+//     description: List of connections
+//     schema:
+//       "$ref": "#/definitions/ConnectionListDTO"
+func (ce *ConnectionsEndpoint) List(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	connections := []connectionResponse{}
+	for id, status := range ce.pool.Connections() {
+		connections = append(connections, toConnectionResponse(id, status))
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ID < connections[j].ID
+	})
+
+	utils.WriteAsJSON(connectionsResponse{Connections: connections}, resp)
+}
+
+// Create starts new connection alongside the existing ones
+// swagger:operation POST /connections Connection addConnection
+// ---
+// summary: Starts additional connection
+// description: Consumer opens one more connection to given provider or to the best provider matching given criteria
+// parameters:
+//   - in: body
+//     name: body
+//     description: Parameters in body (consumerId, providerId, criteria or hops, serviceType) required for creating new connection
+//     schema:
+//       $ref: "#/definitions/ConnectionRequestDTO"
+// responses:
+//   201:
+//     description: Connection started
+//     schema:
+//       "$ref": "#/definitions/ConnectionDTO"
+//   400:
+//     description: Bad request, kill switch is not available for additional connections
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   404:
+//     description: No provider matches given criteria
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   499:
+//     description: Connection was cancelled
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   503:
+//     description: Provider doesn't take more sessions
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   504:
+//     description: Connect phase named in the message timed out
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) Create(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var id connection.ID
+	connect := func(consumerID identity.Identity, proposal market.ServiceProposal, params connection.ConnectParams) (err error) {
+		id, err = ce.pool.Connect(consumerID, proposal, params)
+		return err
+	}
+	if _, ok := ce.connect(resp, req, connect); !ok {
+		return
+	}
+
+	status, err := ce.pool.Status(id)
+	if err != nil {
+		// connection may be lost right after it was established
+		status = connection.ConnectionStatus{State: connection.NotConnected}
+	}
+	resp.WriteHeader(http.StatusCreated)
+	utils.WriteAsJSON(toConnectionResponse(id, status), resp)
+}
+
+// Status returns status of connection with given ID
+// swagger:operation GET /connections/{id} Connection getConnection
+// ---
+// summary: Returns connection status
+// description: Returns status of connection with given ID
+// parameters:
+//   - in: path
+//     name: id
+//     description: connection ID, "default" for the connection managed through /connection
+//     type: string
+//     required: true
+// responses:
+//   200:
+//     description: Status
+//     schema:
+//       "$ref": "#/definitions/ConnectionDTO"
+//   404:
+//     description: Connection not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) Status(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	id := connection.ID(params.ByName("id"))
+	status, err := ce.pool.Status(id)
+	if err != nil {
+		sendConnectionError(resp, err)
+		return
+	}
+
+	utils.WriteAsJSON(toConnectionResponse(id, status), resp)
+}
+
+// Kill stops connection with given ID
+// swagger:operation DELETE /connections/{id} Connection killConnectionByID
+// ---
+// summary: Stops connection
+// description: Stops connection with given ID
+// parameters:
+//   - in: path
+//     name: id
+//     description: connection ID
+//     type: string
+//     required: true
+// responses:
+//   202:
+//     description: Connection Stopped
+//   404:
+//     description: Connection not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) Kill(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	err := ce.pool.Disconnect(connection.ID(params.ByName("id")))
+	if err != nil {
+		sendConnectionError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// GetStatistics returns statistics of connection with given ID
+// swagger:operation GET /connections/{id}/statistics Connection getConnectionStatistics
+// ---
+// summary: Returns connection statistics
+// description: Returns statistics of connection with given ID
+// parameters:
+//   - in: path
+//     name: id
+//     description: connection ID
+//     type: string
+//     required: true
+// responses:
+//   200:
+//     description: Connection statistics
+//     schema:
+//       "$ref": "#/definitions/ConnectionStatisticsDTO"
+//   404:
+//     description: Connection not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) GetStatistics(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	id := connection.ID(params.ByName("id"))
+	statistics, duration, err := ce.pool.Statistics(id)
+	if err != nil {
+		sendConnectionError(resp, err)
+		return
+	}
+	quota, err := ce.pool.Quota(id)
+	if err != nil {
+		sendConnectionError(resp, err)
+		return
+	}
+
+	response := statisticsResponse{
+		BytesSent:     statistics.BytesSent,
+		BytesReceived: statistics.BytesReceived,
+		Duration:      int(duration.Seconds()),
+		Quota:         toQuotaResponse(quota),
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// AddRoutesForConnections adds routes of concurrent connections to given router
+func AddRoutesForConnections(router *httprouter.Router, pool ConnectionPool, proposalProvider ProposalProvider, proposalSelector ProposalSelector) {
+	connectionsEndpoint := NewConnectionsEndpoint(pool, proposalProvider, proposalSelector)
+	router.GET("/connections", connectionsEndpoint.List)
+	router.POST("/connections", connectionsEndpoint.Create)
+	router.GET("/connections/:id", connectionsEndpoint.Status)
+	router.DELETE("/connections/:id", connectionsEndpoint.Kill)
+	router.GET("/connections/:id/statistics", connectionsEndpoint.GetStatistics)
+}
+
+func sendConnectionError(resp http.ResponseWriter, err error) {
+	switch err {
+	case connection.ErrNoConnection:
+		utils.SendError(resp, err, http.StatusNotFound)
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
+
+func toConnectionResponse(id connection.ID, status connection.ConnectionStatus) connectionResponse {
+	return connectionResponse{
+		ID:        string(id),
+		Status:    string(status.State),
+		SessionID: string(status.SessionID),
+	}
+}
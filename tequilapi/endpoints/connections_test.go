@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConnectionPool struct {
+	onConnectReturn connection.ID
+	onConnectError  error
+	requestedParams connection.ConnectParams
+	connections     map[connection.ID]connection.ConnectionStatus
+	statistics      consumer.SessionStatistics
+	duration        time.Duration
+	quota           connection.Quota
+	disconnected    []connection.ID
+}
+
+func (fcp *fakeConnectionPool) Connect(consumerID identity.Identity, proposal market.ServiceProposal, params connection.ConnectParams) (connection.ID, error) {
+	fcp.requestedParams = params
+	if fcp.onConnectError != nil {
+		return connection.ID(""), fcp.onConnectError
+	}
+	fcp.connections[fcp.onConnectReturn] = connection.ConnectionStatus{State: connection.Connected, SessionID: "session-1"}
+	return fcp.onConnectReturn, nil
+}
+
+func (fcp *fakeConnectionPool) Connections() map[connection.ID]connection.ConnectionStatus {
+	return fcp.connections
+}
+
+func (fcp *fakeConnectionPool) Status(id connection.ID) (connection.ConnectionStatus, error) {
+	status, found := fcp.connections[id]
+	if !found {
+		return connection.ConnectionStatus{}, connection.ErrNoConnection
+	}
+	return status, nil
+}
+
+func (fcp *fakeConnectionPool) Statistics(id connection.ID) (consumer.SessionStatistics, time.Duration, error) {
+	if _, found := fcp.connections[id]; !found {
+		return consumer.SessionStatistics{}, 0, connection.ErrNoConnection
+	}
+	return fcp.statistics, fcp.duration, nil
+}
+
+func (fcp *fakeConnectionPool) Quota(id connection.ID) (connection.Quota, error) {
+	if _, found := fcp.connections[id]; !found {
+		return connection.Quota{}, connection.ErrNoConnection
+	}
+	return fcp.quota, nil
+}
+
+func (fcp *fakeConnectionPool) Disconnect(id connection.ID) error {
+	if _, found := fcp.connections[id]; !found {
+		return connection.ErrNoConnection
+	}
+	fcp.disconnected = append(fcp.disconnected, id)
+	return nil
+}
+
+func TestAddRoutesForConnectionsAddsRoutes(t *testing.T) {
+	router := httprouter.New()
+	pool := &fakeConnectionPool{
+		onConnectReturn: "connection-2",
+		connections: map[connection.ID]connection.ConnectionStatus{
+			connection.DefaultConnectionID: {State: connection.Connected, SessionID: "session-0"},
+		},
+		statistics: consumer.SessionStatistics{BytesSent: 20, BytesReceived: 10},
+		duration:   time.Minute,
+	}
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("node1", "noop")
+	AddRoutesForConnections(router, pool, proposalProvider, nil)
+
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{
+			http.MethodPost, "/connections", `{"consumerId": "me", "providerId": "node1", "serviceType": "noop"}`,
+			http.StatusCreated, `{"id": "connection-2", "status": "Connected", "sessionId": "session-1"}`,
+		},
+		{
+			http.MethodGet, "/connections", "",
+			http.StatusOK, `{"connections": [
+				{"id": "connection-2", "status": "Connected", "sessionId": "session-1"},
+				{"id": "default", "status": "Connected", "sessionId": "session-0"}
+			]}`,
+		},
+		{
+			http.MethodGet, "/connections/connection-2", "",
+			http.StatusOK, `{"id": "connection-2", "status": "Connected", "sessionId": "session-1"}`,
+		},
+		{
+			http.MethodGet, "/connections/connection-2/statistics", "",
+			http.StatusOK, `{"bytesSent": 20, "bytesReceived": 10, "duration": 60}`,
+		},
+		{
+			http.MethodDelete, "/connections/connection-2", "",
+			http.StatusAccepted, "",
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, test.expectedStatus, resp.Code)
+		if test.expectedJSON != "" {
+			assert.JSONEq(t, test.expectedJSON, resp.Body.String())
+		} else {
+			assert.Equal(t, "", resp.Body.String())
+		}
+	}
+	assert.Equal(t, []connection.ID{"connection-2"}, pool.disconnected)
+}
+
+func TestConnectionsReturn404ForUnknownConnection(t *testing.T) {
+	router := httprouter.New()
+	pool := &fakeConnectionPool{connections: map[connection.ID]connection.ConnectionStatus{}}
+	AddRoutesForConnections(router, pool, nil, nil)
+
+	for _, test := range []struct{ method, path string }{
+		{http.MethodGet, "/connections/unknown"},
+		{http.MethodGet, "/connections/unknown/statistics"},
+		{http.MethodDelete, "/connections/unknown"},
+	} {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+		assert.JSONEq(t, `{"message": "no connection exists"}`, resp.Body.String())
+	}
+}
+
+func TestConnectionsCreateReturns400WhenKillSwitchIsRequested(t *testing.T) {
+	pool := &fakeConnectionPool{
+		onConnectError: connection.ErrConcurrentKillSwitch,
+		connections:    map[connection.ID]connection.ConnectionStatus{},
+	}
+	proposalProvider := getMockProposalProviderWithSpecifiedProposal("node1", "openvpn")
+	endpoint := NewConnectionsEndpoint(pool, proposalProvider, nil)
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/connections",
+		strings.NewReader(`{"consumerId": "me", "providerId": "node1", "connectOptions": {"killSwitch": true}}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.True(t, pool.requestedParams.EnableKillSwitch)
+	assert.JSONEq(t, `{"message": "kill switch is available for the default connection only"}`, resp.Body.String())
+}
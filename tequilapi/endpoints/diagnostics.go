@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/diagnostics"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model DiagnosticsReportDTO
+type diagnosticsReportResponse struct {
+	// true if all checks are passed
+	// example: false
+	Passed bool `json:"passed"`
+
+	Checks []diagnosticsCheckResponse `json:"checks"`
+}
+
+// swagger:model DiagnosticsCheckDTO
+type diagnosticsCheckResponse struct {
+	// check name. Possible values are "public-ip", "dns", "ipv6" and "default-route"
+	// example: ipv6
+	Name string `json:"name"`
+
+	// example: false
+	Passed bool `json:"passed"`
+
+	// example: IPv6 traffic bypasses the tunnel via eth0
+	Message string `json:"message"`
+
+	// facts check was based on, e.g. detected addresses and interfaces
+	// example: {"interface": "eth0", "tunnelInterface": "tun+"}
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// Diagnostics checks established connection for leaks
+type Diagnostics interface {
+	Run() (diagnostics.Report, error)
+}
+
+type diagnosticsEndpoint struct {
+	diagnostics Diagnostics
+}
+
+// NewDiagnosticsEndpoint creates and returns diagnostics endpoint
+func NewDiagnosticsEndpoint(diagnostics Diagnostics) *diagnosticsEndpoint {
+	return &diagnosticsEndpoint{
+		diagnostics: diagnostics,
+	}
+}
+
+// Diagnose checks established connection for leaks
+// swagger:operation GET /connection/diagnostics Connection connectionDiagnostics
+// ---
+// summary: Checks connection for leaks
+// description: Checks if public IP changed, which DNS resolvers answer queries, whether IPv6 bypasses tunnel and if default route points at tunnel device
+// responses:
+//   200:
+//     description: Diagnostics report
+//     schema:
+//       "$ref": "#/definitions/DiagnosticsReportDTO"
+//   409:
+//     description: Conflict. No connection exists
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (de *diagnosticsEndpoint) Diagnose(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	report, err := de.diagnostics.Run()
+	switch err {
+	case nil:
+	case diagnostics.ErrNotConnected:
+		utils.SendError(resp, err, http.StatusConflict)
+		return
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteAsJSON(toDiagnosticsReportResponse(report), resp)
+}
+
+func toDiagnosticsReportResponse(report diagnostics.Report) diagnosticsReportResponse {
+	response := diagnosticsReportResponse{
+		Passed: report.Passed,
+		Checks: make([]diagnosticsCheckResponse, len(report.Results)),
+	}
+	for i, result := range report.Results {
+		response.Checks[i] = diagnosticsCheckResponse{
+			Name:    string(result.Check),
+			Passed:  result.Passed,
+			Message: result.Message,
+			Values:  result.Values,
+		}
+	}
+	return response
+}
+
+// AddRoutesForDiagnostics adds diagnostics routes to given router
+func AddRoutesForDiagnostics(router *httprouter.Router, diagnostics Diagnostics) {
+	diagnosticsEndpoint := NewDiagnosticsEndpoint(diagnostics)
+	router.GET("/connection/diagnostics", diagnosticsEndpoint.Diagnose)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/diagnostics"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDiagnostics struct {
+	report diagnostics.Report
+	err    error
+}
+
+func (fd *fakeDiagnostics) Run() (diagnostics.Report, error) {
+	return fd.report, fd.err
+}
+
+func TestDiagnoseReturnsReport(t *testing.T) {
+	endpoint := NewDiagnosticsEndpoint(&fakeDiagnostics{
+		report: diagnostics.Report{
+			Passed: false,
+			Results: []diagnostics.Result{
+				{
+					Check:   diagnostics.CheckPublicIP,
+					Passed:  true,
+					Message: "public IP changed",
+					Values:  map[string]string{"originalIP": "10.0.0.1", "publicIP": "20.0.0.1"},
+				},
+				{
+					Check:   diagnostics.CheckIPv6,
+					Passed:  false,
+					Message: "IPv6 traffic bypasses the tunnel via eth0",
+					Values:  map[string]string{"interface": "eth0"},
+				},
+			},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/connection/diagnostics", nil)
+	resp := httptest.NewRecorder()
+
+	endpoint.Diagnose(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"passed": false,
+			"checks": [
+				{
+					"name": "public-ip",
+					"passed": true,
+					"message": "public IP changed",
+					"values": {"originalIP": "10.0.0.1", "publicIP": "20.0.0.1"}
+				},
+				{
+					"name": "ipv6",
+					"passed": false,
+					"message": "IPv6 traffic bypasses the tunnel via eth0",
+					"values": {"interface": "eth0"}
+				}
+			]
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestDiagnoseReturnsConflictWhenNotConnected(t *testing.T) {
+	endpoint := NewDiagnosticsEndpoint(&fakeDiagnostics{err: diagnostics.ErrNotConnected})
+	req := httptest.NewRequest(http.MethodGet, "/connection/diagnostics", nil)
+	resp := httptest.NewRecorder()
+
+	endpoint.Diagnose(resp, req, nil)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.JSONEq(t, `{"message": "diagnostics require established connection"}`, resp.Body.String())
+}
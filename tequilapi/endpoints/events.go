@@ -0,0 +1,438 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/service"
+	identity_registry "github.com/mysteriumnetwork/node/identity/registry"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+const eventsLogPrefix = "[tequilapi-events] "
+
+// Types of events sent to the event stream
+const (
+	ConnectionStateEventType      = "connection-state"
+	ConnectionSessionEventType    = "connection-session"
+	ConnectionStatisticsEventType = "connection-statistics"
+	ConnectionLimitEventType      = "connection-limit"
+	IdentityRegistrationEventType = "identity-registration"
+	ServiceStatusEventType        = "service-status"
+	ServiceRestartEventType       = "service-restart"
+)
+
+const (
+	eventsHistorySize      = 100
+	eventsSubscriberBuffer = 100
+	eventsKeepAlivePeriod  = 15 * time.Second
+)
+
+// EventSubscriber subscribes handlers to topics of event bus
+type EventSubscriber interface {
+	Subscribe(topic string, fn interface{}) error
+}
+
+// ConnectionStateEventDTO is sent when consumer connection changes its state
+// swagger:model ConnectionStateEventDTO
+type ConnectionStateEventDTO struct {
+	// example: default
+	ConnectionID string `json:"connectionId"`
+
+	// example: Connected
+	State string `json:"state"`
+
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// ConnectionSessionEventDTO is sent when consumer session is created or ended
+// swagger:model ConnectionSessionEventDTO
+type ConnectionSessionEventDTO struct {
+	// example: default
+	ConnectionID string `json:"connectionId"`
+
+	// example: Created
+	Status string `json:"status"`
+
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ProviderID string `json:"providerId"`
+
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+}
+
+// ConnectionStatisticsEventDTO is sent when consumer connection reports traffic statistics
+// swagger:model ConnectionStatisticsEventDTO
+type ConnectionStatisticsEventDTO struct {
+	// example: 1024
+	BytesSent uint64 `json:"bytesSent"`
+
+	// example: 1024
+	BytesReceived uint64 `json:"bytesReceived"`
+}
+
+// ConnectionLimitEventDTO is sent when consumer connection gets close to or reaches one of its usage limits
+// swagger:model ConnectionLimitEventDTO
+type ConnectionLimitEventDTO struct {
+	// example: default
+	ConnectionID string `json:"connectionId"`
+
+	// Warning or Reached, connection is closed once limit is reached
+	// example: Warning
+	Status string `json:"status"`
+
+	// Bytes, Duration or Spend
+	// example: Bytes
+	Limit string `json:"limit"`
+
+	Quota *quotaResponse `json:"quota"`
+}
+
+// IdentityRegistrationEventDTO is sent when registration status of provider identity becomes known
+// swagger:model IdentityRegistrationEventDTO
+type IdentityRegistrationEventDTO struct {
+	// example: 0x0000000000000000000000000000000000000001
+	ID string `json:"id"`
+
+	// example: true
+	Registered bool `json:"registered"`
+}
+
+// ServiceStatusEventDTO is sent when provider service changes its lifecycle status
+// swagger:model ServiceStatusEventDTO
+type ServiceStatusEventDTO struct {
+	// example: 6ba7b810-9dad-11d1-80b4-00c04fd430c8
+	ServiceID string `json:"serviceId"`
+
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ProviderID string `json:"providerId"`
+
+	// example: Running
+	Status string `json:"status"`
+
+	// example: failed to start openvpn process
+	Error string `json:"error,omitempty"`
+}
+
+// ServiceRestartEventDTO is sent when provider service stopped by itself and is going to be restarted
+// swagger:model ServiceRestartEventDTO
+type ServiceRestartEventDTO struct {
+	// example: 6ba7b810-9dad-11d1-80b4-00c04fd430c8
+	ServiceID string `json:"serviceId"`
+
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ProviderID string `json:"providerId"`
+
+	// count of restarts within window of restart policy, including this one
+	// example: 1
+	Attempt int `json:"attempt"`
+
+	// delay in seconds before the restart
+	// example: 2
+	Delay float64 `json:"delay"`
+
+	// example: openvpn process exited
+	Error string `json:"error,omitempty"`
+}
+
+type streamEvent struct {
+	ID      uint64
+	Type    string
+	Payload interface{}
+}
+
+type eventsEndpoint struct {
+	mutex       sync.Mutex
+	lastID      uint64
+	history     []streamEvent
+	subscribers map[chan streamEvent]struct{}
+}
+
+// NewEventsEndpoint creates and returns events endpoint
+func NewEventsEndpoint() *eventsEndpoint {
+	return &eventsEndpoint{
+		history:     make([]streamEvent, 0, eventsHistorySize),
+		subscribers: make(map[chan streamEvent]struct{}),
+	}
+}
+
+// Stream sends node events to the client as they happen
+// swagger:operation GET /events Events streamEvents
+// ---
+// summary: Streams node events
+// description: Streams connection, session, statistics, limit, identity registration and service status events
+//   as Server-Sent Events. Each event has an ID, client can resume the stream by passing the last received ID
+//   in "Last-Event-ID" header or "lastEventId" query parameter, recent events after it are sent again.
+// parameters:
+//   - in: query
+//     name: lastEventId
+//     description: ID of the last received event
+//     type: integer
+// produces:
+//   - text/event-stream
+// responses:
+//   200:
+//     description: Stream of events
+//   400:
+//     description: Invalid last event ID
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *eventsEndpoint) Stream(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		utils.SendErrorMessage(resp, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID, err := parseLastEventID(request)
+	if err != nil {
+		utils.SendErrorMessage(resp, "Invalid last event ID", http.StatusBadRequest)
+		return
+	}
+
+	events, missed := endpoint.subscribe(lastEventID)
+	defer endpoint.unsubscribe(events)
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, event := range missed {
+		if err := writeStreamEvent(resp, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, more := <-events:
+			if !more {
+				// client was too slow, it has to resume the stream from the last received event
+				return
+			}
+			if err := writeStreamEvent(resp, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(resp, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-request.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// AddRoutesForEvents attaches events endpoint to router and subscribes it to event topics
+func AddRoutesForEvents(router *httprouter.Router, subscriber EventSubscriber) error {
+	eventsEndpoint := NewEventsEndpoint()
+	if err := eventsEndpoint.subscribeTo(subscriber); err != nil {
+		return err
+	}
+
+	router.GET("/events", eventsEndpoint.Stream)
+	return nil
+}
+
+func (endpoint *eventsEndpoint) subscribeTo(subscriber EventSubscriber) error {
+	handlers := map[string]interface{}{
+		connection.StateEventTopic:               endpoint.consumeStateEvent,
+		connection.SessionEventTopic:             endpoint.consumeSessionEvent,
+		connection.StatisticsEventTopic:          endpoint.consumeStatisticsEvent,
+		connection.LimitEventTopic:               endpoint.consumeLimitEvent,
+		identity_registry.RegistrationEventTopic: endpoint.consumeRegistrationEvent,
+		service.StatusEventTopic:                 endpoint.consumeServiceStatusEvent,
+		service.RestartEventTopic:                endpoint.consumeServiceRestartEvent,
+	}
+	for topic, handler := range handlers {
+		if err := subscriber.Subscribe(topic, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (endpoint *eventsEndpoint) consumeStateEvent(event connection.StateEvent) {
+	endpoint.broadcast(ConnectionStateEventType, ConnectionStateEventDTO{
+		ConnectionID: string(event.ConnectionID),
+		State:        string(event.State),
+		SessionID:    string(event.SessionInfo.SessionID),
+	})
+}
+
+func (endpoint *eventsEndpoint) consumeSessionEvent(event connection.SessionEvent) {
+	endpoint.broadcast(ConnectionSessionEventType, ConnectionSessionEventDTO{
+		ConnectionID: string(event.ConnectionID),
+		Status:       event.Status,
+		SessionID:    string(event.SessionInfo.SessionID),
+		ProviderID:   event.SessionInfo.Proposal.ProviderID,
+		ServiceType:  event.SessionInfo.Proposal.ServiceType,
+	})
+}
+
+func (endpoint *eventsEndpoint) consumeStatisticsEvent(stats consumer.SessionStatistics) {
+	endpoint.broadcast(ConnectionStatisticsEventType, ConnectionStatisticsEventDTO{
+		BytesSent:     stats.BytesSent,
+		BytesReceived: stats.BytesReceived,
+	})
+}
+
+func (endpoint *eventsEndpoint) consumeLimitEvent(event connection.LimitEvent) {
+	endpoint.broadcast(ConnectionLimitEventType, ConnectionLimitEventDTO{
+		ConnectionID: string(event.ConnectionID),
+		Status:       string(event.Status),
+		Limit:        string(event.Limit),
+		Quota:        toQuotaResponse(event.Quota),
+	})
+}
+
+func (endpoint *eventsEndpoint) consumeRegistrationEvent(event identity_registry.RegistrationStatusEvent) {
+	endpoint.broadcast(IdentityRegistrationEventType, IdentityRegistrationEventDTO{
+		ID:         event.ID.Address,
+		Registered: event.Registered,
+	})
+}
+
+func (endpoint *eventsEndpoint) consumeServiceStatusEvent(event service.StatusEvent) {
+	dto := ServiceStatusEventDTO{
+		ServiceID:   string(event.ServiceID),
+		ServiceType: event.ServiceType,
+		ProviderID:  event.ProviderID.Address,
+		Status:      string(event.Status),
+	}
+	if event.Error != nil {
+		dto.Error = event.Error.Error()
+	}
+	endpoint.broadcast(ServiceStatusEventType, dto)
+}
+
+func (endpoint *eventsEndpoint) consumeServiceRestartEvent(event service.RestartEvent) {
+	dto := ServiceRestartEventDTO{
+		ServiceID:   string(event.ServiceID),
+		ServiceType: event.ServiceType,
+		ProviderID:  event.ProviderID.Address,
+		Attempt:     event.Attempt,
+		Delay:       event.Delay.Seconds(),
+	}
+	if event.Error != nil {
+		dto.Error = event.Error.Error()
+	}
+	endpoint.broadcast(ServiceRestartEventType, dto)
+}
+
+func (endpoint *eventsEndpoint) broadcast(eventType string, payload interface{}) {
+	endpoint.mutex.Lock()
+	defer endpoint.mutex.Unlock()
+
+	endpoint.lastID++
+	event := streamEvent{ID: endpoint.lastID, Type: eventType, Payload: payload}
+
+	if len(endpoint.history) == eventsHistorySize {
+		endpoint.history = endpoint.history[1:]
+	}
+	endpoint.history = append(endpoint.history, event)
+
+	for subscriber := range endpoint.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			log.Warn(eventsLogPrefix, "Subscriber is not keeping up with events, closing its stream")
+			delete(endpoint.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// subscribe registers new subscriber and returns events which were published after the given event ID
+func (endpoint *eventsEndpoint) subscribe(lastEventID uint64) (chan streamEvent, []streamEvent) {
+	endpoint.mutex.Lock()
+	defer endpoint.mutex.Unlock()
+
+	var missed []streamEvent
+	if lastEventID > 0 {
+		for _, event := range endpoint.history {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	subscriber := make(chan streamEvent, eventsSubscriberBuffer)
+	endpoint.subscribers[subscriber] = struct{}{}
+	return subscriber, missed
+}
+
+func (endpoint *eventsEndpoint) unsubscribe(subscriber chan streamEvent) {
+	endpoint.mutex.Lock()
+	defer endpoint.mutex.Unlock()
+
+	if _, exists := endpoint.subscribers[subscriber]; exists {
+		delete(endpoint.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+func parseLastEventID(request *http.Request) (uint64, error) {
+	lastEventID := request.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = request.URL.Query().Get("lastEventId")
+	}
+	if lastEventID == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(lastEventID, 10, 64)
+}
+
+func writeStreamEvent(writer http.ResponseWriter, event streamEvent) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload)
+	return err
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+const streamWaitTimeout = 2 * time.Second
+
+// streamRecorder records a stream served in background and tells each time the stream flushes
+type streamRecorder struct {
+	recorder *httptest.ResponseRecorder
+	mutex    sync.Mutex
+	flushes  int
+	flushed  chan struct{}
+	cancel   context.CancelFunc
+	stopped  sync.WaitGroup
+}
+
+// startStream returns once the stream has subscribed to events and written the missed ones
+func startStream(t *testing.T, endpoint *eventsEndpoint, req *http.Request) *streamRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &streamRecorder{
+		recorder: httptest.NewRecorder(),
+		flushed:  make(chan struct{}, 1),
+		cancel:   cancel,
+	}
+	stream.stopped.Add(1)
+	go func() {
+		defer stream.stopped.Done()
+		endpoint.Stream(stream, req.WithContext(ctx), httprouter.Params{})
+	}()
+	if !stream.waitFor(func() bool { return stream.flushes >= 2 }) {
+		t.Fatal("stream did not start")
+	}
+	return stream
+}
+
+func (stream *streamRecorder) Header() http.Header {
+	return stream.recorder.Header()
+}
+
+func (stream *streamRecorder) Write(data []byte) (int, error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return stream.recorder.Write(data)
+}
+
+func (stream *streamRecorder) WriteHeader(code int) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.recorder.WriteHeader(code)
+}
+
+func (stream *streamRecorder) Flush() {
+	stream.mutex.Lock()
+	stream.recorder.Flush()
+	stream.flushes++
+	stream.mutex.Unlock()
+
+	select {
+	case stream.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func (stream *streamRecorder) waitFor(condition func() bool) bool {
+	timeout := time.After(streamWaitTimeout)
+	for {
+		stream.mutex.Lock()
+		done := condition()
+		stream.mutex.Unlock()
+		if done {
+			return true
+		}
+
+		select {
+		case <-stream.flushed:
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func (stream *streamRecorder) waitForEvents(t *testing.T, count int) {
+	received := func() bool {
+		return strings.Count(stream.recorder.Body.String(), "\n\n") >= count
+	}
+	if !stream.waitFor(received) {
+		t.Fatalf("stream did not receive %d events", count)
+	}
+}
+
+func (stream *streamRecorder) stop() {
+	stream.cancel()
+	stream.stopped.Wait()
+}
+
+func (stream *streamRecorder) code() int {
+	return stream.recorder.Code
+}
+
+func (stream *streamRecorder) body() string {
+	return stream.recorder.Body.String()
+}
+
+func TestEventsStreamSendsPublishedEvents(t *testing.T) {
+	bus := EventBus.New()
+	endpoint := NewEventsEndpoint()
+	assert.NoError(t, endpoint.subscribeTo(bus))
+
+	stream := startStream(t, endpoint, httptest.NewRequest(http.MethodGet, "/events", nil))
+	bus.Publish(connection.StateEventTopic, connection.StateEvent{
+		ConnectionID: connection.DefaultConnectionID,
+		State:        connection.Reconnecting,
+		SessionInfo:  connection.SessionInfo{SessionID: "session-1"},
+	})
+	bus.Publish(service.StatusEventTopic, service.StatusEvent{
+		ServiceID:   "service-1",
+		ServiceType: "openvpn",
+		ProviderID:  identity.FromAddress("0x1"),
+		Status:      service.NotRunning,
+		Error:       errors.New("port is taken"),
+	})
+	bus.Publish(service.RestartEventTopic, service.RestartEvent{
+		ServiceID:   "service-1",
+		ServiceType: "openvpn",
+		ProviderID:  identity.FromAddress("0x1"),
+		Attempt:     2,
+		Delay:       2 * time.Second,
+		Error:       errors.New("port is taken"),
+	})
+	stream.waitForEvents(t, 3)
+	stream.stop()
+
+	assert.Equal(t, http.StatusOK, stream.code())
+	assert.Equal(t, "text/event-stream", stream.Header().Get("Content-Type"))
+	assert.Equal(
+		t,
+		"id: 1\nevent: connection-state\ndata: {\"connectionId\":\"default\",\"state\":\"Reconnecting\",\"sessionId\":\"session-1\"}\n\n"+
+			"id: 2\nevent: service-status\ndata: {\"serviceId\":\"service-1\",\"serviceType\":\"openvpn\",\"providerId\":\"0x1\",\"status\":\"NotRunning\",\"error\":\"port is taken\"}\n\n"+
+			"id: 3\nevent: service-restart\ndata: {\"serviceId\":\"service-1\",\"serviceType\":\"openvpn\",\"providerId\":\"0x1\",\"attempt\":2,\"delay\":2,\"error\":\"port is taken\"}\n\n",
+		stream.body(),
+	)
+}
+
+func TestEventsStreamResumesAfterLastEventID(t *testing.T) {
+	endpoint := NewEventsEndpoint()
+	endpoint.broadcast(ConnectionStatisticsEventType, ConnectionStatisticsEventDTO{BytesSent: 1, BytesReceived: 2})
+	endpoint.broadcast(ConnectionStatisticsEventType, ConnectionStatisticsEventDTO{BytesSent: 3, BytesReceived: 4})
+	endpoint.broadcast(IdentityRegistrationEventType, IdentityRegistrationEventDTO{ID: "0x1", Registered: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	stream := startStream(t, endpoint, req)
+	stream.waitForEvents(t, 2)
+	stream.stop()
+
+	assert.Equal(
+		t,
+		"id: 2\nevent: connection-statistics\ndata: {\"bytesSent\":3,\"bytesReceived\":4}\n\n"+
+			"id: 3\nevent: identity-registration\ndata: {\"id\":\"0x1\",\"registered\":true}\n\n",
+		stream.body(),
+	)
+}
+
+func TestEventsStreamDoesNotResendEventsToNewClient(t *testing.T) {
+	endpoint := NewEventsEndpoint()
+	endpoint.broadcast(ConnectionStateEventType, ConnectionStateEventDTO{State: "Connected"})
+
+	stream := startStream(t, endpoint, httptest.NewRequest(http.MethodGet, "/events", nil))
+	stream.stop()
+
+	assert.Equal(t, http.StatusOK, stream.code())
+	assert.Empty(t, stream.body())
+}
+
+func TestEventsStreamReturnsBadRequestForInvalidLastEventID(t *testing.T) {
+	endpoint := NewEventsEndpoint()
+
+	resp := httptest.NewRecorder()
+	endpoint.Stream(resp, httptest.NewRequest(http.MethodGet, "/events?lastEventId=abc", nil), httprouter.Params{})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"message": "Invalid last event ID"}`, resp.Body.String())
+}
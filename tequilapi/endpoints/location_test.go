@@ -43,6 +43,10 @@ func (fm *fakeManagerForLocation) Status() connection.ConnectionStatus {
 	return fm.onStatusReturn
 }
 
+func (fm *fakeManagerForLocation) Quota() connection.Quota {
+	return connection.Quota{}
+}
+
 func (fm *fakeManagerForLocation) Disconnect() error {
 	return nil
 }
@@ -50,6 +50,17 @@ type serviceDefinitionRes struct {
 	LocationOriginate locationRes `json:"locationOriginate"`
 }
 
+// swagger:model EgressPolicyDTO
+type egressPolicyRes struct {
+	// destination ports blocked for both TCP and UDP
+	// example: [25]
+	BlockedPorts []int `json:"blockedPorts,omitempty"`
+
+	// destination networks blocked in CIDR notation
+	// example: ["10.0.0.0/8"]
+	BlockedNetworks []string `json:"blockedNetworks,omitempty"`
+}
+
 // swagger:model ProposalDTO
 type proposalRes struct {
 	// per provider unique serial number of service description provided
@@ -69,13 +80,21 @@ type proposalRes struct {
 
 	// Metrics of the service
 	Metrics json.RawMessage `json:"metrics,omitempty"`
+
+	// destinations which provider doesn't let consumers reach
+	EgressPolicy *egressPolicyRes `json:"egressPolicy,omitempty"`
+
+	// revision of proposal terms, it's increased each time provider updates proposal
+	// example: 2
+	Version int `json:"version,omitempty"`
 }
 
 func proposalToRes(p market.ServiceProposal) proposalRes {
-	return proposalRes{
+	res := proposalRes{
 		ID:          p.ID,
 		ProviderID:  p.ProviderID,
 		ServiceType: p.ServiceType,
+		Version:     p.Version,
 		ServiceDefinition: serviceDefinitionRes{
 			LocationOriginate: locationRes{
 				ASN:     p.ServiceDefinition.GetLocation().ASN,
@@ -84,6 +103,13 @@ func proposalToRes(p market.ServiceProposal) proposalRes {
 			},
 		},
 	}
+	if p.EgressPolicy != nil {
+		res.EgressPolicy = &egressPolicyRes{
+			BlockedPorts:    p.EgressPolicy.BlockedPorts,
+			BlockedNetworks: p.EgressPolicy.BlockedNetworks,
+		}
+	}
+	return res
 }
 
 func mapProposalsToRes(
@@ -219,6 +219,45 @@ func (m *mysteriumMorqaFake) ProposalsMetrics() []json.RawMessage {
 	return nil
 }
 
+func TestProposalsEndpointListShowsEgressPolicy(t *testing.T) {
+	proposal := serviceProposals[0]
+	proposal.EgressPolicy = &market.EgressPolicy{BlockedPorts: []int{25}, BlockedNetworks: []string{"10.0.0.0/8"}}
+	proposalProvider := &mockProposalProvider{
+		proposals: []market.ServiceProposal{proposal},
+	}
+	req, err := http.NewRequest(http.MethodGet, "/irrelevant", nil)
+	assert.Nil(t, err)
+
+	resp := httptest.NewRecorder()
+	handlerFunc := NewProposalsEndpoint(proposalProvider, &mysteriumMorqaFake{}).List
+	handlerFunc(resp, req, nil)
+
+	assert.JSONEq(
+		t,
+		`{
+			"proposals": [
+				{
+					"id": 1,
+					"providerId": "0xProviderId",
+					"serviceType": "testprotocol",
+					"serviceDefinition": {
+						"locationOriginate": {
+							"asn": "LT",
+							"country": "Lithuania",
+							"city": "Vilnius"
+						}
+					},
+					"egressPolicy": {
+						"blockedPorts": [25],
+						"blockedNetworks": ["10.0.0.0/8"]
+					}
+				}
+			]
+		}`,
+		resp.Body.String(),
+	)
+}
+
 type mockProposalProvider struct {
 	recordedProviderId  string
 	recordedServiceType string
@@ -0,0 +1,207 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// ServiceSessionsDTO defines list of provider's sessions representable as json
+// swagger:model ServiceSessionsDTO
+type ServiceSessionsDTO struct {
+	Sessions []ServiceSessionDTO `json:"sessions"`
+}
+
+// ServiceSessionDTO represents the session served by this node
+// swagger:model ServiceSessionDTO
+type ServiceSessionDTO struct {
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ConsumerID string `json:"consumerId"`
+
+	// example: wireguard
+	ServiceType string `json:"serviceType"`
+
+	// example: 2019-03-01T12:00:00Z
+	CreatedAt string `json:"createdAt"`
+
+	// example: 1024
+	BytesSent uint64 `json:"bytesSent"`
+
+	// example: 1024
+	BytesReceived uint64 `json:"bytesReceived"`
+
+	// example: 3
+	PromisesReceived int `json:"promisesReceived"`
+
+	// set only for sessions which have ended
+	// example: 2019-03-01T13:00:00Z
+	EndedAt string `json:"endedAt,omitempty"`
+}
+
+// ServiceSessionStorage lists sessions served by this node
+type ServiceSessionStorage interface {
+	GetAll() []session.Session
+}
+
+// ServiceSessionHistory lists totals of sessions, which this node has served
+type ServiceSessionHistory interface {
+	GetEnded() ([]session.EndedSession, error)
+}
+
+// ServiceSessionKiller ends sessions served by this node
+type ServiceSessionKiller interface {
+	Kill(id session.ID) error
+}
+
+type serviceSessionsEndpoint struct {
+	storage ServiceSessionStorage
+	history ServiceSessionHistory
+	killer  ServiceSessionKiller
+}
+
+// NewServiceSessionsEndpoint creates and returns endpoint of sessions served by this node
+func NewServiceSessionsEndpoint(storage ServiceSessionStorage, history ServiceSessionHistory, killer ServiceSessionKiller) *serviceSessionsEndpoint {
+	return &serviceSessionsEndpoint{
+		storage: storage,
+		history: history,
+		killer:  killer,
+	}
+}
+
+// swagger:operation GET /service-sessions ServiceSession listServiceSessions
+// ---
+// summary: Returns active service sessions
+// description: Returns list of sessions this node is currently serving to consumers
+// responses:
+//   200:
+//     description: List of service sessions
+//     schema:
+//       "$ref": "#/definitions/ServiceSessionsDTO"
+func (endpoint *serviceSessionsEndpoint) List(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	sessions := endpoint.storage.GetAll()
+	sessionsSerializable := ServiceSessionsDTO{Sessions: make([]ServiceSessionDTO, len(sessions))}
+	for i, sessionInstance := range sessions {
+		sessionsSerializable.Sessions[i] = toServiceSessionView(sessionInstance)
+	}
+	utils.WriteAsJSON(sessionsSerializable, resp)
+}
+
+// swagger:operation GET /service-sessions/history ServiceSession listEndedServiceSessions
+// ---
+// summary: Returns ended service sessions
+// description: Returns list of sessions this node has served, with traffic totals kept when each of them ended
+// responses:
+//   200:
+//     description: List of ended service sessions
+//     schema:
+//       "$ref": "#/definitions/ServiceSessionsDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *serviceSessionsEndpoint) History(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	history, err := endpoint.history.GetEnded()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	sessionsSerializable := ServiceSessionsDTO{Sessions: make([]ServiceSessionDTO, len(history))}
+	for i, record := range history {
+		sessionsSerializable.Sessions[i] = toEndedServiceSessionView(record)
+	}
+	utils.WriteAsJSON(sessionsSerializable, resp)
+}
+
+// swagger:operation DELETE /service-sessions/{id} ServiceSession killServiceSession
+// ---
+// summary: Ends service session
+// description: Notifies consumer, stops processing promises and frees resources of the session with given ID
+// parameters:
+//   - in: path
+//     name: id
+//     description: session ID
+//     type: string
+//     required: true
+// responses:
+//   202:
+//     description: Session ended
+//   404:
+//     description: Session not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *serviceSessionsEndpoint) Kill(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	err := endpoint.killer.Kill(session.ID(params.ByName("id")))
+	switch err {
+	case nil:
+		resp.WriteHeader(http.StatusAccepted)
+	case session.ErrorSessionNotExists:
+		utils.SendError(resp, err, http.StatusNotFound)
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
+
+// AddRoutesForServiceSessions attaches endpoints of sessions served by this node to router
+func AddRoutesForServiceSessions(router *httprouter.Router, storage ServiceSessionStorage, history ServiceSessionHistory, killer ServiceSessionKiller) {
+	serviceSessionsEndpoint := NewServiceSessionsEndpoint(storage, history, killer)
+	router.GET("/service-sessions", serviceSessionsEndpoint.List)
+	router.GET("/service-sessions/history", serviceSessionsEndpoint.History)
+	router.DELETE("/service-sessions/:id", serviceSessionsEndpoint.Kill)
+}
+
+func toServiceSessionView(sessionInstance session.Session) ServiceSessionDTO {
+	sessionView := ServiceSessionDTO{
+		SessionID:        string(sessionInstance.ID),
+		ConsumerID:       sessionInstance.ConsumerID.Address,
+		ServiceType:      sessionInstance.ServiceType,
+		CreatedAt:        sessionInstance.CreatedAt.Format(time.RFC3339),
+		PromisesReceived: sessionInstance.PromisesReceived(),
+	}
+	// session is listed even if its traffic can't be told at the moment
+	if transfer, err := sessionInstance.DataTransferred(); err == nil {
+		sessionView.BytesSent = transfer.BytesSent
+		sessionView.BytesReceived = transfer.BytesReceived
+	}
+	return sessionView
+}
+
+func toEndedServiceSessionView(record session.EndedSession) ServiceSessionDTO {
+	return ServiceSessionDTO{
+		SessionID:        string(record.ID),
+		ConsumerID:       record.ConsumerID.Address,
+		ServiceType:      record.ServiceType,
+		CreatedAt:        record.Created.Format(time.RFC3339),
+		BytesSent:        record.BytesSent,
+		BytesReceived:    record.BytesReceived,
+		PromisesReceived: record.PromisesReceived,
+		EndedAt:          record.Ended.Format(time.RFC3339),
+	}
+}
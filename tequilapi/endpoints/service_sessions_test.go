@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceSessions struct {
+	sessions   []session.Session
+	history    []session.EndedSession
+	historyErr error
+	killErr    error
+	killed     []session.ID
+}
+
+func (fss *fakeServiceSessions) GetAll() []session.Session {
+	return fss.sessions
+}
+
+func (fss *fakeServiceSessions) GetEnded() ([]session.EndedSession, error) {
+	return fss.history, fss.historyErr
+}
+
+func (fss *fakeServiceSessions) Kill(id session.ID) error {
+	fss.killed = append(fss.killed, id)
+	return fss.killErr
+}
+
+func TestServiceSessionsListReturnsActiveSessions(t *testing.T) {
+	sessions := &fakeServiceSessions{
+		sessions: []session.Session{{
+			ID:          "session-1",
+			ServiceType: "wireguard",
+			ConsumerID:  identity.FromAddress("0x1"),
+			CreatedAt:   time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, sessions, sessions, sessions)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/service-sessions", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"sessions": [{
+				"sessionId": "session-1",
+				"consumerId": "0x1",
+				"serviceType": "wireguard",
+				"createdAt": "2019-03-01T12:00:00Z",
+				"bytesSent": 0,
+				"bytesReceived": 0,
+				"promisesReceived": 0
+			}]
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestServiceSessionsHistoryReturnsEndedSessions(t *testing.T) {
+	sessions := &fakeServiceSessions{
+		history: []session.EndedSession{{
+			ID:               "session-1",
+			ServiceType:      "openvpn",
+			ConsumerID:       identity.FromAddress("0x1"),
+			Created:          time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+			Ended:            time.Date(2019, 3, 1, 13, 0, 0, 0, time.UTC),
+			BytesSent:        2048,
+			BytesReceived:    1024,
+			PromisesReceived: 3,
+		}},
+	}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, sessions, sessions, sessions)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/service-sessions/history", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"sessions": [{
+				"sessionId": "session-1",
+				"consumerId": "0x1",
+				"serviceType": "openvpn",
+				"createdAt": "2019-03-01T12:00:00Z",
+				"endedAt": "2019-03-01T13:00:00Z",
+				"bytesSent": 2048,
+				"bytesReceived": 1024,
+				"promisesReceived": 3
+			}]
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestServiceSessionsHistoryReturnsStorageError(t *testing.T) {
+	sessions := &fakeServiceSessions{historyErr: errors.New("boom")}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, sessions, sessions, sessions)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/service-sessions/history", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "boom"}`, resp.Body.String())
+}
+
+func TestServiceSessionsKillEndsSession(t *testing.T) {
+	sessions := &fakeServiceSessions{}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, sessions, sessions, sessions)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/service-sessions/session-1", nil))
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, []session.ID{"session-1"}, sessions.killed)
+}
+
+func TestServiceSessionsKillReturnsErrors(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{session.ErrorSessionNotExists, http.StatusNotFound, `{"message": "session does not exists"}`},
+		{errors.New("boom"), http.StatusInternalServerError, `{"message": "boom"}`},
+	}
+
+	for _, test := range tests {
+		sessions := &fakeServiceSessions{killErr: test.err}
+		router := httprouter.New()
+		AddRoutesForServiceSessions(router, sessions, sessions, sessions)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/service-sessions/unknown", nil))
+
+		assert.Equal(t, test.expectedStatus, resp.Code)
+		assert.JSONEq(t, test.expectedJSON, resp.Body.String())
+	}
+}
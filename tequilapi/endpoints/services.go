@@ -0,0 +1,507 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/shaper"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+// defaultEgressBlockedPorts are blocked when request doesn't tell otherwise, same as by default of CLI
+var defaultEgressBlockedPorts = []int{25}
+
+// defaultRestartPolicy is applied when request doesn't tell otherwise, same as by default of CLI
+var defaultRestartPolicy = service.RestartPolicy{
+	Mode:        service.RestartOnFailure,
+	MaxRestarts: 5,
+	Window:      10 * time.Minute,
+	Backoff:     time.Second,
+}
+
+// ServiceStartRequestDTO describes service, which should be started
+// swagger:model ServiceStartRequestDTO
+type ServiceStartRequestDTO struct {
+	// type of service to start
+	// required: true
+	// example: openvpn
+	Type string `json:"type"`
+
+	// identity to provide service with, the last used or a new one is taken if empty
+	// example: 0x0000000000000000000000000000000000000001
+	ProviderID string `json:"providerId"`
+
+	// passphrase of provider identity
+	Passphrase string `json:"passphrase"`
+
+	// options specific to the service type
+	// example: {"protocol": "udp", "port": 1194}
+	Options *json.RawMessage `json:"options,omitempty"`
+
+	// maximum count of concurrent sessions, 0 means unlimited
+	// example: 10
+	MaxSessions int `json:"maxSessions"`
+
+	// maximum count of concurrent sessions per consumer, 0 means unlimited
+	// example: 1
+	MaxSessionsPerConsumer int `json:"maxSessionsPerConsumer"`
+
+	// maximum count of sessions created per minute, 0 means unlimited
+	// example: 5
+	MaxNewSessionsPerMinute int `json:"maxNewSessionsPerMinute"`
+
+	// upload rate limit of each session in KB/s, 0 means unlimited
+	// example: 512
+	SessionUploadLimit uint `json:"sessionUploadLimit"`
+
+	// download rate limit of each session in KB/s, 0 means unlimited
+	// example: 1024
+	SessionDownloadLimit uint `json:"sessionDownloadLimit"`
+
+	// destination ports, which consumers are not allowed to reach, port 25 is blocked if not given
+	// example: [25, 465]
+	EgressBlockedPorts []int `json:"egressBlockedPorts"`
+
+	// destination networks, which consumers are not allowed to reach, private and link-local networks are always blocked
+	// example: ["203.0.113.0/24"]
+	EgressBlockedNetworks []string `json:"egressBlockedNetworks"`
+
+	// IPv4 addresses of DNS servers advertised to consumers, public system resolvers are advertised if not given
+	// example: ["1.1.1.1"]
+	DNS []string `json:"dns"`
+
+	// how service is restarted once it stops by itself, it's restarted on failure up to 5 times in 10 minutes if not given
+	RestartPolicy *RestartPolicyDTO `json:"restartPolicy,omitempty"`
+}
+
+// RestartPolicyDTO describes how service is restarted once it stops by itself
+// swagger:model RestartPolicyDTO
+type RestartPolicyDTO struct {
+	// never, on-failure or always
+	// required: true
+	// example: on-failure
+	Mode string `json:"mode"`
+
+	// maximum count of restarts within window, 0 means unlimited
+	// example: 5
+	MaxRestarts int `json:"maxRestarts"`
+
+	// period in seconds restarts are counted in, 0 means that all restarts are counted
+	// example: 600
+	Window int `json:"window"`
+
+	// delay in seconds before the restart, it's doubled for each restart within window
+	// example: 1
+	Backoff int `json:"backoff"`
+}
+
+// ServiceStartResponseDTO identifies service instance, which is being started
+// swagger:model ServiceStartResponseDTO
+type ServiceStartResponseDTO struct {
+	// example: 6ba7b810-9dad-11d1-80b4-00c04fd430c8
+	ID string `json:"id"`
+}
+
+// ServiceListDTO lists services running on this node
+// swagger:model ServiceListDTO
+type ServiceListDTO struct {
+	Services []ServiceInfoDTO `json:"services"`
+}
+
+// ServiceInfoDTO describes state of the service running on this node
+// swagger:model ServiceInfoDTO
+type ServiceInfoDTO struct {
+	// example: 6ba7b810-9dad-11d1-80b4-00c04fd430c8
+	ID string `json:"id"`
+
+	// example: openvpn
+	Type string `json:"type"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ProviderID string `json:"providerId"`
+
+	// stage of service lifecycle
+	// example: Running
+	Status string `json:"status"`
+
+	// stage of proposal registration in discovery
+	// example: PingProposal
+	DiscoveryStatus string `json:"discoveryStatus"`
+
+	// count of sessions service is serving
+	// example: 2
+	Sessions int `json:"sessions"`
+
+	// count of times service was restarted after it stopped by itself
+	// example: 1
+	Restarts int `json:"restarts"`
+
+	// proposal of the service, it's not known until service is started
+	Proposal *proposalRes `json:"proposal,omitempty"`
+}
+
+// ServiceRunner starts and stops services of this node
+type ServiceRunner interface {
+	Launch(serviceType string, options service.Options) (service.ID, error)
+	KillService(id service.ID) error
+	ServiceInfo(id service.ID) (service.Info, error)
+	Services() []service.Info
+	UpdateProposal(id service.ID, proposal market.ServiceProposal) (market.ServiceProposal, error)
+}
+
+type servicesEndpoint struct {
+	runner ServiceRunner
+}
+
+// NewServicesEndpoint creates and returns endpoint of services running on this node
+func NewServicesEndpoint(runner ServiceRunner) *servicesEndpoint {
+	return &servicesEndpoint{runner: runner}
+}
+
+// swagger:operation GET /services Service listServices
+// ---
+// summary: Returns running services
+// description: Returns list of services this node is running
+// responses:
+//   200:
+//     description: List of running services
+//     schema:
+//       "$ref": "#/definitions/ServiceListDTO"
+func (endpoint *servicesEndpoint) List(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	services := endpoint.runner.Services()
+	servicesSerializable := ServiceListDTO{Services: make([]ServiceInfoDTO, len(services))}
+	for i, info := range services {
+		servicesSerializable.Services[i] = toServiceInfoDTO(info)
+	}
+	utils.WriteAsJSON(servicesSerializable, resp)
+}
+
+// swagger:operation POST /services Service startService
+// ---
+// summary: Starts service
+// description: Starts a new instance of the service in background, progress of starting is told by service status.
+//   Multiple instances of the same service type can be run with different options
+// parameters:
+//   - in: body
+//     name: body
+//     description: Service to start
+//     schema:
+//       $ref: "#/definitions/ServiceStartRequestDTO"
+// responses:
+//   202:
+//     description: Service is starting
+//     schema:
+//       "$ref": "#/definitions/ServiceStartResponseDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+func (endpoint *servicesEndpoint) Start(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var request ServiceStartRequestDTO
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validateServiceStartRequest(request)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	id, err := endpoint.runner.Launch(request.Type, toServiceOptions(request))
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+	utils.WriteAsJSON(ServiceStartResponseDTO{ID: string(id)}, resp)
+}
+
+// swagger:operation GET /services/{id} Service getService
+// ---
+// summary: Returns service status
+// description: Returns state of the running service instance of given ID
+// parameters:
+//   - in: path
+//     name: id
+//     description: service instance ID
+//     type: string
+//     required: true
+// responses:
+//   200:
+//     description: Service status
+//     schema:
+//       "$ref": "#/definitions/ServiceInfoDTO"
+//   404:
+//     description: Service is not running
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *servicesEndpoint) Get(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	info, err := endpoint.runner.ServiceInfo(service.ID(params.ByName("id")))
+	if err != nil {
+		utils.SendError(resp, err, http.StatusNotFound)
+		return
+	}
+	utils.WriteAsJSON(toServiceInfoDTO(info), resp)
+}
+
+// swagger:operation DELETE /services/{id} Service stopService
+// ---
+// summary: Stops service
+// description: Unregisters proposal and stops the running service instance of given ID
+// parameters:
+//   - in: path
+//     name: id
+//     description: service instance ID
+//     type: string
+//     required: true
+// responses:
+//   202:
+//     description: Service is stopping
+//   404:
+//     description: Service is not running
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *servicesEndpoint) Stop(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	err := endpoint.runner.KillService(service.ID(params.ByName("id")))
+	switch err {
+	case nil:
+		resp.WriteHeader(http.StatusAccepted)
+	case service.ErrServiceNotRunning:
+		utils.SendError(resp, err, http.StatusNotFound)
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
+
+// swagger:operation PUT /services/{id}/proposal Service updateServiceProposal
+// ---
+// summary: Updates proposal of service
+// description: Replaces proposal of the running service instance of given ID and registers it in discovery again
+//   without restarting the service. Proposal version is increased, new sessions are created on terms of the updated
+//   proposal, while existing ones keep the terms they were created on. Proposal is given in the format of discovery,
+//   only its price and location are taken, other terms (e.g. bandwidth, egress policy) are enforced by the service.
+//   Service definition may leave them out, while changing them is refused with 400 Bad request.
+// parameters:
+//   - in: path
+//     name: id
+//     description: service instance ID
+//     type: string
+//     required: true
+//   - in: body
+//     name: body
+//     description: Proposal to offer service with
+//     schema:
+//       type: object
+// responses:
+//   200:
+//     description: Service status with updated proposal
+//     schema:
+//       "$ref": "#/definitions/ServiceInfoDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   404:
+//     description: Service is not running
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+func (endpoint *servicesEndpoint) UpdateProposal(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	var proposal market.ServiceProposal
+	if err := json.NewDecoder(req.Body).Decode(&proposal); err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validateServiceProposal(proposal)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	id := service.ID(params.ByName("id"))
+	_, err := endpoint.runner.UpdateProposal(id, proposal)
+	switch err {
+	case nil:
+	case service.ErrServiceNotRunning:
+		utils.SendError(resp, err, http.StatusNotFound)
+		return
+	default:
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	info, err := endpoint.runner.ServiceInfo(id)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusNotFound)
+		return
+	}
+	utils.WriteAsJSON(toServiceInfoDTO(info), resp)
+}
+
+// AddRoutesForServices attaches endpoints of services running on this node to router
+func AddRoutesForServices(router *httprouter.Router, runner ServiceRunner) {
+	servicesEndpoint := NewServicesEndpoint(runner)
+	router.GET("/services", servicesEndpoint.List)
+	router.POST("/services", servicesEndpoint.Start)
+	router.GET("/services/:id", servicesEndpoint.Get)
+	router.DELETE("/services/:id", servicesEndpoint.Stop)
+	router.PUT("/services/:id/proposal", servicesEndpoint.UpdateProposal)
+}
+
+func validateServiceStartRequest(request ServiceStartRequestDTO) *validation.FieldErrorMap {
+	errors := validation.NewErrorMap()
+	if request.Type == "" {
+		errors.ForField("type").AddError("required", "Field is required")
+	}
+	for field, value := range map[string]int{
+		"maxSessions":             request.MaxSessions,
+		"maxSessionsPerConsumer":  request.MaxSessionsPerConsumer,
+		"maxNewSessionsPerMinute": request.MaxNewSessionsPerMinute,
+	} {
+		if value < 0 {
+			errors.ForField(field).AddError("invalid", "Value can not be negative")
+		}
+	}
+	for _, port := range request.EgressBlockedPorts {
+		if port < 1 || port > 65535 {
+			errors.ForField("egressBlockedPorts").AddError("invalid", "Invalid port")
+		}
+	}
+	for _, network := range request.EgressBlockedNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			errors.ForField("egressBlockedNetworks").AddError("invalid", "Invalid network: "+network)
+		}
+	}
+	for _, server := range request.DNS {
+		if ip := net.ParseIP(server); ip == nil || ip.To4() == nil {
+			errors.ForField("dns").AddError("invalid", "Invalid DNS server: "+server)
+		}
+	}
+	if policy := request.RestartPolicy; policy != nil {
+		if !service.ValidRestartMode(service.RestartMode(policy.Mode)) {
+			errors.ForField("restartPolicy").AddError("invalid", "Invalid restart mode: "+policy.Mode)
+		}
+		if policy.MaxRestarts < 0 || policy.Window < 0 || policy.Backoff < 0 {
+			errors.ForField("restartPolicy").AddError("invalid", "Values can not be negative")
+		}
+	}
+	return errors
+}
+
+func validateServiceProposal(proposal market.ServiceProposal) *validation.FieldErrorMap {
+	errors := validation.NewErrorMap()
+	if proposal.ServiceType == "" {
+		errors.ForField("service_type").AddError("required", "Field is required")
+	}
+	if _, unsupported := proposal.ServiceDefinition.(market.UnsupportedServiceDefinition); unsupported {
+		errors.ForField("service_definition").AddError("invalid", "Unsupported service definition")
+	}
+	if _, unsupported := proposal.PaymentMethod.(market.UnsupportedPaymentMethod); unsupported {
+		errors.ForField("payment_method").AddError("invalid", "Unsupported payment method")
+	}
+	return errors
+}
+
+func toServiceOptions(request ServiceStartRequestDTO) service.Options {
+	ports := request.EgressBlockedPorts
+	if ports == nil {
+		ports = defaultEgressBlockedPorts
+	}
+	var networks []net.IPNet
+	for _, cidr := range request.EgressBlockedNetworks {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, *network)
+	}
+	var dns []net.IP
+	for _, server := range request.DNS {
+		dns = append(dns, net.ParseIP(server))
+	}
+
+	options := service.Options{
+		Identity:   request.ProviderID,
+		Passphrase: request.Passphrase,
+		Type:       request.Type,
+		Limits: session.Limits{
+			MaxSessions:             request.MaxSessions,
+			MaxSessionsPerConsumer:  request.MaxSessionsPerConsumer,
+			MaxNewSessionsPerMinute: request.MaxNewSessionsPerMinute,
+		},
+		Bandwidth: shaper.Limits{
+			Upload:   datasize.BitSize(request.SessionUploadLimit) * datasize.KB,
+			Download: datasize.BitSize(request.SessionDownloadLimit) * datasize.KB,
+		},
+		Egress:  firewall.NewEgressPolicy(ports, networks),
+		Restart: defaultRestartPolicy,
+		DNS:     dns,
+	}
+	if policy := request.RestartPolicy; policy != nil {
+		options.Restart = service.RestartPolicy{
+			Mode:        service.RestartMode(policy.Mode),
+			MaxRestarts: policy.MaxRestarts,
+			Window:      time.Duration(policy.Window) * time.Second,
+			Backoff:     time.Duration(policy.Backoff) * time.Second,
+		}
+	}
+	if request.Options != nil {
+		options.Options = *request.Options
+	}
+	return options
+}
+
+func toServiceInfoDTO(info service.Info) ServiceInfoDTO {
+	infoDTO := ServiceInfoDTO{
+		ID:              string(info.ID),
+		Type:            info.Type,
+		ProviderID:      info.ProviderID.Address,
+		Status:          string(info.Status),
+		DiscoveryStatus: info.Discovery.String(),
+		Sessions:        info.Sessions,
+		Restarts:        info.Restarts,
+	}
+	if info.Proposal.ServiceType != "" {
+		proposal := proposalToRes(info.Proposal)
+		infoDTO.Proposal = &proposal
+	}
+	return infoDTO
+}
@@ -0,0 +1,289 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/proposals/registry"
+	"github.com/mysteriumnetwork/node/services/noop"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceRunner struct {
+	services  []service.Info
+	launched  []service.Options
+	launchErr error
+	killed    []service.ID
+}
+
+func (runner *fakeServiceRunner) Launch(serviceType string, options service.Options) (service.ID, error) {
+	if runner.launchErr != nil {
+		return "", runner.launchErr
+	}
+	runner.launched = append(runner.launched, options)
+	return service.ID(fmt.Sprintf("service-%d", len(runner.launched))), nil
+}
+
+func (runner *fakeServiceRunner) KillService(id service.ID) error {
+	for _, info := range runner.services {
+		if info.ID == id {
+			runner.killed = append(runner.killed, id)
+			return nil
+		}
+	}
+	return service.ErrServiceNotRunning
+}
+
+func (runner *fakeServiceRunner) ServiceInfo(id service.ID) (service.Info, error) {
+	for _, info := range runner.services {
+		if info.ID == id {
+			return info, nil
+		}
+	}
+	return service.Info{}, service.ErrServiceNotRunning
+}
+
+func (runner *fakeServiceRunner) Services() []service.Info {
+	return runner.services
+}
+
+func (runner *fakeServiceRunner) UpdateProposal(id service.ID, proposal market.ServiceProposal) (market.ServiceProposal, error) {
+	for i, info := range runner.services {
+		if info.ID != id {
+			continue
+		}
+		if proposal.ServiceType != info.Proposal.ServiceType {
+			return market.ServiceProposal{}, service.ErrProposalServiceTypeMismatch
+		}
+		proposal, err := info.Proposal.WithEditableTerms(proposal)
+		if err != nil {
+			return market.ServiceProposal{}, err
+		}
+		proposal.Version = info.Proposal.Version + 1
+		runner.services[i].Proposal = proposal
+		return proposal, nil
+	}
+	return market.ServiceProposal{}, service.ErrServiceNotRunning
+}
+
+var runningService = service.Info{
+	ID:         "service-1",
+	Type:       "noop",
+	ProviderID: identity.FromAddress("0x1"),
+	Status:     service.Running,
+	Proposal: market.ServiceProposal{
+		ID:                1,
+		ServiceType:       "noop",
+		ProviderID:        "0x1",
+		ServiceDefinition: TestServiceDefinition{},
+	},
+	Discovery: registry.PingProposal,
+	Sessions:  2,
+	Restarts:  1,
+}
+
+const runningServiceJSON = `{
+	"id": "service-1",
+	"type": "noop",
+	"providerId": "0x1",
+	"status": "Running",
+	"discoveryStatus": "PingProposal",
+	"sessions": 2,
+	"restarts": 1,
+	"proposal": {
+		"id": 1,
+		"providerId": "0x1",
+		"serviceType": "noop",
+		"serviceDefinition": {
+			"locationOriginate": {"asn": "LT", "country": "Lithuania", "city": "Vilnius"}
+		}
+	}
+}`
+
+func serveServicesRequest(runner ServiceRunner, method, path, body string) *httptest.ResponseRecorder {
+	router := httprouter.New()
+	AddRoutesForServices(router, runner)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return resp
+}
+
+func TestServicesListReturnsRunningServices(t *testing.T) {
+	runner := &fakeServiceRunner{services: []service.Info{runningService}}
+
+	resp := serveServicesRequest(runner, http.MethodGet, "/services", "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"services": [`+runningServiceJSON+`]}`, resp.Body.String())
+}
+
+func TestServicesGetReturnsServiceStatus(t *testing.T) {
+	runner := &fakeServiceRunner{services: []service.Info{runningService}}
+
+	resp := serveServicesRequest(runner, http.MethodGet, "/services/service-1", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, runningServiceJSON, resp.Body.String())
+
+	resp = serveServicesRequest(runner, http.MethodGet, "/services/service-2", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"message": "service is not running"}`, resp.Body.String())
+}
+
+func TestServicesStartLaunchesServiceWithOptions(t *testing.T) {
+	runner := &fakeServiceRunner{}
+
+	resp := serveServicesRequest(runner, http.MethodPost, "/services", `{
+		"type": "openvpn",
+		"providerId": "0x1",
+		"options": {"protocol": "tcp"},
+		"maxSessions": 10,
+		"sessionDownloadLimit": 1024,
+		"egressBlockedNetworks": ["203.0.113.0/24"],
+		"dns": ["1.1.1.1"]
+	}`)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.JSONEq(t, `{"id": "service-1"}`, resp.Body.String())
+	assert.Len(t, runner.launched, 1)
+	options := runner.launched[0]
+	assert.Equal(t, "openvpn", options.Type)
+	assert.Equal(t, "0x1", options.Identity)
+	assert.Equal(t, session.Limits{MaxSessions: 10}, options.Limits)
+	assert.Equal(t, 1024*datasize.KB, options.Bandwidth.Download)
+	assert.Equal(t, []int{25}, options.Egress.BlockedPorts)
+	assert.Contains(t, options.Egress.Advertised().BlockedNetworks, "203.0.113.0/24")
+	assert.Contains(t, options.Egress.Advertised().BlockedNetworks, "192.168.0.0/16")
+	assert.JSONEq(t, `{"protocol": "tcp"}`, string(options.Options.(json.RawMessage)))
+	assert.Equal(t, defaultRestartPolicy, options.Restart)
+	assert.Equal(t, []net.IP{net.ParseIP("1.1.1.1")}, options.DNS)
+}
+
+func TestServicesStartTakesRestartPolicy(t *testing.T) {
+	runner := &fakeServiceRunner{}
+
+	resp := serveServicesRequest(runner, http.MethodPost, "/services", `{
+		"type": "openvpn",
+		"restartPolicy": {"mode": "always", "maxRestarts": 3, "window": 60, "backoff": 2}
+	}`)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(
+		t,
+		service.RestartPolicy{Mode: service.RestartAlways, MaxRestarts: 3, Window: time.Minute, Backoff: 2 * time.Second},
+		runner.launched[0].Restart,
+	)
+}
+
+func TestServicesStartReturnsErrors(t *testing.T) {
+	tests := []struct {
+		body           string
+		launchErr      error
+		expectedStatus int
+	}{
+		{`{"type": "unknown"}`, errors.New(`unknown service type "unknown"`), http.StatusBadRequest},
+		{`{"type": "noop", "maxSessions": -1}`, nil, http.StatusUnprocessableEntity},
+		{`{"type": "noop", "egressBlockedPorts": [70000]}`, nil, http.StatusUnprocessableEntity},
+		{`{"type": "noop", "egressBlockedNetworks": ["nonsense"]}`, nil, http.StatusUnprocessableEntity},
+		{`{"type": "noop", "dns": ["2001:db8::1"]}`, nil, http.StatusUnprocessableEntity},
+		{`{"type": "noop", "restartPolicy": {"mode": "sometimes"}}`, nil, http.StatusUnprocessableEntity},
+		{`{"type": "noop", "restartPolicy": {"mode": "always", "backoff": -1}}`, nil, http.StatusUnprocessableEntity},
+		{`{}`, nil, http.StatusUnprocessableEntity},
+		{`not json`, nil, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		runner := &fakeServiceRunner{launchErr: test.launchErr}
+
+		resp := serveServicesRequest(runner, http.MethodPost, "/services", test.body)
+
+		assert.Equal(t, test.expectedStatus, resp.Code, test.body)
+	}
+}
+
+func TestServicesStopKillsRunningService(t *testing.T) {
+	runner := &fakeServiceRunner{services: []service.Info{runningService}}
+
+	resp := serveServicesRequest(runner, http.MethodDelete, "/services/service-1", "")
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, []service.ID{"service-1"}, runner.killed)
+
+	resp = serveServicesRequest(runner, http.MethodDelete, "/services/service-2", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestServicesUpdateProposalReplacesEditableTermsOfRunningService(t *testing.T) {
+	noop.Bootstrap()
+	runner := &fakeServiceRunner{services: []service.Info{runningService}}
+	proposal := noop.GetProposal("DE")
+	proposal.EgressPolicy = &market.EgressPolicy{BlockedPorts: []int{25}}
+	body, _ := json.Marshal(proposal)
+
+	resp := serveServicesRequest(runner, http.MethodPut, "/services/service-1/proposal", string(body))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var info ServiceInfoDTO
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &info))
+	assert.Equal(t, 1, info.Proposal.ID)
+	assert.Equal(t, 1, info.Proposal.Version)
+	assert.Equal(t, "DE", info.Proposal.ServiceDefinition.LocationOriginate.Country)
+	assert.Nil(t, info.Proposal.EgressPolicy)
+}
+
+func TestServicesUpdateProposalReturnsErrors(t *testing.T) {
+	noop.Bootstrap()
+	proposal, _ := json.Marshal(noop.GetProposal("DE"))
+	tests := []struct {
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"/services/service-1/proposal", `{`, http.StatusBadRequest},
+		{"/services/service-1/proposal", `{}`, http.StatusUnprocessableEntity},
+		{"/services/service-1/proposal", `{"service_type": "unknown"}`, http.StatusUnprocessableEntity},
+		{"/services/service-2/proposal", string(proposal), http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		runner := &fakeServiceRunner{services: []service.Info{runningService}}
+		resp := serveServicesRequest(runner, http.MethodPut, test.path, test.body)
+
+		assert.Equal(t, test.expectedStatus, resp.Code, test.body)
+		assert.Equal(t, runningService, runner.services[0])
+	}
+
+	runner := &fakeServiceRunner{services: []service.Info{{ID: "service-1", Proposal: market.ServiceProposal{ServiceType: "openvpn"}}}}
+	resp := serveServicesRequest(runner, http.MethodPut, "/services/service-1/proposal", string(proposal))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"message": "proposal is of other service type"}`, resp.Body.String())
+}
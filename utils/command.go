@@ -41,3 +41,14 @@ func SudoExec(args ...string) error {
 	}
 	return nil
 }
+
+// SudoExecWithInput executes external command with a sudo privileges and given standard input.
+// It returns an combined stderr and stdout output and exit code in case of error.
+func SudoExecWithInput(input string, args ...string) error {
+	cmd := exec.Command("sudo", args...)
+	cmd.Stdin = strings.NewReader(input)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("'sudo %v': %v output: %s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}